@@ -0,0 +1,416 @@
+package xlsx
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tealeg/xlsx/v3/formula"
+)
+
+// refAdjustment describes how the rows or columns of a sheet are
+// about to move, so that references to them elsewhere in the workbook
+// can follow.
+type refAdjustment struct {
+	// formula adjusts a formula that lives on the sheet named home.
+	formula func(f, home string) (string, error)
+	// ref adjusts a reference to the sheet, returning false if
+	// nothing it referred to remains.
+	ref func(ref formula.Ref) (formula.Ref, bool)
+	// origin maps a zero based row and column after the move to
+	// the row and column they were at before it.
+	origin func(row, col int) (int, int)
+}
+
+// rowAdjustment returns the refAdjustment for n rows being inserted
+// on the sheet s before row at, or for -n rows being removed from row
+// at onwards.
+func rowAdjustment(s *Sheet, at, n int) refAdjustment {
+	return refAdjustment{
+		formula: func(f, home string) (string, error) {
+			return formula.AdjustForInsertedRowsFrom(f, home, s.Name, at, n)
+		},
+		ref: func(ref formula.Ref) (formula.Ref, bool) {
+			return ref.InsertRows(at, n)
+		},
+		origin: func(row, col int) (int, int) {
+			if row >= at {
+				row -= n
+			}
+			return row, col
+		},
+	}
+}
+
+// colAdjustment returns the refAdjustment for n columns being
+// inserted on the sheet s before column at, or for -n columns being
+// removed from column at onwards.
+func colAdjustment(s *Sheet, at, n int) refAdjustment {
+	return refAdjustment{
+		formula: func(f, home string) (string, error) {
+			return formula.AdjustForInsertedColsFrom(f, home, s.Name, at, n)
+		},
+		ref: func(ref formula.Ref) (formula.Ref, bool) {
+			return ref.InsertCols(at, n)
+		},
+		origin: func(row, col int) (int, int) {
+			if col >= at {
+				col -= n
+			}
+			return row, col
+		},
+	}
+}
+
+// renameAdjustment returns the refAdjustment for the sheet called
+// oldName being renamed newName, which leaves every row and column
+// where it is.
+func renameAdjustment(oldName, newName string) refAdjustment {
+	return refAdjustment{
+		formula: func(f, home string) (string, error) {
+			return formula.RenameSheetInFormula(f, oldName, newName)
+		},
+		ref: func(ref formula.Ref) (formula.Ref, bool) {
+			return ref, true
+		},
+		origin: func(row, col int) (int, int) {
+			return row, col
+		},
+	}
+}
+
+// adjustReferences updates every formula, merged range, conditional
+// format, data validation, protected range, page break, auto filter,
+// table, picture and chart anchor, chart series, sparkline and
+// defined name in the workbook that refers to s, before its rows or
+// columns are moved as described by adj.
+func (s *Sheet) adjustReferences(adj refAdjustment) error {
+	sheets := []*Sheet{s}
+	if s.File != nil {
+		sheets = s.File.Sheets
+	}
+	for _, sheet := range sheets {
+		err := sheet.adjustCellReferences(adj, sheet == s)
+		if err != nil {
+			return err
+		}
+		for _, dv := range sheet.DataValidations {
+			adj.dataValidationFormulas(dv, sheet.Name)
+		}
+		for _, chart := range sheet.Charts {
+			adj.chartSeries(chart)
+		}
+		for _, group := range sheet.SparklineGroups {
+			adj.sparklines(group, sheet == s)
+		}
+	}
+
+	var formats []*ConditionalFormat
+	for _, cf := range s.ConditionalFormats {
+		if adj.conditionalFormat(cf, s.Name) {
+			formats = append(formats, cf)
+		}
+	}
+	s.ConditionalFormats = formats
+
+	var validations []*xlsxDataValidation
+	for _, dv := range s.DataValidations {
+		dv.Sqref = adj.sqref(dv.Sqref)
+		if dv.Sqref != "" {
+			validations = append(validations, dv)
+		}
+	}
+	s.DataValidations = validations
+
+	var protectedRanges []*ProtectedRange
+	for _, r := range s.ProtectedRanges {
+		r.Ref = adj.sqref(r.Ref)
+		if r.Ref != "" {
+			protectedRanges = append(protectedRanges, r)
+		}
+	}
+	s.ProtectedRanges = protectedRanges
+	adj.pageBreaks(s)
+
+	if s.AutoFilter != nil {
+		ref, err := formula.ParseRef(s.AutoFilter.TopLeftCell + ":" + s.AutoFilter.BottomRightCell)
+		if err == nil {
+			ref, ok := adj.ref(ref)
+			if !ok {
+				s.AutoFilter = nil
+			} else {
+				bounds := strings.Split(ref.String(), ":")
+				s.AutoFilter = &AutoFilter{TopLeftCell: bounds[0], BottomRightCell: bounds[1]}
+			}
+		}
+	}
+
+	var tables []*Table
+	for _, t := range s.Tables {
+		if adj.table(t) {
+			tables = append(tables, t)
+		}
+	}
+	s.Tables = tables
+
+	for _, picture := range s.Pictures {
+		adj.anchor(&picture.Anchor)
+	}
+	for _, chart := range s.Charts {
+		adj.anchor(&chart.Anchor)
+	}
+
+	if s.File != nil {
+		for _, dn := range s.File.DefinedNames {
+			// Only sheet qualified references in defined names
+			// refer to a particular sheet.
+			if refersTo, err := adj.formula(dn.RefersTo, ""); err == nil {
+				dn.RefersTo = refersTo
+			}
+		}
+	}
+	return nil
+}
+
+// adjustCellReferences adjusts the formulas of the cells of s, and,
+// if s is the sheet whose rows or columns move, its merged ranges and
+// the ranges of its array formulas.
+func (s *Sheet) adjustCellReferences(adj refAdjustment, moving bool) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("adjustCellReferences: %w", err)
+	}
+
+	type merge struct {
+		row, col       int
+		hMerge, vMerge int
+	}
+	var merges []merge
+	// The merged ranges of the Sheet, where they are once the rows
+	// or columns have moved.
+	merged := make(map[mergeOrigin]*Range)
+
+	err := s.ForEachRow(func(r *Row) error {
+		for _, cell := range r.cells {
+			if cell == nil {
+				continue
+			}
+			if cell.formula != "" {
+				// A formula we can't parse is left as it is
+				if f, err := adj.formula(cell.formula, s.Name); err == nil {
+					cell.formula = f
+				}
+			}
+			if cell.DataValidation != nil {
+				adj.dataValidationFormulas(cell.DataValidation, s.Name)
+			}
+			if moving && cell.arrayRef != "" {
+				if ref, err := formula.ParseRef(cell.arrayRef); err == nil {
+					if ref, ok := adj.ref(ref); ok {
+						cell.arrayRef = ref.String()
+					}
+				}
+			}
+			if moving && (cell.HMerge > 0 || cell.VMerge > 0) {
+				ref := formula.Ref{
+					Col1: cell.num, Row1: r.num,
+					Col2: cell.num + cell.HMerge, Row2: r.num + cell.VMerge,
+					IsArea: true,
+				}
+				cell.HMerge, cell.VMerge = 0, 0
+				if ref, ok := adj.ref(ref); ok {
+					// If the top left cell of the range is
+					// removed the next cell that remains
+					// becomes the top left.
+					row, col := adj.origin(ref.Row1, ref.Col1)
+					merges = append(merges, merge{row, col, ref.Col2 - ref.Col1, ref.Row2 - ref.Row1})
+					if ref.Col2 > ref.Col1 || ref.Row2 > ref.Row1 {
+						merged[mergeOrigin{ref.Row1, ref.Col1}] = s.mergedRange(ref.Row1, ref.Col1, ref.Col2-ref.Col1, ref.Row2-ref.Row1)
+					}
+				}
+			}
+		}
+		return nil
+	}, SkipEmptyRows)
+	if err != nil {
+		return wrap(err)
+	}
+
+	for _, m := range merges {
+		if m.hMerge == 0 && m.vMerge == 0 {
+			continue
+		}
+		cell, err := s.Cell(m.row, m.col)
+		if err != nil {
+			return wrap(err)
+		}
+		cell.Merge(m.hMerge, m.vMerge)
+	}
+	if moving {
+		s.merged = merged
+	}
+	return nil
+}
+
+// dataValidationFormulas adjusts the formulas of a data validation
+// that lives on the sheet named home.  Formulas that can't be parsed,
+// such as literal lists, are left as they are.
+func (adj refAdjustment) dataValidationFormulas(dv *xlsxDataValidation, home string) {
+	if f, err := adj.formula(dv.Formula1, home); err == nil {
+		dv.Formula1 = f
+	}
+	if dv.Formula2 != "" {
+		if f, err := adj.formula(dv.Formula2, home); err == nil {
+			dv.Formula2 = f
+		}
+	}
+}
+
+// chartSeries adjusts the references of the series of a chart.
+// References to cells that are all removed are left as they are.
+func (adj refAdjustment) chartSeries(chart *Chart) {
+	for i := range chart.Spec.Series {
+		ser := &chart.Spec.Series[i]
+		for _, ref := range []*string{&ser.NameRef, &ser.Categories, &ser.Values} {
+			if *ref == "" {
+				continue
+			}
+			if f, err := adj.formula(*ref, ""); err == nil && !strings.Contains(f, "#REF!") {
+				*ref = f
+			}
+		}
+	}
+}
+
+// conditionalFormat adjusts the range of cf, and the formulas of its
+// rules, which live on the sheet named home.  It returns false if none
+// of the cells of the range are left.
+func (adj refAdjustment) conditionalFormat(cf *ConditionalFormat, home string) bool {
+	cf.Range = adj.sqref(cf.Range)
+	if cf.Range == "" {
+		return false
+	}
+	for _, rule := range cf.Rules {
+		for i, f := range rule.Formulas {
+			if f, err := adj.formula(f, home); err == nil {
+				rule.Formulas[i] = f
+			}
+		}
+	}
+	return true
+}
+
+// sparklines adjusts the data of the sparklines of group and, if the
+// group is on the sheet whose rows or columns are moved, their
+// locations.  Sparklines whose cells are removed are dropped.
+func (adj refAdjustment) sparklines(group *SparklineGroup, moveLocations bool) {
+	var sparklines []Sparkline
+	for _, sparkline := range group.Sparklines {
+		if f, err := adj.formula(sparkline.Data, ""); err == nil && !strings.Contains(f, "#REF!") {
+			sparkline.Data = f
+		}
+		if moveLocations {
+			sparkline.Location = adj.sqref(sparkline.Location)
+			if sparkline.Location == "" {
+				continue
+			}
+		}
+		sparklines = append(sparklines, sparkline)
+	}
+	group.Sparklines = sparklines
+}
+
+// table adjusts the range of t.  Columns inserted within the Table
+// become new columns of it, named "Column1" and so on, and the columns
+// removed from it are dropped.  It returns false if none of the Table
+// is left.
+func (adj refAdjustment) table(t *Table) bool {
+	old, err := formula.ParseRef(t.Range)
+	if err != nil {
+		return true
+	}
+	ref, ok := adj.ref(old)
+	if !ok {
+		return false
+	}
+	if ref.Col2-ref.Col1 != old.Col2-old.Col1 {
+		// An inserted column comes from the same place as the
+		// column to its left.
+		var columns []TableColumn
+		last := -1
+		for col := ref.Col1; col <= ref.Col2; col++ {
+			_, origin := adj.origin(ref.Row1, col)
+			if origin > last && origin >= old.Col1 && origin <= old.Col2 && origin-old.Col1 < len(t.Options.Columns) {
+				columns = append(columns, t.Options.Columns[origin-old.Col1])
+				last = origin
+			} else {
+				columns = append(columns, TableColumn{})
+			}
+		}
+		t.Options.Columns = columns
+		t.nameColumns()
+	}
+	t.Range = ref.String()
+	return true
+}
+
+// nameInsertedTableColumns writes the names of the columns of the
+// Tables of s to the header cells left empty by the columns inserted
+// into them.  It is called once the columns have moved.
+func (s *Sheet) nameInsertedTableColumns() error {
+	for _, t := range s.Tables {
+		if t.Options.NoHeaderRow {
+			continue
+		}
+		ref, err := formula.ParseRef(t.Range)
+		if err != nil {
+			continue
+		}
+		for i, col := range t.Options.Columns {
+			cell, err := s.Cell(ref.Row1, ref.Col1+i)
+			if err != nil {
+				return err
+			}
+			if cell.Value == "" && cell.formula == "" {
+				cell.SetString(col.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// anchor moves the cells that a one or two cell anchor is tied to.
+// An anchor whose cells are all removed is left where it is.
+func (adj refAdjustment) anchor(a *Anchor) {
+	if a.Type == AbsoluteAnchor {
+		return
+	}
+	ref := formula.Ref{Col1: a.Col, Row1: a.Row, Col2: a.Col, Row2: a.Row}
+	hasTo := a.Type == TwoCellAnchor && (a.ToCol != 0 || a.ToRow != 0 || a.ToOffsetX != 0 || a.ToOffsetY != 0)
+	if hasTo {
+		ref.Col2, ref.Row2, ref.IsArea = a.ToCol, a.ToRow, true
+	}
+	ref, ok := adj.ref(ref)
+	if !ok {
+		return
+	}
+	a.Col, a.Row = ref.Col1, ref.Row1
+	if hasTo {
+		a.ToCol, a.ToRow = ref.Col2, ref.Row2
+	}
+}
+
+// sqref adjusts a space separated list of references, as used by
+// data validations, dropping those that no longer refer to anything.
+func (adj refAdjustment) sqref(sqref string) string {
+	var refs []string
+	for _, s := range strings.Fields(sqref) {
+		ref, err := formula.ParseRef(s)
+		if err != nil {
+			refs = append(refs, s)
+			continue
+		}
+		if ref, ok := adj.ref(ref); ok {
+			refs = append(refs, ref.String())
+		}
+	}
+	return strings.Join(refs, " ")
+}
@@ -0,0 +1,368 @@
+package xlsx
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestAdjustReferencesForRows(t *testing.T) {
+	c := qt.New(t)
+
+	setUp := func(c *qt.C, option FileOption) (*File, *Sheet, *Sheet) {
+		f := NewFile(option)
+		data, err := f.AddSheet("Data")
+		c.Assert(err, qt.IsNil)
+		summary, err := f.AddSheet("Summary")
+		c.Assert(err, qt.IsNil)
+		setCells(c, data, map[string]interface{}{
+			"A1": 1, "A2": 2, "A3": 3, "A4": 4, "A5": 5,
+			"A6": "=SUM(A1:A5)",
+			"B1": "=A3*2",
+			"B2": "=$A$5",
+			"C2": "merged",
+			"F4": "link",
+		})
+		cellAt(c, data, "C2").Merge(0, 2)
+		cellAt(c, data, "F4").SetHyperlink("http://example.com", "Example", "")
+		dv := NewDataValidation(0, 3, 0, 4, true)
+		dv.Sqref = "D2:D5 E1"
+		dv.Formula1 = "$A$1:$A$5"
+		data.AddDataValidation(dv)
+		data.AutoFilter = &AutoFilter{TopLeftCell: "A1", BottomRightCell: "A5"}
+		setCells(c, summary, map[string]interface{}{
+			"A1": "=Data!A6",
+			"A2": "=SUM(Data!A:A)",
+			"A3": "=A4+A5",
+			"A7": "=[1]Data!A5",
+		})
+		f.DefinedNames = append(f.DefinedNames,
+			&DefinedName{Name: "Total", RefersTo: "Data!$A$6"},
+			&DefinedName{Name: "Other", RefersTo: "Summary!$A$4"},
+		)
+		return f, data, summary
+	}
+
+	formulaAt := func(c *qt.C, sheet *Sheet, ref string) string {
+		return cellAt(c, sheet, ref).Formula()
+	}
+
+	csRunO(c, "InsertRow", func(c *qt.C, option FileOption) {
+		f, data, summary := setUp(c, option)
+		_, err := data.AddRowAtIndex(2)
+		c.Assert(err, qt.IsNil)
+
+		c.Assert(formulaAt(c, data, "A7"), qt.Equals, "SUM(A1:A6)")
+		c.Assert(formulaAt(c, data, "B1"), qt.Equals, "A4*2")
+		c.Assert(formulaAt(c, data, "B2"), qt.Equals, "$A$6")
+		c.Assert(cellAt(c, data, "C2").VMerge, qt.Equals, 3)
+		c.Assert(cellAt(c, data, "F5").Hyperlink.Link, qt.Equals, "http://example.com")
+		c.Assert(data.DataValidations[0].Sqref, qt.Equals, "D2:D6 E1")
+		c.Assert(data.DataValidations[0].Formula1, qt.Equals, "$A$1:$A$6")
+		c.Assert(*data.AutoFilter, qt.Equals, AutoFilter{TopLeftCell: "A1", BottomRightCell: "A6"})
+
+		c.Assert(formulaAt(c, summary, "A1"), qt.Equals, "Data!A7")
+		c.Assert(formulaAt(c, summary, "A2"), qt.Equals, "SUM(Data!A:A)")
+		c.Assert(formulaAt(c, summary, "A3"), qt.Equals, "A4+A5")
+		c.Assert(formulaAt(c, summary, "A7"), qt.Equals, "[1]Data!A5")
+		c.Assert(f.DefinedNames[0].RefersTo, qt.Equals, "Data!$A$7")
+		c.Assert(f.DefinedNames[1].RefersTo, qt.Equals, "Summary!$A$4")
+
+		// The totals are still right
+		err = f.Recalculate()
+		c.Assert(err, qt.IsNil)
+		c.Assert(cellAt(c, summary, "A1").Value, qt.Equals, "15")
+	})
+
+	csRunO(c, "RemoveRow", func(c *qt.C, option FileOption) {
+		f, data, summary := setUp(c, option)
+		err := data.RemoveRowAtIndex(2)
+		c.Assert(err, qt.IsNil)
+
+		c.Assert(formulaAt(c, data, "A5"), qt.Equals, "SUM(A1:A4)")
+		c.Assert(formulaAt(c, data, "B1"), qt.Equals, "#REF!*2")
+		c.Assert(formulaAt(c, data, "B2"), qt.Equals, "$A$4")
+		c.Assert(cellAt(c, data, "C2").VMerge, qt.Equals, 1)
+		c.Assert(cellAt(c, data, "F3").Hyperlink.Link, qt.Equals, "http://example.com")
+		c.Assert(data.DataValidations[0].Sqref, qt.Equals, "D2:D4 E1")
+		c.Assert(*data.AutoFilter, qt.Equals, AutoFilter{TopLeftCell: "A1", BottomRightCell: "A4"})
+
+		c.Assert(formulaAt(c, summary, "A1"), qt.Equals, "Data!A5")
+		c.Assert(f.DefinedNames[0].RefersTo, qt.Equals, "Data!$A$5")
+
+		err = f.Recalculate()
+		c.Assert(err, qt.IsNil)
+		c.Assert(cellAt(c, summary, "A1").Value, qt.Equals, "12")
+	})
+
+	csRunO(c, "RemoveTopLeftOfMergedRange", func(c *qt.C, option FileOption) {
+		_, data, _ := setUp(c, option)
+		err := data.RemoveRowAtIndex(1)
+		c.Assert(err, qt.IsNil)
+
+		// C2:C4 becomes C2:C3, anchored at what was C3
+		cell := cellAt(c, data, "C2")
+		c.Assert(cell.VMerge, qt.Equals, 1)
+		c.Assert(cell.Value, qt.Equals, "")
+	})
+
+	csRunO(c, "RemoveEverythingAValidationCovers", func(c *qt.C, option FileOption) {
+		_, data, _ := setUp(c, option)
+		data.DataValidations[0].Sqref = "D3"
+		err := data.RemoveRowAtIndex(2)
+		c.Assert(err, qt.IsNil)
+		c.Assert(data.DataValidations, qt.HasLen, 0)
+	})
+}
+
+func TestAdjustReferencesForCols(t *testing.T) {
+	c := qt.New(t)
+
+	setUp := func(c *qt.C, option FileOption) (*File, *Sheet, *Sheet) {
+		f := NewFile(option)
+		data, err := f.AddSheet("Data")
+		c.Assert(err, qt.IsNil)
+		summary, err := f.AddSheet("Summary")
+		c.Assert(err, qt.IsNil)
+		setCells(c, data, map[string]interface{}{
+			"A1": 1, "B1": 2, "C1": 3, "D1": 4,
+			"E1": "=SUM(A1:D1)",
+			"A2": "=$C$1*2",
+			"B3": "merged",
+			"D4": "link",
+		})
+		cellAt(c, data, "B3").Merge(2, 0)
+		cellAt(c, data, "D4").SetHyperlink("http://example.com", "Example", "")
+		dv := NewDataValidation(0, 3, 0, 4, true)
+		dv.Sqref = "B5:D5"
+		data.AddDataValidation(dv)
+		data.AutoFilter = &AutoFilter{TopLeftCell: "A1", BottomRightCell: "D1"}
+		col := NewColForRange(2, 4)
+		col.SetWidth(20)
+		data.SetColParameters(col)
+		setCells(c, summary, map[string]interface{}{
+			"A1": "=Data!E1",
+			"B1": "=C1",
+		})
+		f.DefinedNames = append(f.DefinedNames,
+			&DefinedName{Name: "Third", RefersTo: "Data!$C$1"},
+		)
+		return f, data, summary
+	}
+
+	csRunO(c, "InsertCols", func(c *qt.C, option FileOption) {
+		f, data, summary := setUp(c, option)
+		// Insert two columns before C
+		err := data.InsertColsAt(2, 2)
+		c.Assert(err, qt.IsNil)
+
+		c.Assert(cellAt(c, data, "E1").Value, qt.Equals, "3")
+		c.Assert(cellAt(c, data, "C1").Value, qt.Equals, "")
+		c.Assert(cellAt(c, data, "G1").Formula(), qt.Equals, "SUM(A1:F1)")
+		c.Assert(cellAt(c, data, "A2").Formula(), qt.Equals, "$E$1*2")
+		c.Assert(cellAt(c, data, "B3").HMerge, qt.Equals, 4)
+		c.Assert(cellAt(c, data, "F4").Hyperlink.Link, qt.Equals, "http://example.com")
+		c.Assert(data.DataValidations[0].Sqref, qt.Equals, "B5:F5")
+		c.Assert(*data.AutoFilter, qt.Equals, AutoFilter{TopLeftCell: "A1", BottomRightCell: "F1"})
+		// B keeps its width, the new columns have none, D:E become F:G
+		c.Assert(*data.Col(1).Width, qt.Equals, 20.0)
+		c.Assert(data.Col(2), qt.IsNil)
+		c.Assert(data.Col(3), qt.IsNil)
+		c.Assert(*data.Col(5).Width, qt.Equals, 20.0)
+
+		c.Assert(cellAt(c, summary, "A1").Formula(), qt.Equals, "Data!G1")
+		c.Assert(cellAt(c, summary, "B1").Formula(), qt.Equals, "C1")
+		c.Assert(f.DefinedNames[0].RefersTo, qt.Equals, "Data!$E$1")
+
+		err = f.Recalculate()
+		c.Assert(err, qt.IsNil)
+		c.Assert(cellAt(c, summary, "A1").Value, qt.Equals, "10")
+	})
+
+	csRunO(c, "RemoveCols", func(c *qt.C, option FileOption) {
+		f, data, summary := setUp(c, option)
+		// Remove column C
+		err := data.RemoveColsAt(2, 1)
+		c.Assert(err, qt.IsNil)
+
+		c.Assert(cellAt(c, data, "C1").Value, qt.Equals, "4")
+		c.Assert(cellAt(c, data, "D1").Formula(), qt.Equals, "SUM(A1:C1)")
+		c.Assert(cellAt(c, data, "A2").Formula(), qt.Equals, "#REF!*2")
+		c.Assert(cellAt(c, data, "B3").HMerge, qt.Equals, 1)
+		c.Assert(cellAt(c, data, "C4").Hyperlink.Link, qt.Equals, "http://example.com")
+		c.Assert(data.DataValidations[0].Sqref, qt.Equals, "B5:C5")
+		c.Assert(*data.AutoFilter, qt.Equals, AutoFilter{TopLeftCell: "A1", BottomRightCell: "C1"})
+		c.Assert(data.Col(1).Max, qt.Equals, 3)
+
+		c.Assert(cellAt(c, summary, "A1").Formula(), qt.Equals, "Data!D1")
+		c.Assert(f.DefinedNames[0].RefersTo, qt.Equals, "Data!#REF!")
+
+		err = f.Recalculate()
+		c.Assert(err, qt.IsNil)
+		c.Assert(cellAt(c, summary, "A1").Value, qt.Equals, "7")
+	})
+
+	c.Run("OutOfRange", func(c *qt.C) {
+		f := NewFile()
+		sheet, _ := f.AddSheet("Sheet1")
+		c.Assert(sheet.InsertColsAt(-1, 1), qt.ErrorMatches, "InsertColsAt: index out of bounds")
+		c.Assert(sheet.InsertColsAt(0, 0), qt.ErrorMatches, "InsertColsAt: index out of bounds")
+		c.Assert(sheet.RemoveColsAt(16384, 1), qt.ErrorMatches, "RemoveColsAt: index out of range: 16384")
+	})
+}
+
+// TestAdjustReferencesOfSheetParts checks that what is anchored to, or
+// refers to, cells of a Sheet follows them when rows or columns are
+// inserted before them.
+func TestAdjustReferencesOfSheetParts(t *testing.T) {
+	c := qt.New(t)
+
+	for _, test := range []struct {
+		name  string
+		setUp func(c *qt.C, data *Sheet)
+		// rows checks data after a row is inserted before row 2
+		rows func(c *qt.C, data *Sheet)
+		// cols checks data after a column is inserted before column B
+		cols func(c *qt.C, data *Sheet)
+	}{{
+		name: "ArrayFormula",
+		setUp: func(c *qt.C, data *Sheet) {
+			err := cellAt(c, data, "B2").SetArrayFormula("A2:A4*2", "B2:B4")
+			c.Assert(err, qt.IsNil)
+		},
+		rows: func(c *qt.C, data *Sheet) {
+			c.Assert(cellAt(c, data, "B3").ArrayFormulaRef(), qt.Equals, "B3:B5")
+			c.Assert(cellAt(c, data, "B3").Formula(), qt.Equals, "A3:A5*2")
+		},
+		cols: func(c *qt.C, data *Sheet) {
+			c.Assert(cellAt(c, data, "C2").ArrayFormulaRef(), qt.Equals, "C2:C4")
+			c.Assert(cellAt(c, data, "C2").Formula(), qt.Equals, "A2:A4*2")
+		},
+	}, {
+		name: "Chart",
+		setUp: func(c *qt.C, data *Sheet) {
+			_, err := data.AddChart(ChartSpec{
+				Type:   ChartTypeColumn,
+				Series: []ChartSeries{{NameRef: "B1", Categories: "A2:A4", Values: "B2:B4"}},
+			}, Anchor{Type: TwoCellAnchor, Col: 3, Row: 2})
+			c.Assert(err, qt.IsNil)
+		},
+		rows: func(c *qt.C, data *Sheet) {
+			series := data.Charts[0].Spec.Series[0]
+			c.Assert(series.NameRef, qt.Equals, "Data!$B$1")
+			c.Assert(series.Categories, qt.Equals, "Data!$A$3:$A$5")
+			c.Assert(series.Values, qt.Equals, "Data!$B$3:$B$5")
+			c.Assert(data.Charts[0].Anchor.Row, qt.Equals, 3)
+		},
+		cols: func(c *qt.C, data *Sheet) {
+			series := data.Charts[0].Spec.Series[0]
+			c.Assert(series.NameRef, qt.Equals, "Data!$C$1")
+			c.Assert(series.Categories, qt.Equals, "Data!$A$2:$A$4")
+			c.Assert(series.Values, qt.Equals, "Data!$C$2:$C$4")
+			c.Assert(data.Charts[0].Anchor.Col, qt.Equals, 4)
+		},
+	}, {
+		name: "ConditionalFormat",
+		setUp: func(c *qt.C, data *Sheet) {
+			_, err := data.AddConditionalFormat("B2:B6",
+				&ConditionalFormatRule{Type: ConditionalFormatCellIs, Operator: ConditionalFormatBetween, Formulas: []string{"30", "$D$2"}},
+				&ConditionalFormatRule{Type: ConditionalFormatExpression, Formulas: []string{"$B2>$C2"}},
+			)
+			c.Assert(err, qt.IsNil)
+		},
+		rows: func(c *qt.C, data *Sheet) {
+			cf := data.ConditionalFormats[0]
+			c.Assert(cf.Range, qt.Equals, "B3:B7")
+			c.Assert(cf.Rules[0].Formulas, qt.DeepEquals, []string{"30", "$D$3"})
+			c.Assert(cf.Rules[1].Formulas, qt.DeepEquals, []string{"$B3>$C3"})
+		},
+		cols: func(c *qt.C, data *Sheet) {
+			cf := data.ConditionalFormats[0]
+			c.Assert(cf.Range, qt.Equals, "C2:C6")
+			c.Assert(cf.Rules[0].Formulas, qt.DeepEquals, []string{"30", "$E$2"})
+			c.Assert(cf.Rules[1].Formulas, qt.DeepEquals, []string{"$C2>$D2"})
+		},
+	}, {
+		name: "Picture",
+		setUp: func(c *qt.C, data *Sheet) {
+			png := testImage(c, PictureFormatPNG, 10, 10)
+			_, err := data.AddPicture(png, PictureFormatPNG, Anchor{Col: 2, Row: 2, OffsetY: 5})
+			c.Assert(err, qt.IsNil)
+			_, err = data.AddPicture(png, PictureFormatPNG, Anchor{Type: TwoCellAnchor, Col: 0, Row: 0, ToCol: 2, ToRow: 3})
+			c.Assert(err, qt.IsNil)
+			_, err = data.AddPicture(png, PictureFormatPNG, Anchor{Type: AbsoluteAnchor, OffsetX: 300, OffsetY: 10})
+			c.Assert(err, qt.IsNil)
+		},
+		rows: func(c *qt.C, data *Sheet) {
+			c.Assert(data.Pictures[0].Anchor, qt.Equals, Anchor{Col: 2, Row: 3, OffsetY: 5})
+			c.Assert(data.Pictures[1].Anchor, qt.Equals, Anchor{Type: TwoCellAnchor, Col: 0, Row: 0, ToCol: 2, ToRow: 4})
+			c.Assert(data.Pictures[2].Anchor, qt.Equals, Anchor{Type: AbsoluteAnchor, OffsetX: 300, OffsetY: 10})
+		},
+		cols: func(c *qt.C, data *Sheet) {
+			c.Assert(data.Pictures[0].Anchor, qt.Equals, Anchor{Col: 3, Row: 2, OffsetY: 5})
+			c.Assert(data.Pictures[1].Anchor, qt.Equals, Anchor{Type: TwoCellAnchor, Col: 0, Row: 0, ToCol: 3, ToRow: 3})
+			c.Assert(data.Pictures[2].Anchor, qt.Equals, Anchor{Type: AbsoluteAnchor, OffsetX: 300, OffsetY: 10})
+		},
+	}, {
+		name: "Sparkline",
+		setUp: func(c *qt.C, data *Sheet) {
+			_, err := data.AddSparklineGroup(SparklineLine, "B2:E3", "F2:F3", SparklineOptions{})
+			c.Assert(err, qt.IsNil)
+		},
+		rows: func(c *qt.C, data *Sheet) {
+			c.Assert(data.SparklineGroups[0].Sparklines, qt.DeepEquals, []Sparkline{
+				{Data: "Data!$B$3:$E$3", Location: "F3"},
+				{Data: "Data!$B$4:$E$4", Location: "F4"},
+			})
+		},
+		cols: func(c *qt.C, data *Sheet) {
+			c.Assert(data.SparklineGroups[0].Sparklines, qt.DeepEquals, []Sparkline{
+				{Data: "Data!$C$2:$F$2", Location: "G2"},
+				{Data: "Data!$C$3:$F$3", Location: "G3"},
+			})
+		},
+	}, {
+		name: "Table",
+		setUp: func(c *qt.C, data *Sheet) {
+			setCells(c, data, map[string]interface{}{"A1": "Item", "B1": "Qty", "C1": "Price"})
+			_, err := data.AddTable("A1:C4", TableOptions{})
+			c.Assert(err, qt.IsNil)
+		},
+		rows: func(c *qt.C, data *Sheet) {
+			c.Assert(data.Tables[0].Range, qt.Equals, "A1:C5")
+		},
+		cols: func(c *qt.C, data *Sheet) {
+			c.Assert(data.Tables[0].Range, qt.Equals, "A1:D4")
+		},
+	}} {
+		test := test
+		c.Run(test.name, func(c *qt.C) {
+			setUp := func(c *qt.C, option FileOption) *Sheet {
+				f := NewFile(option)
+				data, err := f.AddSheet("Data")
+				c.Assert(err, qt.IsNil)
+				for row := 0; row < 6; row++ {
+					for col := 0; col < 6; col++ {
+						cell, err := data.Cell(row, col)
+						c.Assert(err, qt.IsNil)
+						cell.SetInt(row*6 + col)
+					}
+				}
+				test.setUp(c, data)
+				return data
+			}
+			csRunO(c, "InsertRow", func(c *qt.C, option FileOption) {
+				data := setUp(c, option)
+				_, err := data.AddRowAtIndex(1)
+				c.Assert(err, qt.IsNil)
+				test.rows(c, data)
+			})
+			csRunO(c, "InsertCol", func(c *qt.C, option FileOption) {
+				data := setUp(c, option)
+				err := data.InsertColsAt(1, 1)
+				c.Assert(err, qt.IsNil)
+				test.cols(c, data)
+			})
+		})
+	}
+}
@@ -0,0 +1,407 @@
+package xlsx
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/tealeg/xlsx/v3/formula"
+)
+
+// fontMetrics holds the advance widths, in thousandths of an em, of
+// the printable ASCII characters of a font, from the space to the
+// tilde.
+type fontMetrics struct {
+	widths [95]uint16
+	// bold is how much wider the bold face is than the regular.
+	bold float64
+}
+
+// width returns the advance width of r, in ems.  Characters outside
+// the table are as wide as an "n", or, if they are East Asian wide
+// characters, a whole em.
+func (m *fontMetrics) width(r rune) float64 {
+	switch {
+	case r >= ' ' && r <= '~':
+		return float64(m.widths[r-' ']) / 1000
+	case isWideRune(r):
+		return 1
+	case unicode.Is(unicode.Mn, r) || unicode.IsControl(r):
+		return 0
+	}
+	return float64(m.widths['n'-' ']) / 1000
+}
+
+// isWideRune reports whether r is one of the East Asian characters
+// that take up a whole em.
+func isWideRune(r rune) bool {
+	return r >= 0x1100 && r <= 0x115F ||
+		r >= 0x2E80 && r <= 0xA4CF ||
+		r >= 0xAC00 && r <= 0xD7A3 ||
+		r >= 0xF900 && r <= 0xFAFF ||
+		r >= 0xFE30 && r <= 0xFE4F ||
+		r >= 0xFF00 && r <= 0xFF60 ||
+		r >= 0xFFE0 && r <= 0xFFE6
+}
+
+var (
+	calibriMetrics = &fontMetrics{
+		widths: [95]uint16{
+			226, 326, 401, 498, 507, 715, 682, 221, 303, 303, 498, 498, 250, 306, 252, 386, // space to /
+			507, 507, 507, 507, 507, 507, 507, 507, 507, 507, // 0 to 9
+			268, 268, 498, 498, 498, 463, 894, // : to @
+			579, 544, 533, 615, 488, 459, 631, 623, 252, 319, 520, 420, 855,
+			646, 662, 517, 673, 543, 459, 487, 642, 567, 890, 519, 487, 468, // A to Z
+			307, 386, 307, 498, 498, 291, // [ to `
+			479, 525, 423, 525, 498, 305, 471, 525, 230, 239, 455, 230, 799,
+			525, 527, 525, 525, 349, 391, 335, 525, 452, 715, 433, 453, 395, // a to z
+			314, 460, 314, 498, // { to ~
+		},
+		bold: 1.05,
+	}
+	arialMetrics = &fontMetrics{
+		widths: [95]uint16{
+			278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+			556, 556, 556, 556, 556, 556, 556, 556, 556, 556,
+			278, 278, 584, 584, 584, 556, 1015,
+			667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833,
+			722, 778, 667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611,
+			278, 278, 278, 469, 556, 333,
+			556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833,
+			556, 556, 556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500,
+			334, 260, 334, 584,
+		},
+		bold: 1.07,
+	}
+	verdanaMetrics = &fontMetrics{
+		widths: [95]uint16{
+			352, 394, 459, 818, 636, 1076, 727, 269, 454, 454, 636, 818, 364, 454, 364, 454,
+			636, 636, 636, 636, 636, 636, 636, 636, 636, 636,
+			454, 454, 818, 818, 818, 545, 1000,
+			684, 686, 698, 771, 632, 575, 775, 751, 421, 455, 693, 557, 843,
+			748, 787, 603, 787, 695, 684, 616, 732, 684, 989, 685, 615, 685,
+			454, 454, 454, 818, 636, 636,
+			601, 623, 521, 623, 596, 352, 623, 633, 274, 344, 592, 274, 973,
+			633, 607, 623, 623, 427, 521, 394, 633, 592, 818, 592, 592, 525,
+			635, 454, 635, 818,
+		},
+		bold: 1.12,
+	}
+	timesMetrics = &fontMetrics{
+		widths: [95]uint16{
+			250, 333, 408, 500, 500, 833, 778, 180, 333, 333, 500, 564, 250, 333, 250, 278,
+			500, 500, 500, 500, 500, 500, 500, 500, 500, 500,
+			278, 278, 564, 564, 564, 444, 921,
+			722, 667, 667, 722, 611, 556, 722, 722, 333, 389, 722, 611, 889,
+			722, 722, 556, 722, 667, 556, 611, 722, 722, 944, 722, 722, 611,
+			333, 278, 333, 469, 500, 333,
+			444, 500, 444, 500, 444, 333, 500, 500, 278, 278, 500, 278, 778,
+			500, 500, 500, 500, 333, 389, 278, 500, 500, 722, 500, 500, 444,
+			480, 200, 480, 541,
+		},
+		bold: 1.05,
+	}
+	courierMetrics = &fontMetrics{
+		widths: [95]uint16{
+			600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600,
+			600, 600, 600, 600, 600, 600, 600, 600, 600, 600,
+			600, 600, 600, 600, 600, 600, 600,
+			600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600,
+			600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600,
+			600, 600, 600, 600, 600, 600,
+			600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600,
+			600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600,
+			600, 600, 600, 600,
+		},
+		bold: 1,
+	}
+)
+
+// metricsForFont returns the metrics of the font called name, or of
+// Calibri, Excel's own default, if it isn't one that we know.
+func metricsForFont(name string) *fontMetrics {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "arial", "helvetica", "arial narrow":
+		return arialMetrics
+	case "verdana", "tahoma":
+		return verdanaMetrics
+	case "times new roman", "times":
+		return timesMetrics
+	case "courier new", "courier", "consolas":
+		return courierMetrics
+	}
+	return calibriMetrics
+}
+
+// measureText returns the width, in pixels, of text in the font called
+// name, of size points.
+func measureText(text, name string, size float64, bold bool) float64 {
+	m := metricsForFont(name)
+	var ems float64
+	for _, r := range text {
+		ems += m.width(r)
+	}
+	if bold {
+		ems *= m.bold
+	}
+	return ems * size * 96 / 72
+}
+
+// maxColWidth is the widest that Excel allows a column to be, in
+// characters.
+const maxColWidth = 255
+
+// AutoFitOptions control how Sheet.AutoFitColumns and an AutoFitter
+// fit the widths of columns.
+type AutoFitOptions struct {
+	// MinWidth and MaxWidth limit the fitted widths, in
+	// characters.  A MaxWidth of 0 is Excel's limit of 255.
+	MinWidth float64
+	MaxWidth float64
+	// DefaultFont is the font of the workbook's Normal style, which
+	// is used for cells without a style of their own and sets the
+	// width of a character.  If it is nil, DefaultFont() is used.
+	DefaultFont *Font
+}
+
+// AutoFitter measures cells, so that the widths of the columns that
+// they are in can be fitted to them.  Sheet.AutoFitColumns uses one
+// to measure every cell of a Sheet, but when rows are written one at
+// a time, as with a DiskV cell store, the rows can be given to an
+// AutoFitter as they are written rather than read back later.
+type AutoFitter struct {
+	options     AutoFitOptions
+	defaultFont Font
+	// digitWidth is the maximum digit width of the default font,
+	// in pixels, which is the unit that column widths are in.
+	digitWidth float64
+	// widths holds the width needed by each zero based column,
+	// in characters.
+	widths map[int]float64
+	merged []mergedWidth
+}
+
+// mergedWidth is the width needed by a cell merged across the zero
+// based columns from to to.
+type mergedWidth struct {
+	from, to int
+	width    float64
+}
+
+// NewAutoFitter returns an AutoFitter that has measured nothing yet.
+func NewAutoFitter(options AutoFitOptions) *AutoFitter {
+	a := &AutoFitter{
+		options: options,
+		widths:  make(map[int]float64),
+	}
+	if options.DefaultFont != nil {
+		a.defaultFont = *options.DefaultFont
+	} else {
+		a.defaultFont = *DefaultFont()
+	}
+	if a.defaultFont.Size <= 0 {
+		a.defaultFont.Size = defaultFontSize
+	}
+	a.digitWidth = math.Round(measureText("0", a.defaultFont.Name, a.defaultFont.Size, false))
+	if a.digitWidth < 1 {
+		a.digitWidth = 1
+	}
+	return a
+}
+
+// AddRow measures the cells of row.
+func (a *AutoFitter) AddRow(row *Row) error {
+	return row.ForEachCell(a.AddCell, SkipEmptyCells)
+}
+
+// AddCell measures cell, as its formatted value would be shown in its
+// font.  Text that wraps needs only room for its longest word, and a
+// cell merged across several columns shares the room it needs
+// between them.
+func (a *AutoFitter) AddCell(cell *Cell) error {
+	font := a.defaultFont
+	wrap := false
+	if cell.style != nil {
+		if cell.style.Font.Name != "" {
+			font.Name = cell.style.Font.Name
+		}
+		if cell.style.Font.Size > 0 {
+			font.Size = cell.style.Font.Size
+		}
+		font.Bold = cell.style.Font.Bold
+		wrap = cell.style.Alignment.WrapText
+	}
+
+	var pixels float64
+	if len(cell.RichText) > 0 {
+		pixels = a.measureRichText(cell.RichText, font, wrap)
+	} else {
+		// A value that can't be formatted is shown, and
+		// returned, as it is.
+		value, _ := cell.FormattedValue()
+		pixels = measureLines(value, font, wrap)
+	}
+	if pixels == 0 {
+		return nil
+	}
+	width := a.toWidth(pixels)
+
+	col := cell.num
+	if cell.HMerge > 0 {
+		a.merged = append(a.merged, mergedWidth{from: col, to: col + cell.HMerge, width: width})
+		return nil
+	}
+	if width > a.widths[col] {
+		a.widths[col] = width
+	}
+	return nil
+}
+
+// measureLines returns the width, in pixels, of the widest line of
+// text, or of the widest word if it wraps.
+func measureLines(text string, font Font, wrap bool) float64 {
+	var parts []string
+	if wrap {
+		parts = strings.Fields(text)
+	} else {
+		parts = strings.Split(text, "\n")
+	}
+	var widest float64
+	for _, part := range parts {
+		if w := measureText(part, font.Name, font.Size, font.Bold); w > widest {
+			widest = w
+		}
+	}
+	return widest
+}
+
+// measureRichText returns the width, in pixels, of the widest line of
+// runs, each in its own font, or of the widest word if it wraps.
+func (a *AutoFitter) measureRichText(runs []RichTextRun, font Font, wrap bool) float64 {
+	var widest, current float64
+	end := func() {
+		if current > widest {
+			widest = current
+		}
+		current = 0
+	}
+	for _, run := range runs {
+		runFont := font
+		if run.Font != nil {
+			if run.Font.Name != "" {
+				runFont.Name = run.Font.Name
+			}
+			if run.Font.Size > 0 {
+				runFont.Size = run.Font.Size
+			}
+			runFont.Bold = run.Font.Bold
+		}
+		for _, r := range run.Text {
+			switch {
+			case r == '\n', wrap && unicode.IsSpace(r):
+				end()
+			default:
+				current += measureText(string(r), runFont.Name, runFont.Size, runFont.Bold)
+			}
+		}
+	}
+	end()
+	return widest
+}
+
+// toWidth converts a width in pixels to a column width, which is in
+// characters of the default font and includes the 5 pixels of padding
+// that Excel puts around the text, truncated to 1/256 of a
+// character as Excel does.
+func (a *AutoFitter) toWidth(pixels float64) float64 {
+	return math.Trunc((pixels+5)/a.digitWidth*256) / 256
+}
+
+// Apply sets the widths of the columns of s from min to max,
+// inclusive, to fit the cells measured so far.  Like SetColWidth,
+// min and max count from 1.  Columns with nothing in them are left
+// as they are.
+func (a *AutoFitter) Apply(s *Sheet, min, max int) error {
+	if min < 1 || max < min || max > formula.MaxCols {
+		return fmt.Errorf("Apply: invalid columns %d to %d", min, max)
+	}
+	widths := make(map[int]float64, len(a.widths))
+	for col, width := range a.widths {
+		if col+1 >= min && col+1 <= max {
+			widths[col] = width
+		}
+	}
+
+	// A merged cell that is wider than the columns it spans
+	// widens those of them that are being fitted equally.
+	for _, m := range a.merged {
+		var have float64
+		var fitted []int
+		for col := m.from; col <= m.to; col++ {
+			if col+1 >= min && col+1 <= max {
+				fitted = append(fitted, col)
+				have += widths[col]
+				continue
+			}
+			have += s.colWidth(col)
+		}
+		if len(fitted) == 0 || have >= m.width {
+			continue
+		}
+		extra := (m.width - have) / float64(len(fitted))
+		for _, col := range fitted {
+			widths[col] += extra
+		}
+	}
+
+	maxWidth := a.options.MaxWidth
+	if maxWidth <= 0 || maxWidth > maxColWidth {
+		maxWidth = maxColWidth
+	}
+	for col, width := range widths {
+		if width < a.options.MinWidth {
+			width = a.options.MinWidth
+		}
+		if width > maxWidth {
+			width = maxWidth
+		}
+		width = math.Trunc(width*256) / 256
+		s.setCol(col+1, col+1, func(c *Col) {
+			c.SetWidth(width)
+			c.BestFit = bPtr(true)
+		})
+	}
+	return nil
+}
+
+// colWidth returns the width, in characters, of the zero based
+// column col.
+func (s *Sheet) colWidth(col int) float64 {
+	if c := s.Col(col); c != nil && c.Width != nil {
+		return *c.Width
+	}
+	if s.SheetFormat.DefaultColWidth > 0 {
+		return s.SheetFormat.DefaultColWidth
+	}
+	return ColWidth
+}
+
+// AutoFitColumns sets the widths of the columns of the Sheet from min
+// to max, inclusive, to fit their contents.  Like SetColWidth, min and
+// max count from 1.
+func (s *Sheet) AutoFitColumns(min, max int, options AutoFitOptions) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("AutoFitColumns: %w", err)
+	}
+	a := NewAutoFitter(options)
+	err := s.ForEachRow(a.AddRow, SkipEmptyRows)
+	if err != nil {
+		return wrap(err)
+	}
+	err = a.Apply(s, min, max)
+	if err != nil {
+		return wrap(err)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package xlsx
+
+import (
+	"math"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestAutoFit(t *testing.T) {
+	c := qt.New(t)
+
+	calibri := AutoFitOptions{DefaultFont: NewFont(11, "Calibri")}
+	colWidth := func(c *qt.C, sheet *Sheet, col int) float64 {
+		c.Helper()
+		cl := sheet.Col(col)
+		c.Assert(cl, qt.Not(qt.IsNil), qt.Commentf("column %d", col))
+		c.Assert(cl.Width, qt.Not(qt.IsNil), qt.Commentf("column %d", col))
+		return *cl.Width
+	}
+
+	c.Run("Measure", func(c *qt.C) {
+		a := NewAutoFitter(calibri)
+		// Calibri 11 has digits 7 pixels wide.
+		c.Assert(a.digitWidth, qt.Equals, 7.0)
+		c.Assert(NewAutoFitter(AutoFitOptions{DefaultFont: NewFont(10, "Arial")}).digitWidth, qt.Equals, 7.0)
+
+		regular := measureText("Revenue", "Arial", 10, false)
+		c.Assert(measureText("Revenue", "Arial", 10, true) > regular, qt.Equals, true)
+		c.Assert(measureText("Revenue", "Arial", 20, false), qt.Equals, 2*regular)
+		c.Assert(measureText("iiii", "Courier New", 10, false), qt.Equals, measureText("WWWW", "Courier New", 10, false))
+		c.Assert(measureText("iiii", "Times New Roman", 10, false) < measureText("WWWW", "Times New Roman", 10, false), qt.Equals, true)
+		c.Assert(measureText("日本", "Verdana", 12, false), qt.Equals, 2*16.0)
+		// Fonts that we don't know are measured as Calibri.
+		c.Assert(measureText("Revenue", "Unknown", 11, false), qt.Equals, measureText("Revenue", "Calibri", 11, false))
+
+		// Ten digits and the padding are a little over ten
+		// characters.
+		c.Assert(a.toWidth(measureText("0123456789", "Calibri", 11, false)), qt.Equals, 11.3359375)
+	})
+
+	csRunO(c, "AutoFitColumns", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		setCells(c, sheet, map[string]interface{}{
+			"A1": "Name", "A2": "A rather longer name", "A3": "Short",
+			"B1": "Total", "B2": 1234567.891,
+			"C1": "Heading",
+			"D1": "Wrapped text in a narrow column",
+			"E1": "A title that is merged across two columns",
+			"G1": "Bold",
+			"H1": "Beyond",
+		})
+		cellAt(c, sheet, "B2").NumFmt = "0.0"
+		wrapped := NewStyle()
+		wrapped.Font = *NewFont(11, "Calibri")
+		wrapped.Alignment.WrapText = true
+		cellAt(c, sheet, "D1").SetStyle(wrapped)
+		cellAt(c, sheet, "E1").Merge(1, 0)
+		bold := NewStyle()
+		bold.Font = *NewFont(16, "Calibri")
+		bold.Font.Bold = true
+		cellAt(c, sheet, "G1").SetStyle(bold)
+
+		options := calibri
+		options.MinWidth = 2
+		err = sheet.AutoFitColumns(1, 7, options)
+		c.Assert(err, qt.IsNil)
+
+		a := NewAutoFitter(calibri)
+		calibri11 := func(text string) float64 {
+			return a.toWidth(measureText(text, "Calibri", 11, false))
+		}
+		c.Assert(colWidth(c, sheet, 0), qt.Equals, calibri11("A rather longer name"))
+		c.Assert(colWidth(c, sheet, 1), qt.Equals, calibri11("1234567.9"))
+		c.Assert(*sheet.Col(0).BestFit, qt.Equals, true)
+		// Wrapped text needs only room for its longest word.
+		c.Assert(colWidth(c, sheet, 3), qt.Equals, calibri11("Wrapped"))
+		// The merged cell is shared equally by E and F, which
+		// have nothing else in them.
+		merged := calibri11("A title that is merged across two columns")
+		c.Assert(colWidth(c, sheet, 4), qt.Equals, colWidth(c, sheet, 5))
+		c.Assert(merged-2*colWidth(c, sheet, 4) < 1.0/128, qt.Equals, true)
+		c.Assert(colWidth(c, sheet, 6), qt.Equals, a.toWidth(measureText("Bold", "Calibri", 16, true)))
+		c.Assert(colWidth(c, sheet, 6) > calibri11("Bold"), qt.Equals, true)
+		// Columns beyond max are left alone.
+		c.Assert(sheet.Col(7), qt.IsNil)
+
+		options.MaxWidth = 10
+		err = sheet.AutoFitColumns(1, 1, options)
+		c.Assert(err, qt.IsNil)
+		c.Assert(colWidth(c, sheet, 0), qt.Equals, 10.0)
+
+		// A merged cell only widens the columns that are fitted,
+		// after counting the width of those that aren't.
+		before := colWidth(c, sheet, 4)
+		sheet.SetColWidth(6, 6, 40)
+		err = sheet.AutoFitColumns(5, 5, options)
+		c.Assert(err, qt.IsNil)
+		c.Assert(colWidth(c, sheet, 4), qt.Equals, before)
+		sheet.SetColWidth(6, 6, 10)
+		options.MaxWidth = 0
+		err = sheet.AutoFitColumns(5, 5, options)
+		c.Assert(err, qt.IsNil)
+		c.Assert(colWidth(c, sheet, 4), qt.Equals, math.Trunc((merged-10)*256)/256)
+
+		err = sheet.AutoFitColumns(0, 1, options)
+		c.Assert(err, qt.ErrorMatches, `AutoFitColumns: Apply: invalid columns 0 to 1`)
+
+		f = reopen(c, f, option)
+		c.Assert(colWidth(c, f.Sheets[0], 0), qt.Equals, 10.0)
+		c.Assert(colWidth(c, f.Sheets[0], 3), qt.Equals, calibri11("Wrapped"))
+	})
+
+	csRunO(c, "RichText", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		cellAt(c, sheet, "B2").SetRichText([]RichTextRun{
+			{Text: "Quarterly "},
+			{Font: &RichTextFont{Size: 16}, Text: "revenue"},
+			{Font: &RichTextFont{Name: "Arial", Bold: true}, Text: " by region"},
+		})
+		cellAt(c, sheet, "B3").SetString("Short")
+
+		a := NewAutoFitter(calibri)
+		// Each run is measured in its own font, which takes
+		// the size and name it doesn't give from the cell.
+		want := a.toWidth(measureText("Quarterly ", "Calibri", 11, false) +
+			measureText("revenue", "Calibri", 16, false) +
+			measureText(" by region", "Arial", 11, true))
+		err = sheet.ForEachRow(a.AddRow)
+		c.Assert(err, qt.IsNil)
+		err = a.Apply(sheet, 2, 2)
+		c.Assert(err, qt.IsNil)
+		c.Assert(colWidth(c, sheet, 1), qt.Equals, want)
+
+		sheet.Col(1).Width = nil
+		err = sheet.AutoFitColumns(2, 2, calibri)
+		c.Assert(err, qt.IsNil)
+		c.Assert(colWidth(c, sheet, 1), qt.Equals, want)
+	})
+
+	csRunO(c, "Incremental", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		a := NewAutoFitter(calibri)
+		for _, values := range [][]interface{}{
+			{"Region", "Sales"},
+			{"North", 120},
+			{"South-South-West", 98765},
+		} {
+			row := sheet.AddRow()
+			row.WriteSlice(&values, -1)
+			c.Assert(a.AddRow(row), qt.IsNil)
+		}
+		err = a.Apply(sheet, 1, 2)
+		c.Assert(err, qt.IsNil)
+		incremental := []float64{colWidth(c, sheet, 0), colWidth(c, sheet, 1)}
+
+		err = sheet.AutoFitColumns(1, 2, calibri)
+		c.Assert(err, qt.IsNil)
+		c.Assert([]float64{colWidth(c, sheet, 0), colWidth(c, sheet, 1)}, qt.DeepEquals, incremental)
+	})
+}
@@ -0,0 +1,911 @@
+package xlsx
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tealeg/xlsx/v3/formula"
+)
+
+// Error values produced by formula evaluation.  They are stored as
+// the Value of a Cell with the CellType CellTypeError.
+const (
+	formulaErrorNull  = "#NULL!"
+	formulaErrorDiv0  = "#DIV/0!"
+	formulaErrorValue = "#VALUE!"
+	formulaErrorRef   = "#REF!"
+	formulaErrorName  = "#NAME?"
+	formulaErrorNum   = "#NUM!"
+	formulaErrorNA    = "#N/A"
+)
+
+// maxNameDepth limits how deeply defined names may refer to other
+// defined names, which protects against names that refer to
+// themselves.
+const maxNameDepth = 32
+
+type calcKind int
+
+const (
+	calcEmpty calcKind = iota
+	calcNumber
+	calcString
+	calcBool
+	calcError
+	calcRange
+)
+
+// calcValue is the result of evaluating an expression.  For
+// calcString str holds the text, and for calcError it holds the error
+// literal, e.g. "#DIV/0!".
+type calcValue struct {
+	kind calcKind
+	num  float64
+	str  string
+	b    bool
+	area *calcArea
+}
+
+func numberValue(f float64) calcValue {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return errorValue(formulaErrorNum)
+	}
+	return calcValue{kind: calcNumber, num: f}
+}
+
+func stringValue(s string) calcValue {
+	return calcValue{kind: calcString, str: s}
+}
+
+func boolValue(b bool) calcValue {
+	return calcValue{kind: calcBool, b: b}
+}
+
+func errorValue(e string) calcValue {
+	return calcValue{kind: calcError, str: e}
+}
+
+func (v calcValue) isError() bool {
+	return v.kind == calcError
+}
+
+// calcArea is a rectangular block of values, either taken from a
+// worksheet or from an array constant.  The logical size of the area
+// is rows x cols, but only the first len(values)/width rows, those
+// within the populated extent of the sheet, are stored; anything
+// beyond is empty.
+type calcArea struct {
+	row, col   int // the top left corner on the sheet
+	rows, cols int
+	width      int
+	values     []calcValue
+	isRef      bool
+}
+
+// at returns the value at the zero based position (r, c) within the
+// area.
+func (a *calcArea) at(r, c int) calcValue {
+	if a.width == 0 || c >= a.width {
+		return calcValue{}
+	}
+	i := r*a.width + c
+	if i >= len(a.values) {
+		return calcValue{}
+	}
+	return a.values[i]
+}
+
+// storedRows returns the number of rows that hold stored values.
+func (a *calcArea) storedRows() int {
+	if a.width == 0 {
+		return 0
+	}
+	return len(a.values) / a.width
+}
+
+// each calls fn for every stored value in the area, in row major
+// order.
+func (a *calcArea) each(fn func(v calcValue)) {
+	for _, v := range a.values {
+		fn(v)
+	}
+}
+
+type calcKey struct {
+	sheet    *Sheet
+	row, col int
+}
+
+type calcRowKey struct {
+	sheet *Sheet
+	row   int
+}
+
+type calcState int
+
+const (
+	calcPending calcState = iota
+	calcRunning
+	calcDone
+)
+
+// calcNode holds what the calculation engine knows about a single
+// cell: either its constant value or its formula and, once evaluated,
+// the result.
+type calcNode struct {
+	value   calcValue
+	formula string
+	state   calcState
+	isDate  bool
+}
+
+// calcContext caches the contents of the workbook while formulas are
+// evaluated.  Rows are read directly from the cell store as they are
+// needed, without disturbing the Sheet's current Row, so that
+// evaluation is safe regardless of the CellStore in use.
+type calcContext struct {
+	file     *File
+	sheets   []*Sheet
+	nodes    map[calcKey]*calcNode
+	loaded   map[calcRowKey]bool
+	rowWidth map[calcRowKey]int
+	date1904 bool
+	now      time.Time
+	depth    int
+}
+
+func newCalcContext(f *File, sheet *Sheet) *calcContext {
+	ctx := &calcContext{
+		file:     f,
+		nodes:    make(map[calcKey]*calcNode),
+		loaded:   make(map[calcRowKey]bool),
+		rowWidth: make(map[calcRowKey]int),
+		now:      time.Now(),
+	}
+	if f != nil {
+		ctx.sheets = f.Sheets
+		ctx.date1904 = f.Date1904
+	} else if sheet != nil {
+		ctx.sheets = []*Sheet{sheet}
+	}
+	return ctx
+}
+
+// sheetByName finds a sheet, ignoring case as Excel does.
+func (ctx *calcContext) sheetByName(name string) *Sheet {
+	for _, s := range ctx.sheets {
+		if strings.EqualFold(s.Name, name) {
+			return s
+		}
+	}
+	return nil
+}
+
+// loadRow reads a row of a sheet into the node cache.  Nodes that
+// are already cached are left alone.
+func (ctx *calcContext) loadRow(s *Sheet, row int) {
+	key := calcRowKey{s, row}
+	if ctx.loaded[key] {
+		return
+	}
+	ctx.loaded[key] = true
+	var r *Row
+	if s.currentRow != nil && s.currentRow.num == row {
+		r = s.currentRow
+	} else if s.cellStore != nil {
+		r, _ = s.cellStore.ReadRow(makeRowKey(s, row))
+	}
+	if r == nil {
+		return
+	}
+	width := 0
+	for ci, c := range r.cells {
+		if c == nil {
+			continue
+		}
+		ck := calcKey{s, row, ci}
+		if _, ok := ctx.nodes[ck]; !ok {
+			ctx.nodes[ck] = newCalcNode(c)
+		}
+		width = ci + 1
+	}
+	ctx.rowWidth[key] = width
+}
+
+func newCalcNode(c *Cell) *calcNode {
+	node := &calcNode{formula: strings.TrimSpace(c.formula)}
+	if node.formula == "" {
+		node.value = cellCalcValue(c)
+		node.state = calcDone
+	}
+	return node
+}
+
+// cellCalcValue converts the stored value of a Cell into a calcValue.
+func cellCalcValue(c *Cell) calcValue {
+	value := c.Value
+	if value == "" && len(c.RichText) > 0 {
+		var b strings.Builder
+		for _, run := range c.RichText {
+			b.WriteString(run.Text)
+		}
+		value = b.String()
+	}
+	switch c.cellType {
+	case CellTypeNumeric:
+		if value == "" {
+			return calcValue{}
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return stringValue(value)
+		}
+		return numberValue(f)
+	case CellTypeBool:
+		return boolValue(value == "1" || strings.EqualFold(value, "TRUE"))
+	case CellTypeError:
+		return errorValue(value)
+	case CellTypeDate:
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t, err = time.Parse("2006-01-02", value)
+		}
+		if err != nil {
+			return stringValue(value)
+		}
+		return numberValue(TimeToExcelTime(t, c.date1904))
+	}
+	if value == "" {
+		return calcValue{}
+	}
+	return stringValue(value)
+}
+
+// cellValue returns the value of a cell, evaluating its formula if
+// necessary.
+func (ctx *calcContext) cellValue(s *Sheet, row, col int) (calcValue, error) {
+	ctx.loadRow(s, row)
+	node, ok := ctx.nodes[calcKey{s, row, col}]
+	if !ok {
+		return calcValue{}, nil
+	}
+	return ctx.evaluateNode(s, row, col, node)
+}
+
+func (ctx *calcContext) evaluateNode(s *Sheet, row, col int, node *calcNode) (calcValue, error) {
+	switch node.state {
+	case calcDone:
+		return node.value, nil
+	case calcRunning:
+		return calcValue{}, fmt.Errorf("circular reference to %s!%s", s.Name, GetCellIDStringFromCoords(col, row))
+	}
+	node.state = calcRunning
+	expr, err := formula.Parse(node.formula)
+	if err != nil {
+		node.state = calcPending
+		return calcValue{}, err
+	}
+	ev := &calcEvaluator{ctx: ctx, sheet: s, row: row, col: col}
+	v, err := ev.eval(expr)
+	if err != nil {
+		node.state = calcPending
+		return calcValue{}, err
+	}
+	node.value = ev.scalar(v)
+	node.isDate = isDateFormula(expr)
+	node.state = calcDone
+	return node.value, nil
+}
+
+// isDateFormula reports whether the outermost operation of a formula
+// is a function returning a date, in which case the cell is given a
+// date format, as Excel does.
+func isDateFormula(expr formula.Expr) bool {
+	if fn, ok := expr.(formula.Call); ok {
+		switch canonicalFuncName(fn.Name) {
+		case "DATE", "TODAY":
+			return true
+		}
+	}
+	return false
+}
+
+// area builds a calcArea for a reference.
+func (ctx *calcContext) area(s *Sheet, ref formula.Ref) *calcArea {
+	row1, col1, row2, col2 := ref.Row1, ref.Col1, ref.Row2, ref.Col2
+	if ref.IsWholeCols() {
+		row1, row2 = 0, formula.MaxRows-1
+	}
+	if ref.IsWholeRows() {
+		col1, col2 = 0, formula.MaxCols-1
+	}
+	a := &calcArea{row: row1, col: col1, rows: row2 - row1 + 1, cols: col2 - col1 + 1, isRef: true}
+
+	// Only the populated part of the sheet is stored.
+	lastRow := row2
+	if lastRow >= s.MaxRow {
+		lastRow = s.MaxRow - 1
+	}
+	lastCol := col1 - 1
+	for r := row1; r <= lastRow; r++ {
+		ctx.loadRow(s, r)
+		if w := ctx.rowWidth[calcRowKey{s, r}] - 1; w > lastCol {
+			lastCol = w
+		}
+	}
+	if lastCol > col2 {
+		lastCol = col2
+	}
+	if lastRow < row1 || lastCol < col1 {
+		return a
+	}
+	a.width = lastCol - col1 + 1
+	return a
+}
+
+// fillArea evaluates the stored cells of an area.
+func (ctx *calcContext) fillArea(s *Sheet, a *calcArea) error {
+	if a.width == 0 {
+		return nil
+	}
+	lastRow := a.row + a.rows - 1
+	if lastRow >= s.MaxRow {
+		lastRow = s.MaxRow - 1
+	}
+	a.values = make([]calcValue, 0, (lastRow-a.row+1)*a.width)
+	for r := a.row; r <= lastRow; r++ {
+		for c := a.col; c < a.col+a.width; c++ {
+			v, err := ctx.cellValue(s, r, c)
+			if err != nil {
+				return err
+			}
+			a.values = append(a.values, v)
+		}
+	}
+	return nil
+}
+
+// definedName finds a defined name, preferring one that is local to
+// the given sheet.
+func (ctx *calcContext) definedName(s *Sheet, name string) *DefinedName {
+	if ctx.file == nil {
+		return nil
+	}
+	var global *DefinedName
+	for _, dn := range ctx.file.DefinedNames {
+		if !strings.EqualFold(dn.Name, name) {
+			continue
+		}
+		if dn.Scope != nil {
+			if dn.Scope == s {
+				return dn
+			}
+			continue
+		}
+		if global == nil {
+			global = dn
+		}
+	}
+	return global
+}
+
+// table finds the Table called name or, if name is empty, the Table
+// on the sheet s that holds the cell with the zero based row and col.
+func (ctx *calcContext) table(s *Sheet, row, col int, name string) *Table {
+	if name == "" {
+		if s == nil {
+			return nil
+		}
+		return s.tableAt(row, col)
+	}
+	for _, sheet := range ctx.sheets {
+		if t := sheet.table(name); t != nil {
+			return t
+		}
+	}
+	return nil
+}
+
+// calcEvaluator evaluates expressions on behalf of the formula in a
+// particular cell.
+type calcEvaluator struct {
+	ctx      *calcContext
+	sheet    *Sheet
+	row, col int
+}
+
+func (ev *calcEvaluator) eval(expr formula.Expr) (calcValue, error) {
+	switch e := expr.(type) {
+	case formula.Number:
+		return numberValue(e.Value), nil
+	case formula.String:
+		return stringValue(e.Value), nil
+	case formula.Bool:
+		return boolValue(e.Value), nil
+	case formula.Error:
+		return errorValue(e.Value), nil
+	case formula.Empty:
+		return calcValue{}, nil
+	case formula.Reference:
+		return ev.evalRef(e.Ref)
+	case formula.TableReference:
+		return ev.evalTableRef(e.Ref)
+	case formula.Name:
+		return ev.evalName(e)
+	case formula.Unary:
+		return ev.evalUnary(e)
+	case formula.Binary:
+		return ev.evalBinary(e)
+	case formula.Call:
+		return ev.evalFunc(e)
+	case formula.Array:
+		return ev.evalArray(e)
+	}
+	return calcValue{}, fmt.Errorf("unsupported expression %T", expr)
+}
+
+func (ev *calcEvaluator) evalRef(ref formula.Ref) (calcValue, error) {
+	if ref.Book != 0 {
+		// We don't have the cells of external workbooks
+		return errorValue(formulaErrorRef), nil
+	}
+	s := ev.sheet
+	if ref.Sheet != "" {
+		s = ev.ctx.sheetByName(ref.Sheet)
+	}
+	if s == nil {
+		return errorValue(formulaErrorRef), nil
+	}
+	a := ev.ctx.area(s, ref)
+	err := ev.ctx.fillArea(s, a)
+	if err != nil {
+		return calcValue{}, err
+	}
+	return calcValue{kind: calcRange, area: a}, nil
+}
+
+func (ev *calcEvaluator) evalTableRef(tr formula.TableRef) (calcValue, error) {
+	t := ev.ctx.table(ev.sheet, ev.row, ev.col, tr.Table)
+	if t == nil {
+		return errorValue(formulaErrorRef), nil
+	}
+	ref, ok := t.resolve(tr, ev.row)
+	if !ok {
+		return errorValue(formulaErrorRef), nil
+	}
+	return ev.evalRef(ref)
+}
+
+func (ev *calcEvaluator) evalName(e formula.Name) (calcValue, error) {
+	if e.Book != 0 {
+		return errorValue(formulaErrorRef), nil
+	}
+	s := ev.sheet
+	if e.Sheet != "" {
+		s = ev.ctx.sheetByName(e.Sheet)
+		if s == nil {
+			return errorValue(formulaErrorRef), nil
+		}
+	}
+	dn := ev.ctx.definedName(s, e.Name)
+	if dn == nil {
+		return errorValue(formulaErrorName), nil
+	}
+	if ev.ctx.depth >= maxNameDepth {
+		return calcValue{}, fmt.Errorf("defined name %q is too deeply nested", e.Name)
+	}
+	expr, err := formula.Parse(dn.RefersTo)
+	if err != nil {
+		return calcValue{}, fmt.Errorf("defined name %q: %w", e.Name, err)
+	}
+	ev.ctx.depth++
+	defer func() { ev.ctx.depth-- }()
+	return ev.eval(expr)
+}
+
+func (ev *calcEvaluator) evalArray(e formula.Array) (calcValue, error) {
+	a := &calcArea{rows: len(e.Rows), cols: len(e.Rows[0]), width: len(e.Rows[0])}
+	for _, row := range e.Rows {
+		for _, x := range row {
+			v, err := ev.eval(x)
+			if err != nil {
+				return calcValue{}, err
+			}
+			a.values = append(a.values, v)
+		}
+	}
+	return calcValue{kind: calcRange, area: a}, nil
+}
+
+// scalar reduces an area to a single value by implicit intersection
+// with the row or column of the cell being calculated.
+func (ev *calcEvaluator) scalar(v calcValue) calcValue {
+	if v.kind != calcRange {
+		return v
+	}
+	a := v.area
+	switch {
+	case a.rows == 1 && a.cols == 1:
+		return a.at(0, 0)
+	case !a.isRef:
+		return a.at(0, 0)
+	case a.cols == 1 && ev.row >= a.row && ev.row < a.row+a.rows:
+		return a.at(ev.row-a.row, 0)
+	case a.rows == 1 && ev.col >= a.col && ev.col < a.col+a.cols:
+		return a.at(0, ev.col-a.col)
+	}
+	return errorValue(formulaErrorValue)
+}
+
+func (ev *calcEvaluator) evalScalar(expr formula.Expr) (calcValue, error) {
+	v, err := ev.eval(expr)
+	if err != nil {
+		return v, err
+	}
+	return ev.scalar(v), nil
+}
+
+func (ev *calcEvaluator) evalUnary(e formula.Unary) (calcValue, error) {
+	v, err := ev.evalScalar(e.X)
+	if err != nil {
+		return v, err
+	}
+	n, errv := toNumber(v)
+	if errv != nil {
+		return *errv, nil
+	}
+	switch e.Op {
+	case "-":
+		return numberValue(-n), nil
+	case "%":
+		return numberValue(n / 100), nil
+	}
+	return numberValue(n), nil
+}
+
+func (ev *calcEvaluator) evalBinary(e formula.Binary) (calcValue, error) {
+	x, err := ev.evalScalar(e.X)
+	if err != nil {
+		return x, err
+	}
+	y, err := ev.evalScalar(e.Y)
+	if err != nil {
+		return y, err
+	}
+	if x.isError() {
+		return x, nil
+	}
+	if y.isError() {
+		return y, nil
+	}
+	switch e.Op {
+	case "&":
+		return stringValue(toText(x) + toText(y)), nil
+	case "=":
+		return boolValue(compareValues(x, y) == 0), nil
+	case "<>":
+		return boolValue(compareValues(x, y) != 0), nil
+	case "<":
+		return boolValue(compareValues(x, y) < 0), nil
+	case "<=":
+		return boolValue(compareValues(x, y) <= 0), nil
+	case ">":
+		return boolValue(compareValues(x, y) > 0), nil
+	case ">=":
+		return boolValue(compareValues(x, y) >= 0), nil
+	}
+	a, errv := toNumber(x)
+	if errv != nil {
+		return *errv, nil
+	}
+	b, errv := toNumber(y)
+	if errv != nil {
+		return *errv, nil
+	}
+	switch e.Op {
+	case "+":
+		return numberValue(a + b), nil
+	case "-":
+		return numberValue(a - b), nil
+	case "*":
+		return numberValue(a * b), nil
+	case "/":
+		if b == 0 {
+			return errorValue(formulaErrorDiv0), nil
+		}
+		return numberValue(a / b), nil
+	case "^":
+		if a == 0 && b == 0 {
+			return errorValue(formulaErrorNum), nil
+		}
+		if a == 0 && b < 0 {
+			return errorValue(formulaErrorDiv0), nil
+		}
+		return numberValue(math.Pow(a, b)), nil
+	}
+	return calcValue{}, fmt.Errorf("unsupported operator %q", e.Op)
+}
+
+// canonicalFuncName returns the upper case name of a function,
+// without the "_xlfn." prefix that Excel uses for newer functions.
+func canonicalFuncName(name string) string {
+	name = strings.ToUpper(name)
+	return strings.TrimPrefix(name, "_XLFN.")
+}
+
+func (ev *calcEvaluator) evalFunc(e formula.Call) (calcValue, error) {
+	name := canonicalFuncName(e.Name)
+	fn, ok := calcFunctions[name]
+	if !ok {
+		return errorValue(formulaErrorName), nil
+	}
+	if len(e.Args) < fn.minArgs || (fn.maxArgs >= 0 && len(e.Args) > fn.maxArgs) {
+		return calcValue{}, fmt.Errorf("wrong number of arguments to %s", name)
+	}
+	if fn.lazy != nil {
+		return fn.lazy(ev, e.Args)
+	}
+	args := make([]calcValue, len(e.Args))
+	for i, arg := range e.Args {
+		v, err := ev.eval(arg)
+		if err != nil {
+			return v, err
+		}
+		args[i] = v
+	}
+	return fn.call(ev, args), nil
+}
+
+// toNumber converts a scalar to a number, returning an error value
+// if that isn't possible.
+func toNumber(v calcValue) (float64, *calcValue) {
+	switch v.kind {
+	case calcEmpty:
+		return 0, nil
+	case calcNumber:
+		return v.num, nil
+	case calcBool:
+		if v.b {
+			return 1, nil
+		}
+		return 0, nil
+	case calcString:
+		s := strings.TrimSpace(v.str)
+		if strings.HasSuffix(s, "%") {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(s[:len(s)-1]), 64); err == nil {
+				return f / 100, nil
+			}
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, nil
+		}
+	case calcError:
+		return 0, &v
+	}
+	errv := errorValue(formulaErrorValue)
+	return 0, &errv
+}
+
+// toText converts a scalar to its text representation.
+func toText(v calcValue) string {
+	switch v.kind {
+	case calcNumber:
+		return formatCalcNumber(v.num)
+	case calcBool:
+		if v.b {
+			return "TRUE"
+		}
+		return "FALSE"
+	case calcString, calcError:
+		return v.str
+	}
+	return ""
+}
+
+// toBool converts a scalar to a boolean, returning an error value if
+// that isn't possible.
+func toBool(v calcValue) (bool, *calcValue) {
+	switch v.kind {
+	case calcEmpty:
+		return false, nil
+	case calcNumber:
+		return v.num != 0, nil
+	case calcBool:
+		return v.b, nil
+	case calcString:
+		switch strings.ToUpper(v.str) {
+		case "TRUE":
+			return true, nil
+		case "FALSE":
+			return false, nil
+		}
+	case calcError:
+		return false, &v
+	}
+	errv := errorValue(formulaErrorValue)
+	return false, &errv
+}
+
+// formatCalcNumber formats a number the way Excel does when it is
+// converted to text, with at most 15 significant digits.
+func formatCalcNumber(f float64) string {
+	s := strconv.FormatFloat(f, 'G', 15, 64)
+	if strings.Contains(s, "E") {
+		mantissa, exp := s[:strings.Index(s, "E")], s[strings.Index(s, "E"):]
+		if strings.Contains(mantissa, ".") {
+			mantissa = strings.TrimRight(strings.TrimRight(mantissa, "0"), ".")
+		}
+		return mantissa + exp
+	}
+	return s
+}
+
+// compareValues compares two scalars using Excel's rules: numbers sort
+// before text, which sorts before logical values, and text is
+// compared without regard to case.  An empty value takes on the zero
+// value of the type it is compared with.
+func compareValues(x, y calcValue) int {
+	if x.kind == calcEmpty {
+		x = zeroValueOf(y.kind)
+	}
+	if y.kind == calcEmpty {
+		y = zeroValueOf(x.kind)
+	}
+	rank := func(v calcValue) int {
+		switch v.kind {
+		case calcNumber:
+			return 0
+		case calcString:
+			return 1
+		case calcBool:
+			return 2
+		}
+		return 3
+	}
+	if rx, ry := rank(x), rank(y); rx != ry {
+		if rx < ry {
+			return -1
+		}
+		return 1
+	}
+	switch x.kind {
+	case calcNumber:
+		switch {
+		case x.num < y.num:
+			return -1
+		case x.num > y.num:
+			return 1
+		}
+		return 0
+	case calcString:
+		return strings.Compare(strings.ToLower(x.str), strings.ToLower(y.str))
+	case calcBool:
+		switch {
+		case x.b == y.b:
+			return 0
+		case !x.b:
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+func zeroValueOf(kind calcKind) calcValue {
+	switch kind {
+	case calcString:
+		return stringValue("")
+	case calcBool:
+		return boolValue(false)
+	}
+	return numberValue(0)
+}
+
+// setCalculatedValue stores the result of evaluating the Cell's
+// formula as its Value, leaving the formula in place.
+func (c *Cell) setCalculatedValue(v calcValue, isDate bool) {
+	c.RichText = nil
+	switch v.kind {
+	case calcNumber:
+		c.Value = strconv.FormatFloat(v.num, 'f', -1, 64)
+		c.cellType = CellTypeNumeric
+		if isDate && (c.NumFmt == "" || c.NumFmt == builtInNumFmt[builtInNumFmtIndex_GENERAL]) {
+			c.NumFmt = DefaultDateFormat
+		}
+	case calcString:
+		c.Value = v.str
+		c.cellType = CellTypeStringFormula
+	case calcBool:
+		if v.b {
+			c.Value = "1"
+		} else {
+			c.Value = "0"
+		}
+		c.cellType = CellTypeBool
+	case calcError:
+		c.Value = v.str
+		c.cellType = CellTypeError
+	default:
+		// A formula that refers to an empty cell evaluates to zero
+		c.Value = "0"
+		c.cellType = CellTypeNumeric
+	}
+}
+
+// Evaluate calculates the Cell's formula, using the current contents
+// of the workbook, and stores the result as the Cell's Value with the
+// matching CellType.  Formulas in the cells it refers to are
+// calculated as needed, but only this Cell is updated.  Errors within
+// the calculation, such as division by zero, produce an error value
+// like "#DIV/0!" with the CellType CellTypeError, rather than an
+// error from Evaluate, which is reserved for formulas that cannot be
+// parsed and circular references.  A Cell without a formula is left
+// unchanged.
+func (c *Cell) Evaluate() error {
+	if strings.TrimSpace(c.formula) == "" {
+		return nil
+	}
+	var sheet *Sheet
+	var file *File
+	row := 0
+	if c.Row != nil {
+		sheet = c.Row.Sheet
+		row = c.Row.num
+	}
+	if sheet != nil {
+		file = sheet.File
+	}
+	ctx := newCalcContext(file, sheet)
+	node := newCalcNode(c)
+	if sheet != nil {
+		ctx.nodes[calcKey{sheet, row, c.num}] = node
+	}
+	v, err := ctx.evaluateNode(sheet, row, c.num, node)
+	if err != nil {
+		return fmt.Errorf("Evaluate: %w", err)
+	}
+	c.setCalculatedValue(v, node.isDate)
+	return nil
+}
+
+// Recalculate evaluates every formula in the File and stores the
+// results as the values of their cells, so that the File can be read
+// correctly by applications that don't calculate formulas themselves.
+// Results are given the appropriate CellType, and errors within a
+// calculation produce error values such as "#DIV/0!".  An error is
+// returned if a formula cannot be parsed or is part of a circular
+// reference.
+func (f *File) Recalculate() error {
+	wrap := func(err error) error {
+		return fmt.Errorf("Recalculate: %w", err)
+	}
+	ctx := newCalcContext(f, nil)
+	for _, sheet := range f.Sheets {
+		err := sheet.ForEachRow(func(r *Row) error {
+			for ci, c := range r.cells {
+				if c == nil || strings.TrimSpace(c.formula) == "" {
+					continue
+				}
+				ctx.loadRow(sheet, r.num)
+				node, ok := ctx.nodes[calcKey{sheet, r.num, ci}]
+				if !ok {
+					node = newCalcNode(c)
+					ctx.nodes[calcKey{sheet, r.num, ci}] = node
+				}
+				v, err := ctx.evaluateNode(sheet, r.num, ci, node)
+				if err != nil {
+					return fmt.Errorf("%s!%s: %w", sheet.Name, GetCellIDStringFromCoords(ci, r.num), err)
+				}
+				c.setCalculatedValue(v, node.isDate)
+			}
+			return nil
+		})
+		if err != nil {
+			return wrap(err)
+		}
+	}
+	return nil
+}
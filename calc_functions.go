@@ -0,0 +1,801 @@
+package xlsx
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tealeg/xlsx/v3/formula"
+)
+
+// calcFunction describes a worksheet function.  Most functions are
+// given their evaluated arguments via call, but those that must only
+// evaluate some of their arguments, such as IF, use lazy instead.  A
+// maxArgs of -1 means the function takes any number of arguments.
+type calcFunction struct {
+	minArgs, maxArgs int
+	call             func(ev *calcEvaluator, args []calcValue) calcValue
+	lazy             func(ev *calcEvaluator, args []formula.Expr) (calcValue, error)
+}
+
+// calcFunctions is populated by init, rather than by its declaration,
+// because lazy functions such as IF evaluate expressions, which in turn
+// refer back to calcFunctions.
+var calcFunctions map[string]calcFunction
+
+func init() {
+	calcFunctions = map[string]calcFunction{
+		"SUM":         {minArgs: 1, maxArgs: -1, call: fnSum},
+		"AVERAGE":     {minArgs: 1, maxArgs: -1, call: fnAverage},
+		"MIN":         {minArgs: 1, maxArgs: -1, call: fnMin},
+		"MAX":         {minArgs: 1, maxArgs: -1, call: fnMax},
+		"COUNT":       {minArgs: 1, maxArgs: -1, call: fnCount},
+		"COUNTIF":     {minArgs: 2, maxArgs: 2, call: fnCountIf},
+		"SUMIF":       {minArgs: 2, maxArgs: 3, call: fnSumIf},
+		"IF":          {minArgs: 2, maxArgs: 3, lazy: fnIf},
+		"IFERROR":     {minArgs: 2, maxArgs: 2, lazy: fnIfError},
+		"AND":         {minArgs: 1, maxArgs: -1, call: fnAnd},
+		"OR":          {minArgs: 1, maxArgs: -1, call: fnOr},
+		"VLOOKUP":     {minArgs: 3, maxArgs: 4, call: fnVLookup},
+		"INDEX":       {minArgs: 2, maxArgs: 3, call: fnIndex},
+		"MATCH":       {minArgs: 2, maxArgs: 3, call: fnMatch},
+		"ROUND":       {minArgs: 2, maxArgs: 2, call: fnRound},
+		"TEXT":        {minArgs: 2, maxArgs: 2, call: fnText},
+		"DATE":        {minArgs: 3, maxArgs: 3, call: fnDate},
+		"TODAY":       {minArgs: 0, maxArgs: 0, call: fnToday},
+		"LEFT":        {minArgs: 1, maxArgs: 2, call: fnLeft},
+		"RIGHT":       {minArgs: 1, maxArgs: 2, call: fnRight},
+		"MID":         {minArgs: 3, maxArgs: 3, call: fnMid},
+		"LEN":         {minArgs: 1, maxArgs: 1, call: fnLen},
+		"CONCATENATE": {minArgs: 1, maxArgs: -1, call: fnConcatenate},
+	}
+}
+
+// eachNumber calls fn for every number in the arguments, following
+// the rules used by SUM and friends: numbers within references are
+// used while text, logical values and empty cells are ignored, but
+// arguments given directly are converted to numbers where possible.
+// An error value is returned if one is found.
+func eachNumber(args []calcValue, fn func(f float64)) *calcValue {
+	for _, arg := range args {
+		switch arg.kind {
+		case calcRange:
+			var errv *calcValue
+			arg.area.each(func(v calcValue) {
+				switch {
+				case errv != nil:
+				case v.kind == calcError:
+					e := v
+					errv = &e
+				case v.kind == calcNumber:
+					fn(v.num)
+				case !arg.area.isRef && v.kind == calcBool:
+					// Array constants behave like direct arguments
+					n, _ := toNumber(v)
+					fn(n)
+				}
+			})
+			if errv != nil {
+				return errv
+			}
+		case calcEmpty:
+		default:
+			n, errv := toNumber(arg)
+			if errv != nil {
+				return errv
+			}
+			fn(n)
+		}
+	}
+	return nil
+}
+
+func fnSum(ev *calcEvaluator, args []calcValue) calcValue {
+	sum := 0.0
+	if errv := eachNumber(args, func(f float64) { sum += f }); errv != nil {
+		return *errv
+	}
+	return numberValue(sum)
+}
+
+func fnAverage(ev *calcEvaluator, args []calcValue) calcValue {
+	sum, count := 0.0, 0
+	if errv := eachNumber(args, func(f float64) { sum += f; count++ }); errv != nil {
+		return *errv
+	}
+	if count == 0 {
+		return errorValue(formulaErrorDiv0)
+	}
+	return numberValue(sum / float64(count))
+}
+
+func fnMin(ev *calcEvaluator, args []calcValue) calcValue {
+	min, found := 0.0, false
+	errv := eachNumber(args, func(f float64) {
+		if !found || f < min {
+			min, found = f, true
+		}
+	})
+	if errv != nil {
+		return *errv
+	}
+	return numberValue(min)
+}
+
+func fnMax(ev *calcEvaluator, args []calcValue) calcValue {
+	max, found := 0.0, false
+	errv := eachNumber(args, func(f float64) {
+		if !found || f > max {
+			max, found = f, true
+		}
+	})
+	if errv != nil {
+		return *errv
+	}
+	return numberValue(max)
+}
+
+// fnCount counts the numbers in its arguments.  Unlike SUM it never
+// fails, values that are not numbers are simply not counted.
+func fnCount(ev *calcEvaluator, args []calcValue) calcValue {
+	count := 0
+	for _, arg := range args {
+		switch arg.kind {
+		case calcRange:
+			arg.area.each(func(v calcValue) {
+				if v.kind == calcNumber {
+					count++
+				}
+			})
+		case calcNumber, calcBool:
+			count++
+		case calcString:
+			if _, errv := toNumber(arg); errv == nil {
+				count++
+			}
+		}
+	}
+	return numberValue(float64(count))
+}
+
+// calcCriteria is the parsed form of the criteria argument to
+// COUNTIF and SUMIF, for example ">=10" or "app*".
+type calcCriteria struct {
+	op      string
+	value   calcValue
+	pattern bool
+}
+
+func parseCriteria(v calcValue) calcCriteria {
+	if v.kind != calcString {
+		return calcCriteria{op: "=", value: v}
+	}
+	s := v.str
+	op := "="
+	for _, prefix := range []string{">=", "<=", "<>", ">", "<", "="} {
+		if strings.HasPrefix(s, prefix) {
+			op = prefix
+			s = s[len(prefix):]
+			break
+		}
+	}
+	if s != "" {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return calcCriteria{op: op, value: numberValue(f)}
+		}
+		switch strings.ToUpper(s) {
+		case "TRUE":
+			return calcCriteria{op: op, value: boolValue(true)}
+		case "FALSE":
+			return calcCriteria{op: op, value: boolValue(false)}
+		}
+	}
+	c := calcCriteria{op: op, value: stringValue(s)}
+	c.pattern = strings.ContainsAny(s, "*?~")
+	return c
+}
+
+func (c calcCriteria) match(v calcValue) bool {
+	if c.value.kind == calcString && c.value.str == "" {
+		empty := v.kind == calcEmpty || (v.kind == calcString && v.str == "")
+		switch c.op {
+		case "=":
+			return empty
+		case "<>":
+			return !empty
+		}
+	}
+	if v.kind != c.value.kind {
+		return c.op == "<>"
+	}
+	if c.pattern && (c.op == "=" || c.op == "<>") {
+		return matchWildcard(c.value.str, v.str) == (c.op == "=")
+	}
+	cmp := compareValues(v, c.value)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "<>":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// matchWildcard matches s against an Excel wildcard pattern, in which
+// "*" matches any run of characters, "?" matches a single character
+// and "~" escapes the following character.  Case is ignored.
+func matchWildcard(pattern, s string) bool {
+	p := []rune(strings.ToLower(pattern))
+	r := []rune(strings.ToLower(s))
+	var match func(i, j int) bool
+	match = func(i, j int) bool {
+		for i < len(p) {
+			switch p[i] {
+			case '*':
+				for k := j; k <= len(r); k++ {
+					if match(i+1, k) {
+						return true
+					}
+				}
+				return false
+			case '?':
+				if j >= len(r) {
+					return false
+				}
+			case '~':
+				if i+1 < len(p) {
+					i++
+				}
+				fallthrough
+			default:
+				if j >= len(r) || r[j] != p[i] {
+					return false
+				}
+			}
+			i++
+			j++
+		}
+		return j == len(r)
+	}
+	return match(0, 0)
+}
+
+// asArea returns the area held by v, wrapping a scalar in a one cell
+// area.
+func asArea(v calcValue) *calcArea {
+	if v.kind == calcRange {
+		return v.area
+	}
+	return &calcArea{rows: 1, cols: 1, width: 1, values: []calcValue{v}}
+}
+
+func fnCountIf(ev *calcEvaluator, args []calcValue) calcValue {
+	if args[0].kind != calcRange {
+		return errorValue(formulaErrorValue)
+	}
+	criteria := parseCriteria(ev.scalar(args[1]))
+	count := 0
+	args[0].area.each(func(v calcValue) {
+		if criteria.match(v) {
+			count++
+		}
+	})
+	return numberValue(float64(count))
+}
+
+func fnSumIf(ev *calcEvaluator, args []calcValue) calcValue {
+	if args[0].kind != calcRange {
+		return errorValue(formulaErrorValue)
+	}
+	criteria := parseCriteria(ev.scalar(args[1]))
+	rng := args[0].area
+	sumRange := rng
+	if len(args) == 3 && args[2].kind != calcEmpty {
+		if args[2].kind != calcRange {
+			return errorValue(formulaErrorValue)
+		}
+		sumRange = args[2].area
+	}
+	sum := 0.0
+	for r := 0; r < rng.storedRows(); r++ {
+		for c := 0; c < rng.width; c++ {
+			if !criteria.match(rng.at(r, c)) {
+				continue
+			}
+			v := sumRange.at(r, c)
+			switch v.kind {
+			case calcNumber:
+				sum += v.num
+			case calcError:
+				return v
+			}
+		}
+	}
+	return numberValue(sum)
+}
+
+func fnIf(ev *calcEvaluator, args []formula.Expr) (calcValue, error) {
+	cond, err := ev.evalScalar(args[0])
+	if err != nil {
+		return cond, err
+	}
+	b, errv := toBool(cond)
+	if errv != nil {
+		return *errv, nil
+	}
+	if b {
+		if _, ok := args[1].(formula.Empty); ok {
+			return numberValue(0), nil
+		}
+		return ev.eval(args[1])
+	}
+	if len(args) < 3 {
+		return boolValue(false), nil
+	}
+	if _, ok := args[2].(formula.Empty); ok {
+		return numberValue(0), nil
+	}
+	return ev.eval(args[2])
+}
+
+func fnIfError(ev *calcEvaluator, args []formula.Expr) (calcValue, error) {
+	v, err := ev.evalScalar(args[0])
+	if err != nil {
+		return v, err
+	}
+	if v.isError() {
+		return ev.eval(args[1])
+	}
+	return v, nil
+}
+
+// logicalArgs collects the logical values of the arguments to AND and
+// OR.  Text and empty cells within references are ignored.
+func logicalArgs(args []calcValue) ([]bool, *calcValue) {
+	var values []bool
+	for _, arg := range args {
+		if arg.kind == calcRange {
+			var errv *calcValue
+			arg.area.each(func(v calcValue) {
+				switch {
+				case errv != nil:
+				case v.kind == calcError:
+					e := v
+					errv = &e
+				case v.kind == calcBool || v.kind == calcNumber:
+					b, _ := toBool(v)
+					values = append(values, b)
+				}
+			})
+			if errv != nil {
+				return nil, errv
+			}
+			continue
+		}
+		if arg.kind == calcEmpty {
+			continue
+		}
+		b, errv := toBool(arg)
+		if errv != nil {
+			return nil, errv
+		}
+		values = append(values, b)
+	}
+	if len(values) == 0 {
+		errv := errorValue(formulaErrorValue)
+		return nil, &errv
+	}
+	return values, nil
+}
+
+func fnAnd(ev *calcEvaluator, args []calcValue) calcValue {
+	values, errv := logicalArgs(args)
+	if errv != nil {
+		return *errv
+	}
+	for _, b := range values {
+		if !b {
+			return boolValue(false)
+		}
+	}
+	return boolValue(true)
+}
+
+func fnOr(ev *calcEvaluator, args []calcValue) calcValue {
+	values, errv := logicalArgs(args)
+	if errv != nil {
+		return *errv
+	}
+	for _, b := range values {
+		if b {
+			return boolValue(true)
+		}
+	}
+	return boolValue(false)
+}
+
+// intArg converts an argument to an integer, truncating any fraction.
+func intArg(ev *calcEvaluator, v calcValue) (int, *calcValue) {
+	n, errv := toNumber(ev.scalar(v))
+	if errv != nil {
+		return 0, errv
+	}
+	return int(math.Trunc(n)), nil
+}
+
+// lookupMatches reports whether v is an exact match for the lookup
+// value, allowing wildcards when looking up text.
+func lookupMatches(lookup, v calcValue) bool {
+	if lookup.kind != v.kind {
+		return false
+	}
+	if lookup.kind == calcString && strings.ContainsAny(lookup.str, "*?~") {
+		return matchWildcard(lookup.str, v.str)
+	}
+	return compareValues(lookup, v) == 0
+}
+
+// lookupPosition finds the position of lookup within values, which
+// has n entries.  A matchType of 0 requires an exact match, 1 finds
+// the largest value that is less than or equal to lookup in an
+// ascending list, and -1 finds the smallest value that is greater
+// than or equal to lookup in a descending list.  -1 is returned if
+// there is no match.
+func lookupPosition(lookup calcValue, n int, value func(i int) calcValue, matchType int) int {
+	if matchType == 0 {
+		for i := 0; i < n; i++ {
+			if lookupMatches(lookup, value(i)) {
+				return i
+			}
+		}
+		return -1
+	}
+	found := -1
+	for i := 0; i < n; i++ {
+		v := value(i)
+		if v.kind != lookup.kind {
+			continue
+		}
+		cmp := compareValues(v, lookup)
+		if matchType > 0 {
+			if cmp > 0 {
+				break
+			}
+		} else if cmp < 0 {
+			break
+		}
+		found = i
+	}
+	return found
+}
+
+func fnVLookup(ev *calcEvaluator, args []calcValue) calcValue {
+	lookup := ev.scalar(args[0])
+	if lookup.isError() {
+		return lookup
+	}
+	if args[1].kind != calcRange {
+		return errorValue(formulaErrorNA)
+	}
+	table := args[1].area
+	col, errv := intArg(ev, args[2])
+	if errv != nil {
+		return *errv
+	}
+	if col < 1 {
+		return errorValue(formulaErrorValue)
+	}
+	if col > table.cols {
+		return errorValue(formulaErrorRef)
+	}
+	approximate := true
+	if len(args) == 4 {
+		b, errv := toBool(ev.scalar(args[3]))
+		if errv != nil {
+			return *errv
+		}
+		approximate = b
+	}
+	matchType := 0
+	if approximate {
+		matchType = 1
+	}
+	row := lookupPosition(lookup, table.storedRows(), func(i int) calcValue {
+		return table.at(i, 0)
+	}, matchType)
+	if row < 0 {
+		return errorValue(formulaErrorNA)
+	}
+	return table.at(row, col-1)
+}
+
+func fnMatch(ev *calcEvaluator, args []calcValue) calcValue {
+	lookup := ev.scalar(args[0])
+	if lookup.isError() {
+		return lookup
+	}
+	a := asArea(args[1])
+	if a.rows != 1 && a.cols != 1 {
+		return errorValue(formulaErrorNA)
+	}
+	matchType := 1
+	if len(args) == 3 {
+		n, errv := toNumber(ev.scalar(args[2]))
+		if errv != nil {
+			return *errv
+		}
+		switch {
+		case n > 0:
+			matchType = 1
+		case n < 0:
+			matchType = -1
+		default:
+			matchType = 0
+		}
+	}
+	var pos int
+	if a.cols == 1 {
+		pos = lookupPosition(lookup, a.storedRows(), func(i int) calcValue { return a.at(i, 0) }, matchType)
+	} else {
+		pos = lookupPosition(lookup, a.width, func(i int) calcValue { return a.at(0, i) }, matchType)
+	}
+	if pos < 0 {
+		return errorValue(formulaErrorNA)
+	}
+	return numberValue(float64(pos + 1))
+}
+
+func fnIndex(ev *calcEvaluator, args []calcValue) calcValue {
+	a := asArea(args[0])
+	row, errv := intArg(ev, args[1])
+	if errv != nil {
+		return *errv
+	}
+	col := 0
+	if len(args) == 3 {
+		if col, errv = intArg(ev, args[2]); errv != nil {
+			return *errv
+		}
+	} else if a.rows == 1 {
+		// With a single row the only index is the column
+		row, col = 0, row
+	}
+	if row < 0 || col < 0 {
+		return errorValue(formulaErrorValue)
+	}
+	if row > a.rows || col > a.cols {
+		return errorValue(formulaErrorRef)
+	}
+	if a.rows == 1 && row == 0 && col > 0 {
+		row = 1
+	}
+	if a.cols == 1 && col == 0 && row > 0 {
+		col = 1
+	}
+	if row == 0 || col == 0 {
+		// A whole row or column of the area
+		sub := &calcArea{row: a.row, col: a.col, rows: a.rows, cols: a.cols, isRef: a.isRef}
+		if row > 0 {
+			sub.row, sub.rows = a.row+row-1, 1
+		}
+		if col > 0 {
+			sub.col, sub.cols = a.col+col-1, 1
+		}
+		for r := 0; r < sub.rows && r+sub.row-a.row < a.storedRows(); r++ {
+			for c := 0; c < sub.cols; c++ {
+				sub.values = append(sub.values, a.at(r+sub.row-a.row, c+sub.col-a.col))
+			}
+		}
+		sub.width = sub.cols
+		return calcValue{kind: calcRange, area: sub}
+	}
+	return a.at(row-1, col-1)
+}
+
+// roundDigits rounds half away from zero, as Excel does.  The value is
+// first reduced to 15 significant digits so that, for example,
+// 2.675 rounds to 2.68 despite its binary representation being
+// slightly smaller.
+func roundDigits(f float64, digits int) float64 {
+	p := math.Pow(10, float64(digits))
+	scaled, err := strconv.ParseFloat(strconv.FormatFloat(f*p, 'g', 15, 64), 64)
+	if err != nil {
+		return f
+	}
+	return math.Round(scaled) / p
+}
+
+func fnRound(ev *calcEvaluator, args []calcValue) calcValue {
+	f, errv := toNumber(ev.scalar(args[0]))
+	if errv != nil {
+		return *errv
+	}
+	digits, errv := intArg(ev, args[1])
+	if errv != nil {
+		return *errv
+	}
+	return numberValue(roundDigits(f, digits))
+}
+
+// fnText formats a value with a number format, reusing the formatting
+// applied by Cell.FormattedValue.
+func fnText(ev *calcEvaluator, args []calcValue) calcValue {
+	v := ev.scalar(args[0])
+	if v.isError() {
+		return v
+	}
+	format := ev.scalar(args[1])
+	if format.isError() {
+		return format
+	}
+	cell := &Cell{NumFmt: toText(format), date1904: ev.ctx.date1904}
+	if n, errv := toNumber(v); errv == nil && v.kind != calcBool {
+		cell.Value = strconv.FormatFloat(n, 'f', -1, 64)
+		cell.cellType = CellTypeNumeric
+	} else {
+		cell.Value = toText(v)
+		cell.cellType = CellTypeString
+	}
+	s, err := cell.FormattedValue()
+	if err != nil {
+		return errorValue(formulaErrorValue)
+	}
+	return stringValue(s)
+}
+
+// dateSerial converts a date to an Excel serial number.  In the 1900
+// date system dates before March 1900 are one less than a naive
+// calculation suggests, because Excel treats 1900 as a leap year.
+func dateSerial(t time.Time, date1904 bool) float64 {
+	serial := math.Round(TimeToExcelTime(t, date1904))
+	if !date1904 && serial < 61 {
+		serial--
+	}
+	return serial
+}
+
+func fnDate(ev *calcEvaluator, args []calcValue) calcValue {
+	var parts [3]int
+	for i := range parts {
+		n, errv := intArg(ev, args[i])
+		if errv != nil {
+			return *errv
+		}
+		parts[i] = n
+	}
+	year, month, day := parts[0], parts[1], parts[2]
+	if year < 0 || year >= 10000 {
+		return errorValue(formulaErrorNum)
+	}
+	if year < 1900 {
+		year += 1900
+	}
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	serial := dateSerial(t, ev.ctx.date1904)
+	if serial < 0 {
+		return errorValue(formulaErrorNum)
+	}
+	return numberValue(serial)
+}
+
+func fnToday(ev *calcEvaluator, args []calcValue) calcValue {
+	y, m, d := ev.ctx.now.Date()
+	return numberValue(dateSerial(time.Date(y, m, d, 0, 0, 0, 0, time.UTC), ev.ctx.date1904))
+}
+
+// textArg converts an argument to text.
+func textArg(ev *calcEvaluator, v calcValue) (string, *calcValue) {
+	v = ev.scalar(v)
+	if v.isError() {
+		return "", &v
+	}
+	return toText(v), nil
+}
+
+// substring implements LEFT, RIGHT and MID, with start being a zero
+// based character offset.
+func substring(s string, start, n int) string {
+	r := []rune(s)
+	if start > len(r) {
+		return ""
+	}
+	end := start + n
+	if end > len(r) {
+		end = len(r)
+	}
+	return string(r[start:end])
+}
+
+// countArg returns the optional character count argument of LEFT and
+// RIGHT, which defaults to one.
+func countArg(ev *calcEvaluator, args []calcValue) (int, *calcValue) {
+	if len(args) < 2 || args[1].kind == calcEmpty {
+		return 1, nil
+	}
+	n, errv := intArg(ev, args[1])
+	if errv != nil {
+		return 0, errv
+	}
+	if n < 0 {
+		e := errorValue(formulaErrorValue)
+		return 0, &e
+	}
+	return n, nil
+}
+
+func fnLeft(ev *calcEvaluator, args []calcValue) calcValue {
+	s, errv := textArg(ev, args[0])
+	if errv != nil {
+		return *errv
+	}
+	n, errv := countArg(ev, args)
+	if errv != nil {
+		return *errv
+	}
+	return stringValue(substring(s, 0, n))
+}
+
+func fnRight(ev *calcEvaluator, args []calcValue) calcValue {
+	s, errv := textArg(ev, args[0])
+	if errv != nil {
+		return *errv
+	}
+	n, errv := countArg(ev, args)
+	if errv != nil {
+		return *errv
+	}
+	length := utf8.RuneCountInString(s)
+	if n > length {
+		n = length
+	}
+	return stringValue(substring(s, length-n, n))
+}
+
+func fnMid(ev *calcEvaluator, args []calcValue) calcValue {
+	s, errv := textArg(ev, args[0])
+	if errv != nil {
+		return *errv
+	}
+	start, errv := intArg(ev, args[1])
+	if errv != nil {
+		return *errv
+	}
+	n, errv := intArg(ev, args[2])
+	if errv != nil {
+		return *errv
+	}
+	if start < 1 || n < 0 {
+		return errorValue(formulaErrorValue)
+	}
+	return stringValue(substring(s, start-1, n))
+}
+
+func fnLen(ev *calcEvaluator, args []calcValue) calcValue {
+	s, errv := textArg(ev, args[0])
+	if errv != nil {
+		return *errv
+	}
+	return numberValue(float64(utf8.RuneCountInString(s)))
+}
+
+func fnConcatenate(ev *calcEvaluator, args []calcValue) calcValue {
+	var b strings.Builder
+	for _, arg := range args {
+		s, errv := textArg(ev, arg)
+		if errv != nil {
+			return *errv
+		}
+		b.WriteString(s)
+	}
+	return stringValue(b.String())
+}
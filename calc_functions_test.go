@@ -0,0 +1,158 @@
+package xlsx
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestCalcFunctions(t *testing.T) {
+	c := qt.New(t)
+
+	// A small table of fruit, in A1:C5, for the functions to work on.
+	f := NewFile()
+	sheet, err := f.AddSheet("Sheet1")
+	c.Assert(err, qt.IsNil)
+	setCells(c, sheet, map[string]interface{}{
+		"A1": "Fruit", "B1": "Price", "C1": "Stock",
+		"A2": "Apple", "B2": 0.5, "C2": 10,
+		"A3": "Banana", "B3": 0.25, "C3": 0,
+		"A4": "Cherry", "B4": 3, "C4": 25,
+		"A5": "Date", "B5": 2, "C5": "n/a",
+		"E1": 10, "E2": 20, "E3": 30, "E4": 40,
+		"F1": true, "F2": "#N/A",
+	})
+	// F2 is an error rather than text
+	cellAt(c, sheet, "F2").cellType = CellTypeError
+
+	evaluate := func(formula string) *Cell {
+		cell := cellAt(c, sheet, "H1")
+		cell.SetFormula(formula)
+		err := cell.Evaluate()
+		c.Assert(err, qt.IsNil, qt.Commentf(formula))
+		return cell
+	}
+
+	cases := []struct {
+		formula string
+		value   string
+	}{
+		// Aggregates ignore text and logical values in references,
+		// but convert direct arguments.
+		{"SUM(B2:C5)", "40.75"},
+		{`SUM(1,"2",TRUE)`, "4"},
+		{`SUM("x")`, "#VALUE!"},
+		{"SUM(E1:F2)", "#N/A"},
+		{"SUM(E:E)", "100"},
+		{"AVERAGE(E1:E4)", "25"},
+		{"AVERAGE(A1:A5)", "#DIV/0!"},
+		{"MIN(B2:B5)", "0.25"},
+		{"MAX(B2:C5,100)", "100"},
+		{"MAX(A1:A5)", "0"},
+		{"COUNT(A1:C5)", "7"},
+		{`COUNT(1,"2","x",TRUE)`, "3"},
+
+		{`COUNTIF(C2:C5,">5")`, "2"},
+		{`COUNTIF(A2:A5,"?a*")`, "2"},
+		{`COUNTIF(A2:A5,"cherry")`, "1"},
+		{`COUNTIF(B2:B5,0.5)`, "1"},
+		{`COUNTIF(A1:C5,"<>Apple")`, "14"},
+		{`COUNTIF(G1:G3,"")`, "3"},
+		{`SUMIF(C2:C5,">=10",B2:B5)`, "3.5"},
+		{`SUMIF(E1:E4,">15")`, "90"},
+		{`SUMIF(A2:A5,"B*",C2:C5)`, "0"},
+
+		{`IF(E1>5,"big","small")`, "big"},
+		{`IF(E1>50,"big")`, "0"},
+		{`IF(E1>5,,1)`, "0"},
+		{`IF("x",1,2)`, "#VALUE!"},
+		{`IF(FALSE,1/0,"lazy")`, "lazy"},
+		{`IFERROR(1/0,"oops")`, "oops"},
+		{`IFERROR(F2,-1)`, "-1"},
+		{`IFERROR(5,-1)`, "5"},
+		{"AND(TRUE,E1>5)", "1"},
+		{"AND(E1:E4)", "1"},
+		{"AND(C2:C3)", "0"},
+		{"OR(FALSE,0)", "0"},
+		{"OR(A1:A5)", "#VALUE!"},
+
+		{"VLOOKUP(\"Cherry\",A2:C5,2,FALSE)", "3"},
+		{"VLOOKUP(\"cherry\",A2:C5,3,FALSE)", "25"},
+		{"VLOOKUP(\"Ch*\",A2:C5,3,FALSE)", "25"},
+		{"VLOOKUP(\"Fig\",A2:C5,2,FALSE)", "#N/A"},
+		{"VLOOKUP(\"Blueberry\",A2:C5,2)", "0.25"},
+		{"VLOOKUP(\"Apple\",A2:C5,4,FALSE)", "#REF!"},
+		{"VLOOKUP(\"Apple\",A2:C5,0,FALSE)", "#VALUE!"},
+		{"MATCH(30,E1:E4,0)", "3"},
+		{"MATCH(35,E1:E4)", "3"},
+		{"MATCH(5,E1:E4)", "#N/A"},
+		{"MATCH(25,{40,30,20,10},-1)", "2"},
+		{`MATCH("banana",A1:A5,0)`, "3"},
+		{"INDEX(A1:C5,3,1)", "Banana"},
+		{"INDEX(E1:E4,2)", "20"},
+		{"INDEX({1,2,3},3)", "3"},
+		{"INDEX(A1:C5,6,1)", "#REF!"},
+		{"SUM(INDEX(B2:C5,0,2))", "35"},
+		{`INDEX(B2:B5,MATCH("Date",A2:A5,0))`, "2"},
+
+		{"ROUND(2.675,2)", "2.68"},
+		{"ROUND(-2.5,0)", "-3"},
+		{"ROUND(1234.5,-2)", "1200"},
+		{`TEXT(1234.5,"0.00")`, "1234.50"},
+		{`TEXT(0.25,"0%")`, "25%"},
+		{`TEXT(DATE(2021,3,14),"yyyy-mm-dd")`, "2021-03-14"},
+		{"DATE(2020,1,1)", "43831"},
+		{"DATE(2020,13,1)", "44197"},
+		{"DATE(2020,3,0)", "43890"},
+		{"DATE(120,1,1)", "43831"},
+		{"DATE(1900,1,1)", "1"},
+		{"DATE(-1,1,1)", "#NUM!"},
+
+		{`LEFT("Spreadsheet",6)`, "Spread"},
+		{`LEFT("Spreadsheet")`, "S"},
+		{`RIGHT("Spreadsheet",5)`, "sheet"},
+		{`RIGHT("abc",10)`, "abc"},
+		{`MID("Spreadsheet",7,3)`, "she"},
+		{`MID("abc",0,1)`, "#VALUE!"},
+		{`LEFT("abc",-1)`, "#VALUE!"},
+		{`LEN("héllo")`, "5"},
+		{"LEN(E1)", "2"},
+		{`CONCATENATE(A2," costs ",B2)`, "Apple costs 0.5"},
+		{`_xlfn.CONCATENATE("a","b")`, "ab"},
+		{`concatenate("a",F2)`, "#N/A"},
+	}
+	for _, tc := range cases {
+		cell := evaluate(tc.formula)
+		c.Assert(cell.Value, qt.Equals, tc.value, qt.Commentf(tc.formula))
+	}
+
+	c.Run("Today", func(c *qt.C) {
+		cell := evaluate("TODAY()")
+		y, m, d := time.Now().Date()
+		expected := dateSerial(time.Date(y, m, d, 0, 0, 0, 0, time.UTC), false)
+		value, err := cell.Float()
+		c.Assert(err, qt.IsNil)
+		c.Assert(value, qt.Equals, expected)
+		c.Assert(cell.IsTime(), qt.Equals, true)
+	})
+
+	c.Run("WrongNumberOfArguments", func(c *qt.C) {
+		cell := cellAt(c, sheet, "H2")
+		cell.SetFormula("ROUND(1)")
+		err := cell.Evaluate()
+		c.Assert(err, qt.ErrorMatches, "Evaluate: wrong number of arguments to ROUND")
+	})
+}
+
+func TestMatchWildcard(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(matchWildcard("a*", "Apple"), qt.Equals, true)
+	c.Assert(matchWildcard("*le", "apple"), qt.Equals, true)
+	c.Assert(matchWildcard("a?ple", "apple"), qt.Equals, true)
+	c.Assert(matchWildcard("a?le", "apple"), qt.Equals, false)
+	c.Assert(matchWildcard("what~?", "what?"), qt.Equals, true)
+	c.Assert(matchWildcard("what~?", "whats"), qt.Equals, false)
+	c.Assert(matchWildcard("~*", "*"), qt.Equals, true)
+	c.Assert(matchWildcard("*", ""), qt.Equals, true)
+}
@@ -0,0 +1,280 @@
+package xlsx
+
+import (
+	"bytes"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestRecalculate(t *testing.T) {
+	c := qt.New(t)
+
+	csRunO(c, "ResultsAndTypes", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		setCells(c, sheet, map[string]interface{}{
+			"A1": 10,
+			"A2": 20,
+			"A3": "thirty",
+			"B1": "=SUM(A1:A3)",
+			"B2": `=A3&" and "&A1`,
+			"B3": "=A1>A2",
+			"B4": "=A1/0",
+			"B5": "=B1*2",
+			"B6": "=C10",
+			"B7": "=UNKNOWNFUNC(1)",
+			"B8": "=NoSuchName",
+		})
+		err = f.Recalculate()
+		c.Assert(err, qt.IsNil)
+
+		expect := func(ref, value string, cellType CellType) {
+			cell := cellAt(c, sheet, ref)
+			c.Assert(cell.Value, qt.Equals, value, qt.Commentf(ref))
+			c.Assert(cell.Type(), qt.Equals, cellType, qt.Commentf(ref))
+		}
+		expect("B1", "30", CellTypeNumeric)
+		expect("B2", "thirty and 10", CellTypeStringFormula)
+		expect("B3", "0", CellTypeBool)
+		expect("B4", "#DIV/0!", CellTypeError)
+		expect("B5", "60", CellTypeNumeric)
+		expect("B6", "0", CellTypeNumeric)
+		expect("B7", "#NAME?", CellTypeError)
+		expect("B8", "#NAME?", CellTypeError)
+
+		// The formulas are left in place
+		c.Assert(cellAt(c, sheet, "B5").Formula(), qt.Equals, "B1*2")
+	})
+
+	csRunO(c, "CrossSheetAndDefinedNames", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		data, err := f.AddSheet("Data Sheet")
+		c.Assert(err, qt.IsNil)
+		summary, err := f.AddSheet("Summary")
+		c.Assert(err, qt.IsNil)
+		setCells(c, data, map[string]interface{}{
+			"A1": 1.5,
+			"A2": 2.5,
+			"A3": "=A1+A2",
+		})
+		setCells(c, summary, map[string]interface{}{
+			"A1": "='Data Sheet'!A3*2",
+			"A2": "=SUM(Prices)*TaxRate",
+			"A3": "=MAX('data sheet'!A:A)",
+			"A4": "=Missing!A1",
+			"A5": "='[1]Data Sheet'!A3",
+		})
+		f.DefinedNames = append(f.DefinedNames,
+			&DefinedName{Name: "Prices", RefersTo: "'Data Sheet'!$A$1:$A$2"},
+			&DefinedName{Name: "TaxRate", RefersTo: "0.5"},
+		)
+		err = f.Recalculate()
+		c.Assert(err, qt.IsNil)
+		c.Assert(cellAt(c, data, "A3").Value, qt.Equals, "4")
+		c.Assert(cellAt(c, summary, "A1").Value, qt.Equals, "8")
+		c.Assert(cellAt(c, summary, "A2").Value, qt.Equals, "2")
+		c.Assert(cellAt(c, summary, "A3").Value, qt.Equals, "4")
+		c.Assert(cellAt(c, summary, "A4").Value, qt.Equals, "#REF!")
+		// We can't see the cells of other workbooks.
+		c.Assert(cellAt(c, summary, "A5").Value, qt.Equals, "#REF!")
+	})
+
+	csRunO(c, "FormulasDependOnLaterCells", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, _ := f.AddSheet("Sheet1")
+		setCells(c, sheet, map[string]interface{}{
+			"A1": "=A2+1",
+			"A2": "=A3+1",
+			"A3": 1,
+		})
+		err := f.Recalculate()
+		c.Assert(err, qt.IsNil)
+		c.Assert(cellAt(c, sheet, "A1").Value, qt.Equals, "3")
+		c.Assert(cellAt(c, sheet, "A2").Value, qt.Equals, "2")
+	})
+
+	csRunO(c, "CircularReference", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, _ := f.AddSheet("Sheet1")
+		setCells(c, sheet, map[string]interface{}{
+			"A1": "=B1",
+			"B1": "=A1+1",
+		})
+		err := f.Recalculate()
+		c.Assert(err, qt.ErrorMatches, `Recalculate: Sheet1!A1: circular reference to Sheet1!A1`)
+	})
+
+	csRunO(c, "InvalidFormula", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, _ := f.AddSheet("Sheet1")
+		setCells(c, sheet, map[string]interface{}{
+			"C2": "=SUM(1,",
+		})
+		err := f.Recalculate()
+		c.Assert(err, qt.ErrorMatches, `Recalculate: Sheet1!C2: invalid formula "SUM\(1,": unexpected end of formula`)
+	})
+
+	csRunO(c, "DateResultsGetADateFormat", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, _ := f.AddSheet("Sheet1")
+		setCells(c, sheet, map[string]interface{}{
+			"A1": "=DATE(2020,1,2)",
+			"A2": "=DATE(2020,1,2)+1",
+		})
+		err := f.Recalculate()
+		c.Assert(err, qt.IsNil)
+		cell := cellAt(c, sheet, "A1")
+		c.Assert(cell.Value, qt.Equals, "43832")
+		c.Assert(cell.IsTime(), qt.Equals, true)
+		cell = cellAt(c, sheet, "A2")
+		c.Assert(cell.Value, qt.Equals, "43833")
+		c.Assert(cell.IsTime(), qt.Equals, false)
+	})
+
+	csRunO(c, "RoundTrip", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, _ := f.AddSheet("Sheet1")
+		setCells(c, sheet, map[string]interface{}{
+			"A1": 2,
+			"A2": 3,
+			"B1": "=A1*A2",
+			"B2": `=IF(B1>5,"big","small")`,
+			"B3": "=1/0",
+			"B4": "=AND(TRUE,A1=2)",
+		})
+		err := f.Recalculate()
+		c.Assert(err, qt.IsNil)
+
+		var buf bytes.Buffer
+		err = f.Write(&buf)
+		c.Assert(err, qt.IsNil)
+		f2, err := OpenBinary(buf.Bytes(), option)
+		c.Assert(err, qt.IsNil)
+		sheet2 := f2.Sheets[0]
+
+		cell := cellAt(c, sheet2, "B1")
+		c.Assert(cell.Formula(), qt.Equals, "A1*A2")
+		c.Assert(cell.Value, qt.Equals, "6")
+		c.Assert(cell.Type(), qt.Equals, CellTypeNumeric)
+		cell = cellAt(c, sheet2, "B2")
+		c.Assert(cell.Value, qt.Equals, "big")
+		c.Assert(cell.Type(), qt.Equals, CellTypeStringFormula)
+		cell = cellAt(c, sheet2, "B3")
+		c.Assert(cell.Value, qt.Equals, "#DIV/0!")
+		c.Assert(cell.Type(), qt.Equals, CellTypeError)
+		cell = cellAt(c, sheet2, "B4")
+		c.Assert(cell.Value, qt.Equals, "1")
+		c.Assert(cell.Type(), qt.Equals, CellTypeBool)
+
+		// A stale cached value is replaced by recalculating
+		cellAt(c, sheet2, "A1").SetInt(10)
+		err = f2.Recalculate()
+		c.Assert(err, qt.IsNil)
+		c.Assert(cellAt(c, sheet2, "B1").Value, qt.Equals, "30")
+	})
+}
+
+func TestCellEvaluate(t *testing.T) {
+	c := qt.New(t)
+
+	csRunO(c, "UpdatesOnlyTheCell", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, _ := f.AddSheet("Sheet1")
+		setCells(c, sheet, map[string]interface{}{
+			"A1": 4,
+			"A2": "=A1*2",
+			"A3": "=A2+1",
+		})
+		cell := cellAt(c, sheet, "A3")
+		err := cell.Evaluate()
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Value, qt.Equals, "9")
+		c.Assert(cell.Type(), qt.Equals, CellTypeNumeric)
+		c.Assert(cellAt(c, sheet, "A2").Value, qt.Equals, "")
+	})
+
+	c.Run("WithoutASheet", func(c *qt.C) {
+		cell := &Cell{}
+		cell.SetFormula(`LEN("hello")`)
+		err := cell.Evaluate()
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Value, qt.Equals, "5")
+
+		cell.SetFormula("A1")
+		err = cell.Evaluate()
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Value, qt.Equals, "#REF!")
+	})
+
+	c.Run("NoFormula", func(c *qt.C) {
+		cell := &Cell{}
+		cell.SetString("plain")
+		err := cell.Evaluate()
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Value, qt.Equals, "plain")
+	})
+
+	c.Run("SelfReference", func(c *qt.C) {
+		f := NewFile()
+		sheet, _ := f.AddSheet("Sheet1")
+		setCells(c, sheet, map[string]interface{}{"B2": "=B2+1"})
+		err := cellAt(c, sheet, "B2").Evaluate()
+		c.Assert(err, qt.ErrorMatches, "Evaluate: circular reference to Sheet1!B2")
+	})
+}
+
+func TestCalcOperators(t *testing.T) {
+	c := qt.New(t)
+
+	cases := []struct {
+		formula string
+		value   string
+	}{
+		{"1+2*3", "7"},
+		{"(1+2)*3", "9"},
+		{"-2^2", "4"},
+		{"2^3^2", "64"},
+		{"50%", "0.5"},
+		{"0.1+0.2", "0.30000000000000004"},
+		{`"3"+1`, "4"},
+		{`"abc"+1`, "#VALUE!"},
+		{"TRUE+1", "2"},
+		{`1&2`, "12"},
+		{`0.1+0.2&""`, "0.3"},
+		{`TRUE&"x"`, "TRUEx"},
+		{`"a"="A"`, "1"},
+		{`"b">"A"`, "1"},
+		{`1<"a"`, "1"},
+		{`"z"<TRUE`, "1"},
+		{"1<>1", "0"},
+		{"#N/A+1", "#N/A"},
+		{"0^0", "#NUM!"},
+		{"(-8)^0.5", "#NUM!"},
+		{"{1,2,3}", "1"},
+	}
+	for _, tc := range cases {
+		cell := &Cell{}
+		cell.SetFormula(tc.formula)
+		err := cell.Evaluate()
+		c.Assert(err, qt.IsNil, qt.Commentf(tc.formula))
+		c.Assert(cell.Value, qt.Equals, tc.value, qt.Commentf(tc.formula))
+	}
+}
+
+func TestFormatCalcNumber(t *testing.T) {
+	c := qt.New(t)
+	cases := map[float64]string{
+		0:                   "0",
+		1.5:                 "1.5",
+		-42:                 "-42",
+		0.30000000000000004: "0.3",
+		123456789012345:     "123456789012345",
+		1e20:                "1E+20",
+		1.5e-10:             "1.5E-10",
+	}
+	for f, s := range cases {
+		c.Assert(formatCalcNumber(f), qt.Equals, s)
+	}
+}
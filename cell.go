@@ -5,9 +5,12 @@ import (
 	"encoding/base64"
 	"fmt"
 	"math"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/tealeg/xlsx/v3/formula"
 )
 
 const (
@@ -62,16 +65,22 @@ type Cell struct {
 	Value          string
 	RichText       []RichTextRun
 	formula        string
+	arrayRef       string
+	dynamicArray   bool
 	style          *Style
 	NumFmt         string
 	parsedNumFmt   *parsedNumberFormat
 	date1904       bool
 	Hidden         bool
+	// HMerge and VMerge are the number of columns and rows that
+	// the cell is merged across.  They must be set with Merge,
+	// which keeps the Sheet's index of merged ranges up to date.
 	HMerge         int
 	VMerge         int
 	cellType       CellType
 	DataValidation *xlsxDataValidation
 	Hyperlink      Hyperlink
+	Comment        *Comment
 	num            int
 }
 
@@ -146,6 +155,9 @@ func newCell(r *Row, num int) *Cell {
 func (c *Cell) Merge(hcells, vcells int) {
 	c.HMerge = hcells
 	c.VMerge = vcells
+	if c.Row != nil && c.Row.Sheet != nil {
+		c.Row.Sheet.setMerge(c.Row.num, c.num, hcells, vcells)
+	}
 }
 
 // Type returns the CellType of a cell. See CellType constants for more details.
@@ -406,14 +418,157 @@ func (c *Cell) Bool() bool {
 // SetFormula sets the format string for a cell.
 func (c *Cell) SetFormula(formula string) {
 	c.formula = formula
+	c.arrayRef = ""
+	c.dynamicArray = false
 	c.cellType = CellTypeNumeric
 }
 
 func (c *Cell) SetStringFormula(formula string) {
 	c.formula = formula
+	c.arrayRef = ""
+	c.dynamicArray = false
 	c.cellType = CellTypeStringFormula
 }
 
+// SetFormulaWithResult sets the formula of a cell together with the
+// result it gives, which is written to the file as the formula's
+// cached value.  Applications that don't calculate formulas, such as
+// file previewers, show the cached value instead of a blank cell.
+// The result may be any integer or floating point type, a string, a
+// bool, a time.Time, a formula.Error such as formula.Error{"#N/A"},
+// or nil for no cached value.
+func (c *Cell) SetFormulaWithResult(formula string, result interface{}) error {
+	v, isDate, err := resultToCalcValue(result, c.date1904)
+	if err != nil {
+		return fmt.Errorf("SetFormulaWithResult: %w", err)
+	}
+	c.SetFormula(formula)
+	if result == nil {
+		c.Value = ""
+		c.RichText = nil
+		return nil
+	}
+	c.setCalculatedValue(v, isDate)
+	return nil
+}
+
+// resultToCalcValue converts a result passed to SetFormulaWithResult
+// to a calcValue, reporting whether it is a date.
+func resultToCalcValue(result interface{}, date1904 bool) (calcValue, bool, error) {
+	switch t := result.(type) {
+	case nil:
+		return calcValue{}, false, nil
+	case string:
+		return stringValue(t), false, nil
+	case bool:
+		return boolValue(t), false, nil
+	case time.Time:
+		return numberValue(TimeToExcelTime(t, date1904)), true, nil
+	case formula.Error:
+		return errorValue(t.Value), false, nil
+	}
+	v := reflect.ValueOf(result)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return numberValue(float64(v.Int())), false, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return numberValue(float64(v.Uint())), false, nil
+	case reflect.Float32, reflect.Float64:
+		return numberValue(v.Float()), false, nil
+	}
+	return calcValue{}, false, fmt.Errorf("unsupported result type %T", result)
+}
+
+// FormulaResult returns the cached result of the cell's formula, as
+// read from a file, set by SetFormulaWithResult or calculated by
+// Evaluate.  The result is a float64, string, bool or formula.Error,
+// or nil if the cell has no formula or no cached result.  Value
+// holds the same result as text.
+func (c *Cell) FormulaResult() interface{} {
+	if c.formula == "" || c.Value == "" {
+		return nil
+	}
+	switch c.cellType {
+	case CellTypeNumeric:
+		if f, err := strconv.ParseFloat(c.Value, 64); err == nil {
+			return f
+		}
+	case CellTypeBool:
+		return c.Value == "1"
+	case CellTypeError:
+		return formula.Error{Value: c.Value}
+	}
+	return c.Value
+}
+
+// SetArrayFormula sets an array formula, of the kind entered in
+// Excel with Ctrl+Shift+Enter, whose results fill the range rangeRef
+// (e.g. "B2:B10").  The cell must be the top left cell of the range.
+// An empty rangeRef means the formula's result fills only this cell.
+func (c *Cell) SetArrayFormula(formula, rangeRef string) error {
+	ref, err := c.arrayRange(rangeRef)
+	if err != nil {
+		return fmt.Errorf("SetArrayFormula: %w", err)
+	}
+	c.SetFormula(formula)
+	c.arrayRef = ref
+	return nil
+}
+
+// SetDynamicArrayFormula sets a dynamic array formula, whose results
+// spill into the range rangeRef, as Excel does for functions such as
+// SORT and UNIQUE.  rangeRef gives the extent of the spill when the
+// workbook was last calculated; Excel recalculates it on opening.  The
+// cell must be the top left cell of the range.  An empty rangeRef
+// means the formula's result fills only this cell.
+func (c *Cell) SetDynamicArrayFormula(formula, rangeRef string) error {
+	ref, err := c.arrayRange(rangeRef)
+	if err != nil {
+		return fmt.Errorf("SetDynamicArrayFormula: %w", err)
+	}
+	c.SetFormula(formula)
+	c.arrayRef = ref
+	c.dynamicArray = true
+	return nil
+}
+
+// arrayRange checks that rangeRef is a range whose top left cell is
+// c, and returns it in its canonical form.
+func (c *Cell) arrayRange(rangeRef string) (string, error) {
+	col, row := c.num, c.Row.num
+	if rangeRef == "" {
+		return GetCellIDStringFromCoords(col, row), nil
+	}
+	ref, err := formula.ParseRef(rangeRef)
+	if err != nil {
+		return "", err
+	}
+	if ref.Sheet != "" || ref.IsWholeCols() || ref.IsWholeRows() {
+		return "", fmt.Errorf("invalid array formula range %q", rangeRef)
+	}
+	if ref.Col1 != col || ref.Row1 != row {
+		return "", fmt.Errorf("array formula range %q does not start at %s", rangeRef, GetCellIDStringFromCoords(col, row))
+	}
+	ref.AbsCol1, ref.AbsRow1, ref.AbsCol2, ref.AbsRow2 = false, false, false, false
+	return ref.String(), nil
+}
+
+// ArrayFormulaRef returns the range filled by the cell's array
+// formula, or an empty string if the cell doesn't hold an array
+// formula.
+func (c *Cell) ArrayFormulaRef() string {
+	if c.formula == "" {
+		return ""
+	}
+	return c.arrayRef
+}
+
+// IsDynamicArrayFormula returns true if the cell holds a dynamic
+// array formula.
+func (c *Cell) IsDynamicArrayFormula() bool {
+	return c.formula != "" && c.dynamicArray
+}
+
 // Formula returns the formula string for the cell.
 func (c *Cell) Formula() string {
 	return c.formula
@@ -1,12 +1,14 @@
 package xlsx
 
 import (
+	"bytes"
 	"math"
 	"path/filepath"
 	"testing"
 	"time"
 
 	qt "github.com/frankban/quicktest"
+	"github.com/tealeg/xlsx/v3/formula"
 )
 
 func TestCell(t *testing.T) {
@@ -639,6 +641,59 @@ func TestCell(t *testing.T) {
 		c.Assert(cell.Type(), qt.Equals, CellTypeStringFormula)
 	})
 
+	csRunO(c, "TestSetFormulaWithResult", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		results := map[string]interface{}{
+			"A1": 42,
+			"A2": 1.5,
+			"A3": "text",
+			"A4": true,
+			"A5": formula.Error{Value: "#DIV/0!"},
+			"A6": time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+			"A7": nil,
+		}
+		for ref, result := range results {
+			err := cellAt(c, sheet, ref).SetFormulaWithResult("Z"+ref[1:], result)
+			c.Assert(err, qt.IsNil)
+		}
+		err = cellAt(c, sheet, "B1").SetFormulaWithResult("Z1", struct{}{})
+		c.Assert(err, qt.ErrorMatches, `SetFormulaWithResult: unsupported result type struct {}`)
+
+		c.Assert(cellAt(c, sheet, "A3").Type(), qt.Equals, CellTypeStringFormula)
+		c.Assert(cellAt(c, sheet, "A6").IsTime(), qt.Equals, true)
+
+		parts, err := f.MakeStreamParts()
+		c.Assert(err, qt.IsNil)
+		sheetXML := parts["xl/worksheets/sheet1.xml"]
+		c.Assert(sheetXML, qt.Contains, `<f>Z1</f><v>42</v>`)
+		c.Assert(sheetXML, qt.Contains, `t="str"><f>Z3</f><v>text</v>`)
+		c.Assert(sheetXML, qt.Contains, `t="b"><f>Z4</f><v>1</v>`)
+		c.Assert(sheetXML, qt.Contains, `t="e"><f>Z5</f><v>#DIV/0!</v>`)
+
+		var buf bytes.Buffer
+		err = f.Write(&buf)
+		c.Assert(err, qt.IsNil)
+		f, err = OpenBinary(buf.Bytes(), option)
+		c.Assert(err, qt.IsNil)
+		sheet = f.Sheets[0]
+		expected := map[string]interface{}{
+			"A1": 42.0,
+			"A2": 1.5,
+			"A3": "text",
+			"A4": true,
+			"A5": formula.Error{Value: "#DIV/0!"},
+			"A6": 43832.0,
+			"A7": nil,
+		}
+		for ref, result := range expected {
+			cell := cellAt(c, sheet, ref)
+			c.Assert(cell.Formula(), qt.Equals, "Z"+ref[1:])
+			c.Assert(cell.FormulaResult(), qt.Equals, result, qt.Commentf(ref))
+		}
+	})
+
 	// TestOddInput is a regression test for #101. When the number format
 	// was "@" (string), the input below caused a crash in strconv.ParseFloat.
 	// The solution was to check if cell.Value was both a CellTypeString and
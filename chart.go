@@ -0,0 +1,399 @@
+package xlsx
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tealeg/xlsx/v3/formula"
+)
+
+// ChartType is the kind of a Chart.
+type ChartType int
+
+const (
+	ChartTypeColumn ChartType = iota // vertical bars
+	ChartTypeBar                     // horizontal bars
+	ChartTypeLine
+	ChartTypeArea
+	ChartTypePie
+	ChartTypeDoughnut
+	ChartTypeScatter
+)
+
+// ChartGrouping determines how the series of a column, bar, line or
+// area chart are combined.
+type ChartGrouping string
+
+const (
+	// ChartGroupingClustered places the series of a column or bar
+	// chart side by side, and plots the series of a line or area
+	// chart independently.  It is the default.
+	ChartGroupingClustered ChartGrouping = ""
+	// ChartGroupingStacked stacks each series on those before it.
+	ChartGroupingStacked ChartGrouping = "stacked"
+	// ChartGroupingPercentStacked stacks the series to show how
+	// much each contributes to the total.
+	ChartGroupingPercentStacked ChartGrouping = "percentStacked"
+)
+
+// LegendPosition is where the legend of a Chart is shown.
+type LegendPosition string
+
+const (
+	LegendRight  LegendPosition = "" // the default
+	LegendLeft   LegendPosition = "l"
+	LegendTop    LegendPosition = "t"
+	LegendBottom LegendPosition = "b"
+	LegendNone   LegendPosition = "none"
+)
+
+// The size, in pixels, that Excel gives a new chart.
+const (
+	defaultChartWidth  = 480
+	defaultChartHeight = 288
+)
+
+// ChartSeries is a series of values plotted on a Chart.  References
+// are to ranges of cells, such as "Sheet1!$B$2:$B$10".  A reference
+// without a sheet refers to the sheet the Chart is added to.
+type ChartSeries struct {
+	// Name is the name of the series shown in the legend.  NameRef,
+	// a reference to a cell holding the name, is used instead if it
+	// is set.
+	Name    string
+	NameRef string
+	// Categories refers to the labels of the values or, for a
+	// scatter chart, the x values.
+	Categories string
+	// Values refers to the values, or y values, of the series.
+	Values string
+	// Color is the color of the series as six hexadecimal RGB
+	// digits, such as "4472C4".  Empty means the color Excel
+	// chooses.  The slices of pie and doughnut charts each have a
+	// color of their own, so Color isn't used for them.
+	Color string
+}
+
+// ChartSpec describes a Chart.
+type ChartSpec struct {
+	Type     ChartType
+	Grouping ChartGrouping
+	Title    string
+	Series   []ChartSeries
+	// XAxisTitle and YAxisTitle are the titles of the category, or
+	// x, axis and of the value, or y, axis.  Pie and doughnut charts
+	// have no axes.
+	XAxisTitle, YAxisTitle string
+	Legend                 LegendPosition
+	// DataLabels shows the value of each point.
+	DataLabels bool
+	// Width and Height are the size of the Chart in pixels.  Zero
+	// means the size Excel gives a new chart.
+	Width, Height int
+}
+
+// Chart is a chart drawn on a Sheet from the values of its cells.
+type Chart struct {
+	Spec   ChartSpec
+	Anchor Anchor
+}
+
+// hexColor matches a color given as six hexadecimal RGB digits.
+var hexColor = regexp.MustCompile(`^[0-9A-Fa-f]{6}$`)
+
+// AddChart draws the chart described by spec on the Sheet at anchor
+// and returns it.  Charts are written with the File, but existing
+// charts aren't read.
+func (s *Sheet) AddChart(spec ChartSpec, anchor Anchor) (*Chart, error) {
+	wrap := func(err error) (*Chart, error) {
+		return nil, fmt.Errorf("AddChart: %w", err)
+	}
+
+	if spec.Type < ChartTypeColumn || spec.Type > ChartTypeScatter {
+		return wrap(fmt.Errorf("unsupported chart type %d", spec.Type))
+	}
+	switch spec.Grouping {
+	case ChartGroupingClustered, ChartGroupingStacked, ChartGroupingPercentStacked:
+	default:
+		return wrap(fmt.Errorf("unsupported chart grouping %q", spec.Grouping))
+	}
+	switch spec.Legend {
+	case LegendRight, LegendLeft, LegendTop, LegendBottom, LegendNone:
+	default:
+		return wrap(fmt.Errorf("unsupported legend position %q", spec.Legend))
+	}
+	if len(spec.Series) == 0 {
+		return wrap(errors.New("a chart needs at least one series"))
+	}
+	if spec.Width < 0 || spec.Height < 0 {
+		return wrap(errors.New("chart size must not be negative"))
+	}
+	if err := anchor.validate(); err != nil {
+		return wrap(err)
+	}
+	series := make([]ChartSeries, len(spec.Series))
+	for i, ser := range spec.Series {
+		var err error
+		if ser.Values == "" {
+			return wrap(fmt.Errorf("series %d has no values", i))
+		}
+		if ser.Values, err = s.chartRef(ser.Values); err != nil {
+			return wrap(fmt.Errorf("series %d: %w", i, err))
+		}
+		if ser.Categories != "" {
+			if ser.Categories, err = s.chartRef(ser.Categories); err != nil {
+				return wrap(fmt.Errorf("series %d: %w", i, err))
+			}
+		}
+		if ser.NameRef != "" {
+			if ser.NameRef, err = s.chartRef(ser.NameRef); err != nil {
+				return wrap(fmt.Errorf("series %d: %w", i, err))
+			}
+		}
+		if ser.Color != "" && !hexColor.MatchString(ser.Color) {
+			return wrap(fmt.Errorf("series %d: invalid color %q", i, ser.Color))
+		}
+		series[i] = ser
+	}
+	spec.Series = series
+
+	chart := &Chart{Spec: spec, Anchor: anchor}
+	s.Charts = append(s.Charts, chart)
+	return chart, nil
+}
+
+// chartRef returns ref, a reference to a range of cells, as an
+// absolute reference qualified by its sheet name, which is that of
+// the Sheet if ref doesn't have one.
+func (s *Sheet) chartRef(ref string) (string, error) {
+	r, err := formula.ParseRef(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid reference %q", ref)
+	}
+	if r.Sheet == "" {
+		r.Sheet = s.Name
+	}
+	r.AbsCol1, r.AbsRow1, r.AbsCol2, r.AbsRow2 = true, true, true, true
+	return r.String(), nil
+}
+
+// extent returns the width and height of the Chart, as shown, in
+// EMUs.
+func (c *Chart) extent() (int64, int64) {
+	width, height := c.Spec.Width, c.Spec.Height
+	if width == 0 {
+		width = defaultChartWidth
+	}
+	if height == 0 {
+		height = defaultChartHeight
+	}
+	scaleX, scaleY := c.Anchor.ScaleX, c.Anchor.ScaleY
+	if scaleX == 0 {
+		scaleX = 1
+	}
+	if scaleY == 0 {
+		scaleY = 1
+	}
+	return int64(float64(width)*scaleX*emusPerPixel + 0.5),
+		int64(float64(height)*scaleY*emusPerPixel + 0.5)
+}
+
+// makeChartParts adds the chart parts of the Sheet's charts to sp,
+// and places the charts on its drawing.
+func (s *Sheet) makeChartParts(sp *sheetParts) {
+	for i, chart := range s.Charts {
+		sp.wp.charts++
+		name := fmt.Sprintf("xl/charts/chart%d.xml", sp.wp.charts)
+		sp.addPart(name, chart.Spec.makeChartSpace(),
+			"application/vnd.openxmlformats-officedocument.drawingml.chart+xml")
+		dp := sp.drawing()
+		rId := dp.addRelation(RelationshipTypeChart, "../"+strings.TrimPrefix(name, "xl/"))
+		cx, cy := chart.extent()
+		dp.addAnchor(s, chart.Anchor, cx, cy, fmt.Sprintf(`<xdr:graphicFrame macro="">`+
+			`<xdr:nvGraphicFramePr><xdr:cNvPr id="%d" name="Chart %d"/><xdr:cNvGraphicFramePr/></xdr:nvGraphicFramePr>`+
+			`<xdr:xfrm><a:off x="0" y="0"/><a:ext cx="0" cy="0"/></xdr:xfrm>`+
+			`<a:graphic><a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/chart">`+
+			`<c:chart xmlns:c="http://schemas.openxmlformats.org/drawingml/2006/chart" r:id="%s"/>`+
+			`</a:graphicData></a:graphic></xdr:graphicFrame>`,
+			dp.nextShapeId(), i+1, rId))
+	}
+}
+
+// The ids that tie the series of a chart to its axes.
+const (
+	catAxId = 1
+	valAxId = 2
+)
+
+// makeChartSpace returns the XML of the chart part for spec.
+func (spec ChartSpec) makeChartSpace() string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<c:chartSpace xmlns:c="http://schemas.openxmlformats.org/drawingml/2006/chart" ` +
+		`xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<c:roundedCorners val="0"/><c:chart>`)
+	if spec.Title != "" {
+		writeChartTitle(&b, spec.Title)
+		b.WriteString(`<c:autoTitleDeleted val="0"/>`)
+	} else {
+		b.WriteString(`<c:autoTitleDeleted val="1"/>`)
+	}
+	b.WriteString(`<c:plotArea><c:layout/>`)
+
+	grouping := string(spec.Grouping)
+	switch spec.Type {
+	case ChartTypeColumn, ChartTypeBar:
+		barDir := "col"
+		if spec.Type == ChartTypeBar {
+			barDir = "bar"
+		}
+		if grouping == "" {
+			grouping = "clustered"
+		}
+		fmt.Fprintf(&b, `<c:barChart><c:barDir val="%s"/><c:grouping val="%s"/><c:varyColors val="0"/>`, barDir, grouping)
+		spec.writeSeries(&b)
+		b.WriteString(`<c:gapWidth val="150"/>`)
+		if spec.Grouping != ChartGroupingClustered {
+			b.WriteString(`<c:overlap val="100"/>`)
+		}
+		writeAxIds(&b)
+		b.WriteString(`</c:barChart>`)
+	case ChartTypeLine, ChartTypeArea:
+		elem := "lineChart"
+		if spec.Type == ChartTypeArea {
+			elem = "areaChart"
+		}
+		if grouping == "" {
+			grouping = "standard"
+		}
+		fmt.Fprintf(&b, `<c:%s><c:grouping val="%s"/><c:varyColors val="0"/>`, elem, grouping)
+		spec.writeSeries(&b)
+		if spec.Type == ChartTypeLine {
+			b.WriteString(`<c:marker val="1"/>`)
+		}
+		writeAxIds(&b)
+		fmt.Fprintf(&b, `</c:%s>`, elem)
+	case ChartTypePie:
+		b.WriteString(`<c:pieChart><c:varyColors val="1"/>`)
+		spec.writeSeries(&b)
+		b.WriteString(`<c:firstSliceAng val="0"/></c:pieChart>`)
+	case ChartTypeDoughnut:
+		b.WriteString(`<c:doughnutChart><c:varyColors val="1"/>`)
+		spec.writeSeries(&b)
+		b.WriteString(`<c:firstSliceAng val="0"/><c:holeSize val="50"/></c:doughnutChart>`)
+	case ChartTypeScatter:
+		b.WriteString(`<c:scatterChart><c:scatterStyle val="lineMarker"/><c:varyColors val="0"/>`)
+		spec.writeSeries(&b)
+		writeAxIds(&b)
+		b.WriteString(`</c:scatterChart>`)
+	}
+
+	switch spec.Type {
+	case ChartTypeColumn, ChartTypeLine, ChartTypeArea:
+		writeAxis(&b, "catAx", catAxId, "b", spec.XAxisTitle, "between")
+		writeAxis(&b, "valAx", valAxId, "l", spec.YAxisTitle, "between")
+	case ChartTypeBar:
+		writeAxis(&b, "catAx", catAxId, "l", spec.XAxisTitle, "between")
+		writeAxis(&b, "valAx", valAxId, "b", spec.YAxisTitle, "between")
+	case ChartTypeScatter:
+		writeAxis(&b, "valAx", catAxId, "b", spec.XAxisTitle, "midCat")
+		writeAxis(&b, "valAx", valAxId, "l", spec.YAxisTitle, "midCat")
+	}
+	b.WriteString(`</c:plotArea>`)
+
+	if spec.Legend != LegendNone {
+		legendPos := string(spec.Legend)
+		if legendPos == "" {
+			legendPos = "r"
+		}
+		fmt.Fprintf(&b, `<c:legend><c:legendPos val="%s"/><c:overlay val="0"/></c:legend>`, legendPos)
+	}
+	b.WriteString(`<c:plotVisOnly val="1"/><c:dispBlanksAs val="gap"/></c:chart></c:chartSpace>`)
+	return b.String()
+}
+
+// writeSeries writes the ser elements of the chart, followed by its
+// data labels.
+func (spec ChartSpec) writeSeries(b *strings.Builder) {
+	for i, ser := range spec.Series {
+		fmt.Fprintf(b, `<c:ser><c:idx val="%d"/><c:order val="%d"/>`, i, i)
+		if ser.NameRef != "" {
+			fmt.Fprintf(b, `<c:tx><c:strRef><c:f>%s</c:f></c:strRef></c:tx>`, escapeXML(ser.NameRef))
+		} else if ser.Name != "" {
+			fmt.Fprintf(b, `<c:tx><c:v>%s</c:v></c:tx>`, escapeXML(ser.Name))
+		}
+		if ser.Color != "" && spec.Type != ChartTypePie && spec.Type != ChartTypeDoughnut {
+			fill := fmt.Sprintf(`<a:solidFill><a:srgbClr val="%s"/></a:solidFill>`, strings.ToUpper(ser.Color))
+			switch spec.Type {
+			case ChartTypeLine, ChartTypeScatter:
+				fmt.Fprintf(b, `<c:spPr><a:ln w="28575" cap="rnd">%s</a:ln></c:spPr>`, fill)
+				fmt.Fprintf(b, `<c:marker><c:symbol val="circle"/><c:size val="5"/><c:spPr>%s</c:spPr></c:marker>`, fill)
+			default:
+				fmt.Fprintf(b, `<c:spPr>%s</c:spPr>`, fill)
+			}
+		}
+		if spec.Type == ChartTypeColumn || spec.Type == ChartTypeBar {
+			b.WriteString(`<c:invertIfNegative val="0"/>`)
+		}
+		if spec.Type == ChartTypeScatter {
+			if ser.Categories != "" {
+				fmt.Fprintf(b, `<c:xVal><c:numRef><c:f>%s</c:f></c:numRef></c:xVal>`, escapeXML(ser.Categories))
+			}
+			fmt.Fprintf(b, `<c:yVal><c:numRef><c:f>%s</c:f></c:numRef></c:yVal><c:smooth val="0"/>`, escapeXML(ser.Values))
+		} else {
+			if ser.Categories != "" {
+				fmt.Fprintf(b, `<c:cat><c:strRef><c:f>%s</c:f></c:strRef></c:cat>`, escapeXML(ser.Categories))
+			}
+			fmt.Fprintf(b, `<c:val><c:numRef><c:f>%s</c:f></c:numRef></c:val>`, escapeXML(ser.Values))
+			if spec.Type == ChartTypeLine {
+				b.WriteString(`<c:smooth val="0"/>`)
+			}
+		}
+		b.WriteString(`</c:ser>`)
+	}
+	if spec.DataLabels {
+		b.WriteString(`<c:dLbls><c:showLegendKey val="0"/><c:showVal val="1"/><c:showCatName val="0"/>` +
+			`<c:showSerName val="0"/><c:showPercent val="0"/><c:showBubbleSize val="0"/></c:dLbls>`)
+	}
+}
+
+// writeChartTitle writes a title element holding text.
+func writeChartTitle(b *strings.Builder, text string) {
+	fmt.Fprintf(b, `<c:title><c:tx><c:rich><a:bodyPr/><a:lstStyle/><a:p><a:r><a:t>%s</a:t></a:r></a:p></c:rich></c:tx>`+
+		`<c:overlay val="0"/></c:title>`, escapeXML(text))
+}
+
+// writeAxIds writes the ids of the axes that the series of a chart
+// are plotted against.
+func writeAxIds(b *strings.Builder) {
+	fmt.Fprintf(b, `<c:axId val="%d"/><c:axId val="%d"/>`, catAxId, valAxId)
+}
+
+// writeAxis writes the axis with the id axId, of the kind elem, catAx
+// or valAx, at the position axPos.  crossBetween is where a value axis
+// crosses the other axis.
+func writeAxis(b *strings.Builder, elem string, axId int, axPos, title, crossBetween string) {
+	crossAx := valAxId
+	if axId == valAxId {
+		crossAx = catAxId
+	}
+	fmt.Fprintf(b, `<c:%s><c:axId val="%d"/><c:scaling><c:orientation val="minMax"/></c:scaling>`+
+		`<c:delete val="0"/><c:axPos val="%s"/>`, elem, axId, axPos)
+	if axId == valAxId {
+		b.WriteString(`<c:majorGridlines/>`)
+	}
+	if title != "" {
+		writeChartTitle(b, title)
+	}
+	fmt.Fprintf(b, `<c:majorTickMark val="out"/><c:minorTickMark val="none"/><c:tickLblPos val="nextTo"/>`+
+		`<c:crossAx val="%d"/><c:crosses val="autoZero"/>`, crossAx)
+	if elem == "catAx" {
+		b.WriteString(`<c:auto val="1"/><c:lblAlgn val="ctr"/><c:lblOffset val="100"/><c:noMultiLvlLbl val="0"/>`)
+	} else {
+		fmt.Fprintf(b, `<c:crossBetween val="%s"/>`, crossBetween)
+	}
+	fmt.Fprintf(b, `</c:%s>`, elem)
+}
@@ -0,0 +1,129 @@
+package xlsx
+
+import (
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestCharts(t *testing.T) {
+	c := qt.New(t)
+
+	setUp := func(c *qt.C, option FileOption) *File {
+		f := NewFile(option)
+		sales, err := f.AddSheet("Sales 2020")
+		c.Assert(err, qt.IsNil)
+		for i, row := range [][]string{{"Month", "North", "South"}, {"Jan", "10", "20"}, {"Feb", "15", "18"}, {"Mar", "12", "25"}} {
+			for j, v := range row {
+				cell, err := sales.Cell(i, j)
+				c.Assert(err, qt.IsNil)
+				cell.SetString(v)
+			}
+		}
+		_, err = sales.AddChart(ChartSpec{
+			Type:       ChartTypeColumn,
+			Grouping:   ChartGroupingStacked,
+			Title:      "Sales & returns",
+			XAxisTitle: "Month",
+			YAxisTitle: "Units",
+			Legend:     LegendBottom,
+			DataLabels: true,
+			Series: []ChartSeries{
+				{NameRef: "B1", Categories: "A2:A4", Values: "B2:B4", Color: "4472c4"},
+				{Name: "South", Categories: "A2:A4", Values: "$C$2:$C$4"},
+			},
+		}, Anchor{Type: TwoCellAnchor, Col: 4, Row: 1})
+		c.Assert(err, qt.IsNil)
+		_, err = sales.AddPicture(testImage(c, PictureFormatPNG, 10, 10), PictureFormatPNG, Anchor{Col: 0, Row: 6})
+		c.Assert(err, qt.IsNil)
+
+		summary, err := f.AddSheet("Summary")
+		c.Assert(err, qt.IsNil)
+		_, err = summary.AddChart(ChartSpec{
+			Type:   ChartTypePie,
+			Legend: LegendNone,
+			Series: []ChartSeries{{Categories: "'Sales 2020'!A2:A4", Values: "'Sales 2020'!B2:B4", Color: "FF0000"}},
+		}, Anchor{})
+		c.Assert(err, qt.IsNil)
+		_, err = summary.AddChart(ChartSpec{
+			Type:   ChartTypeScatter,
+			Series: []ChartSeries{{Categories: "'Sales 2020'!B2:B4", Values: "'Sales 2020'!C2:C4", Color: "00FF00"}},
+			Width:  200,
+			Height: 100,
+		}, Anchor{Type: AbsoluteAnchor, OffsetX: 500})
+		c.Assert(err, qt.IsNil)
+		return f
+	}
+
+	c.Run("AddChart", func(c *qt.C) {
+		sheet, err := NewSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		_, err = sheet.AddChart(ChartSpec{}, Anchor{})
+		c.Assert(err, qt.ErrorMatches, `AddChart: a chart needs at least one series`)
+		_, err = sheet.AddChart(ChartSpec{Type: ChartTypeScatter + 1}, Anchor{})
+		c.Assert(err, qt.ErrorMatches, `AddChart: unsupported chart type 7`)
+		_, err = sheet.AddChart(ChartSpec{Grouping: "sideways"}, Anchor{})
+		c.Assert(err, qt.ErrorMatches, `AddChart: unsupported chart grouping "sideways"`)
+		_, err = sheet.AddChart(ChartSpec{Legend: "middle"}, Anchor{})
+		c.Assert(err, qt.ErrorMatches, `AddChart: unsupported legend position "middle"`)
+		_, err = sheet.AddChart(ChartSpec{Series: []ChartSeries{{Name: "Empty"}}}, Anchor{})
+		c.Assert(err, qt.ErrorMatches, `AddChart: series 0 has no values`)
+		_, err = sheet.AddChart(ChartSpec{Series: []ChartSeries{{Values: "A1:A3"}, {Values: "not a range"}}}, Anchor{})
+		c.Assert(err, qt.ErrorMatches, `AddChart: series 1: invalid reference "not a range"`)
+		_, err = sheet.AddChart(ChartSpec{Series: []ChartSeries{{Values: "A1:A3", Color: "blue"}}}, Anchor{})
+		c.Assert(err, qt.ErrorMatches, `AddChart: series 0: invalid color "blue"`)
+		_, err = sheet.AddChart(ChartSpec{Series: []ChartSeries{{Values: "A1:A3"}}}, Anchor{Row: -1})
+		c.Assert(err, qt.ErrorMatches, `AddChart: anchor positions must not be negative`)
+
+		series := []ChartSeries{{Values: "A1:A3", Categories: "Other!B1:B3"}}
+		chart, err := sheet.AddChart(ChartSpec{Series: series}, Anchor{})
+		c.Assert(err, qt.IsNil)
+		c.Assert(chart.Spec.Series[0].Values, qt.Equals, "Sheet1!$A$1:$A$3")
+		c.Assert(chart.Spec.Series[0].Categories, qt.Equals, "Other!$B$1:$B$3")
+		c.Assert(series[0].Values, qt.Equals, "A1:A3")
+		c.Assert(sheet.Charts, qt.HasLen, 1)
+		c.Assert(sheet.Charts[0], qt.Equals, chart)
+	})
+
+	csRunParts(c, "Parts", setUp, func(c *qt.C, parts map[string]string) {
+		chart := parts["xl/charts/chart1.xml"]
+		c.Assert(chart, qt.Contains, `<c:title><c:tx><c:rich><a:bodyPr/><a:lstStyle/><a:p><a:r><a:t>Sales &amp; returns</a:t>`)
+		c.Assert(chart, qt.Contains, `<c:barChart><c:barDir val="col"/><c:grouping val="stacked"/>`)
+		c.Assert(chart, qt.Contains, `<c:tx><c:strRef><c:f>&#39;Sales 2020&#39;!$B$1</c:f></c:strRef></c:tx><c:spPr><a:solidFill><a:srgbClr val="4472C4"/></a:solidFill></c:spPr>`)
+		c.Assert(chart, qt.Contains, `<c:cat><c:strRef><c:f>&#39;Sales 2020&#39;!$A$2:$A$4</c:f></c:strRef></c:cat><c:val><c:numRef><c:f>&#39;Sales 2020&#39;!$B$2:$B$4</c:f></c:numRef></c:val>`)
+		c.Assert(chart, qt.Contains, `<c:tx><c:v>South</c:v></c:tx><c:invertIfNegative val="0"/>`)
+		c.Assert(chart, qt.Contains, `<c:dLbls><c:showLegendKey val="0"/><c:showVal val="1"/>`)
+		c.Assert(chart, qt.Contains, `<c:overlap val="100"/><c:axId val="1"/><c:axId val="2"/></c:barChart>`)
+		c.Assert(chart, qt.Contains, `<a:t>Month</a:t>`)
+		c.Assert(chart, qt.Contains, `<a:t>Units</a:t>`)
+		c.Assert(chart, qt.Contains, `<c:legend><c:legendPos val="b"/>`)
+
+		pie := parts["xl/charts/chart2.xml"]
+		c.Assert(pie, qt.Contains, `<c:pieChart><c:varyColors val="1"/>`)
+		c.Assert(pie, qt.Contains, `<c:autoTitleDeleted val="1"/>`)
+		c.Assert(pie, qt.Not(qt.Contains), `<c:legend>`)
+		c.Assert(pie, qt.Not(qt.Contains), `<c:axId`)
+		c.Assert(pie, qt.Not(qt.Contains), `srgbClr`)
+
+		scatter := parts["xl/charts/chart3.xml"]
+		c.Assert(scatter, qt.Contains, `<c:xVal><c:numRef><c:f>&#39;Sales 2020&#39;!$B$2:$B$4</c:f></c:numRef></c:xVal>`)
+		c.Assert(scatter, qt.Contains, `<c:legendPos val="r"/>`)
+		c.Assert(strings.Count(scatter, `<c:valAx>`), qt.Equals, 2)
+
+		drawing := parts["xl/drawings/drawing1.xml"]
+		c.Assert(drawing, qt.Contains, `<xdr:cNvPr id="2" name="Picture 1" descr=""/>`)
+		c.Assert(drawing, qt.Contains, `<xdr:cNvPr id="3" name="Chart 1"/>`)
+		c.Assert(drawing, qt.Contains, `<c:chart xmlns:c="http://schemas.openxmlformats.org/drawingml/2006/chart" r:id="rId2"/>`)
+		// 480 by 288 pixels from E2 spans seven columns and a half
+		// and fourteen rows and a half.
+		c.Assert(drawing, qt.Contains, `<xdr:to><xdr:col>11</xdr:col><xdr:colOff>304800</xdr:colOff><xdr:row>15</xdr:row><xdr:rowOff>76200</xdr:rowOff></xdr:to>`)
+		c.Assert(parts["xl/drawings/_rels/drawing1.xml.rels"], qt.Contains, `Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/chart" Target="../charts/chart1.xml"`)
+		c.Assert(parts["xl/drawings/_rels/drawing2.xml.rels"], qt.Contains, `Target="../charts/chart3.xml"`)
+		c.Assert(parts["xl/drawings/drawing2.xml"], qt.Contains, `<xdr:absoluteAnchor><xdr:pos x="4762500" y="0"/><xdr:ext cx="1905000" cy="952500"/>`)
+		c.Assert(parts["xl/worksheets/sheet2.xml"], qt.Contains, `<drawing r:id="rId1"`)
+
+		types := parts["[Content_Types].xml"]
+		c.Assert(types, qt.Contains, `<Override PartName="/xl/charts/chart3.xml" ContentType="application/vnd.openxmlformats-officedocument.drawingml.chart+xml">`)
+	})
+}
@@ -1,5 +1,9 @@
 package xlsx
 
+import (
+	"github.com/tealeg/xlsx/v3/formula"
+)
+
 // Default column width in excel
 const ColWidth = 9.5
 const Excel2006MaxRowCount = 1048576
@@ -391,6 +395,56 @@ func chainOp(csn *ColStoreNode, fn func(idx int, col *Col)) {
 	fn(i+1, csn.Col)
 }
 
+// shiftCols moves the Cols in the ColStore to follow n columns being
+// inserted before the one based column at, or -n columns being
+// removed from at onwards.  A Col that spans the inserted columns is
+// split, so that they have no Col of their own, and a Col that spans
+// removed columns shrinks.
+func (cs *ColStore) shiftCols(at, n int) {
+	var cols []*Col
+	cs.ForEach(func(_ int, col *Col) {
+		cols = append(cols, col)
+	})
+	*cs = ColStore{}
+	add := func(col *Col) {
+		if col.Min > formula.MaxCols {
+			return
+		}
+		if col.Max > formula.MaxCols {
+			col.Max = formula.MaxCols
+		}
+		cs.Add(col)
+	}
+	end := at - n // the first column after those removed
+	for _, col := range cols {
+		switch {
+		case col.Max < at:
+			add(col)
+		case n > 0 && col.Min >= at, n < 0 && col.Min >= end:
+			col.Min += n
+			col.Max += n
+			add(col)
+		case n > 0:
+			tail := col.copyToRange(at+n, col.Max+n)
+			col.Max = at - 1
+			add(col)
+			add(tail)
+		case col.Min >= at && col.Max < end:
+			// All of the Col's columns are removed
+		default:
+			if col.Min > at {
+				col.Min = at
+			}
+			if col.Max < end {
+				col.Max = at - 1
+			} else {
+				col.Max += n
+			}
+			add(col)
+		}
+	}
+}
+
 // ForEach calls the function fn for each Col defined in the ColStore.
 func (cs *ColStore) ForEach(fn func(idx int, col *Col)) {
 	if cs.Root == nil {
@@ -578,3 +578,61 @@ func (css *ColStoreSuite) TestGetOrMakeColsForRange(c *C) {
 	)
 
 }
+
+func TestShiftCols(t *testing.T) {
+	c := qt.New(t)
+
+	// ranges returns the Min and Max of each Col in the store
+	ranges := func(cs *ColStore) [][2]int {
+		var r [][2]int
+		cs.ForEach(func(_ int, col *Col) {
+			r = append(r, [2]int{col.Min, col.Max})
+		})
+		return r
+	}
+	setUp := func() *ColStore {
+		cs := &ColStore{}
+		cs.Add(NewColForRange(1, 1))
+		cs.Add(NewColForRange(3, 6))
+		cs.Add(NewColForRange(8, 9))
+		return cs
+	}
+
+	c.Run("InsertSplitsAndShifts", func(c *qt.C) {
+		cs := setUp()
+		width := 20.0
+		cs.FindColByIndex(4).Width = &width
+		cs.shiftCols(5, 2)
+		c.Assert(ranges(cs), qt.DeepEquals, [][2]int{{1, 1}, {3, 4}, {7, 8}, {10, 11}})
+		// The split Col keeps its settings on both sides
+		c.Assert(*cs.FindColByIndex(8).Width, qt.Equals, 20.0)
+		c.Assert(cs.FindColByIndex(5), qt.IsNil)
+	})
+
+	c.Run("InsertBeforeAll", func(c *qt.C) {
+		cs := setUp()
+		cs.shiftCols(1, 1)
+		c.Assert(ranges(cs), qt.DeepEquals, [][2]int{{2, 2}, {4, 7}, {9, 10}})
+	})
+
+	c.Run("InsertPushesOffTheSheet", func(c *qt.C) {
+		cs := &ColStore{}
+		cs.Add(NewColForRange(16380, 16384))
+		cs.shiftCols(16383, 1)
+		c.Assert(ranges(cs), qt.DeepEquals, [][2]int{{16380, 16382}, {16384, 16384}})
+	})
+
+	c.Run("RemoveShrinksAndDrops", func(c *qt.C) {
+		cs := setUp()
+		// Remove columns 1 and 2
+		cs.shiftCols(1, -2)
+		c.Assert(ranges(cs), qt.DeepEquals, [][2]int{{1, 4}, {6, 7}})
+	})
+
+	c.Run("RemoveAcrossRanges", func(c *qt.C) {
+		cs := setUp()
+		// Remove columns 5 to 8
+		cs.shiftCols(5, -4)
+		c.Assert(ranges(cs), qt.DeepEquals, [][2]int{{1, 1}, {3, 4}, {5, 5}})
+	})
+}
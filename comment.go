@@ -0,0 +1,262 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The size, in points, that Excel gives a new note.
+const (
+	defaultCommentWidth  = 108.0
+	defaultCommentHeight = 59.25
+)
+
+// Comment is a note attached to a Cell, displayed in a box beside it.
+type Comment struct {
+	// Author is the name of the person who wrote the Comment.
+	Author string
+	// Text is the body of the Comment.
+	Text []RichTextRun
+	// Visible makes the Comment show all the time, rather than only
+	// when the mouse is over its Cell.
+	Visible bool
+	// Width and Height are the size of the box the Comment is shown
+	// in, in points.  Zero means the size Excel gives a new note.
+	Width, Height float64
+}
+
+// NewComment returns a hidden Comment by author with the plain text
+// body text.
+func NewComment(author, text string) *Comment {
+	return &Comment{Author: author, Text: []RichTextRun{{Text: text}}}
+}
+
+// PlainText returns the body of the Comment without its formatting.
+func (c *Comment) PlainText() string {
+	return richTextToPlainText(c.Text)
+}
+
+// size returns the width and height of the Comment's box in points.
+func (c *Comment) size() (float64, float64) {
+	width, height := c.Width, c.Height
+	if width <= 0 {
+		width = defaultCommentWidth
+	}
+	if height <= 0 {
+		height = defaultCommentHeight
+	}
+	return width, height
+}
+
+// commentedCell is a Comment and the position of its Cell.
+type commentedCell struct {
+	col, row int
+	comment  *Comment
+}
+
+// makeCommentParts adds the comments part of the Sheet and the VML
+// drawing that displays its notes to sp.  Nothing is added if the
+// Sheet has no comments.
+func (s *Sheet) makeCommentParts(sp *sheetParts) error {
+	var comments []commentedCell
+	err := s.ForEachRow(func(r *Row) error {
+		for _, cell := range r.cells {
+			if cell != nil && cell.Comment != nil {
+				comments = append(comments, commentedCell{col: cell.num, row: r.num, comment: cell.Comment})
+			}
+		}
+		return nil
+	}, SkipEmptyRows)
+	if err != nil {
+		return err
+	}
+	if len(comments) == 0 {
+		return nil
+	}
+
+	xComments := xlsxComments{}
+	authors := make(map[string]int)
+	for _, cc := range comments {
+		authorId, ok := authors[cc.comment.Author]
+		if !ok {
+			authorId = len(xComments.Authors)
+			authors[cc.comment.Author] = authorId
+			xComments.Authors = append(xComments.Authors, cc.comment.Author)
+		}
+		xComment := xlsxComment{Ref: GetCellIDStringFromCoords(cc.col, cc.row), AuthorId: authorId}
+		if len(cc.comment.Text) == 1 && cc.comment.Text[0].Font == nil {
+			xComment.Text.T = &xlsxT{Text: cc.comment.Text[0].Text}
+		} else {
+			xComment.Text.R = richTextToXml(cc.comment.Text)
+		}
+		xComments.CommentList = append(xComments.CommentList, xComment)
+	}
+	body, err := xml.Marshal(xComments)
+	if err != nil {
+		return err
+	}
+
+	commentsPart := fmt.Sprintf("xl/comments%d.xml", sp.index)
+	sp.addPart(commentsPart, xml.Header+string(body),
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.comments+xml")
+	sp.addRelation(RelationshipTypeComments, "../"+strings.TrimPrefix(commentsPart, "xl/"))
+
+	vmlPart := fmt.Sprintf("xl/drawings/vmlDrawing%d.vml", sp.index)
+	sp.addPart(vmlPart, makeCommentVML(sp.index, comments), "")
+	sp.addDefault("vml", "application/vnd.openxmlformats-officedocument.vmlDrawing")
+	sp.addRelation(RelationshipTypeVMLDrawing, "../"+strings.TrimPrefix(vmlPart, "xl/"))
+	return nil
+}
+
+// makeCommentVML returns the VML drawing that displays the notes of
+// the sheet with the one based index sheetIndex.
+func makeCommentVML(sheetIndex int, comments []commentedCell) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<xml xmlns:v="urn:schemas-microsoft-com:vml" xmlns:o="urn:schemas-microsoft-com:office:office" xmlns:x="urn:schemas-microsoft-com:office:excel">`+
+		`<o:shapelayout v:ext="edit"><o:idmap v:ext="edit" data="%d"/></o:shapelayout>`+
+		`<v:shapetype id="_x0000_t202" coordsize="21600,21600" o:spt="202" path="m,l,21600r21600,l21600,xe">`+
+		`<v:stroke joinstyle="miter"/><v:path gradientshapeok="t" o:connecttype="rect"/></v:shapetype>`, sheetIndex)
+	for i, cc := range comments {
+		width, height := cc.comment.size()
+		// Excel places a note one column to the right of its cell
+		// and a row above it, with columns of 64 pixels and rows
+		// of 20.
+		left, top := cc.col+1, cc.row-1
+		if top < 0 {
+			top = 0
+		}
+		leftOffset, topOffset := 15, 10
+		right := leftOffset + int(width*96/72)
+		bottom := topOffset + int(height*96/72)
+		visibility := ";visibility:hidden"
+		if cc.comment.Visible {
+			visibility = ""
+		}
+		fmt.Fprintf(&b, `<v:shape id="_x0000_s%d" type="#_x0000_t202" `+
+			`style="position:absolute;margin-left:%spt;margin-top:%spt;width:%spt;height:%spt;z-index:%d%s" `+
+			`fillcolor="#ffffe1" o:insetmode="auto">`+
+			`<v:fill color2="#ffffe1"/><v:shadow on="t" color="black" obscured="t"/>`+
+			`<v:path o:connecttype="none"/><v:textbox style="mso-direction-alt:auto"><div style="text-align:left"></div></v:textbox>`+
+			`<x:ClientData ObjectType="Note"><x:MoveWithCells/><x:SizeWithCells/>`+
+			`<x:Anchor>%d, %d, %d, %d, %d, %d, %d, %d</x:Anchor><x:AutoFill>False</x:AutoFill>`+
+			`<x:Row>%d</x:Row><x:Column>%d</x:Column>`,
+			1024*sheetIndex+i+1,
+			formatPoints(float64(left*64+leftOffset)*72/96), formatPoints(float64(top*20+topOffset)*72/96),
+			formatPoints(width), formatPoints(height), i+1, visibility,
+			left, leftOffset, top, topOffset,
+			left+right/64, right%64, top+bottom/20, bottom%20,
+			cc.row, cc.col)
+		if cc.comment.Visible {
+			b.WriteString(`<x:Visible/>`)
+		}
+		b.WriteString(`</x:ClientData></v:shape>`)
+	}
+	b.WriteString(`</xml>`)
+	return b.String()
+}
+
+// formatPoints formats a length in points for a VML style.
+func formatPoints(pt float64) string {
+	return strconv.FormatFloat(pt, 'f', -1, 64)
+}
+
+// vmlLength matches a length property in a VML style.
+var vmlLength = regexp.MustCompile(`(?:^|;)\s*(width|height)\s*:\s*([0-9.]+)(pt|px|in)?`)
+
+// vmlSize returns the width and height, in points, from a VML style.
+func vmlSize(style string) (width, height float64) {
+	for _, m := range vmlLength.FindAllStringSubmatch(style, -1) {
+		v, err := strconv.ParseFloat(m[2], 64)
+		if err != nil {
+			continue
+		}
+		switch m[3] {
+		case "px":
+			v = v * 72 / 96
+		case "in":
+			v = v * 72
+		}
+		if m[1] == "width" {
+			width = v
+		} else {
+			height = v
+		}
+	}
+	return width, height
+}
+
+// readComments sets the comments of the cells of sheet from the
+// comments part, and the VML drawing of their notes, that the
+// worksheet relationships rels refer to.
+func readComments(fi *File, sheet *Sheet, rels *xlsxWorksheetRels) error {
+	var commentsFile, vmlFile *zip.File
+	for _, rel := range rels.Relationships {
+		switch rel.Type {
+		case RelationshipTypeComments:
+			commentsFile = fi.zipFiles[resolveTarget("xl/worksheets", rel.Target)]
+		case RelationshipTypeVMLDrawing:
+			vmlFile = fi.zipFiles[resolveTarget("xl/worksheets", rel.Target)]
+		}
+	}
+	if commentsFile == nil {
+		return nil
+	}
+
+	type cellKey struct{ col, row int }
+	shapes := make(map[cellKey]vmlShape)
+	if vmlFile != nil {
+		rc, err := vmlFile.Open()
+		if err != nil {
+			return fmt.Errorf("file.Open: %w", err)
+		}
+		defer rc.Close()
+		var drawing vmlDrawing
+		decoder := xml.NewDecoder(rc)
+		decoder.Strict = false
+		decoder.AutoClose = xml.HTMLAutoClose
+		err = decoder.Decode(&drawing)
+		if err != nil {
+			return fmt.Errorf("xml.Decoder.Decode: %w", err)
+		}
+		for _, shape := range drawing.Shapes {
+			if shape.ClientData != nil && shape.ClientData.ObjectType == "Note" {
+				shapes[cellKey{shape.ClientData.Column, shape.ClientData.Row}] = shape
+			}
+		}
+	}
+
+	var xComments xlsxComments
+	err := decodeZipFile(commentsFile, &xComments)
+	if err != nil {
+		return err
+	}
+	for _, xComment := range xComments.CommentList {
+		x, y, err := GetCoordsFromCellIDString(xComment.Ref)
+		if err != nil {
+			return err
+		}
+		comment := &Comment{}
+		if xComment.AuthorId >= 0 && xComment.AuthorId < len(xComments.Authors) {
+			comment.Author = xComments.Authors[xComment.AuthorId]
+		}
+		if len(xComment.Text.R) > 0 {
+			comment.Text = xmlToRichText(xComment.Text.R)
+		} else if xComment.Text.T != nil {
+			comment.Text = []RichTextRun{{Text: xComment.Text.T.getText()}}
+		}
+		if shape, ok := shapes[cellKey{x, y}]; ok {
+			comment.Visible = shape.ClientData.Visible != nil
+			comment.Width, comment.Height = vmlSize(shape.Style)
+		}
+		row, err := sheet.Row(y)
+		if err != nil {
+			return err
+		}
+		row.GetCell(x).Comment = comment
+	}
+	return nil
+}
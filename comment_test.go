@@ -0,0 +1,96 @@
+package xlsx
+
+import (
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestComments(t *testing.T) {
+	c := qt.New(t)
+
+	setUp := func(c *qt.C, option FileOption) *File {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		cell := cellAt(c, sheet, "B2")
+		cell.SetString("42")
+		cell.Comment = NewComment("Validator", "Must be less than 10")
+		cell = cellAt(c, sheet, "D5")
+		cell.SetInt(7)
+		cell.Comment = &Comment{
+			Author: "Reviewer",
+			Text: []RichTextRun{
+				{Font: &RichTextFont{Bold: true}, Text: "Reviewer:"},
+				{Text: "\nlooks fine"},
+			},
+			Visible: true,
+			Width:   150,
+			Height:  75,
+		}
+		cell.SetHyperlink("https://example.com", "", "")
+		cellAt(c, sheet, "A9").Comment = NewComment("Validator", "Empty")
+		_, err = f.AddSheet("Sheet2")
+		c.Assert(err, qt.IsNil)
+		return f
+	}
+
+	csRunParts(c, "Parts", setUp, func(c *qt.C, parts map[string]string) {
+		comments := parts["xl/comments1.xml"]
+		c.Assert(comments, qt.Contains, `<authors><author>Validator</author><author>Reviewer</author></authors>`)
+		c.Assert(comments, qt.Contains, `<comment ref="B2" authorId="0"><text><t>Must be less than 10</t></text></comment>`)
+		c.Assert(comments, qt.Contains, `<b></b></rPr><t>Reviewer:</t></r>`)
+		c.Assert(comments, qt.Contains, `<comment ref="A9" authorId="0">`)
+
+		vml := parts["xl/drawings/vmlDrawing1.vml"]
+		c.Assert(strings.Count(vml, `<x:ClientData ObjectType="Note">`), qt.Equals, 3)
+		c.Assert(vml, qt.Contains, `width:108pt;height:59.25pt;z-index:1;visibility:hidden"`)
+		c.Assert(vml, qt.Contains, `width:150pt;height:75pt;z-index:2"`)
+		c.Assert(vml, qt.Contains, `<x:Row>4</x:Row><x:Column>3</x:Column><x:Visible/>`)
+
+		rels := parts["xl/worksheets/_rels/sheet1.xml.rels"]
+		c.Assert(rels, qt.Contains, `<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/comments" Target="../comments1.xml"></Relationship>`)
+		c.Assert(rels, qt.Contains, `<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/vmlDrawing" Target="../drawings/vmlDrawing1.vml"></Relationship>`)
+
+		sheetXML := parts["xl/worksheets/sheet1.xml"]
+		c.Assert(sheetXML, qt.Contains, `<legacyDrawing r:id="rId3"`)
+		c.Assert(strings.Index(sheetXML, "</sheetData>") < strings.Index(sheetXML, "<hyperlinks>"), qt.Equals, true)
+		c.Assert(strings.Index(sheetXML, "</hyperlinks>") < strings.Index(sheetXML, "<legacyDrawing"), qt.Equals, true)
+
+		types := parts["[Content_Types].xml"]
+		c.Assert(types, qt.Contains, `<Override PartName="/xl/comments1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.comments+xml">`)
+		c.Assert(strings.Count(types, `Extension="vml"`), qt.Equals, 1)
+
+		_, ok := parts["xl/comments2.xml"]
+		c.Assert(ok, qt.Equals, false)
+		c.Assert(parts["xl/worksheets/sheet2.xml"], qt.Not(qt.Contains), "legacyDrawing")
+	})
+
+	csRunO(c, "RoundTrip", func(c *qt.C, option FileOption) {
+		f := reopen(c, setUp(c, option), option)
+		sheet := f.Sheets[0]
+
+		b2 := cellAt(c, sheet, "B2")
+		c.Assert(b2.Value, qt.Equals, "42")
+		c.Assert(b2.Comment, qt.DeepEquals, &Comment{
+			Author: "Validator",
+			Text:   []RichTextRun{{Text: "Must be less than 10"}},
+			Width:  108,
+			Height: 59.25,
+		})
+
+		d5 := cellAt(c, sheet, "D5")
+		c.Assert(d5.Comment, qt.Not(qt.IsNil))
+		c.Assert(d5.Comment.Author, qt.Equals, "Reviewer")
+		c.Assert(d5.Comment.PlainText(), qt.Equals, "Reviewer:\nlooks fine")
+		c.Assert(d5.Comment.Text[0].Font.Bold, qt.Equals, true)
+		c.Assert(d5.Comment.Visible, qt.Equals, true)
+		c.Assert(d5.Comment.Width, qt.Equals, 150.0)
+		c.Assert(d5.Comment.Height, qt.Equals, 75.0)
+		c.Assert(d5.Hyperlink.Link, qt.Equals, "https://example.com")
+
+		c.Assert(cellAt(c, sheet, "A9").Comment.PlainText(), qt.Equals, "Empty")
+		c.Assert(cellAt(c, sheet, "A1").Comment, qt.IsNil)
+	})
+}
@@ -0,0 +1,548 @@
+package xlsx
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tealeg/xlsx/v3/formula"
+)
+
+// ConditionalFormatType is the kind of a ConditionalFormatRule.
+type ConditionalFormatType string
+
+const (
+	// ConditionalFormatCellIs compares the value of each cell with
+	// the Formulas of the rule, using its Operator.
+	ConditionalFormatCellIs ConditionalFormatType = "cellIs"
+	// ConditionalFormatExpression formats the cells for which the
+	// single formula of the rule is true.  The formula is written as
+	// for the top left cell of the range.
+	ConditionalFormatExpression ConditionalFormatType = "expression"
+	// ConditionalFormatTop formats the Rank highest, or with Bottom
+	// lowest, values.
+	ConditionalFormatTop ConditionalFormatType = "top10"
+	// ConditionalFormatAboveAverage formats the values above, or
+	// with Below under, the average.
+	ConditionalFormatAboveAverage    ConditionalFormatType = "aboveAverage"
+	ConditionalFormatDuplicateValues ConditionalFormatType = "duplicateValues"
+	ConditionalFormatUniqueValues    ConditionalFormatType = "uniqueValues"
+	// ConditionalFormatContainsText, ConditionalFormatNotContainsText,
+	// ConditionalFormatBeginsWith and ConditionalFormatEndsWith compare
+	// the text of each cell with the Text of the rule.
+	ConditionalFormatContainsText    ConditionalFormatType = "containsText"
+	ConditionalFormatNotContainsText ConditionalFormatType = "notContainsText"
+	ConditionalFormatBeginsWith      ConditionalFormatType = "beginsWith"
+	ConditionalFormatEndsWith        ConditionalFormatType = "endsWith"
+	// ConditionalFormatTimePeriod formats the dates in the TimePeriod
+	// of the rule.
+	ConditionalFormatTimePeriod ConditionalFormatType = "timePeriod"
+	ConditionalFormatColorScale ConditionalFormatType = "colorScale"
+	ConditionalFormatDataBar    ConditionalFormatType = "dataBar"
+	ConditionalFormatIconSet    ConditionalFormatType = "iconSet"
+)
+
+// ConditionalFormatOperator is how a ConditionalFormatCellIs rule
+// compares the value of a cell.
+type ConditionalFormatOperator string
+
+const (
+	ConditionalFormatBetween            ConditionalFormatOperator = "between"
+	ConditionalFormatNotBetween         ConditionalFormatOperator = "notBetween"
+	ConditionalFormatEqual              ConditionalFormatOperator = "equal"
+	ConditionalFormatNotEqual           ConditionalFormatOperator = "notEqual"
+	ConditionalFormatGreaterThan        ConditionalFormatOperator = "greaterThan"
+	ConditionalFormatLessThan           ConditionalFormatOperator = "lessThan"
+	ConditionalFormatGreaterThanOrEqual ConditionalFormatOperator = "greaterThanOrEqual"
+	ConditionalFormatLessThanOrEqual    ConditionalFormatOperator = "lessThanOrEqual"
+)
+
+// TimePeriod is a period of dates relative to today, used by a
+// ConditionalFormatTimePeriod rule.
+type TimePeriod string
+
+const (
+	TimePeriodToday     TimePeriod = "today"
+	TimePeriodYesterday TimePeriod = "yesterday"
+	TimePeriodTomorrow  TimePeriod = "tomorrow"
+	TimePeriodLast7Days TimePeriod = "last7Days"
+	TimePeriodThisWeek  TimePeriod = "thisWeek"
+	TimePeriodLastWeek  TimePeriod = "lastWeek"
+	TimePeriodNextWeek  TimePeriod = "nextWeek"
+	TimePeriodThisMonth TimePeriod = "thisMonth"
+	TimePeriodLastMonth TimePeriod = "lastMonth"
+	TimePeriodNextMonth TimePeriod = "nextMonth"
+)
+
+// timePeriodFormulas are the formulas, for a cell written as %[1]s,
+// that Excel uses for each TimePeriod.
+var timePeriodFormulas = map[TimePeriod]string{
+	TimePeriodToday:     "FLOOR(%[1]s,1)=TODAY()",
+	TimePeriodYesterday: "FLOOR(%[1]s,1)=TODAY()-1",
+	TimePeriodTomorrow:  "FLOOR(%[1]s,1)=TODAY()+1",
+	TimePeriodLast7Days: "AND(TODAY()-FLOOR(%[1]s,1)<=6,FLOOR(%[1]s,1)<=TODAY())",
+	TimePeriodThisWeek:  "AND(TODAY()-ROUNDDOWN(%[1]s,0)<=WEEKDAY(TODAY())-1,ROUNDDOWN(%[1]s,0)-TODAY()<=7-WEEKDAY(TODAY()))",
+	TimePeriodLastWeek:  "AND(TODAY()-ROUNDDOWN(%[1]s,0)>=(WEEKDAY(TODAY())),TODAY()-ROUNDDOWN(%[1]s,0)<(WEEKDAY(TODAY())+7))",
+	TimePeriodNextWeek:  "AND(ROUNDDOWN(%[1]s,0)-TODAY()>(7-WEEKDAY(TODAY())),ROUNDDOWN(%[1]s,0)-TODAY()<(15-WEEKDAY(TODAY())))",
+	TimePeriodThisMonth: "AND(MONTH(%[1]s)=MONTH(TODAY()),YEAR(%[1]s)=YEAR(TODAY()))",
+	TimePeriodLastMonth: "AND(MONTH(%[1]s)=MONTH(EDATE(TODAY(),0-1)),YEAR(%[1]s)=YEAR(EDATE(TODAY(),0-1)))",
+	TimePeriodNextMonth: "AND(MONTH(%[1]s)=MONTH(EDATE(TODAY(),0+1)),YEAR(%[1]s)=YEAR(EDATE(TODAY(),0+1)))",
+}
+
+// ConditionalValueType is how the Value of a ConditionalValue is
+// interpreted.
+type ConditionalValueType string
+
+const (
+	ConditionalValueMin        ConditionalValueType = "min"
+	ConditionalValueMax        ConditionalValueType = "max"
+	ConditionalValueNumber     ConditionalValueType = "num"
+	ConditionalValuePercent    ConditionalValueType = "percent"
+	ConditionalValuePercentile ConditionalValueType = "percentile"
+	ConditionalValueFormula    ConditionalValueType = "formula"
+)
+
+// ConditionalValue is a threshold of a ColorScale, DataBar or
+// IconSet.  Value isn't used with ConditionalValueMin and
+// ConditionalValueMax.
+type ConditionalValue struct {
+	Type  ConditionalValueType
+	Value string
+}
+
+// ColorScale shades cells by where their values lie between two or
+// three thresholds.  Colors are ARGB hex strings, one for each value.
+// If Values is empty, the thresholds are the lowest value, the 50th
+// percentile if there are three colors, and the highest value.
+type ColorScale struct {
+	Values []ConditionalValue
+	Colors []string
+}
+
+// DataBar draws a bar in each cell, as long as its value is far from
+// Min to Max.  Min and Max default to the lowest and the highest value.
+type DataBar struct {
+	Min, Max ConditionalValue
+	Color    string
+	// HideValue shows the bar without the value of the cell.
+	HideValue bool
+}
+
+// iconSetSizes are the number of icons in each icon set.
+var iconSetSizes = map[string]int{
+	"3Arrows": 3, "3ArrowsGray": 3, "3Flags": 3, "3TrafficLights1": 3, "3TrafficLights2": 3,
+	"3Signs": 3, "3Symbols": 3, "3Symbols2": 3,
+	"4Arrows": 4, "4ArrowsGray": 4, "4RedToBlack": 4, "4Rating": 4, "4TrafficLights": 4,
+	"5Arrows": 5, "5ArrowsGray": 5, "5Rating": 5, "5Quarters": 5,
+}
+
+// IconSet shows one of a set of icons in each cell, by where its value
+// lies between the thresholds.
+type IconSet struct {
+	// Style is the name of the set of icons, such as
+	// "3TrafficLights1", "3Arrows", "4Rating" or "5Quarters".
+	// Empty means "3TrafficLights1".
+	Style string
+	// Values holds the threshold of each icon, the first of which is
+	// the lowest.  If it is empty, the cells are split into groups
+	// of equal percentages.
+	Values []ConditionalValue
+	// Reverse shows the icons in the opposite order.
+	Reverse bool
+	// HideValue shows the icon without the value of the cell.
+	HideValue bool
+}
+
+// ConditionalFormatRule is a rule of a ConditionalFormat.
+type ConditionalFormatRule struct {
+	Type ConditionalFormatType
+	// Style is the formatting given to the cells that match the
+	// rule.  Only its font color and effects, fill and border are
+	// used.  It isn't used by color scales, data bars and icon sets.
+	Style *Style
+	// StopIfTrue stops the rules after this one being applied to the
+	// cells that match it.
+	StopIfTrue bool
+
+	// Operator and Formulas are used by ConditionalFormatCellIs
+	// rules, which need two formulas for ConditionalFormatBetween and
+	// ConditionalFormatNotBetween and one otherwise.  Formulas holds
+	// the single formula of a ConditionalFormatExpression rule.  For
+	// text and time period rules it is worked out when the rule is
+	// written.
+	Operator ConditionalFormatOperator
+	Formulas []string
+
+	// Rank, Percent and Bottom are used by ConditionalFormatTop
+	// rules.  Rank is a count of cells or, with Percent, a
+	// percentage of them.
+	Rank    int
+	Percent bool
+	Bottom  bool
+	// Below, EqualAverage and StdDev are used by
+	// ConditionalFormatAboveAverage rules.  StdDev moves the
+	// threshold that many standard deviations from the average.
+	Below        bool
+	EqualAverage bool
+	StdDev       int
+
+	// Text is used by the text rules.
+	Text string
+	// TimePeriod is used by ConditionalFormatTimePeriod rules.
+	TimePeriod TimePeriod
+
+	ColorScale *ColorScale
+	DataBar    *DataBar
+	IconSet    *IconSet
+}
+
+// ConditionalFormat applies its rules to the cells of Range, a space
+// separated list of cells and ranges such as "B2:B100 D2:D100".  The
+// rules of the formats of a Sheet are applied in order.
+type ConditionalFormat struct {
+	Range string
+	Rules []*ConditionalFormatRule
+}
+
+// AddConditionalFormat formats the cells of rangeRef, a space
+// separated list of cells and ranges, by rules and returns the new
+// ConditionalFormat.
+func (s *Sheet) AddConditionalFormat(rangeRef string, rules ...*ConditionalFormatRule) (*ConditionalFormat, error) {
+	wrap := func(err error) (*ConditionalFormat, error) {
+		return nil, fmt.Errorf("AddConditionalFormat: %w", err)
+	}
+
+	refs := strings.Fields(rangeRef)
+	if len(refs) == 0 {
+		return wrap(fmt.Errorf("invalid range %q", rangeRef))
+	}
+	for _, ref := range refs {
+		r, err := formula.ParseRef(ref)
+		if err != nil || r.Sheet != "" {
+			return wrap(fmt.Errorf("invalid range %q", rangeRef))
+		}
+	}
+	if len(rules) == 0 {
+		return wrap(errors.New("a conditional format needs at least one rule"))
+	}
+	for i, rule := range rules {
+		if rule == nil {
+			return wrap(fmt.Errorf("rule %d is nil", i))
+		}
+		err := rule.validate()
+		if err != nil {
+			return wrap(fmt.Errorf("rule %d: %w", i, err))
+		}
+	}
+
+	cf := &ConditionalFormat{Range: strings.Join(refs, " "), Rules: rules}
+	s.ConditionalFormats = append(s.ConditionalFormats, cf)
+	return cf, nil
+}
+
+// validate returns an error if the rule can't be written.
+func (rule *ConditionalFormatRule) validate() error {
+	switch rule.Type {
+	case ConditionalFormatCellIs:
+		n := 1
+		switch rule.Operator {
+		case ConditionalFormatBetween, ConditionalFormatNotBetween:
+			n = 2
+		case ConditionalFormatEqual, ConditionalFormatNotEqual, ConditionalFormatGreaterThan,
+			ConditionalFormatLessThan, ConditionalFormatGreaterThanOrEqual, ConditionalFormatLessThanOrEqual:
+		default:
+			return fmt.Errorf("unsupported operator %q", rule.Operator)
+		}
+		if len(rule.Formulas) != n {
+			return fmt.Errorf("operator %s needs %d formulas, not %d", rule.Operator, n, len(rule.Formulas))
+		}
+	case ConditionalFormatExpression:
+		if len(rule.Formulas) != 1 {
+			return fmt.Errorf("an expression needs 1 formula, not %d", len(rule.Formulas))
+		}
+	case ConditionalFormatTop:
+		if rule.Rank < 1 || (rule.Percent && rule.Rank > 100) {
+			return fmt.Errorf("invalid rank %d", rule.Rank)
+		}
+	case ConditionalFormatAboveAverage, ConditionalFormatDuplicateValues, ConditionalFormatUniqueValues:
+	case ConditionalFormatContainsText, ConditionalFormatNotContainsText,
+		ConditionalFormatBeginsWith, ConditionalFormatEndsWith:
+		if rule.Text == "" {
+			return errors.New("a text rule needs text")
+		}
+	case ConditionalFormatTimePeriod:
+		if _, ok := timePeriodFormulas[rule.TimePeriod]; !ok {
+			return fmt.Errorf("unsupported time period %q", rule.TimePeriod)
+		}
+	case ConditionalFormatColorScale:
+		cs := rule.ColorScale
+		if cs == nil || len(cs.Colors) < 2 || len(cs.Colors) > 3 {
+			return errors.New("a color scale needs 2 or 3 colors")
+		}
+		if len(cs.Values) != 0 && len(cs.Values) != len(cs.Colors) {
+			return fmt.Errorf("a color scale with %d colors needs %d values", len(cs.Colors), len(cs.Colors))
+		}
+	case ConditionalFormatDataBar:
+		if rule.DataBar == nil || rule.DataBar.Color == "" {
+			return errors.New("a data bar needs a color")
+		}
+	case ConditionalFormatIconSet:
+		is := rule.IconSet
+		if is == nil {
+			return errors.New("an icon set rule needs an IconSet")
+		}
+		if n, ok := iconSetSizes[is.iconStyle()]; !ok {
+			return fmt.Errorf("unsupported icon set %q", is.Style)
+		} else if len(is.Values) != 0 && len(is.Values) != n {
+			return fmt.Errorf("icon set %s needs %d values", is.iconStyle(), n)
+		}
+	default:
+		return fmt.Errorf("unsupported rule type %q", rule.Type)
+	}
+	return nil
+}
+
+// iconStyle returns the name of the set of icons.
+func (is *IconSet) iconStyle() string {
+	if is.Style == "" {
+		return "3TrafficLights1"
+	}
+	return is.Style
+}
+
+// makeConditionalFormatting adds the conditional formats of the Sheet
+// to worksheet, and the differential formats of their rules to
+// styles.
+func (s *Sheet) makeConditionalFormatting(worksheet *xlsxWorksheet, styles *xlsxStyleSheet) {
+	priority := 0
+	for _, cf := range s.ConditionalFormats {
+		xCf := xlsxConditionalFormatting{Sqref: cf.Range}
+		// Formulas that Excel derives for a rule refer to the top
+		// left cell of the range.
+		topLeft := "A1"
+		if ref, err := formula.ParseRef(strings.Fields(cf.Range)[0]); err == nil {
+			topLeft = GetCellIDStringFromCoords(ref.Col1, ref.Row1)
+		}
+		for _, rule := range cf.Rules {
+			priority++
+			xCf.CfRule = append(xCf.CfRule, rule.makeXLSXCfRule(priority, topLeft, styles))
+		}
+		worksheet.ConditionalFormatting = append(worksheet.ConditionalFormatting, xCf)
+	}
+}
+
+// makeXLSXCfRule returns the cfRule element of the rule.
+func (rule *ConditionalFormatRule) makeXLSXCfRule(priority int, topLeft string, styles *xlsxStyleSheet) xlsxCfRule {
+	xRule := xlsxCfRule{
+		Type:       string(rule.Type),
+		Priority:   priority,
+		StopIfTrue: rule.StopIfTrue,
+		Formula:    rule.Formulas,
+	}
+	if rule.Style != nil {
+		switch rule.Type {
+		case ConditionalFormatColorScale, ConditionalFormatDataBar, ConditionalFormatIconSet:
+		default:
+			dxfId := styles.addDxf(rule.Style.makeXLSXDxf())
+			xRule.DxfId = &dxfId
+		}
+	}
+	quotedText := `"` + strings.Replace(rule.Text, `"`, `""`, -1) + `"`
+
+	switch rule.Type {
+	case ConditionalFormatCellIs:
+		xRule.Operator = string(rule.Operator)
+	case ConditionalFormatTop:
+		xRule.Rank, xRule.Percent, xRule.Bottom = rule.Rank, rule.Percent, rule.Bottom
+	case ConditionalFormatAboveAverage:
+		if rule.Below {
+			above := false
+			xRule.AboveAverage = &above
+		}
+		xRule.EqualAverage, xRule.StdDev = rule.EqualAverage, rule.StdDev
+	case ConditionalFormatContainsText:
+		xRule.Operator, xRule.Text = "containsText", rule.Text
+		if len(xRule.Formula) == 0 {
+			xRule.Formula = []string{fmt.Sprintf("NOT(ISERROR(SEARCH(%s,%s)))", quotedText, topLeft)}
+		}
+	case ConditionalFormatNotContainsText:
+		xRule.Operator, xRule.Text = "notContains", rule.Text
+		if len(xRule.Formula) == 0 {
+			xRule.Formula = []string{fmt.Sprintf("ISERROR(SEARCH(%s,%s))", quotedText, topLeft)}
+		}
+	case ConditionalFormatBeginsWith:
+		xRule.Operator, xRule.Text = "beginsWith", rule.Text
+		if len(xRule.Formula) == 0 {
+			xRule.Formula = []string{fmt.Sprintf("LEFT(%[2]s,LEN(%[1]s))=%[1]s", quotedText, topLeft)}
+		}
+	case ConditionalFormatEndsWith:
+		xRule.Operator, xRule.Text = "endsWith", rule.Text
+		if len(xRule.Formula) == 0 {
+			xRule.Formula = []string{fmt.Sprintf("RIGHT(%[2]s,LEN(%[1]s))=%[1]s", quotedText, topLeft)}
+		}
+	case ConditionalFormatTimePeriod:
+		xRule.TimePeriod = string(rule.TimePeriod)
+		if len(xRule.Formula) == 0 {
+			xRule.Formula = []string{fmt.Sprintf(timePeriodFormulas[rule.TimePeriod], topLeft)}
+		}
+	case ConditionalFormatColorScale:
+		cs := rule.ColorScale
+		values := cs.Values
+		if len(values) == 0 {
+			values = []ConditionalValue{{Type: ConditionalValueMin}}
+			if len(cs.Colors) == 3 {
+				values = append(values, ConditionalValue{Type: ConditionalValuePercentile, Value: "50"})
+			}
+			values = append(values, ConditionalValue{Type: ConditionalValueMax})
+		}
+		xRule.ColorScale = &xlsxColorScale{Cfvo: makeXLSXCfvos(values)}
+		for _, color := range cs.Colors {
+			xRule.ColorScale.Color = append(xRule.ColorScale.Color, xlsxColor{RGB: color})
+		}
+	case ConditionalFormatDataBar:
+		db := rule.DataBar
+		min, max := db.Min, db.Max
+		if min.Type == "" {
+			min.Type = ConditionalValueMin
+		}
+		if max.Type == "" {
+			max.Type = ConditionalValueMax
+		}
+		xRule.DataBar = &xlsxDataBar{
+			Cfvo:  makeXLSXCfvos([]ConditionalValue{min, max}),
+			Color: xlsxColor{RGB: db.Color},
+		}
+		if db.HideValue {
+			showValue := false
+			xRule.DataBar.ShowValue = &showValue
+		}
+	case ConditionalFormatIconSet:
+		is := rule.IconSet
+		values := is.Values
+		if len(values) == 0 {
+			n := iconSetSizes[is.iconStyle()]
+			for i := 0; i < n; i++ {
+				values = append(values, ConditionalValue{Type: ConditionalValuePercent, Value: strconv.Itoa((i*100 + n/2) / n)})
+			}
+		}
+		xRule.IconSet = &xlsxIconSet{Reverse: is.Reverse, Cfvo: makeXLSXCfvos(values)}
+		if is.Style != "3TrafficLights1" {
+			xRule.IconSet.IconSet = is.Style
+		}
+		if is.HideValue {
+			showValue := false
+			xRule.IconSet.ShowValue = &showValue
+		}
+	}
+	return xRule
+}
+
+// makeXLSXCfvos returns the cfvo elements of values.
+func makeXLSXCfvos(values []ConditionalValue) []xlsxCfvo {
+	cfvos := make([]xlsxCfvo, len(values))
+	for i, v := range values {
+		cfvos[i] = xlsxCfvo{Type: string(v.Type), Val: v.Value}
+		if v.Type == ConditionalValueMin || v.Type == ConditionalValueMax {
+			cfvos[i].Val = ""
+		}
+	}
+	return cfvos
+}
+
+// readConditionalFormatting sets the conditional formats of sheet from
+// those of worksheet, whose differential formats are in styles.  The
+// formats are put in the order of the priorities of their rules.
+func readConditionalFormatting(sheet *Sheet, worksheet *xlsxWorksheet, styles *xlsxStyleSheet) {
+	type prioritised struct {
+		priority int
+		cf       *ConditionalFormat
+	}
+	var formats []prioritised
+	for _, xCf := range worksheet.ConditionalFormatting {
+		xRules := append([]xlsxCfRule(nil), xCf.CfRule...)
+		if len(xRules) == 0 {
+			continue
+		}
+		sort.SliceStable(xRules, func(i, j int) bool { return xRules[i].Priority < xRules[j].Priority })
+		cf := &ConditionalFormat{Range: xCf.Sqref}
+		for _, xRule := range xRules {
+			cf.Rules = append(cf.Rules, readCfRule(xRule, styles))
+		}
+		formats = append(formats, prioritised{xRules[0].Priority, cf})
+	}
+	sort.SliceStable(formats, func(i, j int) bool { return formats[i].priority < formats[j].priority })
+	for _, f := range formats {
+		sheet.ConditionalFormats = append(sheet.ConditionalFormats, f.cf)
+	}
+}
+
+// readCfRule returns the ConditionalFormatRule of xRule.
+func readCfRule(xRule xlsxCfRule, styles *xlsxStyleSheet) *ConditionalFormatRule {
+	rule := &ConditionalFormatRule{
+		Type:         ConditionalFormatType(xRule.Type),
+		StopIfTrue:   xRule.StopIfTrue,
+		Operator:     ConditionalFormatOperator(xRule.Operator),
+		Formulas:     xRule.Formula,
+		Rank:         xRule.Rank,
+		Percent:      xRule.Percent,
+		Bottom:       xRule.Bottom,
+		Below:        xRule.AboveAverage != nil && !*xRule.AboveAverage,
+		EqualAverage: xRule.EqualAverage,
+		StdDev:       xRule.StdDev,
+		Text:         xRule.Text,
+		TimePeriod:   TimePeriod(xRule.TimePeriod),
+	}
+	switch rule.Type {
+	case ConditionalFormatContainsText, ConditionalFormatNotContainsText,
+		ConditionalFormatBeginsWith, ConditionalFormatEndsWith, ConditionalFormatTimePeriod:
+		// The operator is implied by the type.
+		rule.Operator = ""
+	}
+	if xRule.DxfId != nil && styles != nil {
+		rule.Style = styles.getDxfStyle(*xRule.DxfId)
+	}
+	argb := func(color xlsxColor) string {
+		if styles == nil {
+			return color.RGB
+		}
+		return styles.argbValue(color)
+	}
+	if xcs := xRule.ColorScale; xcs != nil {
+		rule.ColorScale = &ColorScale{Values: readCfvos(xcs.Cfvo)}
+		for _, color := range xcs.Color {
+			rule.ColorScale.Colors = append(rule.ColorScale.Colors, argb(color))
+		}
+	}
+	if xdb := xRule.DataBar; xdb != nil {
+		rule.DataBar = &DataBar{
+			Color:     argb(xdb.Color),
+			HideValue: xdb.ShowValue != nil && !*xdb.ShowValue,
+		}
+		if values := readCfvos(xdb.Cfvo); len(values) == 2 {
+			rule.DataBar.Min, rule.DataBar.Max = values[0], values[1]
+		}
+	}
+	if xis := xRule.IconSet; xis != nil {
+		rule.IconSet = &IconSet{
+			Style:     xis.IconSet,
+			Values:    readCfvos(xis.Cfvo),
+			Reverse:   xis.Reverse,
+			HideValue: xis.ShowValue != nil && !*xis.ShowValue,
+		}
+		if rule.IconSet.Style == "" {
+			rule.IconSet.Style = "3TrafficLights1"
+		}
+	}
+	return rule
+}
+
+// readCfvos returns the ConditionalValues of cfvos.
+func readCfvos(cfvos []xlsxCfvo) []ConditionalValue {
+	values := make([]ConditionalValue, len(cfvos))
+	for i, cfvo := range cfvos {
+		values[i] = ConditionalValue{Type: ConditionalValueType(cfvo.Type), Value: cfvo.Val}
+	}
+	return values
+}
@@ -0,0 +1,178 @@
+package xlsx
+
+import (
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestConditionalFormats(t *testing.T) {
+	c := qt.New(t)
+
+	bad := &Style{Font: Font{Color: RGB_Dark_Red}, Fill: Fill{PatternType: Solid_Cell_Fill, FgColor: RGB_Light_Red}}
+	good := &Style{Font: Font{Color: RGB_Dark_Green, Bold: true}, Fill: Fill{PatternType: Solid_Cell_Fill, FgColor: RGB_Light_Green}}
+
+	setUp := func(c *qt.C, option FileOption) *File {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Report")
+		c.Assert(err, qt.IsNil)
+		for row := 1; row <= 5; row++ {
+			cell, err := sheet.Cell(row, 1)
+			c.Assert(err, qt.IsNil)
+			cell.SetInt(row * 10)
+		}
+		_, err = sheet.AddConditionalFormat("B2:B6",
+			&ConditionalFormatRule{Type: ConditionalFormatCellIs, Operator: ConditionalFormatLessThan, Formulas: []string{"20"}, Style: bad, StopIfTrue: true},
+			&ConditionalFormatRule{Type: ConditionalFormatCellIs, Operator: ConditionalFormatBetween, Formulas: []string{"30", "$D$1"}, Style: good},
+		)
+		c.Assert(err, qt.IsNil)
+		_, err = sheet.AddConditionalFormat("C2:C6 E2:E6",
+			&ConditionalFormatRule{Type: ConditionalFormatIconSet, IconSet: &IconSet{}},
+			&ConditionalFormatRule{Type: ConditionalFormatContainsText, Text: `say "hi"`, Style: bad},
+		)
+		c.Assert(err, qt.IsNil)
+		_, err = sheet.AddConditionalFormat("D2:D6",
+			&ConditionalFormatRule{Type: ConditionalFormatColorScale, ColorScale: &ColorScale{Colors: []string{"FFF8696B", "FFFFEB84", "FF63BE7B"}}},
+			&ConditionalFormatRule{Type: ConditionalFormatDataBar, DataBar: &DataBar{Max: ConditionalValue{Type: ConditionalValueNumber, Value: "100"}, Color: "FF638EC6", HideValue: true}},
+			&ConditionalFormatRule{Type: ConditionalFormatTop, Rank: 10, Percent: true, Bottom: true, Style: good},
+			&ConditionalFormatRule{Type: ConditionalFormatAboveAverage, Below: true, Style: bad},
+			&ConditionalFormatRule{Type: ConditionalFormatTimePeriod, TimePeriod: TimePeriodLast7Days, Style: good},
+			&ConditionalFormatRule{Type: ConditionalFormatExpression, Formulas: []string{"$B2>$C2"}, Style: &Style{Border: Border{Bottom: "thin", BottomColor: "FF000000"}}},
+		)
+		c.Assert(err, qt.IsNil)
+		return f
+	}
+
+	c.Run("AddConditionalFormat", func(c *qt.C) {
+		sheet, err := NewSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		_, err = sheet.AddConditionalFormat("", &ConditionalFormatRule{Type: ConditionalFormatUniqueValues})
+		c.Assert(err, qt.ErrorMatches, `AddConditionalFormat: invalid range ""`)
+		_, err = sheet.AddConditionalFormat("A1:A3 Other!B1", &ConditionalFormatRule{Type: ConditionalFormatUniqueValues})
+		c.Assert(err, qt.ErrorMatches, `AddConditionalFormat: invalid range "A1:A3 Other!B1"`)
+		_, err = sheet.AddConditionalFormat("A1:A3")
+		c.Assert(err, qt.ErrorMatches, `AddConditionalFormat: a conditional format needs at least one rule`)
+
+		for _, test := range []struct {
+			rule *ConditionalFormatRule
+			err  string
+		}{
+			{&ConditionalFormatRule{Type: "rainbow"}, `unsupported rule type "rainbow"`},
+			{&ConditionalFormatRule{Type: ConditionalFormatCellIs, Operator: "near", Formulas: []string{"1"}}, `unsupported operator "near"`},
+			{&ConditionalFormatRule{Type: ConditionalFormatCellIs, Operator: ConditionalFormatBetween, Formulas: []string{"1"}}, `operator between needs 2 formulas, not 1`},
+			{&ConditionalFormatRule{Type: ConditionalFormatExpression}, `an expression needs 1 formula, not 0`},
+			{&ConditionalFormatRule{Type: ConditionalFormatTop, Rank: 101, Percent: true}, `invalid rank 101`},
+			{&ConditionalFormatRule{Type: ConditionalFormatBeginsWith}, `a text rule needs text`},
+			{&ConditionalFormatRule{Type: ConditionalFormatTimePeriod, TimePeriod: "nextYear"}, `unsupported time period "nextYear"`},
+			{&ConditionalFormatRule{Type: ConditionalFormatColorScale, ColorScale: &ColorScale{Colors: []string{"FF000000"}}}, `a color scale needs 2 or 3 colors`},
+			{&ConditionalFormatRule{Type: ConditionalFormatColorScale, ColorScale: &ColorScale{
+				Colors: []string{"FF000000", "FFFFFFFF"},
+				Values: []ConditionalValue{{Type: ConditionalValueMin}},
+			}}, `a color scale with 2 colors needs 2 values`},
+			{&ConditionalFormatRule{Type: ConditionalFormatDataBar, DataBar: &DataBar{}}, `a data bar needs a color`},
+			{&ConditionalFormatRule{Type: ConditionalFormatIconSet}, `an icon set rule needs an IconSet`},
+			{&ConditionalFormatRule{Type: ConditionalFormatIconSet, IconSet: &IconSet{Style: "3Smileys"}}, `unsupported icon set "3Smileys"`},
+			{&ConditionalFormatRule{Type: ConditionalFormatIconSet, IconSet: &IconSet{Style: "4Rating", Values: make([]ConditionalValue, 3)}}, `icon set 4Rating needs 4 values`},
+		} {
+			_, err = sheet.AddConditionalFormat("A1:A3", &ConditionalFormatRule{Type: ConditionalFormatDuplicateValues}, test.rule)
+			c.Assert(err, qt.ErrorMatches, `AddConditionalFormat: rule 1: `+test.err)
+		}
+		c.Assert(sheet.ConditionalFormats, qt.HasLen, 0)
+
+		cf, err := sheet.AddConditionalFormat(" A1:A3  C1 ", &ConditionalFormatRule{Type: ConditionalFormatDuplicateValues})
+		c.Assert(err, qt.IsNil)
+		c.Assert(cf.Range, qt.Equals, "A1:A3 C1")
+		c.Assert(sheet.ConditionalFormats, qt.HasLen, 1)
+		c.Assert(sheet.ConditionalFormats[0], qt.Equals, cf)
+	})
+
+	csRunParts(c, "Parts", setUp, func(c *qt.C, parts map[string]string) {
+		sheet := parts["xl/worksheets/sheet1.xml"]
+		c.Assert(sheet, qt.Contains, `</sheetData><conditionalFormatting sqref="B2:B6">`+
+			`<cfRule type="cellIs" dxfId="0" priority="1" stopIfTrue="true" operator="lessThan"><formula>20</formula></cfRule>`+
+			`<cfRule type="cellIs" dxfId="1" priority="2" operator="between"><formula>30</formula><formula>$D$1</formula></cfRule>`+
+			`</conditionalFormatting>`)
+		c.Assert(sheet, qt.Contains, `<conditionalFormatting sqref="C2:C6 E2:E6"><cfRule type="iconSet" priority="3"><iconSet><cfvo type="percent" val="0"`)
+		c.Assert(sheet, qt.Contains, `<cfvo type="percent" val="33"`)
+		c.Assert(sheet, qt.Contains, `<cfvo type="percent" val="67"`)
+		c.Assert(sheet, qt.Contains, `</iconSet></cfRule>`+
+			`<cfRule type="containsText" dxfId="0" priority="4" operator="containsText" text="say &#34;hi&#34;">`+
+			`<formula>NOT(ISERROR(SEARCH(&#34;say &#34;&#34;hi&#34;&#34;&#34;,C2)))</formula></cfRule>`)
+		c.Assert(sheet, qt.Contains, `<cfRule type="colorScale" priority="5"><colorScale><cfvo type="min"`)
+		c.Assert(sheet, qt.Contains, `<cfvo type="percentile" val="50"`)
+		c.Assert(sheet, qt.Contains, `<color rgb="FFF8696B"`)
+		c.Assert(sheet, qt.Contains, `<color rgb="FF63BE7B"`)
+		c.Assert(sheet, qt.Contains, `</colorScale></cfRule><cfRule type="dataBar" priority="6"><dataBar showValue="false">`)
+		c.Assert(sheet, qt.Contains, `<cfvo type="num" val="100"`)
+		c.Assert(sheet, qt.Contains, `<color rgb="FF638EC6"`)
+		c.Assert(sheet, qt.Contains, `<cfRule type="top10" dxfId="1" priority="7" percent="true" bottom="true" rank="10"`)
+		c.Assert(sheet, qt.Contains, `<cfRule type="aboveAverage" dxfId="0" priority="8" aboveAverage="false"`)
+		c.Assert(sheet, qt.Contains, `<cfRule type="timePeriod" dxfId="1" priority="9" timePeriod="last7Days"><formula>AND(TODAY()-FLOOR(D2,1)&lt;=6,FLOOR(D2,1)&lt;=TODAY())</formula></cfRule>`)
+		c.Assert(sheet, qt.Contains, `<cfRule type="expression" dxfId="2" priority="10"><formula>$B2&gt;$C2</formula></cfRule></conditionalFormatting></worksheet>`)
+
+		styles := parts["xl/styles.xml"]
+		c.Assert(styles, qt.Contains, `<dxfs count="3">`+
+			`<dxf><font><color rgb="FF9C0006"/></font><fill><patternFill><bgColor rgb="FFFFC7CE"/></patternFill></fill></dxf>`+
+			`<dxf><font><color rgb="FF006100"/><b/></font><fill><patternFill><bgColor rgb="FFC6EFCE"/></patternFill></fill></dxf>`+
+			`<dxf><border><left/><right/><top/><bottom style="thin"><color rgb="FF000000"/></bottom></border></dxf>`+
+			`</dxfs></styleSheet>`)
+	})
+
+	csRunO(c, "RoundTrip", func(c *qt.C, option FileOption) {
+		f := setUp(c, option)
+		f = reopen(c, f, option)
+		formats := f.Sheets[0].ConditionalFormats
+		c.Assert(formats, qt.HasLen, 3)
+
+		c.Assert(formats[0].Range, qt.Equals, "B2:B6")
+		c.Assert(formats[0].Rules[0], qt.DeepEquals, &ConditionalFormatRule{
+			Type:       ConditionalFormatCellIs,
+			Operator:   ConditionalFormatLessThan,
+			Formulas:   []string{"20"},
+			StopIfTrue: true,
+			Style: &Style{
+				ApplyFont: true,
+				ApplyFill: true,
+				Font:      Font{Color: RGB_Dark_Red},
+				Fill:      Fill{PatternType: Solid_Cell_Fill, FgColor: RGB_Light_Red},
+			},
+		})
+		c.Assert(formats[0].Rules[1].Style.Font.Bold, qt.Equals, true)
+
+		c.Assert(formats[1].Range, qt.Equals, "C2:C6 E2:E6")
+		c.Assert(formats[1].Rules[0].IconSet, qt.DeepEquals, &IconSet{
+			Style: "3TrafficLights1",
+			Values: []ConditionalValue{
+				{Type: ConditionalValuePercent, Value: "0"},
+				{Type: ConditionalValuePercent, Value: "33"},
+				{Type: ConditionalValuePercent, Value: "67"},
+			},
+		})
+		c.Assert(formats[1].Rules[1].Type, qt.Equals, ConditionalFormatContainsText)
+		c.Assert(formats[1].Rules[1].Text, qt.Equals, `say "hi"`)
+		c.Assert(formats[1].Rules[1].Operator, qt.Equals, ConditionalFormatOperator(""))
+
+		rules := formats[2].Rules
+		c.Assert(rules, qt.HasLen, 6)
+		c.Assert(rules[0].ColorScale.Colors, qt.DeepEquals, []string{"FFF8696B", "FFFFEB84", "FF63BE7B"})
+		c.Assert(rules[1].DataBar, qt.DeepEquals, &DataBar{
+			Min:       ConditionalValue{Type: ConditionalValueMin},
+			Max:       ConditionalValue{Type: ConditionalValueNumber, Value: "100"},
+			Color:     "FF638EC6",
+			HideValue: true,
+		})
+		c.Assert(rules[2].Rank, qt.Equals, 10)
+		c.Assert(rules[2].Percent, qt.Equals, true)
+		c.Assert(rules[2].Bottom, qt.Equals, true)
+		c.Assert(rules[3].Below, qt.Equals, true)
+		c.Assert(rules[4].TimePeriod, qt.Equals, TimePeriodLast7Days)
+		c.Assert(rules[5].Formulas, qt.DeepEquals, []string{"$B2>$C2"})
+		c.Assert(rules[5].Style.Border, qt.Equals, Border{Bottom: "thin", BottomColor: "FF000000"})
+
+		// Writing the File again doesn't duplicate the differential
+		// formats.
+		parts := writtenParts(c, f)
+		c.Assert(strings.Count(parts["xl/styles.xml"], "<dxf>"), qt.Equals, 3)
+	})
+}
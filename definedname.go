@@ -0,0 +1,424 @@
+package xlsx
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tealeg/xlsx/v3/formula"
+)
+
+// The built-in names that Excel gives special meaning to.  Each of
+// them is local to a sheet.
+const (
+	// DefinedNamePrintArea is the name of the ranges of a sheet
+	// that are printed.
+	DefinedNamePrintArea = "_xlnm.Print_Area"
+	// DefinedNamePrintTitles is the name of the rows and columns of
+	// a sheet that are repeated on every printed page.
+	DefinedNamePrintTitles = "_xlnm.Print_Titles"
+	// DefinedNameFilterDatabase is the name of the range of a sheet
+	// that its auto filter applies to.
+	DefinedNameFilterDatabase = "_xlnm._FilterDatabase"
+)
+
+// builtInNames maps the lower case form of each of the built-in names
+// that AddDefinedName accepts to its usual spelling.
+var builtInNames = map[string]string{
+	strings.ToLower(DefinedNamePrintArea):      DefinedNamePrintArea,
+	strings.ToLower(DefinedNamePrintTitles):    DefinedNamePrintTitles,
+	strings.ToLower(DefinedNameFilterDatabase): DefinedNameFilterDatabase,
+}
+
+// DefinedName gives a name to a range of cells, a constant or a
+// formula, which formulas can then use in its place.  A name belongs
+// either to the whole File or to one of its sheets.
+type DefinedName struct {
+	// Name is the name, such as "Prices" or "_xlnm.Print_Area".
+	Name string
+	// RefersTo is what the name stands for, written as a formula
+	// without a leading "=", such as "Data!$A$2:$A$20" or "0.2".
+	RefersTo string
+	// Scope is the Sheet the name is local to, or nil if the name
+	// belongs to the whole File.  The sheet is found by position
+	// when the File is saved, so the name stays with its Sheet if
+	// the sheets are reordered.
+	Scope   *Sheet
+	Comment string
+	Hidden  bool
+
+	file *File
+	// xml keeps the attributes, read from a file, that have no
+	// field of their own.
+	xml xlsxDefinedName
+}
+
+// AddDefinedName adds a name that stands for refersTo, which is
+// written as a formula, such as "Data!$A$2:$A$20", with or without a
+// leading "=".  If scope is nil the name belongs to the whole File,
+// otherwise it is local to the scope Sheet.
+//
+// The built-in names DefinedNamePrintArea, DefinedNamePrintTitles and
+// DefinedNameFilterDatabase must be local to a sheet and refer to it:
+// the print area to one or more ranges, the print titles to whole
+// rows, whole columns or one of each, and the filter database to a
+// single range.
+func (f *File) AddDefinedName(name, refersTo string, scope *Sheet) (*DefinedName, error) {
+	wrap := func(err error) (*DefinedName, error) {
+		return nil, fmt.Errorf("AddDefinedName: %w", err)
+	}
+
+	if scope != nil && f.sheetIndex(scope) < 0 {
+		return wrap(errors.New("the scope sheet is not in the File"))
+	}
+	refersTo = strings.TrimPrefix(strings.TrimSpace(refersTo), "=")
+	if refersTo == "" {
+		return wrap(fmt.Errorf("the name %q does not refer to anything", name))
+	}
+	// The formula parser doesn't take unions of references, which
+	// names such as print areas often refer to.
+	if _, err := unionRefs(refersTo); err != nil {
+		if _, err := formula.Parse(refersTo); err != nil {
+			return wrap(fmt.Errorf("the name %q: %w", name, err))
+		}
+	}
+
+	hidden := false
+	if strings.HasPrefix(strings.ToLower(name), "_xlnm.") {
+		builtIn, ok := builtInNames[strings.ToLower(name)]
+		if !ok {
+			return wrap(fmt.Errorf("unsupported built-in name %q", name))
+		}
+		name = builtIn
+		err := checkBuiltInName(name, refersTo, scope)
+		if err != nil {
+			return wrap(err)
+		}
+		hidden = name == DefinedNameFilterDatabase
+	} else if !validTableName(name) {
+		return wrap(fmt.Errorf("invalid name %q", name))
+	}
+
+	for _, dn := range f.DefinedNames {
+		if dn.Scope == scope && strings.EqualFold(dn.Name, name) {
+			return wrap(fmt.Errorf("the name %q is already defined", name))
+		}
+	}
+	if scope == nil {
+		for _, sheet := range f.Sheets {
+			if sheet.table(name) != nil {
+				return wrap(fmt.Errorf("there is already a table called %q", name))
+			}
+		}
+	}
+
+	dn := &DefinedName{
+		Name:     name,
+		RefersTo: refersTo,
+		Scope:    scope,
+		Hidden:   hidden,
+		file:     f,
+	}
+	f.DefinedNames = append(f.DefinedNames, dn)
+	return dn, nil
+}
+
+// checkBuiltInName returns an error if refersTo isn't what the
+// built-in name should refer to on the scope sheet.
+func checkBuiltInName(name, refersTo string, scope *Sheet) error {
+	if scope == nil {
+		return fmt.Errorf("%s must be local to a sheet", name)
+	}
+	refs, err := unionRefs(refersTo)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	for _, ref := range refs {
+		if ref.Book != 0 || !strings.EqualFold(ref.Sheet, scope.Name) {
+			return fmt.Errorf("%s must refer to cells on the sheet %q, not %q", name, scope.Name, ref.String())
+		}
+	}
+	switch name {
+	case DefinedNamePrintTitles:
+		var rows, cols int
+		for _, ref := range refs {
+			switch {
+			case ref.IsWholeRows():
+				rows++
+			case ref.IsWholeCols():
+				cols++
+			default:
+				return fmt.Errorf("%s must refer to whole rows or columns, not %q", name, ref.String())
+			}
+		}
+		if rows > 1 || cols > 1 {
+			return fmt.Errorf("%s can refer to only one range of rows and one of columns", name)
+		}
+	case DefinedNameFilterDatabase:
+		if len(refs) != 1 {
+			return fmt.Errorf("%s must refer to a single range", name)
+		}
+	}
+	return nil
+}
+
+// unionRefs returns the references of refersTo, which must be one
+// reference or several separated by commas.
+func unionRefs(refersTo string) ([]formula.Ref, error) {
+	tokens, err := formula.Tokenize(refersTo)
+	if err != nil {
+		return nil, err
+	}
+	var refs []formula.Ref
+	wantRef := true
+	for _, t := range tokens {
+		switch {
+		case t.Kind == formula.TokenSpace:
+			continue
+		case wantRef && t.Kind == formula.TokenRef:
+			refs = append(refs, t.Ref)
+		case !wantRef && t.Kind == formula.TokenComma:
+		default:
+			return nil, fmt.Errorf("%q is not a reference to cells", refersTo)
+		}
+		wantRef = !wantRef
+	}
+	if wantRef {
+		return nil, fmt.Errorf("%q is not a reference to cells", refersTo)
+	}
+	return refs, nil
+}
+
+// DefinedName returns the name that belongs to the whole File called
+// name, or nil if there isn't one.  A name local to a sheet can be
+// found by qualifying it with the sheet name, as in "Sheet1!Total" or
+// "'My Sheet'!Total".  Names are compared without regard to case.
+func (f *File) DefinedName(name string) *DefinedName {
+	var scope *Sheet
+	if i := strings.LastIndex(name, "!"); i >= 0 {
+		sheetName := name[:i]
+		if len(sheetName) > 1 && sheetName[0] == '\'' && sheetName[len(sheetName)-1] == '\'' {
+			sheetName = strings.Replace(sheetName[1:len(sheetName)-1], "''", "'", -1)
+		}
+		scope = f.sheetByName(sheetName)
+		if scope == nil {
+			return nil
+		}
+		name = name[i+1:]
+	}
+	for _, dn := range f.DefinedNames {
+		if dn.Scope == scope && strings.EqualFold(dn.Name, name) {
+			return dn
+		}
+	}
+	return nil
+}
+
+// DefinedNames returns the names that are local to the Sheet.
+func (s *Sheet) DefinedNames() []*DefinedName {
+	if s.File == nil {
+		return nil
+	}
+	var names []*DefinedName
+	for _, dn := range s.File.DefinedNames {
+		if dn.Scope == s {
+			names = append(names, dn)
+		}
+	}
+	return names
+}
+
+// Range returns the range of cells that the name refers to.  It
+// returns an error if the name refers to anything else, including
+// more than one range.
+func (dn *DefinedName) Range() (*Range, error) {
+	ranges, err := dn.Ranges()
+	if err != nil {
+		return nil, err
+	}
+	if len(ranges) != 1 {
+		return nil, fmt.Errorf("Range: the name %q refers to %d ranges", dn.Name, len(ranges))
+	}
+	return ranges[0], nil
+}
+
+// Ranges returns the ranges of cells that the name refers to, which
+// may be several, as in "Sheet1!$A$1:$B$2,Sheet1!$D$1:$E$2".  A
+// reference without a sheet name is to the Scope of the name.
+func (dn *DefinedName) Ranges() ([]*Range, error) {
+	wrap := func(err error) ([]*Range, error) {
+		return nil, fmt.Errorf("Ranges: %w", err)
+	}
+
+	f := dn.file
+	if f == nil && dn.Scope != nil {
+		f = dn.Scope.File
+	}
+	refs, err := unionRefs(dn.RefersTo)
+	if err != nil {
+		return wrap(fmt.Errorf("the name %q: %w", dn.Name, err))
+	}
+	ranges := make([]*Range, 0, len(refs))
+	for _, ref := range refs {
+		sheet := dn.Scope
+		if ref.Sheet != "" {
+			sheet = nil
+			if f != nil && ref.Book == 0 {
+				sheet = f.sheetByName(ref.Sheet)
+			}
+		}
+		if sheet == nil {
+			return wrap(fmt.Errorf("the name %q refers to %q, which is not on a sheet of the File", dn.Name, ref.String()))
+		}
+		ranges = append(ranges, newRange(sheet, ref))
+	}
+	return ranges, nil
+}
+
+// makeXLSXDefinedNames returns the definedNames element for the
+// names of the File.  Names local to a sheet that isn't in the File
+// are left out.
+func (f *File) makeXLSXDefinedNames() xlsxDefinedNames {
+	var names xlsxDefinedNames
+	for _, dn := range f.DefinedNames {
+		x := dn.xml
+		x.Name = dn.Name
+		x.Data = dn.RefersTo
+		x.Comment = dn.Comment
+		x.Hidden = dn.Hidden
+		x.LocalSheetID = nil
+		if dn.Scope != nil {
+			i := f.sheetIndex(dn.Scope)
+			if i < 0 {
+				continue
+			}
+			x.LocalSheetID = &i
+		}
+		names.DefinedName = append(names.DefinedName, x)
+	}
+	return names
+}
+
+// readDefinedNames adds the names of workbook to the File.  scopes
+// holds the Sheet read for each sheet of the workbook, by position,
+// and names local to a sheet that wasn't read, such as a chart sheet,
+// are dropped.
+func readDefinedNames(fi *File, workbook *xlsxWorkbook, scopes []*Sheet) {
+	for _, x := range workbook.DefinedNames.DefinedName {
+		dn := &DefinedName{
+			Name:     x.Name,
+			RefersTo: x.Data,
+			Comment:  x.Comment,
+			Hidden:   x.Hidden,
+			file:     fi,
+			xml:      x,
+		}
+		if x.LocalSheetID != nil {
+			id := *x.LocalSheetID
+			if id < 0 || id >= len(scopes) || scopes[id] == nil {
+				continue
+			}
+			dn.Scope = scopes[id]
+		}
+		fi.DefinedNames = append(fi.DefinedNames, dn)
+	}
+}
+
+// sheetIndex returns the position of s in the sheets of the File, or
+// -1 if it isn't there.
+func (f *File) sheetIndex(s *Sheet) int {
+	for i, sheet := range f.Sheets {
+		if sheet == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// sheetByName returns the Sheet of the File called name, ignoring
+// case as Excel does, or nil.
+func (f *File) sheetByName(name string) *Sheet {
+	for _, sheet := range f.Sheets {
+		if strings.EqualFold(sheet.Name, name) {
+			return sheet
+		}
+	}
+	return nil
+}
+
+// Range is a rectangular range of cells on a Sheet.
+type Range struct {
+	Sheet *Sheet
+	// FirstRow, FirstCol, LastRow and LastCol are the zero based
+	// rows and columns of the top left and bottom right cells.
+	FirstRow, FirstCol int
+	LastRow, LastCol   int
+
+	wholeRows, wholeCols bool
+}
+
+// newRange returns the Range of ref on sheet.
+func newRange(sheet *Sheet, ref formula.Ref) *Range {
+	r := &Range{
+		Sheet:    sheet,
+		FirstRow: ref.Row1, FirstCol: ref.Col1,
+		LastRow: ref.Row2, LastCol: ref.Col2,
+		wholeRows: ref.IsWholeRows(),
+		wholeCols: ref.IsWholeCols(),
+	}
+	if r.wholeRows {
+		r.FirstCol, r.LastCol = 0, formula.MaxCols-1
+	}
+	if r.wholeCols {
+		r.FirstRow, r.LastRow = 0, formula.MaxRows-1
+	}
+	return r
+}
+
+// String returns the Range as an absolute reference qualified by the
+// name of its Sheet, such as "Data!$A$2:$C$10".
+func (r *Range) String() string {
+	ref := formula.Ref{
+		Sheet: r.Sheet.Name,
+		Row1:  r.FirstRow, Col1: r.FirstCol,
+		Row2: r.LastRow, Col2: r.LastCol,
+		AbsRow1: true, AbsCol1: true,
+		AbsRow2: true, AbsCol2: true,
+		IsArea: r.FirstRow != r.LastRow || r.FirstCol != r.LastCol,
+	}
+	if r.wholeRows {
+		ref.Col1, ref.Col2, ref.IsArea = -1, -1, true
+	}
+	if r.wholeCols {
+		ref.Row1, ref.Row2, ref.IsArea = -1, -1, true
+	}
+	return ref.String()
+}
+
+// ForEachCell calls cellVisitor with each cell of the Range, a row at
+// a time.  Cells are created as needed, except that a Range of whole
+// rows or columns stops at the last row and column that the Sheet
+// uses.  If cellVisitor returns an error ForEachCell stops and
+// returns it.
+func (r *Range) ForEachCell(cellVisitor func(c *Cell) error) error {
+	lastRow, lastCol := r.LastRow, r.LastCol
+	if r.wholeCols && lastRow >= r.Sheet.MaxRow {
+		lastRow = r.Sheet.MaxRow - 1
+	}
+	if r.wholeRows && lastCol >= r.Sheet.MaxCol {
+		lastCol = r.Sheet.MaxCol - 1
+	}
+	for row := r.FirstRow; row <= lastRow; row++ {
+		for col := r.FirstCol; col <= lastCol; col++ {
+			cell, err := r.Sheet.Cell(row, col)
+			if err != nil {
+				return fmt.Errorf("ForEachCell: %w", err)
+			}
+			err = cellVisitor(cell)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
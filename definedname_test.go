@@ -0,0 +1,199 @@
+package xlsx
+
+import (
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestDefinedNames(t *testing.T) {
+	c := qt.New(t)
+
+	setUp := func(c *qt.C, option FileOption) (*File, *Sheet, *Sheet) {
+		f := NewFile(option)
+		summary, err := f.AddSheet("Summary")
+		c.Assert(err, qt.IsNil)
+		data, err := f.AddSheet("Data Sheet")
+		c.Assert(err, qt.IsNil)
+		setCells(c, data, map[string]interface{}{
+			"A1": "Price", "A2": 2, "A3": 3, "A4": 5,
+		})
+		_, err = f.AddDefinedName("Prices", "='Data Sheet'!$A$2:$A$4", nil)
+		c.Assert(err, qt.IsNil)
+		_, err = f.AddDefinedName("Rate", "0.5", summary)
+		c.Assert(err, qt.IsNil)
+		_, err = f.AddDefinedName(DefinedNamePrintArea, "'Data Sheet'!$A$1:$A$4", data)
+		c.Assert(err, qt.IsNil)
+		setCells(c, summary, map[string]interface{}{
+			"A1": "=SUM(Prices)*Rate",
+		})
+		return f, summary, data
+	}
+
+	csRunO(c, "Lookup", func(c *qt.C, option FileOption) {
+		f, summary, data := setUp(c, option)
+
+		dn := f.DefinedName("prices")
+		c.Assert(dn, qt.Not(qt.IsNil))
+		c.Assert(dn.RefersTo, qt.Equals, "'Data Sheet'!$A$2:$A$4")
+		c.Assert(dn.Scope, qt.IsNil)
+
+		// Local names need the sheet name.
+		c.Assert(f.DefinedName("Rate"), qt.IsNil)
+		c.Assert(f.DefinedName("Summary!Rate").Scope, qt.Equals, summary)
+		c.Assert(f.DefinedName("'Data Sheet'!_xlnm.Print_Area").Scope, qt.Equals, data)
+		c.Assert(f.DefinedName("Missing!Rate"), qt.IsNil)
+
+		names := data.DefinedNames()
+		c.Assert(names, qt.HasLen, 1)
+		c.Assert(names[0].Name, qt.Equals, DefinedNamePrintArea)
+		c.Assert(summary.DefinedNames(), qt.HasLen, 1)
+	})
+
+	csRunO(c, "Range", func(c *qt.C, option FileOption) {
+		f, summary, data := setUp(c, option)
+
+		r, err := f.DefinedName("Prices").Range()
+		c.Assert(err, qt.IsNil)
+		c.Assert(r.Sheet, qt.Equals, data)
+		c.Assert(r.String(), qt.Equals, "'Data Sheet'!$A$2:$A$4")
+		var values []string
+		err = r.ForEachCell(func(cell *Cell) error {
+			values = append(values, cell.Value)
+			return nil
+		})
+		c.Assert(err, qt.IsNil)
+		c.Assert(values, qt.DeepEquals, []string{"2", "3", "5"})
+
+		// A reference without a sheet is to the scope of the name.
+		dn, err := f.AddDefinedName("Corner", "$A$1:$B$2,$D$4", summary)
+		c.Assert(err, qt.IsNil)
+		_, err = dn.Range()
+		c.Assert(err, qt.ErrorMatches, `Range: the name "Corner" refers to 2 ranges`)
+		ranges, err := dn.Ranges()
+		c.Assert(err, qt.IsNil)
+		c.Assert(ranges, qt.HasLen, 2)
+		c.Assert(ranges[0].Sheet, qt.Equals, summary)
+		c.Assert(ranges[1].String(), qt.Equals, "Summary!$D$4")
+
+		// Whole columns stop at the last row of the sheet.
+		dn, err = f.AddDefinedName("Column", "'Data Sheet'!$A:$A", nil)
+		c.Assert(err, qt.IsNil)
+		r, err = dn.Range()
+		c.Assert(err, qt.IsNil)
+		c.Assert(r.String(), qt.Equals, "'Data Sheet'!$A:$A")
+		count := 0
+		err = r.ForEachCell(func(cell *Cell) error {
+			count++
+			return nil
+		})
+		c.Assert(err, qt.IsNil)
+		c.Assert(count, qt.Equals, 4)
+
+		_, err = f.DefinedName("Summary!Rate").Range()
+		c.Assert(err, qt.ErrorMatches, `Ranges: the name "Rate": "0.5" is not a reference to cells`)
+	})
+
+	csRunO(c, "Errors", func(c *qt.C, option FileOption) {
+		f, summary, data := setUp(c, option)
+		_, err := f.AddSheet("Other")
+		c.Assert(err, qt.IsNil)
+		stray, err := NewSheet("Stray")
+		c.Assert(err, qt.IsNil)
+
+		for _, test := range []struct {
+			name, refersTo string
+			scope          *Sheet
+			err            string
+		}{
+			{"A1", "1", nil, `invalid name "A1"`},
+			{"Two Words", "1", nil, `invalid name "Two Words"`},
+			{"Empty", "=", nil, `the name "Empty" does not refer to anything`},
+			{"Bad", "SUM(", nil, `the name "Bad": .*`},
+			{"prices", "1", nil, `the name "prices" is already defined`},
+			{"Stray", "1", stray, `the scope sheet is not in the File`},
+			{"_xlnm.Print_Area", "Summary!$A$1", nil, `_xlnm.Print_Area must be local to a sheet`},
+			{"_xlnm.Print_Area", "Other!$A$1", data, `_xlnm.Print_Area must refer to cells on the sheet "Data Sheet", not "Other!\$A\$1"`},
+			{"_xlnm.Print_Titles", "Summary!$A$1:$B$2", summary, `_xlnm.Print_Titles must refer to whole rows or columns, not "Summary!\$A\$1:\$B\$2"`},
+			{"_xlnm.Print_Titles", "Summary!$1:$1,Summary!$3:$3", summary, `_xlnm.Print_Titles can refer to only one range of rows and one of columns`},
+			{"_xlnm._FilterDatabase", "Summary!$A$1:$B$2,Summary!$D$1", summary, `_xlnm._FilterDatabase must refer to a single range`},
+			{"_xlnm.Print_Area", "1+2", summary, `_xlnm.Print_Area: "1\+2" is not a reference to cells`},
+			{"_xlnm.Extract", "Summary!$A$1", summary, `unsupported built-in name "_xlnm.Extract"`},
+		} {
+			_, err := f.AddDefinedName(test.name, test.refersTo, test.scope)
+			c.Assert(err, qt.ErrorMatches, "AddDefinedName: "+test.err, qt.Commentf("%s %s", test.name, test.refersTo))
+		}
+
+		// A name local to a sheet may repeat a global one.
+		_, err = f.AddDefinedName("Prices", "Summary!$A$1", summary)
+		c.Assert(err, qt.IsNil)
+		dn, err := f.AddDefinedName("_XLNM.print_titles", "Summary!$1:$2,Summary!$A:$A", summary)
+		c.Assert(err, qt.IsNil)
+		c.Assert(dn.Name, qt.Equals, DefinedNamePrintTitles)
+		dn, err = f.AddDefinedName(DefinedNameFilterDatabase, "Summary!$A$1:$C$9", summary)
+		c.Assert(err, qt.IsNil)
+		c.Assert(dn.Hidden, qt.Equals, true)
+	})
+
+	csRunO(c, "RoundTrip", func(c *qt.C, option FileOption) {
+		f, _, _ := setUp(c, option)
+		f.DefinedName("Prices").Comment = "what things cost"
+
+		parts := writtenParts(c, f)
+		c.Assert(parts["xl/workbook.xml"], qt.Contains, `<definedName name="Prices" comment="what things cost">&#39;Data Sheet&#39;!$A$2:$A$4</definedName>`)
+		c.Assert(parts["xl/workbook.xml"], qt.Contains, `<definedName name="Rate" localSheetId="0">0.5</definedName>`)
+
+		f = reopen(c, f, option)
+		summary, data := f.Sheets[0], f.Sheets[1]
+		c.Assert(f.DefinedNames, qt.HasLen, 3)
+		c.Assert(f.DefinedName("Prices").Comment, qt.Equals, "what things cost")
+		c.Assert(f.DefinedName("Summary!Rate").Scope, qt.Equals, summary)
+		c.Assert(data.DefinedNames()[0].Name, qt.Equals, DefinedNamePrintArea)
+
+		err := f.Recalculate()
+		c.Assert(err, qt.IsNil)
+		c.Assert(cellAt(c, summary, "A1").Value, qt.Equals, "5")
+	})
+
+	csRunO(c, "Reorder", func(c *qt.C, option FileOption) {
+		f, summary, data := setUp(c, option)
+		f.Sheets[0], f.Sheets[1] = data, summary
+
+		parts := writtenParts(c, f)
+		c.Assert(parts["xl/workbook.xml"], qt.Contains, `<definedName name="Rate" localSheetId="1">0.5</definedName>`)
+		c.Assert(parts["xl/workbook.xml"], qt.Contains, `<definedName name="_xlnm.Print_Area" localSheetId="0">`)
+
+		f = reopen(c, f, option)
+		c.Assert(f.DefinedName("Summary!Rate").Scope, qt.Equals, f.Sheets[1])
+	})
+
+	csRunO(c, "Rename", func(c *qt.C, option FileOption) {
+		f, summary, data := setUp(c, option)
+		_, err := f.AddDefinedName("First", "'Data Sheet'!$A$2", summary)
+		c.Assert(err, qt.IsNil)
+		setCells(c, summary, map[string]interface{}{
+			"A2": "='Data Sheet'!A2+1",
+			"A3": "='[1]Data Sheet'!A2",
+		})
+
+		err = data.SetName("Prices")
+		c.Assert(err, qt.IsNil)
+		c.Assert(data.Name, qt.Equals, "Prices")
+		c.Assert(f.Sheet["Prices"], qt.Equals, data)
+		c.Assert(f.Sheet["Data Sheet"], qt.IsNil)
+		c.Assert(f.DefinedName("Prices").RefersTo, qt.Equals, "Prices!$A$2:$A$4")
+		c.Assert(f.DefinedName("Summary!First").RefersTo, qt.Equals, "Prices!$A$2")
+		c.Assert(f.DefinedName("Prices!_xlnm.Print_Area").RefersTo, qt.Equals, "Prices!$A$1:$A$4")
+		c.Assert(cellAt(c, summary, "A2").Formula(), qt.Equals, "Prices!A2+1")
+		// The link to a sheet of another workbook is left alone.
+		c.Assert(cellAt(c, summary, "A3").Formula(), qt.Equals, "'[1]Data Sheet'!A2")
+
+		err = data.SetName("summary")
+		c.Assert(err, qt.ErrorMatches, `SetName: duplicate sheet name 'summary'.`)
+		err = data.SetName("a/b")
+		c.Assert(err, qt.ErrorMatches, `SetName: sheet name must not contain .*`)
+		err = data.SetName(strings.Repeat("x", 32))
+		c.Assert(err, qt.ErrorMatches, `SetName: sheet name must be 31 or fewer characters long.*`)
+	})
+}
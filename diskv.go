@@ -88,7 +88,7 @@ func (cs *DiskVCellStore) ReadRow(key string) (*Row, error) {
 // within the persistant store.
 func (cs *DiskVCellStore) MoveRow(r *Row, index int) error {
 	oldKey := r.key()
-	r.num++
+	r.num = index
 	newKey := r.key()
 	if cs.store.Has(newKey) {
 		return fmt.Errorf("Target index for row (%d) would overwrite a row already exists", index)
@@ -517,6 +517,15 @@ func (cs *DiskVCellStore) writeStyle(s *Style) error {
 	if err = cs.writeBool(s.ApplyAlignment); err != nil {
 		return err
 	}
+	if err = cs.writeBool(s.ApplyProtection); err != nil {
+		return err
+	}
+	if err = cs.writeBool(s.Protection.Locked); err != nil {
+		return err
+	}
+	if err = cs.writeBool(s.Protection.Hidden); err != nil {
+		return err
+	}
 	if err = cs.writeEndOfRecord(); err != nil {
 		return err
 	}
@@ -550,6 +559,15 @@ func (cs *DiskVCellStore) readStyle() (*Style, error) {
 	if s.ApplyAlignment, err = cs.readBool(); err != nil {
 		return s, err
 	}
+	if s.ApplyProtection, err = cs.readBool(); err != nil {
+		return s, err
+	}
+	if s.Protection.Locked, err = cs.readBool(); err != nil {
+		return s, err
+	}
+	if s.Protection.Hidden, err = cs.readBool(); err != nil {
+		return s, err
+	}
 	if err = cs.readEndOfRecord(); err != nil {
 		return s, err
 	}
@@ -651,6 +669,53 @@ func (cs *DiskVCellStore) readDataValidation() (*xlsxDataValidation, error) {
 	return dv, nil
 }
 
+func (cs *DiskVCellStore) writeComment(c *Comment) error {
+	var err error
+	if err = cs.writeString(c.Author); err != nil {
+		return err
+	}
+	if err = cs.writeRichText(c.Text); err != nil {
+		return err
+	}
+	if err = cs.writeBool(c.Visible); err != nil {
+		return err
+	}
+	if err = cs.writeFloat(c.Width); err != nil {
+		return err
+	}
+	if err = cs.writeFloat(c.Height); err != nil {
+		return err
+	}
+	if err = cs.writeEndOfRecord(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cs *DiskVCellStore) readComment() (*Comment, error) {
+	var err error
+	c := &Comment{}
+	if c.Author, err = cs.readString(); err != nil {
+		return c, err
+	}
+	if c.Text, err = cs.readRichText(); err != nil {
+		return c, err
+	}
+	if c.Visible, err = cs.readBool(); err != nil {
+		return c, err
+	}
+	if c.Width, err = cs.readFloat(); err != nil {
+		return c, err
+	}
+	if c.Height, err = cs.readFloat(); err != nil {
+		return c, err
+	}
+	if err = cs.readEndOfRecord(); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
 func (cs *DiskVCellStore) writeRow(r *Row) error {
 	var err error
 	if err = cs.writeBool(r.Hidden); err != nil {
@@ -666,6 +731,9 @@ func (cs *DiskVCellStore) writeRow(r *Row) error {
 	if err = cs.writeBool(r.isCustom); err != nil {
 		return err
 	}
+	if err = cs.writeBool(r.Collapsed); err != nil {
+		return err
+	}
 	if err = cs.writeInt(r.num); err != nil {
 		return err
 	}
@@ -702,6 +770,12 @@ func (cs *DiskVCellStore) writeCell(c *Cell) error {
 	if err = cs.writeString(c.formula); err != nil {
 		return err
 	}
+	if err = cs.writeString(c.arrayRef); err != nil {
+		return err
+	}
+	if err = cs.writeBool(c.dynamicArray); err != nil {
+		return err
+	}
 	if err = cs.writeBool(c.style != nil); err != nil {
 		return err
 	}
@@ -726,6 +800,9 @@ func (cs *DiskVCellStore) writeCell(c *Cell) error {
 	if err = cs.writeBool(c.DataValidation != nil); err != nil {
 		return err
 	}
+	if err = cs.writeBool(c.Comment != nil); err != nil {
+		return err
+	}
 	if err = cs.writeString(c.Hyperlink.DisplayString); err != nil {
 		return err
 	}
@@ -754,6 +831,11 @@ func (cs *DiskVCellStore) writeCell(c *Cell) error {
 			return err
 		}
 	}
+	if c.Comment != nil {
+		if err = cs.writeComment(c.Comment); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -780,6 +862,10 @@ func (cs *DiskVCellStore) readRow() (*Row, error) {
 	if err != nil {
 		return nil, err
 	}
+	r.Collapsed, err = cs.readBool()
+	if err != nil {
+		return nil, err
+	}
 	r.num, err = cs.readInt()
 	if err != nil {
 		return nil, err
@@ -809,7 +895,7 @@ func (cs *DiskVCellStore) readRow() (*Row, error) {
 func (cs *DiskVCellStore) readCell() (*Cell, error) {
 	var err error
 	var cellType int
-	var hasStyle, hasDataValidation bool
+	var hasStyle, hasDataValidation, hasComment bool
 	var cellIsNil bool
 	if cellIsNil, err = cs.readBool(); err != nil {
 		return nil, err
@@ -827,6 +913,12 @@ func (cs *DiskVCellStore) readCell() (*Cell, error) {
 	if c.formula, err = cs.readString(); err != nil {
 		return c, err
 	}
+	if c.arrayRef, err = cs.readString(); err != nil {
+		return c, err
+	}
+	if c.dynamicArray, err = cs.readBool(); err != nil {
+		return c, err
+	}
 	if hasStyle, err = cs.readBool(); err != nil {
 		return c, err
 	}
@@ -852,6 +944,9 @@ func (cs *DiskVCellStore) readCell() (*Cell, error) {
 	if hasDataValidation, err = cs.readBool(); err != nil {
 		return c, err
 	}
+	if hasComment, err = cs.readBool(); err != nil {
+		return c, err
+	}
 	if c.Hyperlink.DisplayString, err = cs.readString(); err != nil {
 		return c, err
 	}
@@ -880,6 +975,11 @@ func (cs *DiskVCellStore) readCell() (*Cell, error) {
 			return c, err
 		}
 	}
+	if hasComment {
+		if c.Comment, err = cs.readComment(); err != nil {
+			return c, err
+		}
+	}
 	return c, nil
 }
 
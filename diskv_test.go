@@ -445,10 +445,12 @@ line!`)
 				Vertical:     "top",
 				WrapText:     true,
 			},
-			ApplyBorder:    true,
-			ApplyFill:      true,
-			ApplyFont:      true,
-			ApplyAlignment: true,
+			ApplyBorder:     true,
+			ApplyFill:       true,
+			ApplyFont:       true,
+			ApplyAlignment:  true,
+			ApplyProtection: true,
+			Protection:      Protection{Hidden: true},
 		}
 		err = cs.writeStyle(&s)
 		c.Assert(err, qt.IsNil)
@@ -465,6 +467,8 @@ line!`)
 		c.Assert(s2.ApplyFill, qt.Equals, s.ApplyFill)
 		c.Assert(s2.ApplyFont, qt.Equals, s.ApplyFont)
 		c.Assert(s2.ApplyAlignment, qt.Equals, s.ApplyAlignment)
+		c.Assert(s2.ApplyProtection, qt.Equals, s.ApplyProtection)
+		c.Assert(s2.Protection, qt.Equals, s.Protection)
 		_, err = cs.readStyle()
 		c.Assert(err, qt.Not(qt.IsNil))
 
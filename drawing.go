@@ -0,0 +1,298 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+// emusPerPixel is the number of English Metric Units, the unit of
+// length in drawings, in a pixel.
+const emusPerPixel = 9525
+
+// The size, in pixels, that Excel gives a column of the default width
+// and a row of the default height.
+const (
+	defaultColWidthPixels  = 64
+	defaultRowHeightPixels = 20
+)
+
+// drawingPart builds the drawing part of a sheet, which holds the
+// pictures and charts placed on it.
+type drawingPart struct {
+	rels    *xlsxWorksheetRels
+	anchors strings.Builder
+	shapes  int
+}
+
+// drawing returns the drawing part of the sheet, creating it when
+// the first thing is placed on it.
+func (sp *sheetParts) drawing() *drawingPart {
+	if sp.dp == nil {
+		sp.dp = &drawingPart{rels: &xlsxWorksheetRels{XMLName: xml.Name{Local: "Relationships"}}}
+	}
+	return sp.dp
+}
+
+// addRelation adds a relationship from the drawing to target and
+// returns its id.
+func (dp *drawingPart) addRelation(relType RelationshipType, target string) string {
+	id := fmt.Sprintf("rId%d", len(dp.rels.Relationships)+1)
+	dp.rels.Relationships = append(dp.rels.Relationships, xlsxWorksheetRelation{Id: id, Type: relType, Target: target})
+	return id
+}
+
+// nextShapeId returns the id of the next shape in the drawing.
+func (dp *drawingPart) nextShapeId() int {
+	dp.shapes++
+	return dp.shapes + 1
+}
+
+// makeDrawingPart adds the drawing built up in sp, if there is one,
+// to sp with its relationships.
+func (sp *sheetParts) makeDrawingPart() error {
+	if sp.dp == nil {
+		return nil
+	}
+	name := fmt.Sprintf("xl/drawings/drawing%d.xml", sp.index)
+	sp.addPart(name, xml.Header+
+		`<xdr:wsDr xmlns:xdr="http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing" `+
+		`xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" `+
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`+
+		sp.dp.anchors.String()+`</xdr:wsDr>`,
+		"application/vnd.openxmlformats-officedocument.drawing+xml")
+	rels, err := xml.Marshal(sp.dp.rels)
+	if err != nil {
+		return err
+	}
+	sp.addPart(relsPartName(name), xml.Header+string(rels), "")
+	sp.addRelation(RelationshipTypeDrawing, "../"+strings.TrimPrefix(name, "xl/"))
+	return nil
+}
+
+// relsPartName returns the name of the part holding the
+// relationships of the part called name.
+func relsPartName(name string) string {
+	return path.Dir(name) + "/_rels/" + path.Base(name) + ".rels"
+}
+
+// addAnchor places content, the XML of a picture or chart, on the
+// drawing at anchor with the width cx and height cy in EMUs.
+func (dp *drawingPart) addAnchor(s *Sheet, anchor Anchor, cx, cy int64, content string) {
+	b := &dp.anchors
+	marker := func(name string, col, row, x, y int) {
+		fmt.Fprintf(b, `<xdr:%s><xdr:col>%d</xdr:col><xdr:colOff>%d</xdr:colOff><xdr:row>%d</xdr:row><xdr:rowOff>%d</xdr:rowOff></xdr:%s>`,
+			name, col, x*emusPerPixel, row, y*emusPerPixel, name)
+	}
+	ext := fmt.Sprintf(`<xdr:ext cx="%d" cy="%d"/>`, cx, cy)
+	var elem string
+	switch anchor.Type {
+	case TwoCellAnchor:
+		elem = "twoCellAnchor"
+		b.WriteString(`<xdr:twoCellAnchor>`)
+		marker("from", anchor.Col, anchor.Row, anchor.OffsetX, anchor.OffsetY)
+		to := anchor
+		if to.ToCol == 0 && to.ToRow == 0 && to.ToOffsetX == 0 && to.ToOffsetY == 0 {
+			to.ToCol, to.ToOffsetX = s.spanCols(anchor.Col, anchor.OffsetX+int(cx/emusPerPixel))
+			to.ToRow, to.ToOffsetY = s.spanRows(anchor.Row, anchor.OffsetY+int(cy/emusPerPixel))
+		}
+		marker("to", to.ToCol, to.ToRow, to.ToOffsetX, to.ToOffsetY)
+	case AbsoluteAnchor:
+		elem = "absoluteAnchor"
+		fmt.Fprintf(b, `<xdr:absoluteAnchor><xdr:pos x="%d" y="%d"/>`,
+			anchor.OffsetX*emusPerPixel, anchor.OffsetY*emusPerPixel)
+		b.WriteString(ext)
+	default:
+		elem = "oneCellAnchor"
+		b.WriteString(`<xdr:oneCellAnchor>`)
+		marker("from", anchor.Col, anchor.Row, anchor.OffsetX, anchor.OffsetY)
+		b.WriteString(ext)
+	}
+	b.WriteString(content)
+	fmt.Fprintf(b, `<xdr:clientData/></xdr:%s>`, elem)
+}
+
+// spanCols returns the zero based column, and the offset in pixels
+// into it, that is width pixels to the right of the left edge of col.
+func (s *Sheet) spanCols(col, width int) (int, int) {
+	for {
+		w := s.colWidthPixels(col)
+		if width < w {
+			return col, width
+		}
+		width -= w
+		col++
+	}
+}
+
+// spanRows returns the zero based row, and the offset in pixels into
+// it, that is height pixels below the top edge of row.  All rows are
+// taken to be of the default height.
+func (s *Sheet) spanRows(row, height int) (int, int) {
+	h := defaultRowHeightPixels
+	if s.SheetFormat.DefaultRowHeight > 0 {
+		h = int(s.SheetFormat.DefaultRowHeight * 96 / 72)
+	}
+	return row + height/h, height % h
+}
+
+// colWidthPixels returns the width, in pixels, of the zero based
+// column col.
+func (s *Sheet) colWidthPixels(col int) int {
+	width := s.SheetFormat.DefaultColWidth
+	if c := s.Col(col); c != nil {
+		if c.Hidden != nil && *c.Hidden {
+			return 0
+		}
+		if c.Width != nil {
+			width = *c.Width
+		}
+	}
+	if width <= 0 {
+		return defaultColWidthPixels
+	}
+	// The width of a column is measured in characters of the
+	// default font, which are 7 pixels wide, plus 5 pixels of
+	// padding.
+	return int(width*7 + 5)
+}
+
+// makeDrawings refers the worksheet to its drawing and to the VML
+// drawing of its notes, if it has them.
+func (s *Sheet) makeDrawings(worksheet *xlsxWorksheet, relations *xlsxWorksheetRels) {
+	if relations == nil {
+		return
+	}
+	for _, rel := range relations.Relationships {
+		switch rel.Type {
+		case RelationshipTypeDrawing:
+			worksheet.Drawing = &xlsxDrawing{RID: rel.Id}
+		case RelationshipTypeVMLDrawing:
+			worksheet.LegacyDrawing = &xlsxLegacyDrawing{RID: rel.Id}
+		}
+	}
+}
+
+// resolveTarget returns the name of the part that the relationship
+// target refers to, relative to the directory dir of its source part.
+func resolveTarget(dir, target string) string {
+	if strings.HasPrefix(target, "/") {
+		return strings.TrimPrefix(target, "/")
+	}
+	return path.Clean(path.Join(dir, target))
+}
+
+// escapeXML escapes s for use as XML text or as an attribute value.
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// decodeZipFile decodes the XML in f into v.
+func decodeZipFile(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("file.Open: %w", err)
+	}
+	defer rc.Close()
+	err = xml.NewDecoder(rc).Decode(v)
+	if err != nil {
+		return fmt.Errorf("xml.Decoder.Decode: %w", err)
+	}
+	return nil
+}
+
+// readZipFile returns the contents of f.
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("file.Open: %w", err)
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+// readDrawing reads the drawing, if any, that the worksheet
+// relationships rels refer to, adding the things placed on it to
+// sheet.
+func readDrawing(fi *File, sheet *Sheet, rels *xlsxWorksheetRels) error {
+	var name string
+	for _, rel := range rels.Relationships {
+		if rel.Type == RelationshipTypeDrawing {
+			name = resolveTarget("xl/worksheets", rel.Target)
+			break
+		}
+	}
+	drawingFile, ok := fi.zipFiles[name]
+	if !ok {
+		return nil
+	}
+	var wsDr xlsxWsDr
+	err := decodeZipFile(drawingFile, &wsDr)
+	if err != nil {
+		return err
+	}
+	drawingRels := new(xlsxWorksheetRels)
+	if relsFile, ok := fi.zipFiles[relsPartName(name)]; ok {
+		err = decodeZipFile(relsFile, drawingRels)
+		if err != nil {
+			return err
+		}
+	}
+	// target returns the part that the drawing relationship id
+	// refers to.
+	target := func(id string) *zip.File {
+		for _, rel := range drawingRels.Relationships {
+			if rel.Id == id {
+				return fi.zipFiles[resolveTarget(path.Dir(name), rel.Target)]
+			}
+		}
+		return nil
+	}
+
+	for _, xAnchor := range wsDr.Anchors {
+		anchor, cx, cy := readAnchor(xAnchor)
+		if xAnchor.Pic != nil {
+			err = sheet.readPicture(xAnchor.Pic, anchor, cx, cy, target)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readAnchor returns the Anchor, and the size in EMUs, of the thing
+// placed on a drawing at xAnchor.
+func readAnchor(xAnchor xlsxDrawingAnchor) (anchor Anchor, cx, cy int64) {
+	switch xAnchor.XMLName.Local {
+	case "twoCellAnchor":
+		anchor.Type = TwoCellAnchor
+	case "absoluteAnchor":
+		anchor.Type = AbsoluteAnchor
+	default:
+		anchor.Type = OneCellAnchor
+	}
+	if xAnchor.From != nil {
+		anchor.Col, anchor.Row = xAnchor.From.Col, xAnchor.From.Row
+		anchor.OffsetX = int(xAnchor.From.ColOff / emusPerPixel)
+		anchor.OffsetY = int(xAnchor.From.RowOff / emusPerPixel)
+	}
+	if xAnchor.To != nil {
+		anchor.ToCol, anchor.ToRow = xAnchor.To.Col, xAnchor.To.Row
+		anchor.ToOffsetX = int(xAnchor.To.ColOff / emusPerPixel)
+		anchor.ToOffsetY = int(xAnchor.To.RowOff / emusPerPixel)
+	}
+	if xAnchor.Pos != nil {
+		anchor.OffsetX = int(xAnchor.Pos.X / emusPerPixel)
+		anchor.OffsetY = int(xAnchor.Pos.Y / emusPerPixel)
+	}
+	if xAnchor.Ext != nil {
+		cx, cy = xAnchor.Ext.Cx, xAnchor.Ext.Cy
+	}
+	return anchor, cx, cy
+}
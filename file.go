@@ -18,15 +18,21 @@ import (
 type File struct {
 	worksheets           map[string]*zip.File
 	worksheetRels        map[string]*zip.File
+	zipFiles             map[string]*zip.File
 	referenceTable       *RefTable
 	Date1904             bool
 	styles               *xlsxStyleSheet
 	Sheets               []*Sheet
 	Sheet                map[string]*Sheet
 	theme                *theme
-	DefinedNames         []*xlsxDefinedName
+	DefinedNames         []*DefinedName
 	cellStoreConstructor CellStoreConstructor
 	rowLimit             int
+	fillMergedCells      bool
+	writeSharedFormulas  bool
+	CalcSettings         CalcSettings
+	pivotCaches          []*PivotCache
+	Protection           *WorkbookProtection
 }
 
 const NoRowLimit int = -1
@@ -41,13 +47,71 @@ func RowLimit(n int) FileOption {
 	}
 }
 
+// CalcMode is the way in which a spreadsheet application recalculates
+// the formulas of a workbook.
+type CalcMode string
+
+const (
+	// CalcModeAuto recalculates formulas whenever a cell they
+	// depend on changes.  This is the default.
+	CalcModeAuto CalcMode = "auto"
+	// CalcModeAutoNoTable recalculates formulas automatically,
+	// except for data tables.
+	CalcModeAutoNoTable CalcMode = "autoNoTable"
+	// CalcModeManual only recalculates formulas when the user asks.
+	CalcModeManual CalcMode = "manual"
+)
+
+// CalcSettings control how a spreadsheet application calculates the
+// formulas of a File.  The zero value means Excel's defaults.
+type CalcSettings struct {
+	// FullCalcOnLoad asks the application to recalculate every
+	// formula when the workbook is opened, rather than trusting the
+	// cached results stored with them.
+	FullCalcOnLoad bool
+	// Mode is the calculation mode.  An empty Mode means
+	// CalcModeAuto.
+	Mode CalcMode
+	// Iterate allows formulas with circular references to be
+	// calculated by repeated iteration.
+	Iterate bool
+	// IterateCount is the most iterations made when Iterate is
+	// set.  Zero means the default of 100.
+	IterateCount int
+	// IterateDelta is the change below which iteration stops when
+	// Iterate is set.  Zero means the default of 0.001.
+	IterateDelta float64
+}
+
+// makeXLSXCalcPr returns the calcPr element of the workbook for cs.
+func (cs CalcSettings) makeXLSXCalcPr() xlsxCalcPr {
+	calcPr := xlsxCalcPr{
+		CalcMode:       string(cs.Mode),
+		FullCalcOnLoad: cs.FullCalcOnLoad,
+		IterateCount:   cs.IterateCount,
+		RefMode:        "A1",
+		Iterate:        cs.Iterate,
+		IterateDelta:   cs.IterateDelta,
+	}
+	if calcPr.CalcMode == string(CalcModeAuto) {
+		calcPr.CalcMode = ""
+	}
+	if calcPr.IterateCount == 0 {
+		calcPr.IterateCount = 100
+	}
+	if calcPr.IterateDelta == 0 {
+		calcPr.IterateDelta = 0.001
+	}
+	return calcPr
+}
+
 // NewFile creates a new File struct. You may pass it zero, one or
 // many FileOption functions that affect the behaviour of the file.
 func NewFile(options ...FileOption) *File {
 	f := &File{
 		Sheet:                make(map[string]*Sheet),
 		Sheets:               make([]*Sheet, 0),
-		DefinedNames:         make([]*xlsxDefinedName, 0),
+		DefinedNames:         make([]*DefinedName, 0),
 		rowLimit:             NoRowLimit,
 		cellStoreConstructor: NewMemoryCellStore,
 	}
@@ -179,16 +243,9 @@ func (f *File) AddSheetWithCellStore(sheetName string, constructor CellStoreCons
 	if _, exists := f.Sheet[sheetName]; exists {
 		return nil, fmt.Errorf("duplicate sheet name '%s'.", sheetName)
 	}
-	runeLength := utf8.RuneCountInString(sheetName)
-	if runeLength > 31 || runeLength == 0 {
-		return nil, fmt.Errorf("sheet name must be 31 or fewer characters long.  It is currently '%d' characters long", runeLength)
-	}
-	// Iterate over the runes
-	for _, r := range sheetName {
-		// Excel forbids : \ / ? * [ ]
-		if r == ':' || r == '\\' || r == '/' || r == '?' || r == '*' || r == '[' || r == ']' {
-			return nil, fmt.Errorf("sheet name must not contain any restricted characters : \\ / ? * [ ] but contains '%s'", string(r))
-		}
+	err = checkSheetName(sheetName)
+	if err != nil {
+		return nil, err
 	}
 	sheet := &Sheet{
 		Name:     sheetName,
@@ -206,6 +263,23 @@ func (f *File) AddSheetWithCellStore(sheetName string, constructor CellStoreCons
 	return sheet, nil
 }
 
+// checkSheetName returns an error if Excel won't accept name as the
+// name of a sheet.
+func checkSheetName(name string) error {
+	runeLength := utf8.RuneCountInString(name)
+	if runeLength > 31 || runeLength == 0 {
+		return fmt.Errorf("sheet name must be 31 or fewer characters long.  It is currently '%d' characters long", runeLength)
+	}
+	// Iterate over the runes
+	for _, r := range name {
+		// Excel forbids : \ / ? * [ ]
+		if r == ':' || r == '\\' || r == '/' || r == '?' || r == '*' || r == '[' || r == ']' {
+			return fmt.Errorf("sheet name must not contain any restricted characters : \\ / ? * [ ] but contains '%s'", string(r))
+		}
+	}
+	return nil
+}
+
 // Appends an existing Sheet, with the provided name, to a File
 func (f *File) AppendSheet(sheet Sheet, sheetName string) (*Sheet, error) {
 	if _, exists := f.Sheet[sheetName]; exists {
@@ -221,8 +295,9 @@ func (f *File) AppendSheet(sheet Sheet, sheetName string) (*Sheet, error) {
 
 func (f *File) makeWorkbook() xlsxWorkbook {
 	return xlsxWorkbook{
-		FileVersion: xlsxFileVersion{AppName: "Go XLSX"},
-		WorkbookPr:  xlsxWorkbookPr{ShowObjects: "all"},
+		FileVersion:        xlsxFileVersion{AppName: "Go XLSX"},
+		WorkbookPr:         xlsxWorkbookPr{ShowObjects: "all"},
+		WorkbookProtection: f.makeXLSXWorkbookProtection(),
 		BookViews: xlsxBookViews{
 			WorkBookView: []xlsxWorkBookView{
 				{
@@ -237,13 +312,9 @@ func (f *File) makeWorkbook() xlsxWorkbook {
 				},
 			},
 		},
-		Sheets: xlsxSheets{Sheet: make([]xlsxSheet, len(f.Sheets))},
-		CalcPr: xlsxCalcPr{
-			IterateCount: 100,
-			RefMode:      "A1",
-			Iterate:      false,
-			IterateDelta: 0.001,
-		},
+		Sheets:       xlsxSheets{Sheet: make([]xlsxSheet, len(f.Sheets))},
+		DefinedNames: f.makeXLSXDefinedNames(),
+		CalcPr:       f.CalcSettings.makeXLSXCalcPr(),
 	}
 }
 
@@ -270,14 +341,17 @@ func addRelationshipNameSpaceToWorksheet(worksheetMarshal string) string {
 	newXmlns := `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`
 	newSheetMarshall := strings.Replace(worksheetMarshal, oldXmlns, newXmlns, 1)
 
-	oldHyperlink := `<hyperlink id=`
-	newHyperlink := `<hyperlink r:id=`
-	newSheetMarshall = strings.Replace(newSheetMarshall, oldHyperlink, newHyperlink, -1)
+	for name := range relationshipIdElems {
+		newSheetMarshall = strings.Replace(newSheetMarshall, "<"+name+" id=", "<"+name+" r:id=", -1)
+	}
 	return newSheetMarshall
 }
 
 // MakeStreamParts constructs a map of file name to XML content
 // representing the file in terms of the structure of an XLSX file.
+// The worksheets are marshalled with encoding/xml, so, unlike those
+// written by Write, their empty elements are closed with an end tag
+// rather than being self-closing.
 func (f *File) MakeStreamParts() (map[string]string, error) {
 	var parts map[string]string
 	var refTable *RefTable = NewSharedStringRefTable()
@@ -298,6 +372,7 @@ func (f *File) MakeStreamParts() (map[string]string, error) {
 	parts = make(map[string]string)
 	workbook = f.makeWorkbook()
 	sheetIndex := 1
+	wp := newWorkbookParts()
 
 	if f.styles == nil {
 		f.styles = newXlsxStyleSheet(f.theme)
@@ -314,7 +389,11 @@ func (f *File) MakeStreamParts() (map[string]string, error) {
 			return nil, err
 		}
 
-		xSheetRels := sheet.makeXLSXSheetRelations()
+		sp, err := sheet.makeSheetParts(sheetIndex, sheet.makeXLSXSheetRelations(), &types, wp)
+		if err != nil {
+			return nil, err
+		}
+		xSheetRels := sp.rels
 		xSheet := sheet.makeXLSXSheet(refTable, f.styles, xSheetRels)
 		rId := fmt.Sprintf("rId%d", sheetIndex)
 		sheetId := strconv.Itoa(sheetIndex)
@@ -349,9 +428,21 @@ func (f *File) MakeStreamParts() (map[string]string, error) {
 				return parts, err
 			}
 		}
+		for name, part := range sp.parts {
+			parts[name] = part
+		}
 		sheetIndex++
 	}
 
+	xWRel := workbookRels.MakeXLSXWorkbookRels()
+	pp, err := f.makePivotCacheParts(&workbook, &xWRel, &types, wp)
+	if err != nil {
+		return parts, err
+	}
+	for name, part := range pp.parts {
+		parts[name] = part
+	}
+
 	workbookMarshal, err := marshal(workbook)
 	if err != nil {
 		return parts, err
@@ -374,7 +465,10 @@ func (f *File) MakeStreamParts() (map[string]string, error) {
 		return parts, err
 	}
 
-	xWRel := workbookRels.MakeXLSXWorkbookRels()
+	if f.hasDynamicArrays() {
+		addCellMetadata(&xWRel, &types)
+		parts["xl/metadata.xml"] = TEMPLATE_XL_METADATA
+	}
 
 	parts["xl/_rels/workbook.xml.rels"], err = marshal(xWRel)
 	if err != nil {
@@ -394,6 +488,29 @@ func (f *File) MakeStreamParts() (map[string]string, error) {
 	return parts, nil
 }
 
+// hasDynamicArrays returns true if a sheet of f was written with
+// dynamic array formulas.
+func (f *File) hasDynamicArrays() bool {
+	for _, sheet := range f.Sheets {
+		if sheet.dynamicArrays {
+			return true
+		}
+	}
+	return false
+}
+
+// addCellMetadata adds the relationship and content type for the cell
+// metadata part that dynamic array formulas refer to.
+func addCellMetadata(xWRel *xlsxWorkbookRels, types *xlsxTypes) {
+	xWRel.Relationships = append(xWRel.Relationships, xlsxWorkbookRelation{
+		Id:     fmt.Sprintf("rId%d", len(xWRel.Relationships)+1),
+		Target: "metadata.xml",
+		Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/sheetMetadata"})
+	types.Overrides = append(types.Overrides, xlsxOverride{
+		PartName:    "/xl/metadata.xml",
+		ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheetMetadata+xml"})
+}
+
 // MarshallParts constructs a map of file name to XML content representing the file
 // in terms of the structure of an XLSX file.
 func (f *File) MarshallParts(zipWriter *zip.Writer) error {
@@ -431,6 +548,7 @@ func (f *File) MarshallParts(zipWriter *zip.Writer) error {
 	// parts = make(map[string]string)
 	workbook = f.makeWorkbook()
 	sheetIndex := 1
+	wp := newWorkbookParts()
 
 	if f.styles == nil {
 		f.styles = newXlsxStyleSheet(f.theme)
@@ -447,7 +565,11 @@ func (f *File) MarshallParts(zipWriter *zip.Writer) error {
 			return wrap(err)
 		}
 
-		xSheetRels := sheet.makeXLSXSheetRelations()
+		sp, err := sheet.makeSheetParts(sheetIndex, sheet.makeXLSXSheetRelations(), &types, wp)
+		if err != nil {
+			return wrap(err)
+		}
+		xSheetRels := sp.rels
 		rId := fmt.Sprintf("rId%d", sheetIndex)
 		sheetId := strconv.Itoa(sheetIndex)
 		sheetPath := fmt.Sprintf("worksheets/sheet%d.xml", sheetIndex)
@@ -484,9 +606,27 @@ func (f *File) MarshallParts(zipWriter *zip.Writer) error {
 				return wrap(err)
 			}
 		}
+		for _, name := range sp.names {
+			err = writePart(name, sp.parts[name])
+			if err != nil {
+				return wrap(err)
+			}
+		}
 		sheetIndex++
 	}
 
+	xWRel := workbookRels.MakeXLSXWorkbookRels()
+	pp, err := f.makePivotCacheParts(&workbook, &xWRel, &types, wp)
+	if err != nil {
+		return wrap(err)
+	}
+	for _, name := range pp.names {
+		err = writePart(name, pp.parts[name])
+		if err != nil {
+			return wrap(err)
+		}
+	}
+
 	workbookMarshal, err := marshal(workbook)
 	if err != nil {
 		return err
@@ -526,7 +666,13 @@ func (f *File) MarshallParts(zipWriter *zip.Writer) error {
 		return err
 	}
 
-	xWRel := workbookRels.MakeXLSXWorkbookRels()
+	if f.hasDynamicArrays() {
+		addCellMetadata(&xWRel, &types)
+		err = writePart("xl/metadata.xml", TEMPLATE_XL_METADATA)
+		if err != nil {
+			return err
+		}
+	}
 	relPart, err := marshal(xWRel)
 	if err != nil {
 		return err
@@ -1038,6 +1038,39 @@ func TestFile(t *testing.T) {
 		c.Assert(row.GetCell(0).Value, qt.Equals, "#DIV/0!")
 	})
 
+	csRunO(c, "TestCalcSettings", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		_, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+
+		parts, err := f.MakeStreamParts()
+		c.Assert(err, qt.IsNil)
+		c.Assert(parts["xl/workbook.xml"], qt.Contains, `<calcPr iterateCount="100" refMode="A1" iterateDelta="0.001"></calcPr>`)
+
+		f.CalcSettings = CalcSettings{
+			FullCalcOnLoad: true,
+			Mode:           CalcModeManual,
+			Iterate:        true,
+			IterateCount:   10,
+			IterateDelta:   0.5,
+		}
+		parts, err = f.MakeStreamParts()
+		c.Assert(err, qt.IsNil)
+		c.Assert(parts["xl/workbook.xml"], qt.Contains, `<calcPr calcMode="manual" fullCalcOnLoad="true" iterateCount="10" refMode="A1" iterate="true" iterateDelta="0.5"></calcPr>`)
+
+		path := filepath.Join(c.Mkdir(), "test.xlsx")
+		err = f.Save(path)
+		c.Assert(err, qt.IsNil)
+		f, err = OpenFile(path, option)
+		c.Assert(err, qt.IsNil)
+		c.Assert(f.CalcSettings, qt.Equals, CalcSettings{
+			FullCalcOnLoad: true,
+			Mode:           CalcModeManual,
+			Iterate:        true,
+			IterateCount:   10,
+			IterateDelta:   0.5,
+		})
+	})
 }
 
 // Helper function used to test contents of a given xlsxXf against
@@ -0,0 +1,331 @@
+package formula
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Expr is a node in the syntax tree of a parsed formula.
+type Expr interface {
+	exprNode()
+}
+
+type (
+	// Number is a numeric literal.
+	Number struct{ Value float64 }
+	// String is a string literal, with its quotes removed.
+	String struct{ Value string }
+	// Bool is TRUE or FALSE.
+	Bool struct{ Value bool }
+	// Error is an error literal, such as #N/A.
+	Error struct{ Value string }
+	// Reference is a reference to cells.
+	Reference struct{ Ref Ref }
+	// TableReference is a structured reference to part of a table.
+	TableReference struct{ Ref TableRef }
+	// Name is a defined name, optionally qualified by a sheet name.
+	// Book is the index of the external workbook of the sheet, as
+	// in [1]Sheet1!Total, or 0 for a sheet of this workbook.
+	Name struct {
+		Book        int
+		Sheet, Name string
+	}
+	// Empty is an omitted function argument, as in IF(A1,,1).
+	Empty struct{}
+	// Unary is a prefix "-" or "+", or a postfix "%".
+	Unary struct {
+		Op string
+		X  Expr
+	}
+	// Binary is an infix operator.
+	Binary struct {
+		Op   string
+		X, Y Expr
+	}
+	// Call is a function call.  Name is as written, and so may
+	// carry a prefix such as "_xlfn.".
+	Call struct {
+		Name string
+		Args []Expr
+	}
+	// Array is an array constant, such as {1,2;3,4}.
+	Array struct{ Rows [][]Expr }
+)
+
+func (Number) exprNode()         {}
+func (String) exprNode()         {}
+func (Bool) exprNode()           {}
+func (Error) exprNode()          {}
+func (Reference) exprNode()      {}
+func (TableReference) exprNode() {}
+func (Name) exprNode()           {}
+func (Empty) exprNode()          {}
+func (Unary) exprNode()          {}
+func (Binary) exprNode()         {}
+func (Call) exprNode()           {}
+func (Array) exprNode()          {}
+
+type parser struct {
+	formula string
+	tokens  []Token
+	pos     int
+}
+
+// Parse parses a formula, in A1 notation, into a syntax tree.  A
+// leading "=" is ignored.
+func Parse(formula string) (Expr, error) {
+	tokens, err := Tokenize(formula)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{formula: formula}
+	for _, t := range tokens {
+		if t.Kind != TokenSpace {
+			p.tokens = append(p.tokens, t)
+		}
+	}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("invalid formula %q: empty formula", formula)
+	}
+	expr, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, p.unexpected()
+	}
+	return expr, nil
+}
+
+func (p *parser) peek() *Token {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *parser) peekOperator(ops ...string) (string, bool) {
+	t := p.peek()
+	if t == nil || t.Kind != TokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.Text == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	if t == nil {
+		return fmt.Errorf("invalid formula %q: unexpected end of formula", p.formula)
+	}
+	return fmt.Errorf("invalid formula %q: unexpected %q at position %d", p.formula, t.Text, t.Pos)
+}
+
+func (p *parser) parseBinary(next func() (Expr, error), ops ...string) (Expr, error) {
+	x, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.peekOperator(ops...)
+		if !ok {
+			return x, nil
+		}
+		p.pos++
+		y, err := next()
+		if err != nil {
+			return nil, err
+		}
+		x = Binary{Op: op, X: x, Y: y}
+	}
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	return p.parseBinary(p.parseConcat, "=", "<>", "<", "<=", ">", ">=")
+}
+
+func (p *parser) parseConcat() (Expr, error) {
+	return p.parseBinary(p.parseAdditive, "&")
+}
+
+func (p *parser) parseAdditive() (Expr, error) {
+	return p.parseBinary(p.parseMultiplicative, "+", "-")
+}
+
+func (p *parser) parseMultiplicative() (Expr, error) {
+	return p.parseBinary(p.parseExponent, "*", "/")
+}
+
+func (p *parser) parseExponent() (Expr, error) {
+	return p.parseBinary(p.parsePercent, "^")
+}
+
+func (p *parser) parsePercent() (Expr, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.peekOperator("%"); !ok {
+			return x, nil
+		}
+		p.pos++
+		x = Unary{Op: "%", X: x}
+	}
+}
+
+// parseUnary handles negation, which in Excel binds more tightly than
+// any other operator, so that -2^2 is 4.
+func (p *parser) parseUnary() (Expr, error) {
+	if op, ok := p.peekOperator("-", "+"); ok {
+		p.pos++
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Unary{Op: op, X: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, p.unexpected()
+	}
+	switch t.Kind {
+	case TokenNumber:
+		p.pos++
+		f, err := strconv.ParseFloat(t.Text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid formula %q: invalid number %q at position %d", p.formula, t.Text, t.Pos)
+		}
+		return Number{f}, nil
+	case TokenString:
+		p.pos++
+		return String{unquoteString(t.Text)}, nil
+	case TokenBool:
+		p.pos++
+		return Bool{strings.EqualFold(t.Text, "TRUE")}, nil
+	case TokenError:
+		p.pos++
+		text := t.Text
+		if _, _, end, ok := scanSheet(text, 0); ok {
+			// Sheet1!#REF! is as much an error as #REF!
+			text = text[end:]
+		}
+		return Error{strings.ToUpper(text)}, nil
+	case TokenRef:
+		p.pos++
+		return Reference{t.Ref}, nil
+	case TokenTableRef:
+		p.pos++
+		return TableReference{t.TableRef}, nil
+	case TokenName:
+		p.pos++
+		if sheet, book, end, ok := scanSheet(t.Text, 0); ok {
+			return Name{Book: book, Sheet: sheet, Name: t.Text[end:]}, nil
+		}
+		return Name{Name: t.Text}, nil
+	case TokenFunc:
+		p.pos++
+		return p.parseFuncArgs(strings.TrimSuffix(t.Text, "("))
+	case TokenOpenParen:
+		p.pos++
+		x, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.peek(); t == nil || t.Kind != TokenCloseParen {
+			return nil, p.unexpected()
+		}
+		p.pos++
+		return x, nil
+	case TokenOpenBrace:
+		p.pos++
+		return p.parseArray()
+	}
+	return nil, p.unexpected()
+}
+
+func (p *parser) parseFuncArgs(name string) (Expr, error) {
+	fn := Call{Name: name}
+	if t := p.peek(); t != nil && t.Kind == TokenCloseParen {
+		p.pos++
+		return fn, nil
+	}
+	for {
+		t := p.peek()
+		if t == nil {
+			return nil, p.unexpected()
+		}
+		if t.Kind == TokenComma || t.Kind == TokenCloseParen {
+			fn.Args = append(fn.Args, Empty{})
+		} else {
+			arg, err := p.parseComparison()
+			if err != nil {
+				return nil, err
+			}
+			fn.Args = append(fn.Args, arg)
+		}
+		t = p.peek()
+		if t == nil {
+			return nil, p.unexpected()
+		}
+		p.pos++
+		switch t.Kind {
+		case TokenComma:
+			continue
+		case TokenCloseParen:
+			return fn, nil
+		}
+		p.pos--
+		return nil, p.unexpected()
+	}
+}
+
+// parseArray parses an array constant such as {1,2;3,4}, the opening
+// brace having already been consumed.
+func (p *parser) parseArray() (Expr, error) {
+	arr := Array{Rows: [][]Expr{{}}}
+	for {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		last := len(arr.Rows) - 1
+		arr.Rows[last] = append(arr.Rows[last], x)
+		t := p.peek()
+		if t == nil {
+			return nil, p.unexpected()
+		}
+		p.pos++
+		switch t.Kind {
+		case TokenComma:
+		case TokenSemicolon:
+			arr.Rows = append(arr.Rows, nil)
+		case TokenCloseBrace:
+			for _, row := range arr.Rows {
+				if len(row) != len(arr.Rows[0]) {
+					return nil, fmt.Errorf("invalid formula %q: array rows differ in length", p.formula)
+				}
+			}
+			return arr, nil
+		default:
+			p.pos--
+			return nil, p.unexpected()
+		}
+	}
+}
+
+// unquoteString removes the surrounding quotes from a string
+// literal and collapses doubled quotes.
+func unquoteString(s string) string {
+	s = s[1 : len(s)-1]
+	return strings.Replace(s, `""`, `"`, -1)
+}
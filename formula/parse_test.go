@@ -0,0 +1,62 @@
+package formula
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestParse(t *testing.T) {
+	c := qt.New(t)
+
+	c.Run("Precedence", func(c *qt.C) {
+		expr, err := Parse("=1+2*3")
+		c.Assert(err, qt.IsNil)
+		c.Assert(expr, qt.DeepEquals, Binary{Op: "+",
+			X: Number{1},
+			Y: Binary{Op: "*", X: Number{2}, Y: Number{3}}})
+
+		// Negation binds more tightly than exponentiation
+		expr, err = Parse("-2^2")
+		c.Assert(err, qt.IsNil)
+		c.Assert(expr, qt.DeepEquals, Binary{Op: "^",
+			X: Unary{Op: "-", X: Number{2}},
+			Y: Number{2}})
+
+		expr, err = Parse(`A1&"x"=B1`)
+		c.Assert(err, qt.IsNil)
+		c.Assert(expr.(Binary).Op, qt.Equals, "=")
+	})
+
+	c.Run("Functions", func(c *qt.C) {
+		expr, err := Parse("IF(A1,,TODAY())")
+		c.Assert(err, qt.IsNil)
+		c.Assert(expr, qt.DeepEquals, Call{Name: "IF", Args: []Expr{
+			Reference{Ref{}},
+			Empty{},
+			Call{Name: "TODAY"},
+		}})
+	})
+
+	c.Run("Arrays", func(c *qt.C) {
+		expr, err := Parse(`{1,"a";-2,TRUE}`)
+		c.Assert(err, qt.IsNil)
+		c.Assert(expr, qt.DeepEquals, Array{Rows: [][]Expr{
+			{Number{1}, String{"a"}},
+			{Unary{Op: "-", X: Number{2}}, Bool{true}},
+		}})
+		_, err = Parse(`{1,2;3}`)
+		c.Assert(err, qt.ErrorMatches, `invalid formula .*: array rows differ in length`)
+	})
+
+	c.Run("Errors", func(c *qt.C) {
+		_, err := Parse("SUM(1,2")
+		c.Assert(err, qt.ErrorMatches, `invalid formula .*: unexpected end of formula`)
+		_, err = Parse("1+")
+		c.Assert(err, qt.ErrorMatches, `invalid formula .*: unexpected end of formula`)
+		_, err = Parse("(1))")
+		c.Assert(err, qt.ErrorMatches, `invalid formula .*: unexpected "\)" at position 3`)
+		_, err = Parse("")
+		c.Assert(err, qt.ErrorMatches, `invalid formula .*: empty formula`)
+	})
+}
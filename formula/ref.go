@@ -0,0 +1,314 @@
+package formula
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Ref is a reference to a cell, a rectangular area, or whole rows or
+// columns, optionally qualified by a sheet name.  A reference to a
+// sheet of an external workbook, such as "[1]Sheet1!A1", also has the
+// index of the workbook in Book; Book is 0 otherwise.  Rows and columns
+// are zero based.  A whole column reference (e.g. "A:C") has rows of
+// -1, and a whole row reference (e.g. "1:3") has columns of -1.  For
+// a single cell reference Col2 and Row2 repeat Col1 and Row1.  Col1
+// and Row1 are always the top left corner.
+type Ref struct {
+	Book       int
+	Sheet      string
+	Col1, Row1 int
+	Col2, Row2 int
+	AbsCol1    bool
+	AbsRow1    bool
+	AbsCol2    bool
+	AbsRow2    bool
+	// IsArea is true when the reference was written as a range, such
+	// as "A1:B2" or "A1:A1".
+	IsArea bool
+}
+
+// ParseRef parses a single reference in A1 notation, such as "B2",
+// "$A$1:$C$10", "A:A" or "'My Sheet'!A1".
+func ParseRef(s string) (Ref, error) {
+	if s != "" {
+		if ref, end, ok := scanRef(s, 0, nil); ok && end == len(s) {
+			return ref, nil
+		}
+	}
+	return Ref{}, fmt.Errorf("invalid reference %q", s)
+}
+
+// IsWholeCols reports whether r refers to whole columns, as in "A:C".
+func (r Ref) IsWholeCols() bool {
+	return r.Row1 < 0
+}
+
+// IsWholeRows reports whether r refers to whole rows, as in "1:3".
+func (r Ref) IsWholeRows() bool {
+	return r.Col1 < 0
+}
+
+// String returns r in A1 notation, quoting the sheet name if needed.
+func (r Ref) String() string {
+	var b strings.Builder
+	if r.Sheet != "" {
+		b.WriteString(sheetPrefix(r.Book, r.Sheet))
+		b.WriteByte('!')
+	}
+	part := func(col, row int, absCol, absRow bool) {
+		if col >= 0 {
+			if absCol {
+				b.WriteByte('$')
+			}
+			b.WriteString(ColumnLetters(col))
+		}
+		if row >= 0 {
+			if absRow {
+				b.WriteByte('$')
+			}
+			b.WriteString(strconv.Itoa(row + 1))
+		}
+	}
+	part(r.Col1, r.Row1, r.AbsCol1, r.AbsRow1)
+	if r.IsArea {
+		b.WriteByte(':')
+		part(r.Col2, r.Row2, r.AbsCol2, r.AbsRow2)
+	}
+	return b.String()
+}
+
+// Shift returns r moved by dRow rows and dCol columns, as happens
+// when a formula is copied from one cell to another.  Absolute rows
+// and columns are not moved.  The result is false if the reference
+// moves off the sheet.
+func (r Ref) Shift(dRow, dCol int) (Ref, bool) {
+	wholeRows, wholeCols := r.IsWholeRows(), r.IsWholeCols()
+	if !wholeRows {
+		if !r.AbsCol1 {
+			r.Col1 += dCol
+		}
+		if !r.AbsCol2 {
+			r.Col2 += dCol
+		}
+	}
+	if !wholeCols {
+		if !r.AbsRow1 {
+			r.Row1 += dRow
+		}
+		if !r.AbsRow2 {
+			r.Row2 += dRow
+		}
+	}
+	if !wholeRows && (r.Col1 < 0 || r.Col2 < 0 || r.Col1 >= MaxCols || r.Col2 >= MaxCols) {
+		return r, false
+	}
+	if !wholeCols && (r.Row1 < 0 || r.Row2 < 0 || r.Row1 >= MaxRows || r.Row2 >= MaxRows) {
+		return r, false
+	}
+	return r.normalise(), true
+}
+
+// InsertRows returns r adjusted for n rows having been inserted
+// before the zero based row at.  References at or below at move down,
+// and areas that span at grow.  A negative n means that -n rows,
+// starting at row at, were removed: references below them move up
+// and areas that overlap them shrink.  Unlike Shift, absolute rows
+// are adjusted too.  The result is false if the reference no longer
+// refers to anything, because all of it was removed or pushed off the
+// sheet.
+func (r Ref) InsertRows(at, n int) (Ref, bool) {
+	if r.IsWholeCols() {
+		return r, true
+	}
+	var ok bool
+	r.Row1, r.Row2, ok = adjustSpan(r.Row1, r.Row2, at, n, MaxRows)
+	return r, ok
+}
+
+// InsertCols returns r adjusted for n columns having been inserted
+// before the zero based column at.  It works in the same way as
+// InsertRows.
+func (r Ref) InsertCols(at, n int) (Ref, bool) {
+	if r.IsWholeRows() {
+		return r, true
+	}
+	var ok bool
+	r.Col1, r.Col2, ok = adjustSpan(r.Col1, r.Col2, at, n, MaxCols)
+	return r, ok
+}
+
+// adjustSpan moves the span lo..hi for n rows or columns inserted
+// before at, or for -n removed from at onwards.
+func adjustSpan(lo, hi, at, n, limit int) (int, int, bool) {
+	if n >= 0 {
+		if lo >= at {
+			lo += n
+		}
+		if hi >= at {
+			hi += n
+		}
+		if lo >= limit {
+			return lo, hi, false
+		}
+		if hi >= limit {
+			hi = limit - 1
+		}
+		return lo, hi, true
+	}
+	end := at - n // the first row or column after those removed
+	switch {
+	case hi < at:
+	case lo >= end:
+		lo += n
+		hi += n
+	case lo >= at && hi < end:
+		return lo, hi, false
+	default:
+		if lo >= at {
+			lo = at
+		}
+		if hi < end {
+			hi = at - 1
+		} else {
+			hi += n
+		}
+	}
+	return lo, hi, true
+}
+
+// normalise makes Col1/Row1 the top left corner of r.
+func (r Ref) normalise() Ref {
+	if r.Col2 < r.Col1 {
+		r.Col1, r.Col2 = r.Col2, r.Col1
+		r.AbsCol1, r.AbsCol2 = r.AbsCol2, r.AbsCol1
+	}
+	if r.Row2 < r.Row1 {
+		r.Row1, r.Row2 = r.Row2, r.Row1
+		r.AbsRow1, r.AbsRow2 = r.AbsRow2, r.AbsRow1
+	}
+	return r
+}
+
+// inBounds reports whether r lies within the sheet.  References
+// resolved from R1C1 notation are off the sheet when a row or column
+// is MaxRows or MaxCols.
+func (r Ref) inBounds() bool {
+	if !r.IsWholeRows() && (r.Col1 < 0 || r.Col2 >= MaxCols) {
+		return false
+	}
+	if !r.IsWholeCols() && (r.Row1 < 0 || r.Row2 >= MaxRows) {
+		return false
+	}
+	return true
+}
+
+// QuoteSheetName returns name as it must appear before the "!" of a
+// reference, surrounded by single quotes if it contains anything
+// other than letters, digits, underscores and full stops, starts with
+// a digit, or could be mistaken for a cell reference.
+func QuoteSheetName(name string) string {
+	plain := isIdentStart(name, 0) && scanIdent(name, 0) == len(name) && name[0] != '\\'
+	if plain {
+		if p, end := scanRefPart(name, 0); p.kind == refPartCell && end == len(name) {
+			plain = false
+		} else if _, end := scanR1C1Part(name, 0, cellOrigin{}); end == len(name) {
+			plain = false
+		}
+	}
+	if plain {
+		return name
+	}
+	return "'" + strings.Replace(name, "'", "''", -1) + "'"
+}
+
+// sheetPrefix returns the sheet name as it must appear before the
+// "!" of a reference, preceded by the index of its external workbook
+// unless book is 0.  The index goes inside the quotes of a quoted
+// name, as in "'[1]My Sheet'".
+func sheetPrefix(book int, sheet string) string {
+	quoted := QuoteSheetName(sheet)
+	if book == 0 {
+		return quoted
+	}
+	index := "[" + strconv.Itoa(book) + "]"
+	if quoted == sheet {
+		return index + sheet
+	}
+	return "'" + index + quoted[1:]
+}
+
+// cellOrigin is the cell against which relative R1C1 references are
+// resolved.
+type cellOrigin struct {
+	row, col int
+}
+
+// scanR1C1Part scans a single cell ("R1C1", "R[-1]C"), row ("R2",
+// "R[1]") or column ("C3", "C[-2]") in R1C1 notation, starting at
+// position i of s, and resolves it against origin.  Coordinates that
+// resolve to a position off the sheet are set to MaxRows or MaxCols.
+func scanR1C1Part(s string, i int, origin cellOrigin) (refPart, int) {
+	var p refPart
+	j := i
+	scan := func(letter byte, base, limit int) (value int, abs, found bool) {
+		if j >= len(s) || (s[j] != letter && s[j] != letter+'a'-'A') {
+			return 0, false, false
+		}
+		k := j + 1
+		switch {
+		case k < len(s) && s[k] == '[':
+			k++
+			start := k
+			if k < len(s) && s[k] == '-' {
+				k++
+			}
+			digits := k
+			for k < len(s) && isDigit(s[k]) {
+				k++
+			}
+			if k == digits || k >= len(s) || s[k] != ']' {
+				return 0, false, false
+			}
+			n, ok := atoi(s[digits:k])
+			if !ok {
+				return 0, false, false
+			}
+			if digits > start {
+				n = -n
+			}
+			value = base + n
+			k++
+		case k < len(s) && isDigit(s[k]):
+			start := k
+			for k < len(s) && isDigit(s[k]) {
+				k++
+			}
+			n, ok := atoi(s[start:k])
+			if !ok || n < 1 || n > limit {
+				return 0, false, false
+			}
+			value, abs = n-1, true
+		default:
+			value = base
+		}
+		if value < 0 || value >= limit {
+			value = limit
+		}
+		j = k
+		return value, abs, true
+	}
+	row, absRow, hasRow := scan('R', origin.row, MaxRows)
+	col, absCol, hasCol := scan('C', origin.col, MaxCols)
+	switch {
+	case hasRow && hasCol:
+		p = refPart{kind: refPartCell, col: col, row: row, absCol: absCol, absRow: absRow}
+	case hasRow:
+		p = refPart{kind: refPartRow, col: -1, row: row, absRow: absRow}
+	case hasCol:
+		p = refPart{kind: refPartCol, col: col, row: -1, absCol: absCol}
+	default:
+		return refPart{}, i
+	}
+	return p, j
+}
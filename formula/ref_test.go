@@ -0,0 +1,147 @@
+package formula
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestParseRef(t *testing.T) {
+	c := qt.New(t)
+
+	ref, err := ParseRef("'Q1 Sales'!$B2:C$10")
+	c.Assert(err, qt.IsNil)
+	c.Assert(ref, qt.Equals, Ref{Sheet: "Q1 Sales", Col1: 1, Row1: 1, Col2: 2, Row2: 9, AbsCol1: true, AbsRow2: true, IsArea: true})
+
+	for _, s := range []string{"", "A1+1", "Total", "A1:", "XFE1", "A0"} {
+		_, err := ParseRef(s)
+		c.Assert(err, qt.ErrorMatches, `invalid reference ".*"`, qt.Commentf(s))
+	}
+}
+
+func TestRefString(t *testing.T) {
+	c := qt.New(t)
+	for _, s := range []string{
+		"A1", "$A$1", "A1:B2", "A1:A1", "$A:$C", "3:$5", "Sheet2!XFD1048576",
+		"'My Sheet'!A1", "'It''s'!A1", "'2019'!A1", "'A1'!B2", "'R1C1'!A1", "'Sheet-1'!A:A",
+	} {
+		ref, err := ParseRef(s)
+		c.Assert(err, qt.IsNil, qt.Commentf(s))
+		c.Assert(ref.String(), qt.Equals, s)
+	}
+}
+
+func TestQuoteSheetName(t *testing.T) {
+	c := qt.New(t)
+	cases := map[string]string{
+		"Sheet1":    "Sheet1",
+		"Données":   "Données",
+		"Data_2.v1": "Data_2.v1",
+		"My Sheet":  "'My Sheet'",
+		"It's":      "'It''s'",
+		"1st":       "'1st'",
+		"AB12":      "'AB12'",
+		"R2C3":      "'R2C3'",
+		"C":         "'C'",
+		"":          "''",
+	}
+	for name, quoted := range cases {
+		c.Assert(QuoteSheetName(name), qt.Equals, quoted, qt.Commentf(name))
+	}
+}
+
+func TestRefShift(t *testing.T) {
+	c := qt.New(t)
+	cases := []struct {
+		ref        string
+		dRow, dCol int
+		expected   string
+	}{
+		{"A1", 1, 1, "B2"},
+		{"$A1", 1, 1, "$A2"},
+		{"A$1", 1, 1, "B$1"},
+		{"$A$1", 5, 5, "$A$1"},
+		{"A1:B2", 2, 0, "A3:B4"},
+		{"A:B", 10, 1, "B:C"},
+		{"1:2", 1, 10, "2:3"},
+		{"C1:$A1", 0, 5, "$A1:H1"},
+		{"B2", -1, -1, "A1"},
+		{"A1", -1, 0, ""},
+		{"XFD1", 0, 1, ""},
+		{"A1048576", 1, 0, ""},
+	}
+	for _, tc := range cases {
+		ref, err := ParseRef(tc.ref)
+		c.Assert(err, qt.IsNil)
+		shifted, ok := ref.Shift(tc.dRow, tc.dCol)
+		c.Assert(ok, qt.Equals, tc.expected != "", qt.Commentf(tc.ref))
+		if ok {
+			c.Assert(shifted.String(), qt.Equals, tc.expected, qt.Commentf(tc.ref))
+		}
+	}
+}
+
+func TestRefInsertRows(t *testing.T) {
+	c := qt.New(t)
+	cases := []struct {
+		ref      string
+		at, n    int
+		expected string
+	}{
+		// Insert two rows before row 3
+		{"A2", 2, 2, "A2"},
+		{"A3", 2, 2, "A5"},
+		{"$A$3", 2, 2, "$A$5"},
+		{"A1:A3", 2, 2, "A1:A5"},
+		{"A3:A4", 2, 2, "A5:A6"},
+		{"A:A", 2, 2, "A:A"},
+		{"3:4", 2, 2, "5:6"},
+		{"A1:A1048576", 2, 2, "A1:A1048576"},
+		{"A1048575", 2, 2, ""},
+		// Remove rows 3 and 4
+		{"A2", 2, -2, "A2"},
+		{"A3", 2, -2, ""},
+		{"A4", 2, -2, ""},
+		{"A5", 2, -2, "A3"},
+		{"A3:B4", 2, -2, ""},
+		{"A1:A10", 2, -2, "A1:A8"},
+		{"A1:A3", 2, -2, "A1:A2"},
+		{"A4:A6", 2, -2, "A3:A4"},
+		{"$1:$5", 2, -2, "$1:$3"},
+	}
+	for _, tc := range cases {
+		ref, err := ParseRef(tc.ref)
+		c.Assert(err, qt.IsNil)
+		adjusted, ok := ref.InsertRows(tc.at, tc.n)
+		c.Assert(ok, qt.Equals, tc.expected != "", qt.Commentf("%s %d %d", tc.ref, tc.at, tc.n))
+		if ok {
+			c.Assert(adjusted.String(), qt.Equals, tc.expected, qt.Commentf("%s %d %d", tc.ref, tc.at, tc.n))
+		}
+	}
+}
+
+func TestRefInsertCols(t *testing.T) {
+	c := qt.New(t)
+	cases := []struct {
+		ref      string
+		at, n    int
+		expected string
+	}{
+		{"B1", 1, 1, "C1"},
+		{"A1:C1", 1, 1, "A1:D1"},
+		{"1:1", 1, 1, "1:1"},
+		{"$B:$C", 0, 3, "$E:$F"},
+		{"B1", 1, -1, ""},
+		{"A1:C1", 1, -1, "A1:B1"},
+		{"D:E", 0, -2, "B:C"},
+	}
+	for _, tc := range cases {
+		ref, err := ParseRef(tc.ref)
+		c.Assert(err, qt.IsNil)
+		adjusted, ok := ref.InsertCols(tc.at, tc.n)
+		c.Assert(ok, qt.Equals, tc.expected != "", qt.Commentf("%s %d %d", tc.ref, tc.at, tc.n))
+		if ok {
+			c.Assert(adjusted.String(), qt.Equals, tc.expected, qt.Commentf("%s %d %d", tc.ref, tc.at, tc.n))
+		}
+	}
+}
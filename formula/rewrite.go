@@ -0,0 +1,174 @@
+package formula
+
+import (
+	"strings"
+)
+
+// rewrite tokenizes formula and rebuilds it, replacing the text of
+// each Token with the result of fn.  Whitespace, string literals and
+// everything else fn leaves alone are reproduced exactly.
+func rewrite(formula string, origin *cellOrigin, fn func(t Token) string) (string, error) {
+	tokens, err := tokenize(formula, origin)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if strings.HasPrefix(formula, "=") {
+		b.WriteByte('=')
+	}
+	for _, t := range tokens {
+		b.WriteString(fn(t))
+	}
+	return b.String(), nil
+}
+
+// rewriteRefs applies fn to each reference in formula.  A reference
+// for which fn returns false is replaced by #REF!.
+func rewriteRefs(formula string, fn func(ref Ref) (Ref, bool)) (string, error) {
+	return rewrite(formula, nil, func(t Token) string {
+		if t.Kind != TokenRef {
+			return t.Text
+		}
+		ref, ok := fn(t.Ref)
+		if !ok {
+			return refError(t.Ref)
+		}
+		if ref == t.Ref {
+			return t.Text
+		}
+		return ref.String()
+	})
+}
+
+// refError returns the #REF! error that replaces ref.
+func refError(ref Ref) string {
+	if ref.Sheet == "" {
+		return "#REF!"
+	}
+	return sheetPrefix(ref.Book, ref.Sheet) + "!#REF!"
+}
+
+// ShiftReferences returns formula with its relative references moved
+// by dRow rows and dCol columns, as Excel does when a formula is
+// copied from one cell to another.  Absolute rows and columns, those
+// marked with a "$", are left alone.  A reference that would move off
+// the sheet becomes #REF!.
+func ShiftReferences(formula string, dRow, dCol int) (string, error) {
+	return rewriteRefs(formula, func(ref Ref) (Ref, bool) {
+		return ref.Shift(dRow, dCol)
+	})
+}
+
+// RenameSheetInFormula returns formula with references to the sheet
+// oldName, including sheet qualified defined names, changed to refer
+// to newName.  Sheet names are compared without regard to case, and
+// newName is quoted if necessary.  References to sheets of external
+// workbooks are left alone.
+func RenameSheetInFormula(formula, oldName, newName string) (string, error) {
+	return rewrite(formula, nil, func(t Token) string {
+		switch t.Kind {
+		case TokenRef:
+			if t.Ref.Book == 0 && t.Ref.Sheet != "" && strings.EqualFold(t.Ref.Sheet, oldName) {
+				ref := t.Ref
+				ref.Sheet = newName
+				return ref.String()
+			}
+		case TokenName, TokenError:
+			sheet, book, end, ok := scanSheet(t.Text, 0)
+			if ok && book == 0 && strings.EqualFold(sheet, oldName) {
+				return QuoteSheetName(newName) + "!" + t.Text[end:]
+			}
+		}
+		return t.Text
+	})
+}
+
+// QualifyTableRefs returns formula with its structured references
+// written as they are stored in a file: those without a table name,
+// as in "[@Amount]", are qualified with table, and the "@" shorthand
+// for the current row is spelt out as "[#This Row]".
+func QualifyTableRefs(formula, table string) (string, error) {
+	return rewrite(formula, nil, func(t Token) string {
+		if t.Kind != TokenTableRef {
+			return t.Text
+		}
+		ref := t.TableRef
+		if ref.Table == "" {
+			ref.Table = table
+		}
+		return ref.String()
+	})
+}
+
+// AdjustForInsertedRows returns formula with its references updated
+// for n rows having been inserted on the sheet named sheet, before
+// the zero based row at.  A negative n means that -n rows, starting
+// at row at, were removed; references to removed cells become #REF!
+// and areas that lose some of their rows shrink.  Both relative and
+// absolute references are adjusted.  References without a sheet name
+// are taken to be on sheet; use AdjustForInsertedRowsFrom for a
+// formula that lives on another sheet.
+func AdjustForInsertedRows(formula, sheet string, at, n int) (string, error) {
+	return AdjustForInsertedRowsFrom(formula, sheet, sheet, at, n)
+}
+
+// AdjustForInsertedRowsFrom works like AdjustForInsertedRows for a
+// formula that lives on the sheet named home, so that only references
+// qualified with the name of sheet are adjusted when home is a
+// different sheet.
+func AdjustForInsertedRowsFrom(formula, home, sheet string, at, n int) (string, error) {
+	return rewriteRefs(formula, func(ref Ref) (Ref, bool) {
+		if !onSheet(ref, home, sheet) {
+			return ref, true
+		}
+		return ref.InsertRows(at, n)
+	})
+}
+
+// AdjustForInsertedCols returns formula with its references updated
+// for n columns having been inserted on the sheet named sheet, before
+// the zero based column at.  It works in the same way as
+// AdjustForInsertedRows.
+func AdjustForInsertedCols(formula, sheet string, at, n int) (string, error) {
+	return AdjustForInsertedColsFrom(formula, sheet, sheet, at, n)
+}
+
+// AdjustForInsertedColsFrom works like AdjustForInsertedCols for a
+// formula that lives on the sheet named home.
+func AdjustForInsertedColsFrom(formula, home, sheet string, at, n int) (string, error) {
+	return rewriteRefs(formula, func(ref Ref) (Ref, bool) {
+		if !onSheet(ref, home, sheet) {
+			return ref, true
+		}
+		return ref.InsertCols(at, n)
+	})
+}
+
+// onSheet reports whether ref, in a formula on the sheet home, refers
+// to the sheet named sheet.  A reference to a sheet of an external
+// workbook never does.
+func onSheet(ref Ref, home, sheet string) bool {
+	if ref.Book != 0 {
+		return false
+	}
+	if ref.Sheet == "" {
+		return strings.EqualFold(home, sheet)
+	}
+	return strings.EqualFold(ref.Sheet, sheet)
+}
+
+// R1C1ToA1 converts a formula in R1C1 notation, such as "SUM(R[-2]C:R[-1]C)",
+// to A1 notation.  Relative references are resolved against the cell
+// at the zero based row and col.  A reference that resolves to a
+// position off the sheet becomes #REF!.
+func R1C1ToA1(formula string, row, col int) (string, error) {
+	return rewrite(formula, &cellOrigin{row: row, col: col}, func(t Token) string {
+		if t.Kind != TokenRef {
+			return t.Text
+		}
+		if !t.Ref.inBounds() {
+			return refError(t.Ref)
+		}
+		return t.Ref.String()
+	})
+}
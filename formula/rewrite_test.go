@@ -0,0 +1,137 @@
+package formula
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestShiftReferences(t *testing.T) {
+	c := qt.New(t)
+	cases := []struct {
+		formula  string
+		expected string
+	}{
+		{"A1", "B2"},
+		{"=A1", "=B2"},
+		{"$A1+A$1+$A$1", "$A2+B$1+$A$1"},
+		{"IF(C23>=E$12,\"Q\",\"A1\")", "IF(D24>=F$12,\"Q\",\"A1\")"},
+		{"SUM(D44:H44)*IM_A_DEFINED_NAME", "SUM(E45:I45)*IM_A_DEFINED_NAME"},
+		{"AA1", "AB2"},
+		{"Sheet2!A1 + 'My Sheet'!$B3", "Sheet2!B2 + 'My Sheet'!$B4"},
+		{"SUM(A:A,1:1)", "SUM(B:B,2:2)"},
+		{"ABC1x+R1C1+LOG10(A1)", "ABC1x+R1C1+LOG10(B2)"},
+		{"Sheet1!Total*Sheet1!#REF!", "Sheet1!Total*Sheet1!#REF!"},
+		// Copying a formula moves references to other workbooks too.
+		{"[1]Sheet1!A5+'[1]My Sheet'!A5", "[1]Sheet1!B6+'[1]My Sheet'!B6"},
+	}
+	for _, tc := range cases {
+		shifted, err := ShiftReferences(tc.formula, 1, 1)
+		c.Assert(err, qt.IsNil)
+		c.Assert(shifted, qt.Equals, tc.expected, qt.Commentf(tc.formula))
+	}
+
+	c.Run("OffTheSheet", func(c *qt.C) {
+		shifted, err := ShiftReferences("A2+Sheet2!A1+$A$1", -1, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(shifted, qt.Equals, "A1+Sheet2!#REF!+$A$1")
+	})
+
+	c.Run("InvalidFormula", func(c *qt.C) {
+		_, err := ShiftReferences(`"A1`, 1, 1)
+		c.Assert(err, qt.ErrorMatches, `invalid formula .*: unterminated string at position 0`)
+	})
+}
+
+func TestRenameSheetInFormula(t *testing.T) {
+	c := qt.New(t)
+	cases := []struct {
+		formula, newName, expected string
+	}{
+		{"Data!A1+data!$B$2", "Figures", "Figures!A1+Figures!$B$2"},
+		{"SUM(Data!A:A)*A1", "Q1 Figures", "SUM('Q1 Figures'!A:A)*A1"},
+		{"Data!Total+Other!A1", "New", "New!Total+Other!A1"},
+		{"Data!#REF!", "New", "New!#REF!"},
+		{`"Data!A1"&Database!A1`, "New", `"Data!A1"&Database!A1`},
+	}
+	for _, tc := range cases {
+		renamed, err := RenameSheetInFormula(tc.formula, "Data", tc.newName)
+		c.Assert(err, qt.IsNil)
+		c.Assert(renamed, qt.Equals, tc.expected, qt.Commentf(tc.formula))
+	}
+
+	renamed, err := RenameSheetInFormula("'My Data'!A1", "My Data", "Data")
+	c.Assert(err, qt.IsNil)
+	c.Assert(renamed, qt.Equals, "Data!A1")
+
+	// Sheets of other workbooks aren't the sheet being renamed.
+	renamed, err = RenameSheetInFormula("[1]Sheet1!A5+[1]Sheet1!Total+Sheet1!A5", "Sheet1", "Renamed")
+	c.Assert(err, qt.IsNil)
+	c.Assert(renamed, qt.Equals, "[1]Sheet1!A5+[1]Sheet1!Total+Renamed!A5")
+	renamed, err = RenameSheetInFormula("'[1]My Sheet'!A5+'My Sheet'!A5", "My Sheet", "Renamed")
+	c.Assert(err, qt.IsNil)
+	c.Assert(renamed, qt.Equals, "'[1]My Sheet'!A5+Renamed!A5")
+}
+
+func TestAdjustForInsertedRows(t *testing.T) {
+	c := qt.New(t)
+
+	adjusted, err := AdjustForInsertedRows("SUM(A1:A10)+$B$5+Sheet1!C5+Other!C5", "Sheet1", 2, 3)
+	c.Assert(err, qt.IsNil)
+	c.Assert(adjusted, qt.Equals, "SUM(A1:A13)+$B$8+Sheet1!C8+Other!C5")
+
+	adjusted, err = AdjustForInsertedRows("A2+A3+SUM(A1:A10)", "Sheet1", 2, -1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(adjusted, qt.Equals, "A2+#REF!+SUM(A1:A9)")
+
+	// The formula is on Other, so only Sheet1! references move
+	adjusted, err = AdjustForInsertedRowsFrom("A5+Sheet1!A5", "Other", "Sheet1", 0, 1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(adjusted, qt.Equals, "A5+Sheet1!A6")
+
+	// References to other workbooks stay as they are.
+	adjusted, err = AdjustForInsertedRowsFrom("[1]Sheet1!A5", "Sheet1", "Sheet1", 0, 1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(adjusted, qt.Equals, "[1]Sheet1!A5")
+	adjusted, err = AdjustForInsertedRows("'[1]My Sheet'!A5+'My Sheet'!A5", "My Sheet", 0, -1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(adjusted, qt.Equals, "'[1]My Sheet'!A5+'My Sheet'!A4")
+}
+
+func TestAdjustForInsertedCols(t *testing.T) {
+	c := qt.New(t)
+
+	adjusted, err := AdjustForInsertedCols("SUM(A1:D1)+$C$1+C:C+1:1", "Sheet1", 2, 2)
+	c.Assert(err, qt.IsNil)
+	c.Assert(adjusted, qt.Equals, "SUM(A1:F1)+$E$1+E:E+1:1")
+
+	adjusted, err = AdjustForInsertedColsFrom("B1+Sheet1!B1+sheet1!D1", "Other", "Sheet1", 1, -1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(adjusted, qt.Equals, "B1+Sheet1!#REF!+sheet1!C1")
+
+	adjusted, err = AdjustForInsertedCols("[1]Sheet1!B1+'[1]My Sheet'!B1", "Sheet1", 0, 1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(adjusted, qt.Equals, "[1]Sheet1!B1+'[1]My Sheet'!B1")
+}
+
+func TestR1C1ToA1(t *testing.T) {
+	c := qt.New(t)
+	cases := []struct {
+		formula  string
+		expected string
+	}{
+		{"R1C1", "$A$1"},
+		{"RC", "C3"},
+		{"=SUM(R[-2]C:R[-1]C)", "=SUM(C1:C2)"},
+		{"R[1]C[-1]*rc2", "B4*$B3"},
+		{"SUM(R1:R3)+C[1]:C[2]", "SUM($1:$3)+D:E"},
+		{"Sheet2!R2C[1]&\"R1C1\"", "Sheet2!D$2&\"R1C1\""},
+		{"R[-5]C", "#REF!"},
+		{"Rate*2", "Rate*2"},
+	}
+	for _, tc := range cases {
+		a1, err := R1C1ToA1(tc.formula, 2, 2)
+		c.Assert(err, qt.IsNil)
+		c.Assert(a1, qt.Equals, tc.expected, qt.Commentf(tc.formula))
+	}
+}
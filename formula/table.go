@@ -0,0 +1,235 @@
+package formula
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The special items that a structured reference may be restricted to.
+const (
+	TableItemAll     = "#All"
+	TableItemData    = "#Data"
+	TableItemHeaders = "#Headers"
+	TableItemTotals  = "#Totals"
+	TableItemThisRow = "#This Row"
+)
+
+var tableItems = []string{TableItemAll, TableItemData, TableItemHeaders, TableItemTotals, TableItemThisRow}
+
+// TableRef is a structured reference to part of a table, such as
+// "Table1[Amount]", "Table1[[#This Row],[Amount]]" or, within the
+// table itself, "[@Amount]".
+type TableRef struct {
+	// Table is the name of the table.  It is empty in a reference
+	// written within the table, such as "[@Amount]".
+	Table string
+	// Items are the special items, such as TableItemTotals, that the
+	// reference is restricted to.  A reference without items is to
+	// the data rows of the table.
+	Items []string
+	// Col1 and Col2 are the names of the first and last columns
+	// referred to.  Col2 is empty for a reference to a single
+	// column, and both are empty for one to every column.
+	Col1, Col2 string
+}
+
+// ParseTableRef parses a single structured reference, such as
+// "Table1[Amount]" or "[@[Unit Price]]".
+func ParseTableRef(s string) (TableRef, error) {
+	if end, ok := scanTableRef(s, 0); ok && end == len(s) {
+		if ref, ok := parseTableRef(s); ok {
+			return ref, nil
+		}
+	}
+	return TableRef{}, fmt.Errorf("invalid structured reference %q", s)
+}
+
+// HasItem reports whether r is restricted to the special item.
+func (r TableRef) HasItem(item string) bool {
+	for _, it := range r.Items {
+		if it == item {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns r as it is written in a file, which never uses the
+// "@" shorthand for the current row.
+func (r TableRef) String() string {
+	var parts []string
+	for _, item := range r.Items {
+		parts = append(parts, "["+item+"]")
+	}
+	if r.Col1 != "" {
+		if len(parts) == 0 && r.Col2 == "" && isSimpleColumnName(r.Col1) {
+			return r.Table + "[" + escapeColumnName(r.Col1) + "]"
+		}
+		col := "[" + escapeColumnName(r.Col1) + "]"
+		if r.Col2 != "" {
+			col += ":[" + escapeColumnName(r.Col2) + "]"
+		}
+		parts = append(parts, col)
+	}
+	switch {
+	case len(parts) == 0:
+		return r.Table + "[]"
+	case len(parts) == 1 && r.Col1 == "":
+		return r.Table + parts[0]
+	}
+	return r.Table + "[" + strings.Join(parts, ",") + "]"
+}
+
+// columnNameSpecials are the characters that have to be escaped with
+// a single quote in a column name.
+const columnNameSpecials = "[]#'"
+
+// isSimpleColumnName reports whether name can be written without
+// the second pair of brackets, as in Table1[Amount].
+func isSimpleColumnName(name string) bool {
+	return !strings.ContainsAny(name, "\t\n\r,:.[]#'\"{}$^&*+=-<>/") && !strings.HasPrefix(name, "@")
+}
+
+// escapeColumnName escapes the special characters in a column name.
+func escapeColumnName(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if strings.IndexByte(columnNameSpecials, name[i]) >= 0 {
+			b.WriteByte('\'')
+		}
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
+
+// unescapeColumnName removes the escapes from a column name.
+func unescapeColumnName(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\'' && i+1 < len(name) {
+			i++
+		}
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
+
+// scanTableRef returns the end of the structured reference starting
+// at position i of s, which is either a table name or the "[" of a
+// reference without one.  It returns false if there is no structured
+// reference there, or if its brackets aren't closed.
+func scanTableRef(s string, i int) (int, bool) {
+	j := i
+	if isIdentStart(s, j) {
+		j = scanIdent(s, j)
+	}
+	if j >= len(s) || s[j] != '[' {
+		return i, false
+	}
+	depth := 0
+	for ; j < len(s); j++ {
+		switch s[j] {
+		case '\'':
+			j++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return j + 1, true
+			}
+		}
+	}
+	return i, false
+}
+
+// parseTableRef parses the structured reference s, whose brackets
+// have been checked by scanTableRef.
+func parseTableRef(s string) (TableRef, bool) {
+	var ref TableRef
+	open := strings.IndexByte(s, '[')
+	ref.Table = s[:open]
+	inner := s[open+1 : len(s)-1]
+	if strings.HasPrefix(inner, "@") {
+		ref.Items = []string{TableItemThisRow}
+		inner = strings.TrimLeft(inner[1:], " ")
+		if !strings.HasPrefix(inner, "[") {
+			ref.Col1 = unescapeColumnName(inner)
+			return ref, true
+		}
+	} else if !strings.HasPrefix(strings.TrimLeft(inner, " "), "[") {
+		if strings.HasPrefix(inner, "#") {
+			item, ok := tableItem(inner)
+			if !ok {
+				return TableRef{}, false
+			}
+			ref.Items = []string{item}
+			return ref, true
+		}
+		ref.Col1 = unescapeColumnName(inner)
+		return ref, true
+	}
+
+	// A list of bracketed items and columns, with a colon between
+	// the first and last columns of a range.
+	var cols []string
+	separator := byte(0)
+	parts := 0
+	for i := 0; i < len(inner); {
+		switch inner[i] {
+		case ' ':
+			i++
+			continue
+		case ',', ':':
+			if separator != 0 || parts == 0 {
+				return TableRef{}, false
+			}
+			separator = inner[i]
+			i++
+			continue
+		case '[':
+		default:
+			return TableRef{}, false
+		}
+		end, ok := scanTableRef(inner, i)
+		if !ok || (parts > 0 && separator == 0) {
+			return TableRef{}, false
+		}
+		part := inner[i+1 : end-1]
+		if strings.HasPrefix(part, "#") {
+			item, ok := tableItem(part)
+			if !ok || separator == ':' || len(cols) > 0 {
+				return TableRef{}, false
+			}
+			ref.Items = append(ref.Items, item)
+		} else {
+			if (len(cols) > 0) != (separator == ':') {
+				return TableRef{}, false
+			}
+			cols = append(cols, unescapeColumnName(part))
+		}
+		separator = 0
+		parts++
+		i = end
+	}
+	if separator != 0 || len(cols) > 2 {
+		return TableRef{}, false
+	}
+	if len(cols) > 0 {
+		ref.Col1 = cols[0]
+	}
+	if len(cols) > 1 {
+		ref.Col2 = cols[1]
+	}
+	return ref, true
+}
+
+// tableItem returns the canonical spelling of a special item.
+func tableItem(s string) (string, bool) {
+	for _, item := range tableItems {
+		if strings.EqualFold(s, item) {
+			return item, true
+		}
+	}
+	return "", false
+}
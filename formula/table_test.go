@@ -0,0 +1,49 @@
+package formula
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestParseTableRef(t *testing.T) {
+	c := qt.New(t)
+	cases := []struct {
+		ref      string
+		expected TableRef
+		str      string
+	}{
+		{"Table1[Amount]", TableRef{Table: "Table1", Col1: "Amount"}, "Table1[Amount]"},
+		{"Table1[]", TableRef{Table: "Table1"}, "Table1[]"},
+		{"Table1[#totals]", TableRef{Table: "Table1", Items: []string{TableItemTotals}}, "Table1[#Totals]"},
+		{"[@Amount]", TableRef{Items: []string{TableItemThisRow}, Col1: "Amount"}, "[[#This Row],[Amount]]"},
+		{"[@[Unit Price]]", TableRef{Items: []string{TableItemThisRow}, Col1: "Unit Price"}, "[[#This Row],[Unit Price]]"},
+		{"Sales[[#This Row],[Amount]]", TableRef{Table: "Sales", Items: []string{TableItemThisRow}, Col1: "Amount"}, "Sales[[#This Row],[Amount]]"},
+		{"Sales[[#Headers], [#Data], [Jan]:[Mar]]", TableRef{Table: "Sales", Items: []string{TableItemHeaders, TableItemData}, Col1: "Jan", Col2: "Mar"}, "Sales[[#Headers],[#Data],[Jan]:[Mar]]"},
+		{"Sales[[Total $]]", TableRef{Table: "Sales", Col1: "Total $"}, "Sales[[Total $]]"},
+		{"Sales[Item '#]", TableRef{Table: "Sales", Col1: "Item #"}, "Sales[[Item '#]]"},
+	}
+	for _, tc := range cases {
+		ref, err := ParseTableRef(tc.ref)
+		c.Assert(err, qt.IsNil, qt.Commentf(tc.ref))
+		c.Assert(ref, qt.DeepEquals, tc.expected, qt.Commentf(tc.ref))
+		c.Assert(ref.String(), qt.Equals, tc.str)
+	}
+
+	for _, s := range []string{"Table1", "Table1[Amount", "Table1[#Everything]", "Table1[[A]:[#Data]]", "Table1[[A][B]]", "Table1[[A]:[B]:[C]]", "Table1[Amount]x"} {
+		_, err := ParseTableRef(s)
+		c.Assert(err, qt.ErrorMatches, `invalid structured reference ".*"`, qt.Commentf(s))
+	}
+}
+
+func TestQualifyTableRefs(t *testing.T) {
+	c := qt.New(t)
+	qualified, err := QualifyTableRefs(`[@Qty]*Sales[@Price]+SUM(Other[Total])+A1`, "Sales")
+	c.Assert(err, qt.IsNil)
+	c.Assert(qualified, qt.Equals, `Sales[[#This Row],[Qty]]*Sales[[#This Row],[Price]]+SUM(Other[Total])+A1`)
+
+	_, err = QualifyTableRefs(`SUM(Sales[Qty)`, "Sales")
+	c.Assert(err, qt.ErrorMatches, `invalid formula .*: unterminated structured reference at position 4`)
+	_, err = QualifyTableRefs(`SUM([[#Nothing]])`, "Sales")
+	c.Assert(err, qt.ErrorMatches, `invalid formula .*: invalid structured reference at position 4`)
+}
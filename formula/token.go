@@ -0,0 +1,548 @@
+// Package formula provides a tokenizer, parser and reference
+// rewriting tools for Excel formulas, as stored in XLSX files.
+//
+// Formulas are written in A1 notation, without a leading "=", though
+// one is accepted and ignored.  For example:
+//
+//	expr, err := formula.Parse("SUM(Sheet2!A1:A10)*$B$1")
+//
+//	shifted, err := formula.ShiftReferences("A1+$B$1", 1, 0)
+//	// shifted == "A2+$B$1"
+package formula
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// The extent of a worksheet, as defined by Excel.
+const (
+	MaxCols = 16384
+	MaxRows = 1048576
+)
+
+// Errors lists the error literals that may appear in a formula.
+var Errors = []string{
+	"#NULL!", "#DIV/0!", "#VALUE!", "#REF!", "#NAME?", "#NUM!", "#N/A",
+	"#GETTING_DATA", "#SPILL!", "#CALC!",
+}
+
+// TokenKind identifies the kind of a Token.
+type TokenKind int
+
+const (
+	TokenSpace TokenKind = iota
+	TokenNumber
+	TokenString
+	TokenBool
+	TokenError
+	TokenRef
+	TokenTableRef
+	TokenName
+	TokenFunc
+	TokenOperator
+	TokenOpenParen
+	TokenCloseParen
+	TokenOpenBrace
+	TokenCloseBrace
+	TokenComma
+	TokenSemicolon
+)
+
+// Token is a single lexical element of a formula.  Text holds the
+// exact source text of the Token, so that concatenating the Text of
+// every Token reproduces the original formula.  For a TokenFunc the
+// Text includes the opening parenthesis.
+type Token struct {
+	Kind TokenKind
+	Text string
+	Pos  int
+	// Ref holds the parsed reference for a TokenRef.
+	Ref Ref
+	// TableRef holds the parsed structured reference for a
+	// TokenTableRef.
+	TableRef TableRef
+}
+
+// Tokenize splits a formula, in A1 notation, into Tokens.  A leading
+// "=" is ignored.
+func Tokenize(formula string) ([]Token, error) {
+	return tokenize(formula, nil)
+}
+
+// tokenize splits a formula into Tokens.  When origin is not nil the
+// formula is in R1C1 notation, and relative references are resolved
+// against origin.
+func tokenize(formula string, origin *cellOrigin) ([]Token, error) {
+	var tokens []Token
+	s := formula
+	i := 0
+	if strings.HasPrefix(s, "=") {
+		i = 1
+	}
+	emit := func(kind TokenKind, start, end int) {
+		tokens = append(tokens, Token{Kind: kind, Text: s[start:end], Pos: start})
+	}
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			start := i
+			for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n' || s[i] == '\r') {
+				i++
+			}
+			emit(TokenSpace, start, i)
+		case c == '"':
+			start := i
+			i++
+			for {
+				if i >= len(s) {
+					return nil, fmt.Errorf("invalid formula %q: unterminated string at position %d", formula, start)
+				}
+				if s[i] == '"' {
+					if i+1 < len(s) && s[i+1] == '"' {
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				i++
+			}
+			emit(TokenString, start, i)
+		case c == '#':
+			end, ok := scanError(s, i)
+			if !ok {
+				return nil, fmt.Errorf("invalid formula %q: unknown error literal at position %d", formula, i)
+			}
+			emit(TokenError, i, end)
+			i = end
+		case c == '\'' || c == '$' || isIdentStart(s, i) || isDigit(c) || c == '.' || hasBook(s, i):
+			if ref, end, ok := scanRef(s, i, origin); ok {
+				tokens = append(tokens, Token{Kind: TokenRef, Text: s[i:end], Pos: i, Ref: ref})
+				i = end
+				continue
+			}
+			if end, ok := scanSheetError(s, i); ok {
+				// A reference that has been deleted, e.g. Sheet1!#REF!
+				emit(TokenError, i, end)
+				i = end
+				continue
+			}
+			if _, _, end, ok := scanSheet(s, i); ok {
+				// A sheet qualified defined name, e.g. 'My Sheet'!Total
+				if !isIdentStart(s, end) {
+					return nil, fmt.Errorf("invalid formula %q: invalid reference at position %d", formula, i)
+				}
+				end = scanIdent(s, end)
+				emit(TokenName, i, end)
+				i = end
+				continue
+			}
+			if c == '\'' {
+				return nil, fmt.Errorf("invalid formula %q: invalid sheet reference at position %d", formula, i)
+			}
+			if isDigit(c) || c == '.' {
+				end, ok := scanNumber(s, i)
+				if !ok {
+					return nil, fmt.Errorf("invalid formula %q: invalid number at position %d", formula, i)
+				}
+				emit(TokenNumber, i, end)
+				i = end
+				continue
+			}
+			if c == '$' {
+				return nil, fmt.Errorf("invalid formula %q: invalid reference at position %d", formula, i)
+			}
+			start := i
+			i = scanIdent(s, i)
+			if i < len(s) && s[i] == '[' {
+				// A structured reference, e.g. Table1[Amount]
+				t, err := tableRefToken(formula, start)
+				if err != nil {
+					return nil, err
+				}
+				tokens = append(tokens, t)
+				i = start + len(t.Text)
+				continue
+			}
+			if i < len(s) && s[i] == '!' {
+				return nil, fmt.Errorf("invalid formula %q: invalid reference at position %d", formula, start)
+			}
+			if i < len(s) && s[i] == '(' {
+				i++
+				emit(TokenFunc, start, i)
+				continue
+			}
+			switch strings.ToUpper(s[start:i]) {
+			case "TRUE", "FALSE":
+				emit(TokenBool, start, i)
+			default:
+				emit(TokenName, start, i)
+			}
+		case c == '[':
+			// A structured reference within a table, e.g. [@Amount]
+			t, err := tableRefToken(formula, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, t)
+			i += len(t.Text)
+		case c == '<' || c == '>':
+			start := i
+			i++
+			if i < len(s) && (s[i] == '=' || (c == '<' && s[i] == '>')) {
+				i++
+			}
+			emit(TokenOperator, start, i)
+		case strings.IndexByte("+-*/^&=%:", c) >= 0:
+			i++
+			emit(TokenOperator, i-1, i)
+		case c == '(':
+			i++
+			emit(TokenOpenParen, i-1, i)
+		case c == ')':
+			i++
+			emit(TokenCloseParen, i-1, i)
+		case c == '{':
+			i++
+			emit(TokenOpenBrace, i-1, i)
+		case c == '}':
+			i++
+			emit(TokenCloseBrace, i-1, i)
+		case c == ',':
+			i++
+			emit(TokenComma, i-1, i)
+		case c == ';':
+			i++
+			emit(TokenSemicolon, i-1, i)
+		default:
+			return nil, fmt.Errorf("invalid formula %q: unexpected character %q at position %d", formula, c, i)
+		}
+	}
+	return tokens, nil
+}
+
+// tableRefToken returns the TokenTableRef for the structured
+// reference starting at position i of formula.
+func tableRefToken(formula string, i int) (Token, error) {
+	end, ok := scanTableRef(formula, i)
+	if !ok {
+		return Token{}, fmt.Errorf("invalid formula %q: unterminated structured reference at position %d", formula, i)
+	}
+	ref, ok := parseTableRef(formula[i:end])
+	if !ok {
+		return Token{}, fmt.Errorf("invalid formula %q: invalid structured reference at position %d", formula, i)
+	}
+	return Token{Kind: TokenTableRef, Text: formula[i:end], Pos: i, TableRef: ref}, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+// isIdentStart reports whether position i of s starts a function or
+// defined name.
+func isIdentStart(s string, i int) bool {
+	if i >= len(s) {
+		return false
+	}
+	c := s[i]
+	if isLetter(c) || c == '_' || c == '\\' {
+		return true
+	}
+	if c >= 0x80 {
+		for _, r := range s[i:] {
+			return unicode.IsLetter(r)
+		}
+	}
+	return false
+}
+
+// scanIdent returns the end of the function or defined name starting
+// at position i of s.
+func scanIdent(s string, i int) int {
+	for i < len(s) {
+		c := s[i]
+		if isLetter(c) || isDigit(c) || c == '_' || c == '.' || c == '\\' {
+			i++
+			continue
+		}
+		if c >= 0x80 {
+			r := []rune(s[i:])[0]
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				i += len(string(r))
+				continue
+			}
+		}
+		break
+	}
+	return i
+}
+
+// scanNumber returns the end of the number literal starting at
+// position i of s.
+func scanNumber(s string, i int) (int, bool) {
+	start := i
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+	}
+	if i == start || s[start:i] == "." {
+		return i, false
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			j++
+		}
+		if j < len(s) && isDigit(s[j]) {
+			for j < len(s) && isDigit(s[j]) {
+				j++
+			}
+			i = j
+		}
+	}
+	return i, true
+}
+
+// scanError returns the end of the error literal starting at position
+// i of s.
+func scanError(s string, i int) (int, bool) {
+	upper := strings.ToUpper(s[i:])
+	for _, e := range Errors {
+		if strings.HasPrefix(upper, e) {
+			return i + len(e), true
+		}
+	}
+	return i, false
+}
+
+// scanSheet scans an optional sheet prefix, such as "Sheet1!",
+// "'My Sheet'!" or, for a sheet of an external workbook, "[1]Sheet1!"
+// and "'[1]My Sheet'!", starting at position i of s.  It returns the
+// unquoted sheet name, the index of the external workbook, or 0, and
+// the position after the "!".
+func scanSheet(s string, i int) (sheet string, book int, end int, ok bool) {
+	j := i
+	if s[j] == '\'' {
+		var b strings.Builder
+		book, j = scanBook(s, j+1)
+		for {
+			if j >= len(s) {
+				return "", 0, i, false
+			}
+			if s[j] == '\'' {
+				if j+1 < len(s) && s[j+1] == '\'' {
+					b.WriteByte('\'')
+					j += 2
+					continue
+				}
+				j++
+				break
+			}
+			b.WriteByte(s[j])
+			j++
+		}
+		if j >= len(s) || s[j] != '!' {
+			return "", 0, i, false
+		}
+		return b.String(), book, j + 1, true
+	}
+	book, j = scanBook(s, j)
+	if isIdentStart(s, j) {
+		k := scanIdent(s, j)
+		if k < len(s) && s[k] == '!' {
+			return s[j:k], book, k + 1, true
+		}
+	}
+	return "", 0, i, false
+}
+
+// scanBook scans the index of an external workbook, such as "[1]",
+// starting at position i of s.  It returns 0 and i if there is none.
+func scanBook(s string, i int) (book int, end int) {
+	if i >= len(s) || s[i] != '[' {
+		return 0, i
+	}
+	j := i + 1
+	for j < len(s) && isDigit(s[j]) {
+		j++
+	}
+	if j >= len(s) || s[j] != ']' {
+		return 0, i
+	}
+	n, ok := atoi(s[i+1 : j])
+	if !ok || n == 0 {
+		return 0, i
+	}
+	return n, j + 1
+}
+
+// hasBook reports whether position i of s starts a sheet prefix of an
+// external workbook, such as "[1]Sheet1!".
+func hasBook(s string, i int) bool {
+	_, end := scanBook(s, i)
+	return end > i
+}
+
+// scanSheetError scans a sheet qualified error literal, such as
+// "Sheet1!#REF!", starting at position i of s.
+func scanSheetError(s string, i int) (int, bool) {
+	_, _, j, ok := scanSheet(s, i)
+	if !ok || j >= len(s) || s[j] != '#' {
+		return i, false
+	}
+	return scanError(s, j)
+}
+
+type refPartKind int
+
+const (
+	refPartNone refPartKind = iota
+	refPartCell
+	refPartCol
+	refPartRow
+)
+
+// refPart is one side of a reference: a cell, a column or a row.
+type refPart struct {
+	kind           refPartKind
+	col, row       int
+	absCol, absRow bool
+}
+
+// scanRefPart scans a single cell ("$A$1"), column ("$A") or row
+// ("$1") starting at position i of s.
+func scanRefPart(s string, i int) (refPart, int) {
+	var p refPart
+	j := i
+	if j < len(s) && s[j] == '$' {
+		p.absCol = true
+		j++
+	}
+	letters := j
+	for j < len(s) && isLetter(s[j]) {
+		j++
+	}
+	nLetters := j - letters
+	if nLetters > 3 {
+		return refPart{}, i
+	}
+	if nLetters > 0 {
+		p.col = ColumnIndex(s[letters:j])
+		if p.col >= MaxCols {
+			return refPart{}, i
+		}
+	}
+	k := j
+	if k < len(s) && s[k] == '$' {
+		p.absRow = true
+		k++
+	}
+	digits := k
+	for k < len(s) && isDigit(s[k]) {
+		k++
+	}
+	if k > digits {
+		n, ok := atoi(s[digits:k])
+		if !ok || n < 1 || n > MaxRows {
+			return refPart{}, i
+		}
+		p.row = n - 1
+		if nLetters == 0 {
+			// A whole row, the leading $ belongs to the row
+			return refPart{kind: refPartRow, col: -1, row: p.row, absRow: p.absCol || p.absRow}, k
+		}
+		p.kind = refPartCell
+		return p, k
+	}
+	if nLetters == 0 || p.absRow {
+		return refPart{}, i
+	}
+	return refPart{kind: refPartCol, col: p.col, row: -1, absCol: p.absCol}, j
+}
+
+// atoi converts a short run of digits to an int.
+func atoi(s string) (int, bool) {
+	if len(s) > 9 {
+		return 0, false
+	}
+	n := 0
+	for i := 0; i < len(s); i++ {
+		n = n*10 + int(s[i]-'0')
+	}
+	return n, true
+}
+
+// scanRef attempts to scan a reference, with an optional sheet
+// prefix, starting at position i of s.
+func scanRef(s string, i int, origin *cellOrigin) (ref Ref, end int, ok bool) {
+	j := i
+	if sheet, book, k, ok := scanSheet(s, i); ok {
+		ref.Sheet, ref.Book = sheet, book
+		j = k
+	}
+	scan := scanRefPart
+	if origin != nil {
+		scan = func(s string, i int) (refPart, int) {
+			return scanR1C1Part(s, i, *origin)
+		}
+	}
+	p1, k := scan(s, j)
+	if p1.kind == refPartNone {
+		return ref, i, false
+	}
+	p2 := p1
+	if k < len(s) && s[k] == ':' {
+		if p, k2 := scan(s, k+1); p.kind == p1.kind {
+			p2 = p
+			ref.IsArea = true
+			k = k2
+		}
+	}
+	if p1.kind != refPartCell && !ref.IsArea {
+		return ref, i, false
+	}
+	if k < len(s) && (isLetter(s[k]) || isDigit(s[k]) || s[k] == '_' || s[k] == '.' || s[k] == '(' || s[k] == '!' || s[k] == '[' || s[k] >= 0x80) {
+		// Something like ABC1x, LOG10( or a sheet name
+		return ref, i, false
+	}
+	ref.Col1, ref.Row1, ref.AbsCol1, ref.AbsRow1 = p1.col, p1.row, p1.absCol, p1.absRow
+	ref.Col2, ref.Row2, ref.AbsCol2, ref.AbsRow2 = p2.col, p2.row, p2.absCol, p2.absRow
+	return ref.normalise(), k, true
+}
+
+// ColumnIndex converts column letters, such as "AB", to a zero based
+// column index.
+func ColumnIndex(letters string) int {
+	n := 0
+	for i := 0; i < len(letters); i++ {
+		c := letters[i]
+		if c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		n = n*26 + int(c-'A') + 1
+	}
+	return n - 1
+}
+
+// ColumnLetters converts a zero based column index to its letters,
+// such as "AB".
+func ColumnLetters(n int) string {
+	var b []byte
+	for n++; n > 0; n = (n - 1) / 26 {
+		b = append([]byte{byte('A' + (n-1)%26)}, b...)
+	}
+	return string(b)
+}
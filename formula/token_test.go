@@ -0,0 +1,104 @@
+package formula
+
+import (
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestTokenize(t *testing.T) {
+	c := qt.New(t)
+
+	c.Run("TokensReproduceTheFormula", func(c *qt.C) {
+		formulas := []string{
+			`SUM(A1:B2, 3) * 2`,
+			`'My Sheet'!$A$1&"a ""quoted"" string"`,
+			`IF(A1>=10,"big",IFERROR(1/0,#DIV/0!))`,
+			`{1,2;3,4}`,
+			`Sheet2!A:A+Sheet2!1:3`,
+			`-2^2%`,
+			`SUM(Sales[Amount])+[@[Unit Price]]*Sales[[#This Row], [Qty]]`,
+			`[1]Sheet1!A5+'[1]My Sheet'!A5`,
+		}
+		for _, f := range formulas {
+			tokens, err := Tokenize(f)
+			c.Assert(err, qt.IsNil)
+			var b strings.Builder
+			for _, t := range tokens {
+				b.WriteString(t.Text)
+			}
+			c.Assert(b.String(), qt.Equals, f)
+		}
+	})
+
+	c.Run("References", func(c *qt.C) {
+		cases := []struct {
+			formula string
+			ref     Ref
+		}{
+			{"A1", Ref{Col1: 0, Row1: 0, Col2: 0, Row2: 0}},
+			{"$B$3", Ref{Col1: 1, Row1: 2, Col2: 1, Row2: 2, AbsCol1: true, AbsRow1: true, AbsCol2: true, AbsRow2: true}},
+			{"B$3", Ref{Col1: 1, Row1: 2, Col2: 1, Row2: 2, AbsRow1: true, AbsRow2: true}},
+			{"A1:C4", Ref{Col1: 0, Row1: 0, Col2: 2, Row2: 3, IsArea: true}},
+			{"C4:A1", Ref{Col1: 0, Row1: 0, Col2: 2, Row2: 3, IsArea: true}},
+			{"A:C", Ref{Col1: 0, Row1: -1, Col2: 2, Row2: -1, IsArea: true}},
+			{"$2:$5", Ref{Col1: -1, Row1: 1, Col2: -1, Row2: 4, AbsRow1: true, AbsRow2: true, IsArea: true}},
+			{"Sheet2!XFD1048576", Ref{Sheet: "Sheet2", Col1: 16383, Row1: 1048575, Col2: 16383, Row2: 1048575}},
+			{"'It''s here'!A1", Ref{Sheet: "It's here", Col1: 0, Row1: 0, Col2: 0, Row2: 0}},
+			{"[1]Sheet1!A5", Ref{Book: 1, Sheet: "Sheet1", Col1: 0, Row1: 4, Col2: 0, Row2: 4}},
+			{"'[2]My Sheet'!$A$5", Ref{Book: 2, Sheet: "My Sheet", Col1: 0, Row1: 4, Col2: 0, Row2: 4, AbsCol1: true, AbsRow1: true, AbsCol2: true, AbsRow2: true}},
+		}
+		for _, tc := range cases {
+			tokens, err := Tokenize(tc.formula)
+			c.Assert(err, qt.IsNil)
+			c.Assert(tokens, qt.HasLen, 1)
+			c.Assert(tokens[0].Kind, qt.Equals, TokenRef, qt.Commentf(tc.formula))
+			c.Assert(tokens[0].Ref, qt.Equals, tc.ref, qt.Commentf(tc.formula))
+		}
+	})
+
+	c.Run("NotReferences", func(c *qt.C) {
+		cases := map[string]TokenKind{
+			"ABC1x":            TokenName,
+			"Total":            TokenName,
+			"XFE1":             TokenName,
+			"LOG10(":           TokenFunc,
+			"TRUE":             TokenBool,
+			"1.5E3":            TokenNumber,
+			"#N/A":             TokenError,
+			"R1C1":             TokenName,
+			"Sheet1!Total":     TokenName,
+			"'My Sheet'!Total": TokenName,
+			"'My Sheet'!#REF!": TokenError,
+			"Sales[Amount]":    TokenTableRef,
+			"A1[Amount]":       TokenTableRef,
+			"[#Totals]":        TokenTableRef,
+		}
+		for formula, kind := range cases {
+			tokens, err := Tokenize(formula)
+			c.Assert(err, qt.IsNil)
+			c.Assert(tokens, qt.HasLen, 1)
+			c.Assert(tokens[0].Kind, qt.Equals, kind, qt.Commentf(formula))
+		}
+	})
+
+	c.Run("Errors", func(c *qt.C) {
+		_, err := Tokenize(`"unterminated`)
+		c.Assert(err, qt.ErrorMatches, `invalid formula .*: unterminated string at position 0`)
+		_, err = Tokenize(`#BOGUS!`)
+		c.Assert(err, qt.ErrorMatches, `invalid formula .*: unknown error literal at position 0`)
+		_, err = Tokenize(`1 @ 2`)
+		c.Assert(err, qt.ErrorMatches, `invalid formula .*: unexpected character '@' at position 2`)
+	})
+}
+
+func TestColumnLetters(t *testing.T) {
+	c := qt.New(t)
+	cases := map[int]string{0: "A", 25: "Z", 26: "AA", 701: "ZZ", 702: "AAA", 16383: "XFD"}
+	for n, letters := range cases {
+		c.Assert(ColumnLetters(n), qt.Equals, letters)
+		c.Assert(ColumnIndex(letters), qt.Equals, n)
+	}
+	c.Assert(ColumnIndex("ab"), qt.Equals, 27)
+}
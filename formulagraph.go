@@ -0,0 +1,514 @@
+package xlsx
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tealeg/xlsx/v3/formula"
+)
+
+// FormulaGraph records which cells the formulas of a File refer to,
+// so that the precedents and dependents of a cell can be traced, the
+// formulas put into an order in which they can be calculated, and
+// circular references found.  It is a snapshot of the File at the
+// time File.FormulaGraph was called.
+type FormulaGraph struct {
+	file *File
+	// keys lists the cells with formulas, in sheet, row and column
+	// order.
+	keys  []graphKey
+	cells map[graphKey]*graphCell
+	// bySheet holds the cells with formulas on each sheet.
+	bySheet map[int][]graphKey
+	// sheets maps the name of each sheet to its index.
+	sheets map[string]int
+	issues map[graphKey][]FormulaIssue
+}
+
+// graphKey identifies a cell by the index of its sheet in File.Sheets
+// and its zero based row and column.
+type graphKey struct {
+	sheet, row, col int
+}
+
+// graphCell is a cell with a formula.
+type graphCell struct {
+	// precedents are the references the formula makes, with names
+	// resolved and every reference qualified with its sheet.
+	precedents []formula.Ref
+	// dependsOn are the cells with formulas that lie within the
+	// precedents.
+	dependsOn []graphKey
+}
+
+// FormulaIssueKind identifies a kind of FormulaIssue.
+type FormulaIssueKind int
+
+const (
+	// FormulaIssueRefError is a #REF! error, left behind when the
+	// cells or sheet a formula referred to were deleted.
+	FormulaIssueRefError FormulaIssueKind = iota
+	// FormulaIssueMissingSheet is a reference to a sheet that isn't
+	// in the File.
+	FormulaIssueMissingSheet
+	// FormulaIssueEmptyCell is a reference to a single cell that has
+	// no value or formula.
+	FormulaIssueEmptyCell
+	// FormulaIssueUnknownName is a name, or table, that isn't
+	// defined.
+	FormulaIssueUnknownName
+	// FormulaIssueParseError is a formula that can't be parsed.
+	FormulaIssueParseError
+)
+
+func (k FormulaIssueKind) String() string {
+	switch k {
+	case FormulaIssueRefError:
+		return "#REF! error"
+	case FormulaIssueMissingSheet:
+		return "missing sheet"
+	case FormulaIssueEmptyCell:
+		return "empty cell"
+	case FormulaIssueUnknownName:
+		return "unknown name"
+	case FormulaIssueParseError:
+		return "parse error"
+	}
+	return fmt.Sprintf("FormulaIssueKind(%d)", int(k))
+}
+
+// FormulaIssue is a questionable reference found in a formula.
+type FormulaIssue struct {
+	// Cell is the cell whose formula has the issue.
+	Cell formula.Ref
+	Kind FormulaIssueKind
+	// Text is the reference, name, or for a parse error the
+	// formula, concerned.
+	Text string
+}
+
+func (i FormulaIssue) String() string {
+	return fmt.Sprintf("%s: %s %s", i.Cell, i.Kind, i.Text)
+}
+
+// CircularReferenceError is returned by FormulaGraph.EvaluationOrder
+// when formulas refer to each other in a loop.
+type CircularReferenceError struct {
+	// Cycle lists the cells in the loop.  Each cell's formula refers
+	// to the next, and the last cell's formula to the first.
+	Cycle []formula.Ref
+}
+
+func (e *CircularReferenceError) Error() string {
+	var path []string
+	for _, ref := range e.Cycle {
+		path = append(path, ref.String())
+	}
+	if len(path) > 0 {
+		path = append(path, path[0])
+	}
+	return "circular reference: " + strings.Join(path, " -> ")
+}
+
+// FormulaGraph parses the formula of every cell in the File and
+// returns the graph of their references, across sheets and through
+// defined names.
+func (f *File) FormulaGraph() (*FormulaGraph, error) {
+	wrap := func(err error) (*FormulaGraph, error) {
+		return nil, fmt.Errorf("FormulaGraph: %w", err)
+	}
+	g := &FormulaGraph{
+		file:    f,
+		cells:   make(map[graphKey]*graphCell),
+		bySheet: make(map[int][]graphKey),
+		sheets:  make(map[string]int),
+		issues:  make(map[graphKey][]FormulaIssue),
+	}
+	for i, sheet := range f.Sheets {
+		g.sheets[sheet.Name] = i
+	}
+	ctx := newCalcContext(f, nil)
+
+	for si, sheet := range f.Sheets {
+		err := sheet.ForEachRow(func(r *Row) error {
+			for ci, c := range r.cells {
+				if c == nil || strings.TrimSpace(c.formula) == "" {
+					continue
+				}
+				key := graphKey{si, r.num, ci}
+				cell := &graphCell{}
+				g.keys = append(g.keys, key)
+				g.cells[key] = cell
+				g.bySheet[si] = append(g.bySheet[si], key)
+
+				expr, err := formula.Parse(c.formula)
+				if err != nil {
+					g.issue(key, FormulaIssueParseError, c.formula)
+					continue
+				}
+				g.collect(ctx, key, cell, expr, sheet, 0)
+			}
+			return nil
+		}, SkipEmptyRows)
+		if err != nil {
+			return wrap(err)
+		}
+	}
+
+	for _, key := range g.keys {
+		cell := g.cells[key]
+		for _, ref := range cell.precedents {
+			cell.dependsOn = append(cell.dependsOn, g.formulaCellsIn(ref)...)
+		}
+	}
+
+	err := g.findEmptyCells()
+	if err != nil {
+		return wrap(err)
+	}
+	return g, nil
+}
+
+// collect adds the references made by expr, which is in the formula
+// of the cell key or a name it uses, to cell.
+func (g *FormulaGraph) collect(ctx *calcContext, key graphKey, cell *graphCell, expr formula.Expr, home *Sheet, depth int) {
+	switch e := expr.(type) {
+	case formula.Error:
+		if e.Value == formulaErrorRef {
+			g.issue(key, FormulaIssueRefError, e.Value)
+		}
+	case formula.Reference:
+		ref := e.Ref
+		if ref.Book != 0 {
+			// Cells of external workbooks aren't in the graph
+			return
+		}
+		s := home
+		if ref.Sheet != "" {
+			s = ctx.sheetByName(ref.Sheet)
+		}
+		if s == nil {
+			g.issue(key, FormulaIssueMissingSheet, ref.String())
+			return
+		}
+		ref.Sheet = s.Name
+		cell.precedents = append(cell.precedents, ref)
+	case formula.TableReference:
+		t := ctx.table(home, key.row, key.col, e.Ref.Table)
+		if t == nil {
+			g.issue(key, FormulaIssueUnknownName, e.Ref.String())
+			return
+		}
+		if ref, ok := t.resolve(e.Ref, key.row); ok {
+			cell.precedents = append(cell.precedents, ref)
+		} else {
+			g.issue(key, FormulaIssueRefError, e.Ref.String())
+		}
+	case formula.Name:
+		if e.Book != 0 {
+			return
+		}
+		s := home
+		if e.Sheet != "" {
+			s = ctx.sheetByName(e.Sheet)
+			if s == nil {
+				g.issue(key, FormulaIssueMissingSheet, e.Sheet)
+				return
+			}
+		}
+		dn := ctx.definedName(s, e.Name)
+		if dn == nil {
+			g.issue(key, FormulaIssueUnknownName, e.Name)
+			return
+		}
+		if depth >= maxNameDepth {
+			return
+		}
+		nameExpr, err := formula.Parse(dn.RefersTo)
+		if err != nil {
+			g.issue(key, FormulaIssueParseError, dn.RefersTo)
+			return
+		}
+		// References in a defined name without a sheet are to
+		// the sheet the name is used on.
+		g.collect(ctx, key, cell, nameExpr, home, depth+1)
+	case formula.Unary:
+		g.collect(ctx, key, cell, e.X, home, depth)
+	case formula.Binary:
+		g.collect(ctx, key, cell, e.X, home, depth)
+		g.collect(ctx, key, cell, e.Y, home, depth)
+	case formula.Call:
+		for _, arg := range e.Args {
+			g.collect(ctx, key, cell, arg, home, depth)
+		}
+	case formula.Array:
+		for _, row := range e.Rows {
+			for _, x := range row {
+				g.collect(ctx, key, cell, x, home, depth)
+			}
+		}
+	}
+}
+
+// findEmptyCells reports an issue for each reference to a single cell
+// that is empty.
+func (g *FormulaGraph) findEmptyCells() error {
+	// empty holds the single cells referred to, until they are
+	// found to have content.
+	empty := make(map[graphKey]bool)
+	for _, key := range g.keys {
+		for _, ref := range g.cells[key].precedents {
+			if !ref.IsArea {
+				empty[g.cellKey(ref)] = true
+			}
+		}
+	}
+	for si, sheet := range g.file.Sheets {
+		err := sheet.ForEachRow(func(r *Row) error {
+			for ci, c := range r.cells {
+				if c == nil || (c.Value == "" && c.formula == "" && len(c.RichText) == 0) {
+					continue
+				}
+				delete(empty, graphKey{si, r.num, ci})
+			}
+			return nil
+		}, SkipEmptyRows)
+		if err != nil {
+			return err
+		}
+	}
+	for _, key := range g.keys {
+		for _, ref := range g.cells[key].precedents {
+			if !ref.IsArea && empty[g.cellKey(ref)] {
+				g.issue(key, FormulaIssueEmptyCell, ref.String())
+			}
+		}
+	}
+	return nil
+}
+
+// issue records a FormulaIssue for the cell key.
+func (g *FormulaGraph) issue(key graphKey, kind FormulaIssueKind, text string) {
+	g.issues[key] = append(g.issues[key], FormulaIssue{Cell: g.ref(key), Kind: kind, Text: text})
+}
+
+// cellKey returns the key of the top left cell of ref, whose sheet
+// must be in the File.
+func (g *FormulaGraph) cellKey(ref formula.Ref) graphKey {
+	return graphKey{g.sheets[ref.Sheet], ref.Row1, ref.Col1}
+}
+
+// ref returns the reference to the cell key.
+func (g *FormulaGraph) ref(key graphKey) formula.Ref {
+	return formula.Ref{
+		Sheet: g.file.Sheets[key.sheet].Name,
+		Col1:  key.col, Row1: key.row,
+		Col2: key.col, Row2: key.row,
+	}
+}
+
+// refs returns the references to the cells keys.
+func (g *FormulaGraph) refs(keys []graphKey) []formula.Ref {
+	var refs []formula.Ref
+	for _, key := range keys {
+		refs = append(refs, g.ref(key))
+	}
+	return refs
+}
+
+// formulaCellsIn returns the cells with formulas that lie within ref.
+func (g *FormulaGraph) formulaCellsIn(ref formula.Ref) []graphKey {
+	si := g.sheets[ref.Sheet]
+	candidates := g.bySheet[si]
+	var keys []graphKey
+	if !ref.IsWholeCols() && !ref.IsWholeRows() {
+		area := (ref.Row2 - ref.Row1 + 1) * (ref.Col2 - ref.Col1 + 1)
+		if area <= len(candidates) {
+			for row := ref.Row1; row <= ref.Row2; row++ {
+				for col := ref.Col1; col <= ref.Col2; col++ {
+					key := graphKey{si, row, col}
+					if _, ok := g.cells[key]; ok {
+						keys = append(keys, key)
+					}
+				}
+			}
+			return keys
+		}
+	}
+	for _, key := range candidates {
+		if refContains(ref, key.row, key.col) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// refContains reports whether the zero based row and col lie within
+// ref.
+func refContains(ref formula.Ref, row, col int) bool {
+	if !ref.IsWholeCols() && (row < ref.Row1 || row > ref.Row2) {
+		return false
+	}
+	if !ref.IsWholeRows() && (col < ref.Col1 || col > ref.Col2) {
+		return false
+	}
+	return true
+}
+
+// key parses a reference to a single cell, such as "Sheet1!B2".
+func (g *FormulaGraph) key(ref string) (graphKey, error) {
+	r, err := formula.ParseRef(ref)
+	if err != nil {
+		return graphKey{}, err
+	}
+	if r.Sheet == "" || r.IsArea {
+		return graphKey{}, fmt.Errorf("%q is not a reference to a single cell on a sheet", ref)
+	}
+	for i, sheet := range g.file.Sheets {
+		if strings.EqualFold(sheet.Name, r.Sheet) {
+			return graphKey{i, r.Row1, r.Col1}, nil
+		}
+	}
+	return graphKey{}, fmt.Errorf("no sheet named %q", r.Sheet)
+}
+
+// Precedents returns the references made by the formula of the cell
+// ref, given with its sheet as in "Sheet1!B2".  References made
+// through defined names are included in place of the names.  The
+// result is empty if the cell has no formula.
+func (g *FormulaGraph) Precedents(ref string) ([]formula.Ref, error) {
+	key, err := g.key(ref)
+	if err != nil {
+		return nil, fmt.Errorf("Precedents: %w", err)
+	}
+	cell, ok := g.cells[key]
+	if !ok {
+		return nil, nil
+	}
+	return append([]formula.Ref(nil), cell.precedents...), nil
+}
+
+// Dependents returns the cells whose formulas refer directly to the
+// cell ref, given with its sheet as in "Sheet1!B2", whether on their
+// own or as part of a range.
+func (g *FormulaGraph) Dependents(ref string) ([]formula.Ref, error) {
+	key, err := g.key(ref)
+	if err != nil {
+		return nil, fmt.Errorf("Dependents: %w", err)
+	}
+	name := g.file.Sheets[key.sheet].Name
+	var keys []graphKey
+	for _, k := range g.keys {
+		for _, p := range g.cells[k].precedents {
+			if p.Sheet == name && refContains(p, key.row, key.col) {
+				keys = append(keys, k)
+				break
+			}
+		}
+	}
+	return g.refs(keys), nil
+}
+
+// EvaluationOrder returns the cells with formulas in an order in which
+// they can be calculated, each after the formulas it depends on.  A
+// *CircularReferenceError is returned if formulas refer to each other
+// in a loop.
+func (g *FormulaGraph) EvaluationOrder() ([]formula.Ref, error) {
+	order, remaining := g.sort()
+	if len(remaining) > 0 {
+		cycles := g.cycles(remaining)
+		return nil, fmt.Errorf("EvaluationOrder: %w", &CircularReferenceError{Cycle: cycles[0]})
+	}
+	return g.refs(order), nil
+}
+
+// Cycles returns each loop of formulas that refer to one another.  The
+// cells of a loop are listed as in CircularReferenceError.
+func (g *FormulaGraph) Cycles() [][]formula.Ref {
+	_, remaining := g.sort()
+	return g.cycles(remaining)
+}
+
+// Issues returns the questionable references found in the formulas:
+// #REF! errors, references to missing sheets or empty cells, unknown
+// names and formulas that can't be parsed.
+func (g *FormulaGraph) Issues() []FormulaIssue {
+	var issues []FormulaIssue
+	for _, key := range g.keys {
+		issues = append(issues, g.issues[key]...)
+	}
+	return issues
+}
+
+// sort orders the cells so that each comes after those it depends on.
+// Cells that are part of, or depend on, a loop can't be ordered and are
+// returned as remaining.
+func (g *FormulaGraph) sort() (order []graphKey, remaining map[graphKey]bool) {
+	pending := make(map[graphKey]int)
+	dependents := make(map[graphKey][]graphKey)
+	var ready []graphKey
+	for _, key := range g.keys {
+		dependsOn := g.cells[key].dependsOn
+		pending[key] = len(dependsOn)
+		for _, d := range dependsOn {
+			dependents[d] = append(dependents[d], key)
+		}
+		if len(dependsOn) == 0 {
+			ready = append(ready, key)
+		}
+	}
+	for len(ready) > 0 {
+		key := ready[0]
+		ready = ready[1:]
+		order = append(order, key)
+		for _, d := range dependents[key] {
+			pending[d]--
+			if pending[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+	remaining = make(map[graphKey]bool)
+	for key, n := range pending {
+		if n > 0 {
+			remaining[key] = true
+		}
+	}
+	return order, remaining
+}
+
+// cycles finds the loops among the remaining cells left by sort.
+// Every remaining cell depends on another remaining cell, so
+// following those dependencies from any of them must lead to a loop.
+func (g *FormulaGraph) cycles(remaining map[graphKey]bool) [][]formula.Ref {
+	var cycles [][]formula.Ref
+	explored := make(map[graphKey]bool)
+	for _, start := range g.keys {
+		if !remaining[start] || explored[start] {
+			continue
+		}
+		var path []graphKey
+		index := make(map[graphKey]int)
+		key := start
+		for {
+			if i, ok := index[key]; ok {
+				cycles = append(cycles, g.refs(path[i:]))
+				break
+			}
+			if explored[key] {
+				// Leads to a loop that has already been found
+				break
+			}
+			index[key] = len(path)
+			path = append(path, key)
+			explored[key] = true
+			for _, d := range g.cells[key].dependsOn {
+				if remaining[d] {
+					key = d
+					break
+				}
+			}
+		}
+	}
+	return cycles
+}
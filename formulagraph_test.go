@@ -0,0 +1,131 @@
+package xlsx
+
+import (
+	"errors"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/tealeg/xlsx/v3/formula"
+)
+
+// refStrings returns refs in A1 notation.
+func refStrings(refs []formula.Ref) []string {
+	var s []string
+	for _, ref := range refs {
+		s = append(s, ref.String())
+	}
+	return s
+}
+
+func TestFormulaGraph(t *testing.T) {
+	c := qt.New(t)
+
+	setUp := func(c *qt.C, option FileOption) *File {
+		f := NewFile(option)
+		data, err := f.AddSheet("Data")
+		c.Assert(err, qt.IsNil)
+		model, err := f.AddSheet("Model")
+		c.Assert(err, qt.IsNil)
+		setCells(c, data, map[string]interface{}{
+			"A1": 1, "A2": 2, "A3": 3,
+			"A4": "=SUM(A1:A3)",
+			"B1": "=A4*Rate",
+		})
+		setCells(c, model, map[string]interface{}{
+			"A1": "=Data!B1+Data!A4",
+			"A2": "=A1+C9",
+			"A3": "=Gone!A1+Missing",
+			"A4": "=#REF!+1",
+			"A5": "=SUM(Data!A:A)",
+		})
+		f.DefinedNames = append(f.DefinedNames, &DefinedName{Name: "Rate", RefersTo: "Model!$B$1"})
+		cellAt(c, model, "B1").SetFloat(0.2)
+		return f
+	}
+
+	csRunO(c, "PrecedentsAndDependents", func(c *qt.C, option FileOption) {
+		g, err := setUp(c, option).FormulaGraph()
+		c.Assert(err, qt.IsNil)
+
+		p, err := g.Precedents("Data!B1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(refStrings(p), qt.DeepEquals, []string{"Data!A4", "Model!$B$1"})
+		p, err = g.Precedents("'Data'!A4")
+		c.Assert(err, qt.IsNil)
+		c.Assert(refStrings(p), qt.DeepEquals, []string{"Data!A1:A3"})
+		p, err = g.Precedents("Data!A1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(p, qt.HasLen, 0)
+
+		d, err := g.Dependents("Data!A2")
+		c.Assert(err, qt.IsNil)
+		c.Assert(refStrings(d), qt.DeepEquals, []string{"Data!A4", "Model!A5"})
+		d, err = g.Dependents("Model!B1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(refStrings(d), qt.DeepEquals, []string{"Data!B1"})
+
+		_, err = g.Precedents("A1")
+		c.Assert(err, qt.ErrorMatches, `Precedents: "A1" is not a reference to a single cell on a sheet`)
+		_, err = g.Dependents("Nowhere!A1")
+		c.Assert(err, qt.ErrorMatches, `Dependents: no sheet named "Nowhere"`)
+	})
+
+	csRunO(c, "EvaluationOrder", func(c *qt.C, option FileOption) {
+		g, err := setUp(c, option).FormulaGraph()
+		c.Assert(err, qt.IsNil)
+		order, err := g.EvaluationOrder()
+		c.Assert(err, qt.IsNil)
+		position := make(map[string]int)
+		for i, ref := range refStrings(order) {
+			position[ref] = i
+		}
+		c.Assert(position, qt.HasLen, 7)
+		c.Assert(position["Data!A4"] < position["Data!B1"], qt.Equals, true)
+		c.Assert(position["Data!B1"] < position["Model!A1"], qt.Equals, true)
+		c.Assert(position["Model!A1"] < position["Model!A2"], qt.Equals, true)
+		c.Assert(position["Data!A4"] < position["Model!A5"], qt.Equals, true)
+		c.Assert(g.Cycles(), qt.HasLen, 0)
+	})
+
+	csRunO(c, "Issues", func(c *qt.C, option FileOption) {
+		g, err := setUp(c, option).FormulaGraph()
+		c.Assert(err, qt.IsNil)
+		var issues []string
+		for _, issue := range g.Issues() {
+			issues = append(issues, issue.String())
+		}
+		c.Assert(issues, qt.DeepEquals, []string{
+			"Model!A2: empty cell Model!C9",
+			"Model!A3: missing sheet Gone!A1",
+			"Model!A3: unknown name Missing",
+			"Model!A4: #REF! error #REF!",
+		})
+	})
+
+	csRunO(c, "CircularReferences", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		setCells(c, sheet, map[string]interface{}{
+			"A1": "=B1+1",
+			"B1": "=C1+1",
+			"C1": "=A1+1",
+			"D1": "=A1*2",
+			"E1": "=E1",
+			"F1": 1,
+			"G1": "=F1",
+		})
+		g, err := f.FormulaGraph()
+		c.Assert(err, qt.IsNil)
+
+		cycles := g.Cycles()
+		c.Assert(cycles, qt.HasLen, 2)
+		c.Assert(refStrings(cycles[0]), qt.DeepEquals, []string{"Sheet1!A1", "Sheet1!B1", "Sheet1!C1"})
+		c.Assert(refStrings(cycles[1]), qt.DeepEquals, []string{"Sheet1!E1"})
+
+		_, err = g.EvaluationOrder()
+		var circular *CircularReferenceError
+		c.Assert(errors.As(err, &circular), qt.Equals, true)
+		c.Assert(err, qt.ErrorMatches, `EvaluationOrder: circular reference: Sheet1!A1 -> Sheet1!B1 -> Sheet1!C1 -> Sheet1!A1`)
+	})
+}
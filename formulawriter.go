@@ -0,0 +1,131 @@
+package xlsx
+
+import (
+	"github.com/tealeg/xlsx/v3/formula"
+)
+
+// WriteSharedFormulas is a FileOption that makes a File, when it is
+// written, store each run of cells down a column whose formulas are
+// the same apart from their relative references as a single shared
+// formula, as Excel does when a formula is filled down.  The formula
+// text is then only written once per run, which can make a workbook
+// that repeats the same formula on many rows much smaller.  Reading
+// the file expands shared formulas again, so Cell.Formula is
+// unaffected.
+func WriteSharedFormulas(f *File) {
+	f.writeSharedFormulas = true
+}
+
+// sharedRun is a run of cells down a column that are written as a
+// single shared formula.
+type sharedRun struct {
+	si          int // assigned when the first cell is written
+	first, last int // zero based rows
+}
+
+// formulaWriter makes the f elements for the cells of a sheet as it is
+// written.  Rows must be written in ascending order.
+type formulaWriter struct {
+	// runs holds the shared formula runs of each column, in order.
+	runs map[int][]sharedRun
+	// nextSI is the index of the next shared formula to be written.
+	nextSI int
+	// dynamicArrays is set once a dynamic array formula has been
+	// written.
+	dynamicArrays bool
+}
+
+// newFormulaWriter returns a formulaWriter for s, finding the runs of
+// cells that can share a formula if the File asks for shared formulas.
+func newFormulaWriter(s *Sheet) (*formulaWriter, error) {
+	fw := &formulaWriter{}
+	if s.File == nil || !s.File.writeSharedFormulas {
+		return fw, nil
+	}
+	fw.runs = make(map[int][]sharedRun)
+
+	// open is the run, if any, that the next cell down each column
+	// may continue, with the formula of its first cell.
+	type openRun struct {
+		sharedRun
+		formula string
+	}
+	open := make(map[int]*openRun)
+	closeRun := func(col int, run *openRun) {
+		if run.last > run.first {
+			fw.runs[col] = append(fw.runs[col], run.sharedRun)
+		}
+	}
+
+	err := s.ForEachRow(func(r *Row) error {
+		for _, cell := range r.cells {
+			if cell == nil || cell.formula == "" || cell.arrayRef != "" {
+				continue
+			}
+			col := cell.num
+			if run, ok := open[col]; ok && run.last == r.num-1 {
+				shifted, err := formula.ShiftReferences(run.formula, r.num-run.first, 0)
+				if err == nil && shifted == cell.formula {
+					run.last = r.num
+					continue
+				}
+			}
+			if run, ok := open[col]; ok {
+				closeRun(col, run)
+			}
+			open[col] = &openRun{sharedRun{first: r.num, last: r.num}, cell.formula}
+		}
+		return nil
+	}, SkipEmptyRows)
+	if err != nil {
+		return nil, err
+	}
+	for col, run := range open {
+		closeRun(col, run)
+	}
+	return fw, nil
+}
+
+// write sets the formula, if any, of the xlsxC for cell.
+func (fw *formulaWriter) write(xC *xlsxC, cell *Cell, row int) {
+	if cell.formula == "" {
+		return
+	}
+	if cell.arrayRef != "" {
+		xC.F = &xlsxF{Content: cell.formula, T: "array", Ref: cell.arrayRef}
+		if cell.dynamicArray {
+			// Refers to the dynamic array properties in the
+			// workbook's cell metadata.
+			xC.Cm = 1
+			fw.dynamicArrays = true
+		}
+		return
+	}
+	xC.F = &xlsxF{Content: cell.formula}
+	runs, ok := fw.runs[cell.num]
+	if !ok {
+		return
+	}
+	for len(runs) > 0 && runs[0].last < row {
+		runs = runs[1:]
+	}
+	fw.runs[cell.num] = runs
+	if len(runs) == 0 || runs[0].first > row {
+		return
+	}
+	run := &runs[0]
+	if row == run.first {
+		// Only the first cell of the run holds the formula text
+		run.si = fw.nextSI
+		fw.nextSI++
+		xC.F = &xlsxF{
+			Content: cell.formula,
+			T:       "shared",
+			Ref:     GetCellIDStringFromCoords(cell.num, run.first) + ":" + GetCellIDStringFromCoords(cell.num, run.last),
+		}
+	} else {
+		xC.F = &xlsxF{T: "shared"}
+	}
+	si := run.si
+	xC.F.Si = &si
+}
@@ -0,0 +1,131 @@
+package xlsx
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestWriteSharedFormulas(t *testing.T) {
+	c := qt.New(t)
+
+	setUp := func(c *qt.C, options ...FileOption) *File {
+		f := NewFile(options...)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		setCells(c, sheet, map[string]interface{}{
+			"A1": 1, "A2": 2, "A3": 3, "A4": 4, "A5": 5,
+			"B1": "=A1*2", "B2": "=A2*2", "B3": "=A3*2", "B4": "=A4*2",
+			"B5": "=A5*3",
+			"C1": "=$A$1+A2", "C2": "=$A$1+A3",
+			"C4": "=$A$1+A5", "C5": "=$A$1+A6",
+		})
+		return f
+	}
+
+	csRunParts(c, "SharedOnWrite", func(c *qt.C, option FileOption) *File {
+		return setUp(c, option, WriteSharedFormulas)
+	}, func(c *qt.C, parts map[string]string) {
+		sheetXML := parts["xl/worksheets/sheet1.xml"]
+		c.Assert(sheetXML, qt.Contains, `<f t="shared" ref="B1:B4" si="0">A1*2</f>`)
+		c.Assert(sheetXML, qt.Contains, `<c r="B2"><f t="shared" si="0"></f>`)
+		c.Assert(sheetXML, qt.Contains, `<c r="B3"><f t="shared" si="0"></f>`)
+		c.Assert(sheetXML, qt.Contains, `<c r="B4"><f t="shared" si="0"></f>`)
+		c.Assert(sheetXML, qt.Contains, `<c r="B5"><f>A5*3</f>`)
+		c.Assert(sheetXML, qt.Contains, `<f t="shared" ref="C1:C2" si="1">$A$1+A2</f>`)
+		c.Assert(sheetXML, qt.Contains, `<f t="shared" ref="C4:C5" si="2">$A$1+A5</f>`)
+	})
+
+	csRunO(c, "ExpandedOnRead", func(c *qt.C, option FileOption) {
+		f := reopen(c, setUp(c, option, WriteSharedFormulas), option)
+		sheet := f.Sheets[0]
+		expected := map[string]string{
+			"B1": "A1*2", "B2": "A2*2", "B3": "A3*2", "B4": "A4*2",
+			"B5": "A5*3",
+			"C1": "$A$1+A2", "C2": "$A$1+A3",
+			"C4": "$A$1+A5", "C5": "$A$1+A6",
+		}
+		for ref, formula := range expected {
+			c.Assert(cellAt(c, sheet, ref).Formula(), qt.Equals, formula, qt.Commentf(ref))
+		}
+	})
+
+	csRunO(c, "NotSharedByDefault", func(c *qt.C, option FileOption) {
+		f := setUp(c, option)
+		sheetXML := writtenParts(c, f)["xl/worksheets/sheet1.xml"]
+		c.Assert(sheetXML, qt.Not(qt.Contains), `t="shared"`)
+		c.Assert(sheetXML, qt.Contains, `<c r="B2"><f>A2*2</f>`)
+	})
+}
+
+func TestArrayFormulas(t *testing.T) {
+	c := qt.New(t)
+
+	csRunO(c, "SetArrayFormula", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		cell := cellAt(c, sheet, "C2")
+
+		err = cell.SetArrayFormula("A2:A4*B2:B4", "$C$2:C4")
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Formula(), qt.Equals, "A2:A4*B2:B4")
+		c.Assert(cell.ArrayFormulaRef(), qt.Equals, "C2:C4")
+		c.Assert(cell.IsDynamicArrayFormula(), qt.Equals, false)
+
+		err = cell.SetArrayFormula("SUM(A2:A4*B2:B4)", "")
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.ArrayFormulaRef(), qt.Equals, "C2")
+
+		err = cell.SetArrayFormula("A2:A4", "C3:C5")
+		c.Assert(err, qt.ErrorMatches, `SetArrayFormula: array formula range "C3:C5" does not start at C2`)
+		err = cell.SetDynamicArrayFormula("A2:A4", "C:C")
+		c.Assert(err, qt.ErrorMatches, `SetDynamicArrayFormula: invalid array formula range "C:C"`)
+
+		cell.SetFormula("A2")
+		c.Assert(cell.ArrayFormulaRef(), qt.Equals, "")
+	})
+
+	csRunO(c, "RoundTrip", func(c *qt.C, option FileOption) {
+		f := NewFile(option, WriteSharedFormulas)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		setCells(c, sheet, map[string]interface{}{
+			"A1": 3, "A2": 1, "A3": 2,
+		})
+		err = cellAt(c, sheet, "B1").SetArrayFormula("A1:A3*2", "B1:B3")
+		c.Assert(err, qt.IsNil)
+		err = cellAt(c, sheet, "C1").SetDynamicArrayFormula("_xlfn._xlws.SORT(A1:A3)", "C1:C3")
+		c.Assert(err, qt.IsNil)
+
+		parts := writtenParts(c, f)
+		sheetXML := parts["xl/worksheets/sheet1.xml"]
+		c.Assert(sheetXML, qt.Contains, `<c r="B1"><f t="array" ref="B1:B3">A1:A3*2</f>`)
+		c.Assert(sheetXML, qt.Contains, `<c r="C1" cm="1"><f t="array" ref="C1:C3">_xlfn._xlws.SORT(A1:A3)</f>`)
+		c.Assert(parts["xl/metadata.xml"], qt.Contains, `<xda:dynamicArrayProperties fDynamic="1" fCollapsed="0"/>`)
+		c.Assert(parts["xl/_rels/workbook.xml.rels"], qt.Contains, `Target="metadata.xml"`)
+		c.Assert(parts["[Content_Types].xml"], qt.Contains, `PartName="/xl/metadata.xml"`)
+
+		f = reopen(c, f, option)
+		sheet = f.Sheets[0]
+		b1 := cellAt(c, sheet, "B1")
+		c.Assert(b1.Formula(), qt.Equals, "A1:A3*2")
+		c.Assert(b1.ArrayFormulaRef(), qt.Equals, "B1:B3")
+		c.Assert(b1.IsDynamicArrayFormula(), qt.Equals, false)
+		c1 := cellAt(c, sheet, "C1")
+		c.Assert(c1.Formula(), qt.Equals, "_xlfn._xlws.SORT(A1:A3)")
+		c.Assert(c1.ArrayFormulaRef(), qt.Equals, "C1:C3")
+		c.Assert(c1.IsDynamicArrayFormula(), qt.Equals, true)
+	})
+
+	csRunO(c, "NoMetadataWithoutDynamicArrays", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		err = cellAt(c, sheet, "B1").SetArrayFormula("A1:A3*2", "B1:B3")
+		c.Assert(err, qt.IsNil)
+		parts := writtenParts(c, f)
+		_, ok := parts["xl/metadata.xml"]
+		c.Assert(ok, qt.Equals, false)
+	})
+}
@@ -0,0 +1,99 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// setCells populates a sheet from a map of cell references to
+// values.  Strings starting with "=" become formulas.
+func setCells(c *qt.C, sheet *Sheet, values map[string]interface{}) {
+	for ref, value := range values {
+		x, y, err := GetCoordsFromCellIDString(ref)
+		c.Assert(err, qt.IsNil)
+		cell, err := sheet.Cell(y, x)
+		c.Assert(err, qt.IsNil)
+		if s, ok := value.(string); ok && len(s) > 1 && s[0] == '=' {
+			cell.SetFormula(s[1:])
+			continue
+		}
+		if b, ok := value.(bool); ok {
+			cell.SetBool(b)
+			continue
+		}
+		cell.SetValue(value)
+	}
+}
+
+// cellAt returns the cell with the given reference.
+func cellAt(c *qt.C, sheet *Sheet, ref string) *Cell {
+	x, y, err := GetCoordsFromCellIDString(ref)
+	c.Assert(err, qt.IsNil)
+	cell, err := sheet.Cell(y, x)
+	c.Assert(err, qt.IsNil)
+	return cell
+}
+
+// writtenParts writes f and returns the contents of each of its parts.
+func writtenParts(c *qt.C, f *File) map[string]string {
+	var buf bytes.Buffer
+	err := f.Write(&buf)
+	c.Assert(err, qt.IsNil)
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, qt.IsNil)
+	parts := make(map[string]string)
+	for _, zf := range z.File {
+		rc, err := zf.Open()
+		c.Assert(err, qt.IsNil)
+		b, err := ioutil.ReadAll(rc)
+		c.Assert(err, qt.IsNil)
+		rc.Close()
+		parts[zf.Name] = string(b)
+	}
+	return parts
+}
+
+// zipParts returns an XLSX file holding parts.
+func zipParts(c *qt.C, parts map[string]string) []byte {
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	for name, part := range parts {
+		w, err := z.Create(name)
+		c.Assert(err, qt.IsNil)
+		_, err = w.Write([]byte(part))
+		c.Assert(err, qt.IsNil)
+	}
+	c.Assert(z.Close(), qt.IsNil)
+	return buf.Bytes()
+}
+
+// reopen writes f and reads it back.
+func reopen(c *qt.C, f *File, option FileOption) *File {
+	var buf bytes.Buffer
+	err := f.Write(&buf)
+	c.Assert(err, qt.IsNil)
+	f, err = OpenBinary(buf.Bytes(), option)
+	c.Assert(err, qt.IsNil)
+	return f
+}
+
+// csRunParts runs check, with all available CellStore FileOptions, on
+// the parts of the File made by setUp, both as File.Write writes them
+// and as File.MakeStreamParts makes them.  As only Write self-closes
+// empty elements, checks on worksheets must not depend on how an
+// empty element is closed.
+func csRunParts(c *qt.C, description string, setUp func(c *qt.C, option FileOption) *File, check func(c *qt.C, parts map[string]string)) {
+	csRunO(c, description, func(c *qt.C, option FileOption) {
+		c.Run("Write", func(c *qt.C) {
+			check(c, writtenParts(c, setUp(c, option)))
+		})
+		c.Run("MakeStreamParts", func(c *qt.C) {
+			parts, err := setUp(c, option).MakeStreamParts()
+			c.Assert(err, qt.IsNil)
+			check(c, parts)
+		})
+	})
+}
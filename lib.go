@@ -11,6 +11,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+
+	"github.com/tealeg/xlsx/v3/formula"
 )
 
 const (
@@ -81,7 +83,7 @@ func ColIndexToLetters(n int) string {
 
 	for n > 0 {
 		n -= 1
-		s = string('A'+(n%26)) + s
+		s = string(rune('A'+(n%26))) + s
 		n /= 26
 	}
 
@@ -291,57 +293,25 @@ func formulaForCell(rawcell xlsxC, sharedFormulas map[int]sharedFormula) string
 		return ""
 	}
 	if f.T == "shared" {
+		var si int
+		if f.Si != nil {
+			si = *f.Si
+		}
 		x, y, err := GetCoordsFromCellIDString(rawcell.R)
 		if err != nil {
 			res = f.Content
 		} else {
 			if f.Ref != "" {
 				res = f.Content
-				sharedFormulas[f.Si] = sharedFormula{x, y, res}
+				sharedFormulas[si] = sharedFormula{x, y, res}
 			} else {
-				sharedFormula := sharedFormulas[f.Si]
+				sharedFormula := sharedFormulas[si]
 				dx := x - sharedFormula.x
 				dy := y - sharedFormula.y
-				orig := []byte(sharedFormula.formula)
-				var start, end int
-				var stringLiteral bool
-				for end = 0; end < len(orig); end++ {
-					c := orig[end]
-
-					if c == '"' {
-						stringLiteral = !stringLiteral
-					}
-
-					if stringLiteral {
-						continue // Skip characters in quotes
-					}
-
-					if c >= 'A' && c <= 'Z' || c == '$' {
-						res += string(orig[start:end])
-						start = end
-						end++
-						foundNum := false
-						for ; end < len(orig); end++ {
-							idc := orig[end]
-							if idc >= '0' && idc <= '9' || idc == '$' {
-								foundNum = true
-							} else if idc >= 'A' && idc <= 'Z' {
-								if foundNum {
-									break
-								}
-							} else {
-								break
-							}
-						}
-						if foundNum {
-							cellID := string(orig[start:end])
-							res += shiftCell(cellID, dx, dy)
-							start = end
-						}
-					}
-				}
-				if start < len(orig) {
-					res += string(orig[start:])
+				res, err = formula.ShiftReferences(sharedFormula.formula, dy, dx)
+				if err != nil {
+					// Leave a formula we can't parse as it is
+					res = sharedFormula.formula
 				}
 			}
 		}
@@ -351,61 +321,20 @@ func formulaForCell(rawcell xlsxC, sharedFormulas map[int]sharedFormula) string
 	return strings.Trim(res, " \t\n\r")
 }
 
-// shiftCell returns the cell shifted according to dx and dy taking into consideration of absolute
-// references with dollar sign ($)
-func shiftCell(cellID string, dx, dy int) string {
-	fx, fy, _ := GetCoordsFromCellIDString(cellID)
-
-	// Is fixed column?
-	fixedCol := strings.Index(cellID, fixedCellRefChar) == 0
-
-	// Is fixed row?
-	fixedRow := strings.LastIndex(cellID, fixedCellRefChar) > 0
-
-	if !fixedCol {
-		// Shift column
-		fx += dx
-	}
-
-	if !fixedRow {
-		// Shift row
-		fy += dy
-	}
-
-	// New shifted cell
-	shiftedCellID := GetCellIDStringFromCoords(fx, fy)
-
-	if !fixedCol && !fixedRow {
-		return shiftedCellID
-	}
-
-	// There are absolute references, need to put the $ back into the formula.
-	letterPart := strings.Map(letterOnlyMapF, shiftedCellID)
-	numberPart := strings.Map(intOnlyMapF, shiftedCellID)
-
-	result := ""
-
-	if fixedCol {
-		result += "$"
-	}
-
-	result += letterPart
-
-	if fixedRow {
-		result += "$"
-	}
-
-	result += numberPart
-
-	return result
-}
-
 // fillCellData attempts to extract a valid value, usable in
 // CSV form from the raw cell value.  Note - this is not actually
 // general enough - we should support retaining tabs and newlines.
 func fillCellData(rawCell xlsxC, refTable *RefTable, sharedFormulas map[int]sharedFormula, cell *Cell) {
 	val := strings.Trim(rawCell.V, " \t\n\r")
 	cell.formula = formulaForCell(rawCell, sharedFormulas)
+	if rawCell.F != nil && rawCell.F.T == "array" {
+		cell.arrayRef = rawCell.F.Ref
+		if cell.arrayRef == "" {
+			cell.arrayRef = rawCell.R
+		}
+		// Excel marks dynamic array formulas with cell metadata
+		cell.dynamicArray = rawCell.Cm > 0
+	}
 	switch rawCell.T {
 	case "s": // Shared String
 		cell.cellType = CellTypeString
@@ -488,6 +417,8 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet, rowLi
 
 	rowCount = maxRow + 1
 	colCount = maxCol + 1
+	// The merged cells are indexed as they are read.
+	sheet.merged = make(map[mergeOrigin]*Range)
 
 	if Worksheet.Cols != nil {
 		// Columns can apply to a range, for convenience we expand the
@@ -528,6 +459,7 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet, rowLi
 		row.num = rawrow.R - 1
 
 		row.Hidden = rawrow.Hidden
+		row.Collapsed = rawrow.Collapsed
 		height, err := strconv.ParseFloat(rawrow.Ht, 64)
 		if err == nil {
 			row.SetHeight(height)
@@ -551,8 +483,7 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet, rowLi
 			cellX := x
 
 			cell := newCell(row, cellX)
-			cell.HMerge = h
-			cell.VMerge = v
+			cell.Merge(h, v)
 			fillCellData(rawcell, reftable, sharedFormulas, cell)
 			if file.styles != nil {
 				cell.style = file.styles.getStyle(rawcell.S)
@@ -571,6 +502,12 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet, rowLi
 	sheet.MaxRow = rowCount
 	sheet.MaxCol = colCount
 
+	if file.fillMergedCells {
+		if err := sheet.fillMergedCells(); err != nil {
+			return wrap(err)
+		}
+	}
+
 	if rowCount >= 0 {
 		row, err = sheet.Row(0)
 		if err != nil {
@@ -594,18 +531,7 @@ func readSheetViews(xSheetViews xlsxSheetViews) []SheetView {
 	}
 	sheetViews := []SheetView{}
 	for _, xSheetView := range xSheetViews.SheetView {
-		sheetView := SheetView{}
-		if xSheetView.Pane != nil {
-			xlsxPane := xSheetView.Pane
-			pane := &Pane{}
-			pane.XSplit = xlsxPane.XSplit
-			pane.YSplit = xlsxPane.YSplit
-			pane.TopLeftCell = xlsxPane.TopLeftCell
-			pane.ActivePane = xlsxPane.ActivePane
-			pane.State = xlsxPane.State
-			sheetView.Pane = pane
-		}
-		sheetViews = append(sheetViews, sheetView)
+		sheetViews = append(sheetViews, readSheetView(xSheetView))
 	}
 	return sheetViews
 }
@@ -648,22 +574,13 @@ func readSheetFromFile(rsheet xlsxSheet, fi *File, sheetXMLMap map[string]string
 		sheet.AutoFilter = &AutoFilter{autoFilterBounds[0], autoFilterBounds[1]}
 	}
 
+	worksheetRels, err := readWorksheetRels(fi, rsheet)
+	if err != nil {
+		return wrap(err)
+	}
+
 	// Convert xlsxHyperlinks to Hyperlinks
 	if worksheet.Hyperlinks != nil {
-
-		worksheetRelsFile, ok := fi.worksheetRels["sheet"+rsheet.SheetId]
-		worksheetRels := new(xlsxWorksheetRels)
-		if ok {
-			rc, err := worksheetRelsFile.Open()
-			if err != nil {
-				return wrap(fmt.Errorf("file.Open: %w", err))
-			}
-			decoder := xml.NewDecoder(rc)
-			err = decoder.Decode(worksheetRels)
-			if err != nil {
-				return wrap(fmt.Errorf("xml.Decoder.Decode: %w", err))
-			}
-		}
 		for _, xlsxLink := range worksheet.Hyperlinks.HyperLinks {
 			newHyperLink := Hyperlink{}
 
@@ -694,6 +611,23 @@ func readSheetFromFile(rsheet xlsxSheet, fi *File, sheetXMLMap map[string]string
 		}
 	}
 
+	err = readComments(fi, sheet, worksheetRels)
+	if err != nil {
+		return wrap(err)
+	}
+	err = readDrawing(fi, sheet, worksheetRels)
+	if err != nil {
+		return wrap(err)
+	}
+	err = readTables(fi, sheet, worksheetRels)
+	if err != nil {
+		return wrap(err)
+	}
+	err = readPivotTables(fi, sheet, worksheetRels)
+	if err != nil {
+		return wrap(err)
+	}
+
 	sheet.SheetFormat.DefaultColWidth = worksheet.SheetFormatPr.DefaultColWidth
 	sheet.SheetFormat.DefaultRowHeight = worksheet.SheetFormatPr.DefaultRowHeight
 	sheet.SheetFormat.OutlineLevelCol = worksheet.SheetFormatPr.OutlineLevelCol
@@ -704,10 +638,40 @@ func readSheetFromFile(rsheet xlsxSheet, fi *File, sheetXMLMap map[string]string
 		}
 
 	}
+	readProtection(sheet, worksheet)
+	readPageSetup(sheet, worksheet)
+	readPageBreaks(sheet, worksheet)
+	readOutline(sheet, worksheet)
+	readConditionalFormatting(sheet, worksheet, fi.styles)
+	err = readSparklines(sheet, worksheet)
+	if err != nil {
+		return wrap(err)
+	}
 
 	return sheet, nil
 }
 
+// readWorksheetRels returns the relationships of the worksheet of
+// rsheet, which are empty if it has none.
+func readWorksheetRels(fi *File, rsheet xlsxSheet) (*xlsxWorksheetRels, error) {
+	worksheetRels := new(xlsxWorksheetRels)
+	worksheetRelsFile, ok := fi.worksheetRels["sheet"+rsheet.SheetId]
+	if !ok {
+		return worksheetRels, nil
+	}
+	rc, err := worksheetRelsFile.Open()
+	if err != nil {
+		return nil, fmt.Errorf("file.Open: %w", err)
+	}
+	defer rc.Close()
+	decoder := xml.NewDecoder(rc)
+	err = decoder.Decode(worksheetRels)
+	if err != nil {
+		return nil, fmt.Errorf("xml.Decoder.Decode: %w", err)
+	}
+	return worksheetRels, nil
+}
+
 // readSheetsFromZipFile is an internal helper function that loops
 // over the Worksheets defined in the XSLXWorkbook and loads them into
 // Sheet objects stored in the Sheets slice of a xlsx.File struct.
@@ -733,17 +697,28 @@ func readSheetsFromZipFile(f *zip.File, file *File, sheetXMLMap map[string]strin
 		return wrap(fmt.Errorf("xml.Decoder.Decode: %w", err))
 	}
 	file.Date1904 = workbook.WorkbookPr.Date1904
+	readWorkbookProtection(file, workbook.WorkbookProtection)
+	file.CalcSettings = CalcSettings{
+		FullCalcOnLoad: workbook.CalcPr.FullCalcOnLoad,
+		Mode:           CalcMode(workbook.CalcPr.CalcMode),
+		Iterate:        workbook.CalcPr.Iterate,
+		IterateCount:   workbook.CalcPr.IterateCount,
+		IterateDelta:   workbook.CalcPr.IterateDelta,
+	}
 
-	for entryNum := range workbook.DefinedNames.DefinedName {
-		file.DefinedNames = append(file.DefinedNames, &workbook.DefinedNames.DefinedName[entryNum])
+	err = readPivotCaches(file, workbook, strings.Replace(f.Name, `\`, "/", -1))
+	if err != nil {
+		return wrap(err)
 	}
 
 	// Only try and read sheets that have corresponding files.
 	// Notably this excludes chartsheets don't right now
 	var workbookSheets []xlsxSheet
-	for _, sheet := range workbook.Sheets.Sheet {
+	var positions []int
+	for i, sheet := range workbook.Sheets.Sheet {
 		if f := worksheetFileForSheet(sheet, file.worksheets, sheetXMLMap); f != nil {
 			workbookSheets = append(workbookSheets, sheet)
+			positions = append(positions, i)
 		}
 	}
 	sheetCount = len(workbookSheets)
@@ -773,6 +748,12 @@ func readSheetsFromZipFile(f *zip.File, file *File, sheetXMLMap map[string]strin
 		sheetsByName[sheetName] = sheet.Sheet
 		sheets[sheet.Index] = sheet.Sheet
 	}
+
+	scopes := make([]*Sheet, len(workbook.Sheets.Sheet))
+	for i, position := range positions {
+		scopes[position] = sheets[i]
+	}
+	readDefinedNames(file, workbook, scopes)
 	return sheetsByName, sheets, nil
 }
 
@@ -980,7 +961,9 @@ func ReadZipReader(r *zip.Reader, options ...FileOption) (*File, error) {
 	file = NewFile(options...)
 	worksheets = make(map[string]*zip.File, len(r.File))
 	worksheetRels = make(map[string]*zip.File, len(r.File))
+	file.zipFiles = make(map[string]*zip.File, len(r.File))
 	for _, v = range r.File {
+		file.zipFiles[strings.Replace(v.Name, `\`, "/", -1)] = v
 		_, name := filepath.Split(v.Name)
 		switch name {
 		case `sharedStrings.xml`:
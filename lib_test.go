@@ -1208,6 +1208,11 @@ func TestLib(t *testing.T) {
 			"$AA1",
 			"AA$1",
 			"$AA$1",
+			"Sheet2!A1+'My Sheet'!$A1",
+			"SUM(A:A)+SUM(1:1)",
+			"ABC1x+LOG10(A1)",
+			"R1C1",
+			"A1-1",
 		}
 
 		expected := []string{
@@ -1230,6 +1235,11 @@ func TestLib(t *testing.T) {
 			"$AA2",
 			"AB$1",
 			"$AA$1",
+			"Sheet2!B2+'My Sheet'!$A2",
+			"SUM(B:B)+SUM(2:2)",
+			"ABC1x+LOG10(B2)",
+			"R1C1",
+			"B2-1",
 		}
 
 		anchorCell := "C4"
@@ -1242,12 +1252,13 @@ func TestLib(t *testing.T) {
 		}
 
 		for i, formula := range formulas {
+			si := i
 			testCell := xlsxC{
 				R: "D5",
 				F: &xlsxF{
 					Content: formula,
 					T:       "shared",
-					Si:      i,
+					Si:      &si,
 				},
 			}
 
@@ -0,0 +1,238 @@
+package xlsx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tealeg/xlsx/v3/formula"
+)
+
+// FillMergedCells is a FileOption that gives every cell covered by a
+// merged range the value and number format of the top left cell of
+// the range when a File is read.  This makes reading reports with
+// merged headings simpler, as each row then holds every value that
+// applies to it.  As the covered cells really hold those values, they
+// are written with the File if it is saved again.
+func FillMergedCells(f *File) {
+	f.fillMergedCells = true
+}
+
+// mergeOrigin is the zero based row and column of the top left cell
+// of a merged range.
+type mergeOrigin struct{ row, col int }
+
+// mergedIndex returns the merged ranges of the Sheet by their top left
+// cells.  The first time it is needed the Sheet is walked to find
+// them, and from then on Cell.Merge, and moving rows and columns, keep
+// it up to date.
+func (s *Sheet) mergedIndex() (map[mergeOrigin]*Range, error) {
+	if s.merged != nil {
+		return s.merged, nil
+	}
+	merged := make(map[mergeOrigin]*Range)
+	err := s.ForEachRow(func(r *Row) error {
+		// A merged cell needn't have a value, so we can't skip
+		// the cells that are empty.
+		for _, c := range r.cells {
+			if c != nil && (c.HMerge > 0 || c.VMerge > 0) {
+				merged[mergeOrigin{r.num, c.num}] = s.mergedRange(r.num, c.num, c.HMerge, c.VMerge)
+			}
+		}
+		return nil
+	}, SkipEmptyRows)
+	if err != nil {
+		return nil, err
+	}
+	s.merged = merged
+	return merged, nil
+}
+
+// mergedRange returns the Range merged from the cell at row and col
+// across hcells more columns and vcells more rows.
+func (s *Sheet) mergedRange(row, col, hcells, vcells int) *Range {
+	return &Range{
+		Sheet:    s,
+		FirstRow: row, FirstCol: col,
+		LastRow: row + vcells, LastCol: col + hcells,
+	}
+}
+
+// setMerge records in the index of merged ranges that the cell at row
+// and col is merged across hcells more columns and vcells more rows,
+// or isn't merged if both are zero.  If there is no index yet, the
+// cell is found when it is made.
+func (s *Sheet) setMerge(row, col, hcells, vcells int) {
+	if s.merged == nil {
+		return
+	}
+	origin := mergeOrigin{row, col}
+	if hcells == 0 && vcells == 0 {
+		delete(s.merged, origin)
+		return
+	}
+	s.merged[origin] = s.mergedRange(row, col, hcells, vcells)
+}
+
+// overlaps reports whether any cell is in both r and other.
+func (r *Range) overlaps(other *Range) bool {
+	return r.FirstRow <= other.LastRow && other.FirstRow <= r.LastRow &&
+		r.FirstCol <= other.LastCol && other.FirstCol <= r.LastCol
+}
+
+// MergedRanges returns the ranges of cells that are merged on the
+// Sheet, in the order of their top left cells.  Only cells merged by
+// Cell.Merge or MergeRange, or read from a file, are found, and not
+// those whose HMerge and VMerge are set directly.
+func (s *Sheet) MergedRanges() ([]*Range, error) {
+	index, err := s.mergedIndex()
+	if err != nil {
+		return nil, fmt.Errorf("MergedRanges: %w", err)
+	}
+	var ranges []*Range
+	for _, merged := range index {
+		r := *merged
+		ranges = append(ranges, &r)
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		if ranges[i].FirstRow != ranges[j].FirstRow {
+			return ranges[i].FirstRow < ranges[j].FirstRow
+		}
+		return ranges[i].FirstCol < ranges[j].FirstCol
+	})
+	return ranges, nil
+}
+
+// MergedRangeAt returns the merged range that covers the cell at the
+// zero based row and col, or nil if the cell isn't merged.
+func (s *Sheet) MergedRangeAt(row, col int) (*Range, error) {
+	index, err := s.mergedIndex()
+	if err != nil {
+		return nil, fmt.Errorf("MergedRangeAt: %w", err)
+	}
+	cell := &Range{FirstRow: row, FirstCol: col, LastRow: row, LastCol: col}
+	for _, merged := range index {
+		if merged.overlaps(cell) {
+			r := *merged
+			return &r, nil
+		}
+	}
+	return nil, nil
+}
+
+// parseMergeRef parses ref as a range of cells on the Sheet.
+func (s *Sheet) parseMergeRef(ref string) (*Range, error) {
+	r, err := formula.ParseRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	if r.Sheet != "" && !strings.EqualFold(r.Sheet, s.Name) {
+		return nil, fmt.Errorf("%q is not on sheet %q", ref, s.Name)
+	}
+	if r.IsWholeRows() || r.IsWholeCols() {
+		return nil, fmt.Errorf("cannot merge whole rows or columns: %q", ref)
+	}
+	return &Range{
+		Sheet:    s,
+		FirstRow: r.Row1, FirstCol: r.Col1,
+		LastRow: r.Row2, LastCol: r.Col2,
+	}, nil
+}
+
+// MergeRange merges the cells of ref, such as "B2:D4", into one.  The
+// value and style of the merged cell are those of the top left cell of
+// the range.  A range that overlaps a range that is already merged is
+// rejected.
+func (s *Sheet) MergeRange(ref string) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("MergeRange: %w", err)
+	}
+	r, err := s.parseMergeRef(ref)
+	if err != nil {
+		return wrap(err)
+	}
+	if r.FirstRow == r.LastRow && r.FirstCol == r.LastCol {
+		return wrap(fmt.Errorf("cannot merge the single cell %q", ref))
+	}
+	ranges, err := s.MergedRanges()
+	if err != nil {
+		return wrap(err)
+	}
+	for _, merged := range ranges {
+		if r.overlaps(merged) {
+			return wrap(fmt.Errorf("%q overlaps the merged range %q", ref, merged))
+		}
+	}
+
+	cell, err := s.Cell(r.FirstRow, r.FirstCol)
+	if err != nil {
+		return wrap(err)
+	}
+	cell.Merge(r.LastCol-r.FirstCol, r.LastRow-r.FirstRow)
+	return nil
+}
+
+// UnmergeRange splits every merged range that overlaps ref back into
+// separate cells.  It is not an error if none do.
+func (s *Sheet) UnmergeRange(ref string) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("UnmergeRange: %w", err)
+	}
+	r, err := s.parseMergeRef(ref)
+	if err != nil {
+		return wrap(err)
+	}
+	ranges, err := s.MergedRanges()
+	if err != nil {
+		return wrap(err)
+	}
+	for _, merged := range ranges {
+		if !r.overlaps(merged) {
+			continue
+		}
+		cell, err := s.Cell(merged.FirstRow, merged.FirstCol)
+		if err != nil {
+			return wrap(err)
+		}
+		cell.Merge(0, 0)
+	}
+	return nil
+}
+
+// fillMergedCells gives the cells covered by each merged range of the
+// Sheet the value of the top left cell of the range.  Cells beyond the
+// rows that were read are left alone.
+func (s *Sheet) fillMergedCells() error {
+	ranges, err := s.MergedRanges()
+	if err != nil {
+		return err
+	}
+	for _, r := range ranges {
+		origin, err := s.Cell(r.FirstRow, r.FirstCol)
+		if err != nil {
+			return err
+		}
+		cellType := origin.cellType
+		if cellType == CellTypeStringFormula {
+			cellType = CellTypeString
+		}
+		for row := r.FirstRow; row <= r.LastRow && row < s.MaxRow; row++ {
+			for col := r.FirstCol; col <= r.LastCol; col++ {
+				if row == r.FirstRow && col == r.FirstCol {
+					continue
+				}
+				cell, err := s.Cell(row, col)
+				if err != nil {
+					return err
+				}
+				cell.Value = origin.Value
+				cell.RichText = origin.RichText
+				cell.cellType = cellType
+				cell.NumFmt = origin.NumFmt
+				cell.parsedNumFmt = origin.parsedNumFmt
+				cell.date1904 = origin.date1904
+			}
+		}
+	}
+	return nil
+}
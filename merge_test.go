@@ -0,0 +1,162 @@
+package xlsx
+
+import (
+	"bytes"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestMergedRanges(t *testing.T) {
+	c := qt.New(t)
+
+	refs := func(c *qt.C, sheet *Sheet) []string {
+		ranges, err := sheet.MergedRanges()
+		c.Assert(err, qt.IsNil)
+		var s []string
+		for _, r := range ranges {
+			s = append(s, r.String())
+		}
+		return s
+	}
+	rangeAt := func(c *qt.C, sheet *Sheet, row, col int) *Range {
+		r, err := sheet.MergedRangeAt(row, col)
+		c.Assert(err, qt.IsNil)
+		return r
+	}
+
+	csRunO(c, "MergeAndUnmerge", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Report")
+		c.Assert(err, qt.IsNil)
+		setCells(c, sheet, map[string]interface{}{"B2": "Region", "F1": "Total"})
+
+		err = sheet.MergeRange("B2:D4")
+		c.Assert(err, qt.IsNil)
+		err = sheet.MergeRange("Report!F1:G1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(cellAt(c, sheet, "B2").HMerge, qt.Equals, 2)
+		c.Assert(cellAt(c, sheet, "B2").VMerge, qt.Equals, 2)
+		c.Assert(refs(c, sheet), qt.DeepEquals, []string{"Report!$F$1:$G$1", "Report!$B$2:$D$4"})
+
+		c.Assert(rangeAt(c, sheet, 3, 2).String(), qt.Equals, "Report!$B$2:$D$4")
+		c.Assert(rangeAt(c, sheet, 0, 6).String(), qt.Equals, "Report!$F$1:$G$1")
+		c.Assert(rangeAt(c, sheet, 4, 2), qt.IsNil)
+
+		err = sheet.MergeRange("D4:E5")
+		c.Assert(err, qt.ErrorMatches, `MergeRange: "D4:E5" overlaps the merged range "Report!\$B\$2:\$D\$4"`)
+		err = sheet.MergeRange("A1")
+		c.Assert(err, qt.ErrorMatches, `MergeRange: cannot merge the single cell "A1"`)
+		err = sheet.MergeRange("Other!A1:B2")
+		c.Assert(err, qt.ErrorMatches, `MergeRange: "Other!A1:B2" is not on sheet "Report"`)
+		err = sheet.MergeRange("A:B")
+		c.Assert(err, qt.ErrorMatches, `MergeRange: cannot merge whole rows or columns: "A:B"`)
+
+		xml := writtenParts(c, f)["xl/worksheets/sheet1.xml"]
+		c.Assert(xml, qt.Contains, `<mergeCell ref="B2:D4"`)
+		c.Assert(xml, qt.Contains, `<mergeCell ref="F1:G1"`)
+
+		f = reopen(c, f, option)
+		sheet = f.Sheets[0]
+		c.Assert(refs(c, sheet), qt.DeepEquals, []string{"Report!$F$1:$G$1", "Report!$B$2:$D$4"})
+
+		// Any cell of a merged range unmerges all of it.
+		err = sheet.UnmergeRange("C3")
+		c.Assert(err, qt.IsNil)
+		c.Assert(refs(c, sheet), qt.DeepEquals, []string{"Report!$F$1:$G$1"})
+		err = sheet.UnmergeRange("A10:B12")
+		c.Assert(err, qt.IsNil)
+		err = sheet.UnmergeRange("A1:Z100")
+		c.Assert(err, qt.IsNil)
+		c.Assert(refs(c, sheet), qt.HasLen, 0)
+		err = sheet.MergeRange("C3:D4")
+		c.Assert(err, qt.IsNil)
+		// Sheet names are matched regardless of case.
+		err = sheet.UnmergeRange("REPORT!D4")
+		c.Assert(err, qt.IsNil)
+		c.Assert(refs(c, sheet), qt.HasLen, 0)
+	})
+
+	csRunO(c, "FollowsMovedRowsAndCols", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Report")
+		c.Assert(err, qt.IsNil)
+		setCells(c, sheet, map[string]interface{}{"A1": "Title", "B2": "Region", "E5": "Total", "H8": "End"})
+		c.Assert(sheet.MergeRange("B2:C3"), qt.IsNil)
+		c.Assert(sheet.MergeRange("E5:F5"), qt.IsNil)
+		cellAt(c, sheet, "A1").Merge(0, 0)
+		cellAt(c, sheet, "H8").Merge(1, 0)
+
+		// The index agrees with the merged cells themselves.
+		walked := func() []string {
+			merged := sheet.merged
+			sheet.merged = nil
+			defer func() { sheet.merged = merged }()
+			return refs(c, sheet)
+		}
+
+		_, err = sheet.AddRowAtIndex(0)
+		c.Assert(err, qt.IsNil)
+		err = sheet.InsertColsAt(0, 1)
+		c.Assert(err, qt.IsNil)
+		want := []string{"Report!$C$3:$D$4", "Report!$F$6:$G$6", "Report!$I$9:$J$9"}
+		c.Assert(refs(c, sheet), qt.DeepEquals, want)
+		c.Assert(walked(), qt.DeepEquals, want)
+		c.Assert(rangeAt(c, sheet, 3, 3).String(), qt.Equals, "Report!$C$3:$D$4")
+
+		// Removing the top left cell of a range moves it, and
+		// removing all of a range unmerges it.
+		err = sheet.RemoveRowAtIndex(2)
+		c.Assert(err, qt.IsNil)
+		err = sheet.RemoveColsAt(5, 2)
+		c.Assert(err, qt.IsNil)
+		cellAt(c, sheet, "G8").Merge(0, 0)
+		want = []string{"Report!$C$3:$D$3"}
+		c.Assert(refs(c, sheet), qt.DeepEquals, want)
+		c.Assert(walked(), qt.DeepEquals, want)
+	})
+
+	csRunO(c, "FillMergedCells", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sales")
+		c.Assert(err, qt.IsNil)
+		setCells(c, sheet, map[string]interface{}{
+			"A1": "North", "B1": "Q1", "C1": 100,
+			"B2": "Q2", "C2": 120,
+			"A3": "South", "B3": 0.25,
+		})
+		cellAt(c, sheet, "B3").NumFmt = "0%"
+		c.Assert(sheet.MergeRange("A1:A2"), qt.IsNil)
+		c.Assert(sheet.MergeRange("B3:C3"), qt.IsNil)
+
+		var buf bytes.Buffer
+		err = f.Write(&buf)
+		c.Assert(err, qt.IsNil)
+
+		f, err = OpenBinary(buf.Bytes(), option)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cellAt(c, f.Sheets[0], "A2").Value, qt.Equals, "")
+
+		f, err = OpenBinary(buf.Bytes(), option, FillMergedCells)
+		c.Assert(err, qt.IsNil)
+		var got [][]string
+		err = f.Sheets[0].ForEachRow(func(r *Row) error {
+			var values []string
+			err := r.ForEachCell(func(c *Cell) error {
+				value, err := c.FormattedValue()
+				values = append(values, value)
+				return err
+			}, SkipEmptyCells)
+			got = append(got, values)
+			return err
+		})
+		c.Assert(err, qt.IsNil)
+		c.Assert(got, qt.DeepEquals, [][]string{
+			{"North", "Q1", "100"},
+			{"North", "Q2", "120"},
+			{"South", "25%", "25%"},
+		})
+		// The cells stay merged.
+		c.Assert(refs(c, f.Sheets[0]), qt.DeepEquals, []string{"Sales!$A$1:$A$2", "Sales!$B$3:$C$3"})
+	})
+}
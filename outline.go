@@ -0,0 +1,132 @@
+package xlsx
+
+import (
+	"fmt"
+
+	"github.com/tealeg/xlsx/v3/formula"
+)
+
+// maxOutlineLevel is the deepest that Excel nests outline groups.
+const maxOutlineLevel = 7
+
+// Outline says where the summary row and summary column of each
+// outline group of a Sheet are.  By default, as in Excel, the summary
+// row is the one below its group and the summary column the one to
+// its right.
+type Outline struct {
+	SummaryRowsAbove bool
+	SummaryColsLeft  bool
+}
+
+// GroupRows puts the zero based rows from to to, inclusive, in an
+// outline group, nested in any group that they are already in.  If
+// collapsed is true the rows are hidden and the summary row, as set
+// by Sheet.Outline, is marked as collapsed, so that Excel shows the
+// group as closed.
+func (s *Sheet) GroupRows(from, to int, collapsed bool) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("GroupRows: %w", err)
+	}
+	if from < 0 || to < from || to >= formula.MaxRows {
+		return wrap(fmt.Errorf("invalid rows %d to %d", from, to))
+	}
+
+	for i := from; i <= to; i++ {
+		row, err := s.Row(i)
+		if err != nil {
+			return wrap(err)
+		}
+		if row.GetOutlineLevel() >= maxOutlineLevel {
+			return wrap(fmt.Errorf("row %d is already in %d outline groups", i, maxOutlineLevel))
+		}
+	}
+	for i := from; i <= to; i++ {
+		row, err := s.Row(i)
+		if err != nil {
+			return wrap(err)
+		}
+		row.SetOutlineLevel(row.GetOutlineLevel() + 1)
+		if collapsed {
+			row.Hidden = true
+		}
+	}
+
+	summary := to + 1
+	if s.Outline.SummaryRowsAbove {
+		summary = from - 1
+	}
+	if collapsed && summary >= 0 && summary < formula.MaxRows {
+		row, err := s.Row(summary)
+		if err != nil {
+			return wrap(err)
+		}
+		row.Collapsed = true
+	}
+	return nil
+}
+
+// GroupCols puts the zero based columns from to to, inclusive, in an
+// outline group, in the same way that GroupRows does for rows.
+func (s *Sheet) GroupCols(from, to int, collapsed bool) error {
+	if from < 0 || to < from || to >= formula.MaxCols {
+		return fmt.Errorf("GroupCols: invalid columns %d to %d", from, to)
+	}
+
+	for i := from; i <= to; i++ {
+		col := s.Col(i)
+		if col != nil && col.OutlineLevel != nil && *col.OutlineLevel >= maxOutlineLevel {
+			return fmt.Errorf("GroupCols: column %d is already in %d outline groups", i, maxOutlineLevel)
+		}
+	}
+	// The ColStore counts columns from 1.
+	s.setCol(from+1, to+1, func(col *Col) {
+		var level uint8
+		if col.OutlineLevel != nil {
+			level = *col.OutlineLevel
+		}
+		col.SetOutlineLevel(level + 1)
+		if collapsed {
+			col.Hidden = bPtr(true)
+		}
+	})
+
+	summary := to + 1
+	if s.Outline.SummaryColsLeft {
+		summary = from - 1
+	}
+	if collapsed && summary >= 0 && summary < formula.MaxCols {
+		s.setCol(summary+1, summary+1, func(col *Col) {
+			col.Collapsed = bPtr(true)
+		})
+	}
+	return nil
+}
+
+// makeOutline says where the summary rows and columns of the Sheet
+// are in worksheet, unless they are where Excel expects them.
+func (s *Sheet) makeOutline(worksheet *xlsxWorksheet) {
+	if s.Outline == (Outline{}) {
+		return
+	}
+	outlinePr := &xlsxOutlinePr{}
+	if s.Outline.SummaryRowsAbove {
+		outlinePr.SummaryBelow = bPtr(false)
+	}
+	if s.Outline.SummaryColsLeft {
+		outlinePr.SummaryRight = bPtr(false)
+	}
+	worksheet.SheetPr.OutlinePr = outlinePr
+}
+
+// readOutline reads where the summary rows and columns of the sheet
+// are from worksheet.
+func readOutline(sheet *Sheet, worksheet *xlsxWorksheet) {
+	outlinePr := worksheet.SheetPr.OutlinePr
+	if outlinePr == nil {
+		return
+	}
+	sheet.Outline = Outline{
+		SummaryRowsAbove: outlinePr.SummaryBelow != nil && !*outlinePr.SummaryBelow,
+		SummaryColsLeft:  outlinePr.SummaryRight != nil && !*outlinePr.SummaryRight,
+	}
+}
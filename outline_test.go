@@ -0,0 +1,98 @@
+package xlsx
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestOutline(t *testing.T) {
+	c := qt.New(t)
+
+	csRunO(c, "GroupRows", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("P&L")
+		c.Assert(err, qt.IsNil)
+		setCells(c, sheet, map[string]interface{}{
+			"A1": "Revenue", "A2": "Product", "A3": "Services",
+			"A5": "Total", "B5": "=SUM(B2:B3)",
+		})
+
+		// Row 4 has no cells but must still be written.
+		err = sheet.GroupRows(1, 3, true)
+		c.Assert(err, qt.IsNil)
+		err = sheet.GroupRows(1, 2, false)
+		c.Assert(err, qt.IsNil)
+		err = sheet.GroupRows(-1, 2, false)
+		c.Assert(err, qt.ErrorMatches, `GroupRows: invalid rows -1 to 2`)
+
+		xml := writtenParts(c, f)["xl/worksheets/sheet1.xml"]
+		c.Assert(xml, qt.Contains, `<sheetFormatPr defaultRowHeight="12.85" outlineLevelRow="2"`)
+		c.Assert(xml, qt.Contains, `<row r="2" hidden="true"`)
+		c.Assert(xml, qt.Contains, `<row r="4" hidden="true" outlineLevel="1"`)
+		c.Assert(xml, qt.Contains, `<row r="5" collapsed="true"`)
+
+		f = reopen(c, f, option)
+		sheet = f.Sheets[0]
+		for i, want := range []struct {
+			level             uint8
+			hidden, collapsed bool
+		}{
+			{0, false, false},
+			{2, true, false},
+			{2, true, false},
+			{1, true, false},
+			{0, false, true},
+		} {
+			row, err := sheet.Row(i)
+			c.Assert(err, qt.IsNil)
+			c.Assert(row.GetOutlineLevel(), qt.Equals, want.level, qt.Commentf("row %d", i))
+			c.Assert(row.Hidden, qt.Equals, want.hidden, qt.Commentf("row %d", i))
+			c.Assert(row.Collapsed, qt.Equals, want.collapsed, qt.Commentf("row %d", i))
+		}
+
+		for i := 0; i < 5; i++ {
+			c.Assert(sheet.GroupRows(1, 1, false), qt.IsNil)
+		}
+		c.Assert(sheet.GroupRows(0, 1, false), qt.ErrorMatches, `GroupRows: row 1 is already in 7 outline groups`)
+	})
+
+	csRunO(c, "GroupCols", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		setCells(c, sheet, map[string]interface{}{"A1": "Q1", "D1": "Total"})
+		sheet.Outline = Outline{SummaryRowsAbove: true, SummaryColsLeft: true}
+
+		err = sheet.GroupCols(1, 4, false)
+		c.Assert(err, qt.IsNil)
+		err = sheet.GroupCols(2, 3, true)
+		c.Assert(err, qt.IsNil)
+		err = sheet.GroupCols(3, 2, true)
+		c.Assert(err, qt.ErrorMatches, `GroupCols: invalid columns 3 to 2`)
+
+		xml := writtenParts(c, f)["xl/worksheets/sheet1.xml"]
+		c.Assert(xml, qt.Contains, `<outlinePr summaryBelow="false" summaryRight="false"`)
+		c.Assert(xml, qt.Contains, `outlineLevelCol="2"`)
+
+		f = reopen(c, f, option)
+		sheet = f.Sheets[0]
+		c.Assert(sheet.Outline, qt.Equals, Outline{SummaryRowsAbove: true, SummaryColsLeft: true})
+		for i, want := range []struct {
+			level             uint8
+			hidden, collapsed bool
+		}{
+			{1, false, true},
+			{2, true, false},
+			{2, true, false},
+			{1, false, false},
+		} {
+			col := sheet.Col(i + 1)
+			c.Assert(col, qt.Not(qt.IsNil), qt.Commentf("column %d", i+1))
+			c.Assert(*col.OutlineLevel, qt.Equals, want.level, qt.Commentf("column %d", i+1))
+			c.Assert(col.Hidden != nil && *col.Hidden, qt.Equals, want.hidden, qt.Commentf("column %d", i+1))
+			c.Assert(col.Collapsed != nil && *col.Collapsed, qt.Equals, want.collapsed, qt.Commentf("column %d", i+1))
+		}
+		c.Assert(sheet.Col(0), qt.IsNil)
+	})
+}
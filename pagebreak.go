@@ -0,0 +1,144 @@
+package xlsx
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tealeg/xlsx/v3/formula"
+)
+
+// Manual page breaks force a new printed page to begin at a row or
+// column of a Sheet, so that each section of a report starts on a
+// page of its own.  Excel ignores them when the Sheet is scaled to
+// fit a number of pages, as set by PageSetup.FitToPage.
+
+// AddRowBreak starts a new printed page at the zero based row, which
+// can't be the first.  Adding a break that is already there does
+// nothing.
+func (s *Sheet) AddRowBreak(row int) error {
+	if row <= 0 || row >= formula.MaxRows {
+		return fmt.Errorf("AddRowBreak: index out of range: %d", row)
+	}
+	s.rowBreaks = addBreak(s.rowBreaks, row)
+	return nil
+}
+
+// RemoveRowBreak removes the page break before the zero based row,
+// if there is one.
+func (s *Sheet) RemoveRowBreak(row int) {
+	s.rowBreaks = removeBreak(s.rowBreaks, row)
+}
+
+// RowBreaks returns the zero based rows that start a new printed
+// page, in order.
+func (s *Sheet) RowBreaks() []int {
+	return append([]int(nil), s.rowBreaks...)
+}
+
+// AddColBreak starts a new printed page at the zero based column,
+// which can't be the first.  Adding a break that is already there
+// does nothing.
+func (s *Sheet) AddColBreak(col int) error {
+	if col <= 0 || col >= formula.MaxCols {
+		return fmt.Errorf("AddColBreak: index out of range: %d", col)
+	}
+	s.colBreaks = addBreak(s.colBreaks, col)
+	return nil
+}
+
+// RemoveColBreak removes the page break before the zero based column,
+// if there is one.
+func (s *Sheet) RemoveColBreak(col int) {
+	s.colBreaks = removeBreak(s.colBreaks, col)
+}
+
+// ColBreaks returns the zero based columns that start a new printed
+// page, in order.
+func (s *Sheet) ColBreaks() []int {
+	return append([]int(nil), s.colBreaks...)
+}
+
+// addBreak adds i to the sorted breaks, unless it is there already.
+func addBreak(breaks []int, i int) []int {
+	n := sort.SearchInts(breaks, i)
+	if n < len(breaks) && breaks[n] == i {
+		return breaks
+	}
+	breaks = append(breaks, 0)
+	copy(breaks[n+1:], breaks[n:])
+	breaks[n] = i
+	return breaks
+}
+
+// removeBreak removes i from the sorted breaks.
+func removeBreak(breaks []int, i int) []int {
+	n := sort.SearchInts(breaks, i)
+	if n == len(breaks) || breaks[n] != i {
+		return breaks
+	}
+	return append(breaks[:n], breaks[n+1:]...)
+}
+
+// pageBreaks adjusts the page breaks of the Sheet.  A break before a
+// row or column that is removed goes with it.
+func (adj refAdjustment) pageBreaks(s *Sheet) {
+	var rows []int
+	for _, row := range s.rowBreaks {
+		ref := formula.Ref{Col1: -1, Row1: row, Col2: -1, Row2: row}
+		if ref, ok := adj.ref(ref); ok && ref.Row1 > 0 {
+			rows = append(rows, ref.Row1)
+		}
+	}
+	s.rowBreaks = rows
+
+	var cols []int
+	for _, col := range s.colBreaks {
+		ref := formula.Ref{Col1: col, Row1: -1, Col2: col, Row2: -1}
+		if ref, ok := adj.ref(ref); ok && ref.Col1 > 0 {
+			cols = append(cols, ref.Col1)
+		}
+	}
+	s.colBreaks = cols
+}
+
+// makePageBreaks adds the page breaks of the Sheet to worksheet.
+// Each row break spans every column, and each column break every
+// row.
+func (s *Sheet) makePageBreaks(worksheet *xlsxWorksheet) {
+	worksheet.RowBreaks = makeXLSXPageBreaks(s.rowBreaks, formula.MaxCols-1)
+	worksheet.ColBreaks = makeXLSXPageBreaks(s.colBreaks, formula.MaxRows-1)
+}
+
+func makeXLSXPageBreaks(breaks []int, max int) *xlsxPageBreaks {
+	if len(breaks) == 0 {
+		return nil
+	}
+	xBreaks := &xlsxPageBreaks{
+		Count:            len(breaks),
+		ManualBreakCount: len(breaks),
+	}
+	for _, i := range breaks {
+		xBreaks.Brk = append(xBreaks.Brk, xlsxPageBreak{Id: i, Max: max, Man: true})
+	}
+	return xBreaks
+}
+
+// readPageBreaks reads the manual page breaks of the sheet from
+// worksheet.  Those that Excel placed itself are left for it to place
+// again.
+func readPageBreaks(sheet *Sheet, worksheet *xlsxWorksheet) {
+	read := func(xBreaks *xlsxPageBreaks) []int {
+		if xBreaks == nil {
+			return nil
+		}
+		var breaks []int
+		for _, brk := range xBreaks.Brk {
+			if brk.Man && brk.Id > 0 {
+				breaks = addBreak(breaks, brk.Id)
+			}
+		}
+		return breaks
+	}
+	sheet.rowBreaks = read(worksheet.RowBreaks)
+	sheet.colBreaks = read(worksheet.ColBreaks)
+}
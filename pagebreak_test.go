@@ -0,0 +1,73 @@
+package xlsx
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestPageBreaks(t *testing.T) {
+	c := qt.New(t)
+
+	csRunO(c, "RoundTrip", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Report")
+		c.Assert(err, qt.IsNil)
+		setCells(c, sheet, map[string]interface{}{"A1": "Section 1", "A21": "Section 2"})
+
+		c.Assert(sheet.AddRowBreak(40), qt.IsNil)
+		c.Assert(sheet.AddRowBreak(20), qt.IsNil)
+		c.Assert(sheet.AddRowBreak(20), qt.IsNil)
+		c.Assert(sheet.AddColBreak(5), qt.IsNil)
+		c.Assert(sheet.RowBreaks(), qt.DeepEquals, []int{20, 40})
+		c.Assert(sheet.ColBreaks(), qt.DeepEquals, []int{5})
+
+		c.Assert(sheet.AddRowBreak(0), qt.ErrorMatches, `AddRowBreak: index out of range: 0`)
+		c.Assert(sheet.AddColBreak(16384), qt.ErrorMatches, `AddColBreak: index out of range: 16384`)
+
+		xml := writtenParts(c, f)["xl/worksheets/sheet1.xml"]
+		c.Assert(xml, qt.Contains, `<rowBreaks count="2" manualBreakCount="2"><brk id="20" max="16383" man="true"`)
+		c.Assert(xml, qt.Contains, `<colBreaks count="1" manualBreakCount="1"><brk id="5" max="1048575" man="true"`)
+
+		f = reopen(c, f, option)
+		sheet = f.Sheets[0]
+		c.Assert(sheet.RowBreaks(), qt.DeepEquals, []int{20, 40})
+		c.Assert(sheet.ColBreaks(), qt.DeepEquals, []int{5})
+
+		sheet.RemoveRowBreak(40)
+		sheet.RemoveRowBreak(41)
+		sheet.RemoveColBreak(5)
+		c.Assert(sheet.RowBreaks(), qt.DeepEquals, []int{20})
+		c.Assert(sheet.ColBreaks(), qt.HasLen, 0)
+		xml = writtenParts(c, f)["xl/worksheets/sheet1.xml"]
+		c.Assert(xml, qt.Not(qt.Contains), "<colBreaks")
+	})
+
+	csRunO(c, "Move", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Report")
+		c.Assert(err, qt.IsNil)
+		setCells(c, sheet, map[string]interface{}{"A1": 1, "A11": 2, "A21": 3, "E1": 4})
+		c.Assert(sheet.AddRowBreak(10), qt.IsNil)
+		c.Assert(sheet.AddRowBreak(20), qt.IsNil)
+		c.Assert(sheet.AddColBreak(2), qt.IsNil)
+		c.Assert(sheet.AddColBreak(4), qt.IsNil)
+
+		_, err = sheet.AddRowAtIndex(5)
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.RowBreaks(), qt.DeepEquals, []int{11, 21})
+
+		// A break goes with the row that it is before.
+		err = sheet.RemoveRowAtIndex(11)
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.RowBreaks(), qt.DeepEquals, []int{20})
+
+		err = sheet.InsertColsAt(0, 2)
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.ColBreaks(), qt.DeepEquals, []int{4, 6})
+		err = sheet.RemoveColsAt(3, 2)
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.ColBreaks(), qt.DeepEquals, []int{4})
+		c.Assert(sheet.RowBreaks(), qt.DeepEquals, []int{20})
+	})
+}
@@ -0,0 +1,542 @@
+package xlsx
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tealeg/xlsx/v3/formula"
+)
+
+// PaperSize is a size of paper, as numbered by Excel.
+type PaperSize int
+
+// The paper sizes that reports are most often printed on.
+const (
+	PaperLetter    PaperSize = 1
+	PaperTabloid   PaperSize = 3
+	PaperLedger    PaperSize = 4
+	PaperLegal     PaperSize = 5
+	PaperExecutive PaperSize = 7
+	PaperA3        PaperSize = 8
+	PaperA4        PaperSize = 9
+	PaperA5        PaperSize = 11
+	PaperB4        PaperSize = 12
+	PaperB5        PaperSize = 13
+)
+
+// Orientation is the way round that pages are printed.
+type Orientation string
+
+const (
+	OrientationPortrait  Orientation = "portrait"
+	OrientationLandscape Orientation = "landscape"
+)
+
+// PageOrder is the order in which the pages of a Sheet that is more
+// than a page wide and a page high are printed.
+type PageOrder string
+
+const (
+	PageOrderDownThenOver PageOrder = "downThenOver"
+	PageOrderOverThenDown PageOrder = "overThenDown"
+)
+
+// PageSetup says how a Sheet is laid out on the printed page.  Zero
+// values leave the choice to the printer, or to Excel's defaults.
+type PageSetup struct {
+	PaperSize   PaperSize
+	Orientation Orientation
+	// Scale is the percentage, from 10 to 400, that the Sheet is
+	// scaled by.  It is ignored when FitToPage is set.
+	Scale int
+	// FitToPage scales the Sheet to fit FitToWidth pages across
+	// and FitToHeight pages down; either may be 0, for as many
+	// pages as are needed.
+	FitToPage   bool
+	FitToWidth  int
+	FitToHeight int
+	// FirstPageNumber, if it isn't 0, is the number of the first
+	// printed page, rather than 1.
+	FirstPageNumber int
+	PageOrder       PageOrder
+	BlackAndWhite   bool
+	Draft           bool
+	Copies          int
+}
+
+// PrintOptions are the options for printing a Sheet.
+type PrintOptions struct {
+	// GridLines prints the grid lines between cells.
+	GridLines bool
+	// Headings prints the row numbers and column letters.
+	Headings bool
+	// HorizontalCentered and VerticalCentered center the Sheet
+	// on the page.
+	HorizontalCentered bool
+	VerticalCentered   bool
+}
+
+// PageMargins are the margins of the printed page, and the distances
+// of the header and footer from its edges, in inches.
+type PageMargins struct {
+	Left, Right float64
+	Top, Bottom float64
+	Header      float64
+	Footer      float64
+}
+
+// DefaultPageMargins returns the margins that Excel calls "Normal".
+func DefaultPageMargins() *PageMargins {
+	return &PageMargins{
+		Left: 0.7, Right: 0.7,
+		Top: 0.75, Bottom: 0.75,
+		Header: 0.3, Footer: 0.3,
+	}
+}
+
+// HeaderFooter holds the headers and footers of the printed pages of
+// a Sheet.  Each is written in Excel's codes, as made by a
+// HeaderFooterBuilder.  The odd header and footer are used for every
+// page unless DifferentOddEven or DifferentFirst say otherwise.
+type HeaderFooter struct {
+	OddHeader string
+	OddFooter string
+	// DifferentOddEven uses EvenHeader and EvenFooter for even
+	// numbered pages.
+	DifferentOddEven bool
+	EvenHeader       string
+	EvenFooter       string
+	// DifferentFirst uses FirstHeader and FirstFooter for the
+	// first page.
+	DifferentFirst bool
+	FirstHeader    string
+	FirstFooter    string
+}
+
+// HeaderFooterBuilder builds the text of a header or footer, which is
+// made of left, center and right sections.  For example:
+//
+//	var b HeaderFooterBuilder
+//	b.Left.SheetName()
+//	b.Right.Text("Page ").PageNumber().Text(" of ").PageCount()
+//	sheet.HeaderFooter = &HeaderFooter{OddFooter: b.String()}
+type HeaderFooterBuilder struct {
+	Left, Center, Right HeaderFooterSection
+}
+
+// String returns the header or footer in Excel's codes.
+func (b *HeaderFooterBuilder) String() string {
+	var s strings.Builder
+	for _, section := range []struct {
+		code    string
+		section *HeaderFooterSection
+	}{{"&L", &b.Left}, {"&C", &b.Center}, {"&R", &b.Right}} {
+		if section.section.text.Len() > 0 {
+			s.WriteString(section.code)
+			s.WriteString(section.section.text.String())
+		}
+	}
+	return s.String()
+}
+
+// HeaderFooterSection is a section of a header or footer.  Its
+// methods add to the section and return it, so that calls can be
+// chained.
+type HeaderFooterSection struct {
+	text strings.Builder
+	// afterSize is set when the last thing added was a font size,
+	// which a digit can't follow directly.
+	afterSize bool
+}
+
+// code adds a code to the section.
+func (s *HeaderFooterSection) code(code string) *HeaderFooterSection {
+	s.text.WriteString(code)
+	s.afterSize = false
+	return s
+}
+
+// Text adds literal text, in which "&" has no special meaning.
+func (s *HeaderFooterSection) Text(text string) *HeaderFooterSection {
+	if text == "" {
+		return s
+	}
+	if s.afterSize && text[0] >= '0' && text[0] <= '9' {
+		s.text.WriteByte(' ')
+	}
+	return s.code(strings.Replace(text, "&", "&&", -1))
+}
+
+// PageNumber adds the number of the page.
+func (s *HeaderFooterSection) PageNumber() *HeaderFooterSection {
+	return s.code("&P")
+}
+
+// PageCount adds the number of pages.
+func (s *HeaderFooterSection) PageCount() *HeaderFooterSection {
+	return s.code("&N")
+}
+
+// Date adds the date that the Sheet is printed.
+func (s *HeaderFooterSection) Date() *HeaderFooterSection {
+	return s.code("&D")
+}
+
+// Time adds the time that the Sheet is printed.
+func (s *HeaderFooterSection) Time() *HeaderFooterSection {
+	return s.code("&T")
+}
+
+// SheetName adds the name of the Sheet.
+func (s *HeaderFooterSection) SheetName() *HeaderFooterSection {
+	return s.code("&A")
+}
+
+// FileName adds the name of the file.
+func (s *HeaderFooterSection) FileName() *HeaderFooterSection {
+	return s.code("&F")
+}
+
+// FilePath adds the path of the file.
+func (s *HeaderFooterSection) FilePath() *HeaderFooterSection {
+	return s.code("&Z")
+}
+
+// Font sets the font, and its style, such as "Bold" or "Bold
+// Italic", of what follows.  An empty name keeps the font, and an
+// empty style is "Regular".
+func (s *HeaderFooterSection) Font(name, style string) *HeaderFooterSection {
+	if name == "" {
+		name = "-"
+	}
+	if style == "" {
+		style = "Regular"
+	}
+	return s.code(`&"` + name + "," + style + `"`)
+}
+
+// FontSize sets the size, in points, of the font of what follows.
+func (s *HeaderFooterSection) FontSize(points int) *HeaderFooterSection {
+	s.code("&" + strconv.Itoa(points))
+	s.afterSize = true
+	return s
+}
+
+// Color sets the color of what follows, given as six hexadecimal
+// digits, such as "FF0000".
+func (s *HeaderFooterSection) Color(rgb string) *HeaderFooterSection {
+	return s.code("&K" + strings.ToUpper(rgb))
+}
+
+// Bold turns bold on, or off if it is on.
+func (s *HeaderFooterSection) Bold() *HeaderFooterSection {
+	return s.code("&B")
+}
+
+// Italic turns italics on, or off if they are on.
+func (s *HeaderFooterSection) Italic() *HeaderFooterSection {
+	return s.code("&I")
+}
+
+// Underline turns underlining on, or off if it is on.
+func (s *HeaderFooterSection) Underline() *HeaderFooterSection {
+	return s.code("&U")
+}
+
+// Strikethrough turns strikethrough on, or off if it is on.
+func (s *HeaderFooterSection) Strikethrough() *HeaderFooterSection {
+	return s.code("&S")
+}
+
+// SetPrintArea sets the part of the Sheet that is printed to ref,
+// such as "A1:F40", or to several ranges separated by commas.  An
+// empty ref prints the whole Sheet.  The Sheet must be in a File, as
+// the print area is kept as a defined name.
+func (s *Sheet) SetPrintArea(ref string) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("SetPrintArea: %w", err)
+	}
+
+	var refs []string
+	for _, part := range strings.Split(ref, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		r, err := s.printRef(part)
+		if err != nil {
+			return wrap(err)
+		}
+		refs = append(refs, r.String())
+	}
+	err := s.setBuiltInName(DefinedNamePrintArea, strings.Join(refs, ","))
+	if err != nil {
+		return wrap(err)
+	}
+	return nil
+}
+
+// PrintArea returns the part of the Sheet that is printed, such as
+// "$A$1:$F$40", or an empty string if the whole Sheet is printed.
+func (s *Sheet) PrintArea() string {
+	refs := s.builtInNameRefs(DefinedNamePrintArea)
+	parts := make([]string, len(refs))
+	for i, ref := range refs {
+		parts[i] = ref.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// SetPrintTitles sets the rows, such as "1:2", and the columns, such
+// as "A:A", that are repeated on every printed page.  Either may be
+// empty, and if both are empty the print titles are removed.  The
+// Sheet must be in a File, as the print titles are kept as a defined
+// name.
+func (s *Sheet) SetPrintTitles(rows, cols string) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("SetPrintTitles: %w", err)
+	}
+
+	var refs []string
+	if cols != "" {
+		r, err := s.printRef(cols)
+		if err != nil {
+			return wrap(err)
+		}
+		if !r.IsWholeCols() {
+			return wrap(fmt.Errorf("%q is not a range of columns", cols))
+		}
+		refs = append(refs, r.String())
+	}
+	if rows != "" {
+		r, err := s.printRef(rows)
+		if err != nil {
+			return wrap(err)
+		}
+		if !r.IsWholeRows() {
+			return wrap(fmt.Errorf("%q is not a range of rows", rows))
+		}
+		refs = append(refs, r.String())
+	}
+	err := s.setBuiltInName(DefinedNamePrintTitles, strings.Join(refs, ","))
+	if err != nil {
+		return wrap(err)
+	}
+	return nil
+}
+
+// PrintTitles returns the rows, such as "$1:$2", and the columns,
+// such as "$A:$A", that are repeated on every printed page.
+func (s *Sheet) PrintTitles() (rows, cols string) {
+	for _, ref := range s.builtInNameRefs(DefinedNamePrintTitles) {
+		if ref.IsWholeRows() {
+			rows = ref.String()
+		} else if ref.IsWholeCols() {
+			cols = ref.String()
+		}
+	}
+	return rows, cols
+}
+
+// printRef parses ref, which may be qualified by the name of the
+// Sheet, and returns it as an absolute reference to the Sheet.
+func (s *Sheet) printRef(ref string) (formula.Ref, error) {
+	r, err := formula.ParseRef(ref)
+	if err != nil {
+		return r, err
+	}
+	if r.Sheet != "" && !strings.EqualFold(r.Sheet, s.Name) {
+		return r, fmt.Errorf("%q is not on the sheet %q", ref, s.Name)
+	}
+	r.Sheet = s.Name
+	r.AbsCol1, r.AbsRow1, r.AbsCol2, r.AbsRow2 = true, true, true, true
+	return r, nil
+}
+
+// setBuiltInName replaces the built-in name local to the Sheet with
+// one that refers to refersTo, or removes it if refersTo is empty.
+func (s *Sheet) setBuiltInName(name, refersTo string) error {
+	f := s.File
+	if f == nil {
+		return errors.New("the sheet is not in a File")
+	}
+	var names []*DefinedName
+	for _, dn := range f.DefinedNames {
+		if dn.Scope != s || !strings.EqualFold(dn.Name, name) {
+			names = append(names, dn)
+		}
+	}
+	old := f.DefinedNames
+	f.DefinedNames = names
+	if refersTo == "" {
+		return nil
+	}
+	_, err := f.AddDefinedName(name, refersTo, s)
+	if err != nil {
+		f.DefinedNames = old
+		return err
+	}
+	return nil
+}
+
+// builtInNameRefs returns the references, without the name of the
+// Sheet, of the built-in name local to the Sheet, if it has one.
+func (s *Sheet) builtInNameRefs(name string) []formula.Ref {
+	for _, dn := range s.DefinedNames() {
+		if !strings.EqualFold(dn.Name, name) {
+			continue
+		}
+		refs, err := unionRefs(dn.RefersTo)
+		if err != nil {
+			return nil
+		}
+		for i := range refs {
+			refs[i].Sheet = ""
+		}
+		return refs
+	}
+	return nil
+}
+
+// makePageSetup adds the page setup, print options, margins and
+// header and footer of the Sheet, those that it has, to worksheet.
+func (s *Sheet) makePageSetup(worksheet *xlsxWorksheet) {
+	if p := s.PrintOptions; p != nil {
+		worksheet.PrintOptions = &xlsxPrintOptions{
+			HorizontalCentered: p.HorizontalCentered,
+			VerticalCentered:   p.VerticalCentered,
+			Headings:           p.Headings,
+			GridLines:          p.GridLines,
+		}
+	}
+	if m := s.Margins; m != nil {
+		worksheet.PageMargins = &xlsxPageMargins{
+			Left:   m.Left,
+			Right:  m.Right,
+			Top:    m.Top,
+			Bottom: m.Bottom,
+			Header: m.Header,
+			Footer: m.Footer,
+		}
+	}
+	if p := s.PageSetup; p != nil {
+		xPageSetup := &xlsxPageSetUp{
+			PaperSize:     int(p.PaperSize),
+			Scale:         p.Scale,
+			PageOrder:     string(p.PageOrder),
+			Orientation:   string(p.Orientation),
+			BlackAndWhite: p.BlackAndWhite,
+			Draft:         p.Draft,
+			Copies:        p.Copies,
+		}
+		if p.FitToPage {
+			worksheet.SheetPr.PageSetUpPr = []xlsxPageSetUpPr{{FitToPage: true}}
+			xPageSetup.FitToWidth = iPtr(p.FitToWidth)
+			xPageSetup.FitToHeight = iPtr(p.FitToHeight)
+		}
+		if p.FirstPageNumber != 0 {
+			xPageSetup.FirstPageNumber = p.FirstPageNumber
+			xPageSetup.UseFirstPageNumber = true
+		}
+		worksheet.PageSetUp = xPageSetup
+	}
+	if h := s.HeaderFooter; h != nil {
+		text := func(s string) *string {
+			if s == "" {
+				return nil
+			}
+			return sPtr(s)
+		}
+		xHeaderFooter := &xlsxHeaderFooter{
+			OddHeader: text(h.OddHeader),
+			OddFooter: text(h.OddFooter),
+		}
+		if h.DifferentOddEven {
+			xHeaderFooter.DifferentOddEven = bPtr(true)
+			xHeaderFooter.EvenHeader = text(h.EvenHeader)
+			xHeaderFooter.EvenFooter = text(h.EvenFooter)
+		}
+		if h.DifferentFirst {
+			xHeaderFooter.DifferentFirst = bPtr(true)
+			xHeaderFooter.FirstHeader = text(h.FirstHeader)
+			xHeaderFooter.FirstFooter = text(h.FirstFooter)
+		}
+		worksheet.HeaderFooter = xHeaderFooter
+	}
+}
+
+// readPageSetup reads the page setup, print options, margins and
+// header and footer of the sheet from worksheet.
+func readPageSetup(sheet *Sheet, worksheet *xlsxWorksheet) {
+	if p := worksheet.PrintOptions; p != nil {
+		sheet.PrintOptions = &PrintOptions{
+			GridLines:          p.GridLines,
+			Headings:           p.Headings,
+			HorizontalCentered: p.HorizontalCentered,
+			VerticalCentered:   p.VerticalCentered,
+		}
+	}
+	if m := worksheet.PageMargins; m != nil {
+		sheet.Margins = &PageMargins{
+			Left:   m.Left,
+			Right:  m.Right,
+			Top:    m.Top,
+			Bottom: m.Bottom,
+			Header: m.Header,
+			Footer: m.Footer,
+		}
+	}
+	fitToPage := false
+	for _, pr := range worksheet.SheetPr.PageSetUpPr {
+		fitToPage = fitToPage || pr.FitToPage
+	}
+	if p := worksheet.PageSetUp; p != nil || fitToPage {
+		if p == nil {
+			p = &xlsxPageSetUp{}
+		}
+		pageSetup := &PageSetup{
+			PaperSize:     PaperSize(p.PaperSize),
+			Orientation:   Orientation(p.Orientation),
+			Scale:         p.Scale,
+			FitToPage:     fitToPage,
+			PageOrder:     PageOrder(p.PageOrder),
+			BlackAndWhite: p.BlackAndWhite,
+			Draft:         p.Draft,
+			Copies:        p.Copies,
+		}
+		if fitToPage {
+			// Both default to a single page.
+			pageSetup.FitToWidth, pageSetup.FitToHeight = 1, 1
+			if p.FitToWidth != nil {
+				pageSetup.FitToWidth = *p.FitToWidth
+			}
+			if p.FitToHeight != nil {
+				pageSetup.FitToHeight = *p.FitToHeight
+			}
+		}
+		if p.UseFirstPageNumber {
+			pageSetup.FirstPageNumber = p.FirstPageNumber
+		}
+		sheet.PageSetup = pageSetup
+	}
+	if h := worksheet.HeaderFooter; h != nil {
+		text := func(s *string) string {
+			if s == nil {
+				return ""
+			}
+			return *s
+		}
+		sheet.HeaderFooter = &HeaderFooter{
+			OddHeader:        text(h.OddHeader),
+			OddFooter:        text(h.OddFooter),
+			DifferentOddEven: h.DifferentOddEven != nil && *h.DifferentOddEven,
+			EvenHeader:       text(h.EvenHeader),
+			EvenFooter:       text(h.EvenFooter),
+			DifferentFirst:   h.DifferentFirst != nil && *h.DifferentFirst,
+			FirstHeader:      text(h.FirstHeader),
+			FirstFooter:      text(h.FirstFooter),
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package xlsx
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestPageSetup(t *testing.T) {
+	c := qt.New(t)
+
+	c.Run("HeaderFooterBuilder", func(c *qt.C) {
+		var b HeaderFooterBuilder
+		c.Assert(b.String(), qt.Equals, "")
+
+		b.Left.Font("Arial", "Bold").FontSize(12).Text("2024 Q&A")
+		b.Right.Text("Page ").PageNumber().Text(" of ").PageCount()
+		c.Assert(b.String(), qt.Equals, `&L&"Arial,Bold"&12 2024 Q&&A&RPage &P of &N`)
+
+		b = HeaderFooterBuilder{}
+		b.Center.SheetName().Text(" - ").Date().Text(" ").Time()
+		b.Right.Font("", "").Bold().Italic().Underline().Strikethrough().Color("ff0000").FileName().FilePath()
+		c.Assert(b.String(), qt.Equals, `&C&A - &D &T&R&"-,Regular"&B&I&U&S&KFF0000&F&Z`)
+	})
+
+	csRunO(c, "RoundTrip", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Report")
+		c.Assert(err, qt.IsNil)
+		setCells(c, sheet, map[string]interface{}{"A1": "Total"})
+
+		var header, footer HeaderFooterBuilder
+		header.Center.Bold().Text("Monthly report")
+		footer.Right.Text("Page ").PageNumber().Text(" of ").PageCount()
+		sheet.PageSetup = &PageSetup{
+			PaperSize:       PaperA4,
+			Orientation:     OrientationLandscape,
+			FitToPage:       true,
+			FitToWidth:      1,
+			FirstPageNumber: 3,
+			PageOrder:       PageOrderOverThenDown,
+			BlackAndWhite:   true,
+		}
+		sheet.PrintOptions = &PrintOptions{GridLines: true, HorizontalCentered: true}
+		sheet.Margins = DefaultPageMargins()
+		sheet.Margins.Left = 0.5
+		sheet.HeaderFooter = &HeaderFooter{
+			OddHeader:      header.String(),
+			OddFooter:      footer.String(),
+			DifferentFirst: true,
+			FirstFooter:    "&CConfidential",
+		}
+
+		parts := writtenParts(c, f)
+		xml := parts["xl/worksheets/sheet1.xml"]
+		c.Assert(xml, qt.Contains, `<pageSetUpPr fitToPage="true"`)
+		c.Assert(xml, qt.Contains, `<printOptions horizontalCentered="true" gridLines="true"`)
+		c.Assert(xml, qt.Contains, `<pageMargins left="0.5" right="0.7" top="0.75" bottom="0.75" header="0.3" footer="0.3"`)
+		c.Assert(xml, qt.Contains, `<pageSetup paperSize="9" firstPageNumber="3" fitToWidth="1" fitToHeight="0" pageOrder="overThenDown" orientation="landscape" blackAndWhite="true" useFirstPageNumber="true"`)
+		c.Assert(xml, qt.Contains, `<headerFooter differentFirst="true"><oddHeader>&amp;C&amp;BMonthly report</oddHeader>`)
+
+		f = reopen(c, f, option)
+		sheet = f.Sheets[0]
+		c.Assert(sheet.PageSetup, qt.DeepEquals, &PageSetup{
+			PaperSize:       PaperA4,
+			Orientation:     OrientationLandscape,
+			FitToPage:       true,
+			FitToWidth:      1,
+			FitToHeight:     0,
+			FirstPageNumber: 3,
+			PageOrder:       PageOrderOverThenDown,
+			BlackAndWhite:   true,
+		})
+		c.Assert(sheet.PrintOptions, qt.DeepEquals, &PrintOptions{GridLines: true, HorizontalCentered: true})
+		c.Assert(sheet.Margins, qt.DeepEquals, &PageMargins{
+			Left: 0.5, Right: 0.7, Top: 0.75, Bottom: 0.75, Header: 0.3, Footer: 0.3,
+		})
+		c.Assert(sheet.HeaderFooter, qt.DeepEquals, &HeaderFooter{
+			OddHeader:      "&C&BMonthly report",
+			OddFooter:      "&RPage &P of &N",
+			DifferentFirst: true,
+			FirstFooter:    "&CConfidential",
+		})
+	})
+
+	csRunO(c, "Unset", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		_, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+
+		xml := writtenParts(c, f)["xl/worksheets/sheet1.xml"]
+		c.Assert(xml, qt.Not(qt.Contains), "<pageSetup")
+		c.Assert(xml, qt.Not(qt.Contains), "<headerFooter")
+
+		f = reopen(c, f, option)
+		sheet := f.Sheets[0]
+		c.Assert(sheet.PageSetup, qt.IsNil)
+		c.Assert(sheet.PrintOptions, qt.IsNil)
+		c.Assert(sheet.Margins, qt.IsNil)
+		c.Assert(sheet.HeaderFooter, qt.IsNil)
+	})
+
+	csRunO(c, "PrintAreaAndTitles", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sales Data")
+		c.Assert(err, qt.IsNil)
+		other, err := f.AddSheet("Other")
+		c.Assert(err, qt.IsNil)
+
+		err = sheet.SetPrintArea("A1:F40, H1:H40")
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.PrintArea(), qt.Equals, "$A$1:$F$40,$H$1:$H$40")
+		err = sheet.SetPrintArea("'Sales Data'!B2:C3")
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.PrintArea(), qt.Equals, "$B$2:$C$3")
+		err = sheet.SetPrintTitles("1:2", "A:A")
+		c.Assert(err, qt.IsNil)
+		c.Assert(other.PrintArea(), qt.Equals, "")
+
+		err = sheet.SetPrintArea("Other!A1")
+		c.Assert(err, qt.ErrorMatches, `SetPrintArea: "Other!A1" is not on the sheet "Sales Data"`)
+		err = sheet.SetPrintTitles("A1:B2", "")
+		c.Assert(err, qt.ErrorMatches, `SetPrintTitles: "A1:B2" is not a range of rows`)
+		err = sheet.SetPrintTitles("", "3:3")
+		c.Assert(err, qt.ErrorMatches, `SetPrintTitles: "3:3" is not a range of columns`)
+		stray, err := NewSheet("Stray")
+		c.Assert(err, qt.IsNil)
+		err = stray.SetPrintArea("A1")
+		c.Assert(err, qt.ErrorMatches, `SetPrintArea: the sheet is not in a File`)
+
+		workbook := writtenParts(c, f)["xl/workbook.xml"]
+		c.Assert(workbook, qt.Contains, `<definedName name="_xlnm.Print_Area" localSheetId="0">&#39;Sales Data&#39;!$B$2:$C$3</definedName>`)
+		c.Assert(workbook, qt.Contains, `<definedName name="_xlnm.Print_Titles" localSheetId="0">&#39;Sales Data&#39;!$A:$A,&#39;Sales Data&#39;!$1:$2</definedName>`)
+
+		f = reopen(c, f, option)
+		sheet = f.Sheets[0]
+		c.Assert(sheet.PrintArea(), qt.Equals, "$B$2:$C$3")
+		rows, cols := sheet.PrintTitles()
+		c.Assert(rows, qt.Equals, "$1:$2")
+		c.Assert(cols, qt.Equals, "$A:$A")
+
+		err = sheet.SetPrintTitles("", "")
+		c.Assert(err, qt.IsNil)
+		err = sheet.SetPrintArea("")
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.DefinedNames(), qt.HasLen, 0)
+	})
+}
@@ -0,0 +1,240 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"path"
+	"strings"
+)
+
+// PictureFormat is the image format of a Picture.
+type PictureFormat string
+
+const (
+	PictureFormatPNG  PictureFormat = "png"
+	PictureFormatJPEG PictureFormat = "jpeg"
+	PictureFormatGIF  PictureFormat = "gif"
+)
+
+// mediaContentTypes are the content types of the image formats, other
+// than those of PictureFormat, that pictures read from a file may be
+// in, by the extension of their media part.
+var mediaContentTypes = map[string]string{
+	"bmp":  "image/bmp",
+	"emf":  "image/x-emf",
+	"svg":  "image/svg+xml",
+	"tif":  "image/tiff",
+	"tiff": "image/tiff",
+	"wdp":  "image/vnd.ms-photo",
+	"wmf":  "image/x-wmf",
+}
+
+// AnchorType determines how a Picture or Chart is tied to the cells
+// of its Sheet.
+type AnchorType int
+
+const (
+	// OneCellAnchor ties the top left corner of a Picture to a
+	// cell, so that the Picture moves with the cell but keeps its
+	// size.
+	OneCellAnchor AnchorType = iota
+	// TwoCellAnchor ties the top left and bottom right corners of
+	// a Picture to cells, so that the Picture moves and resizes
+	// with them.
+	TwoCellAnchor
+	// AbsoluteAnchor places a Picture at a fixed position on the
+	// Sheet.
+	AbsoluteAnchor
+)
+
+// Anchor describes where a Picture or Chart is placed on a Sheet.
+// Offsets are in pixels.
+type Anchor struct {
+	Type AnchorType
+	// Col and Row are the zero based column and row of the cell
+	// that the top left corner of the Picture is in, and OffsetX
+	// and OffsetY how far into the cell the corner is.  For an
+	// AbsoluteAnchor, OffsetX and OffsetY are the position of the
+	// corner on the Sheet, and Col and Row aren't used.
+	Col, Row         int
+	OffsetX, OffsetY int
+	// ToCol, ToRow, ToOffsetX and ToOffsetY place the bottom right
+	// corner of a Picture with a TwoCellAnchor in the same way.  If
+	// they are all zero, the corner is worked out when the File is
+	// written from the size of the Picture or Chart, the widths of
+	// the columns it covers and the default height of rows.
+	ToCol, ToRow         int
+	ToOffsetX, ToOffsetY int
+	// ScaleX and ScaleY scale the width and height of the Picture
+	// or Chart.  Zero means 1.  They aren't used with a
+	// TwoCellAnchor that has its bottom right corner set.
+	ScaleX, ScaleY float64
+}
+
+// validate returns an error if the Anchor has a negative position or
+// scale.
+func (a Anchor) validate() error {
+	if a.Col < 0 || a.Row < 0 || a.ToCol < 0 || a.ToRow < 0 ||
+		a.OffsetX < 0 || a.OffsetY < 0 || a.ToOffsetX < 0 || a.ToOffsetY < 0 {
+		return errors.New("anchor positions must not be negative")
+	}
+	if a.ScaleX < 0 || a.ScaleY < 0 {
+		return errors.New("anchor scales must not be negative")
+	}
+	return nil
+}
+
+// Picture is an image placed on a Sheet.  A Picture read from a file
+// may be in a format other than those of PictureFormat, such as emf or
+// tiff, in which case its Format is the extension of the image's part
+// and Data is written back as it was read.
+type Picture struct {
+	Data   []byte
+	Format PictureFormat
+	Anchor Anchor
+	// Name identifies the Picture in Excel's selection pane.
+	Name string
+	// AltText describes the Picture for those who can't see it.
+	AltText string
+	// width and height are the size of the image in pixels.
+	width, height int
+	// contentType is the content type of an image in a format other
+	// than those of PictureFormat, and cx and cy the size it is
+	// shown at in EMUs, as its size in pixels isn't known.
+	contentType string
+	cx, cy      int64
+}
+
+// AddPicture places the image data, which must be in the given
+// format, on the Sheet at anchor and returns the new Picture.
+func (s *Sheet) AddPicture(data []byte, format PictureFormat, anchor Anchor) (*Picture, error) {
+	wrap := func(err error) (*Picture, error) {
+		return nil, fmt.Errorf("AddPicture: %w", err)
+	}
+
+	switch format {
+	case PictureFormatPNG, PictureFormatJPEG, PictureFormatGIF:
+	default:
+		return wrap(fmt.Errorf("unsupported picture format %q", format))
+	}
+	config, decoded, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return wrap(fmt.Errorf("image.DecodeConfig: %w", err))
+	}
+	if decoded != string(format) {
+		return wrap(fmt.Errorf("image is %s, not %s", decoded, format))
+	}
+	if err := anchor.validate(); err != nil {
+		return wrap(err)
+	}
+
+	picture := &Picture{
+		Data:   data,
+		Format: format,
+		Anchor: anchor,
+		Name:   fmt.Sprintf("Picture %d", len(s.Pictures)+1),
+		width:  config.Width,
+		height: config.Height,
+	}
+	s.Pictures = append(s.Pictures, picture)
+	return picture, nil
+}
+
+// extent returns the width and height of the Picture, as shown, in
+// EMUs.
+func (p *Picture) extent() (int64, int64) {
+	if p.contentType != "" {
+		return p.cx, p.cy
+	}
+	scaleX, scaleY := p.Anchor.ScaleX, p.Anchor.ScaleY
+	if scaleX == 0 {
+		scaleX = 1
+	}
+	if scaleY == 0 {
+		scaleY = 1
+	}
+	return int64(float64(p.width)*scaleX*emusPerPixel + 0.5),
+		int64(float64(p.height)*scaleY*emusPerPixel + 0.5)
+}
+
+// makePictureParts adds the pictures of the Sheet, and the media
+// parts holding their images, to the drawing in sp.
+func (s *Sheet) makePictureParts(sp *sheetParts) {
+	for _, picture := range s.Pictures {
+		contentType := picture.contentType
+		if contentType == "" {
+			contentType = "image/" + string(picture.Format)
+		}
+		media := sp.addMedia(picture.Data, string(picture.Format), contentType)
+		dp := sp.drawing()
+		rId := dp.addRelation(RelationshipTypeImage, "../"+strings.TrimPrefix(media, "xl/"))
+		cx, cy := picture.extent()
+		dp.addAnchor(s, picture.Anchor, cx, cy, fmt.Sprintf(`<xdr:pic><xdr:nvPicPr>`+
+			`<xdr:cNvPr id="%d" name="%s" descr="%s"/><xdr:cNvPicPr><a:picLocks noChangeAspect="1"/></xdr:cNvPicPr>`+
+			`</xdr:nvPicPr><xdr:blipFill><a:blip r:embed="%s"/><a:stretch><a:fillRect/></a:stretch></xdr:blipFill>`+
+			`<xdr:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="%d" cy="%d"/></a:xfrm>`+
+			`<a:prstGeom prst="rect"><a:avLst/></a:prstGeom></xdr:spPr></xdr:pic>`,
+			dp.nextShapeId(), escapeXML(picture.Name), escapeXML(picture.AltText), rId, cx, cy))
+	}
+}
+
+// readPicture adds the picture xPic, placed on a drawing at anchor
+// with the size cx by cy EMUs, to the Sheet.  target returns the part
+// that a relationship of the drawing refers to.  Pictures in formats
+// that can't be decoded are kept as they are, with the size they are
+// shown at.
+func (s *Sheet) readPicture(xPic *xlsxPic, anchor Anchor, cx, cy int64, target func(id string) *zip.File) error {
+	mediaFile := target(xPic.BlipFill.Blip.Embed)
+	if mediaFile == nil {
+		return nil
+	}
+	data, err := readZipFile(mediaFile)
+	if err != nil {
+		return err
+	}
+	if xfrm := xPic.SpPr.Xfrm; xfrm != nil && xfrm.Ext != nil {
+		cx, cy = xfrm.Ext.Cx, xfrm.Ext.Cy
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		extension := strings.ToLower(strings.TrimPrefix(path.Ext(mediaFile.Name), "."))
+		if extension == "" {
+			extension = "bin"
+		}
+		contentType, ok := mediaContentTypes[extension]
+		if !ok {
+			contentType = "application/octet-stream"
+		}
+		s.Pictures = append(s.Pictures, &Picture{
+			Data:        data,
+			Format:      PictureFormat(extension),
+			Anchor:      anchor,
+			Name:        xPic.NvPicPr.CNvPr.Name,
+			AltText:     xPic.NvPicPr.CNvPr.Descr,
+			contentType: contentType,
+			cx:          cx,
+			cy:          cy,
+		})
+		return nil
+	}
+	if config.Width > 0 && config.Height > 0 && cx > 0 && cy > 0 {
+		anchor.ScaleX = float64(cx) / float64(config.Width*emusPerPixel)
+		anchor.ScaleY = float64(cy) / float64(config.Height*emusPerPixel)
+	}
+	s.Pictures = append(s.Pictures, &Picture{
+		Data:    data,
+		Format:  PictureFormat(format),
+		Anchor:  anchor,
+		Name:    xPic.NvPicPr.CNvPr.Name,
+		AltText: xPic.NvPicPr.CNvPr.Descr,
+		width:   config.Width,
+		height:  config.Height,
+	})
+	return nil
+}
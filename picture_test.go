@@ -0,0 +1,170 @@
+package xlsx
+
+import (
+	"bytes"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// testImage returns a width by height image encoded in format.
+func testImage(c *qt.C, format PictureFormat, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case PictureFormatPNG:
+		err = png.Encode(&buf, img)
+	case PictureFormatJPEG:
+		err = jpeg.Encode(&buf, img, nil)
+	case PictureFormatGIF:
+		err = gif.Encode(&buf, img, nil)
+	}
+	c.Assert(err, qt.IsNil)
+	return buf.Bytes()
+}
+
+func TestPictures(t *testing.T) {
+	c := qt.New(t)
+
+	setUp := func(c *qt.C, option FileOption) (*File, []byte) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Invoice")
+		c.Assert(err, qt.IsNil)
+		cellAt(c, sheet, "A1").SetString("Invoice")
+		logo := testImage(c, PictureFormatPNG, 40, 20)
+		picture, err := sheet.AddPicture(logo, PictureFormatPNG, Anchor{Col: 3, Row: 1, OffsetX: 5, OffsetY: 2, ScaleX: 2, ScaleY: 0.5})
+		c.Assert(err, qt.IsNil)
+		picture.AltText = `Acme & Co "logo"`
+		_, err = sheet.AddPicture(testImage(c, PictureFormatJPEG, 100, 50), PictureFormatJPEG, Anchor{Type: TwoCellAnchor, Col: 1, Row: 4})
+		c.Assert(err, qt.IsNil)
+		_, err = sheet.AddPicture(testImage(c, PictureFormatGIF, 10, 10), PictureFormatGIF, Anchor{Type: AbsoluteAnchor, OffsetX: 300, OffsetY: 10})
+		c.Assert(err, qt.IsNil)
+
+		catalog, err := f.AddSheet("Catalog")
+		c.Assert(err, qt.IsNil)
+		cellAt(c, catalog, "B2").Comment = NewComment("Sales", "Bestseller")
+		_, err = catalog.AddPicture(logo, PictureFormatPNG, Anchor{Type: TwoCellAnchor, Col: 0, Row: 0, ToCol: 2, ToRow: 3, ToOffsetX: 10})
+		c.Assert(err, qt.IsNil)
+		return f, logo
+	}
+
+	c.Run("AddPicture", func(c *qt.C) {
+		sheet, err := NewSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		png := testImage(c, PictureFormatPNG, 2, 2)
+		_, err = sheet.AddPicture(png, "bmp", Anchor{})
+		c.Assert(err, qt.ErrorMatches, `AddPicture: unsupported picture format "bmp"`)
+		_, err = sheet.AddPicture(png, PictureFormatJPEG, Anchor{})
+		c.Assert(err, qt.ErrorMatches, `AddPicture: image is png, not jpeg`)
+		_, err = sheet.AddPicture([]byte("not an image"), PictureFormatPNG, Anchor{})
+		c.Assert(err, qt.ErrorMatches, `AddPicture: image.DecodeConfig: .*`)
+		_, err = sheet.AddPicture(png, PictureFormatPNG, Anchor{Col: -1})
+		c.Assert(err, qt.ErrorMatches, `AddPicture: anchor positions must not be negative`)
+		picture, err := sheet.AddPicture(png, PictureFormatPNG, Anchor{})
+		c.Assert(err, qt.IsNil)
+		c.Assert(picture.Name, qt.Equals, "Picture 1")
+		c.Assert(sheet.Pictures, qt.HasLen, 1)
+		c.Assert(sheet.Pictures[0], qt.Equals, picture)
+	})
+
+	csRunParts(c, "Parts", func(c *qt.C, option FileOption) *File {
+		f, _ := setUp(c, option)
+		return f
+	}, func(c *qt.C, parts map[string]string) {
+		c.Assert(parts["xl/media/image1.png"], qt.Equals, string(testImage(c, PictureFormatPNG, 40, 20)))
+		c.Assert(parts["xl/media/image2.jpeg"], qt.Not(qt.Equals), "")
+		c.Assert(parts["xl/media/image3.gif"], qt.Not(qt.Equals), "")
+		_, ok := parts["xl/media/image4.png"]
+		c.Assert(ok, qt.Equals, false)
+
+		drawing := parts["xl/drawings/drawing1.xml"]
+		c.Assert(drawing, qt.Contains, `<xdr:oneCellAnchor><xdr:from><xdr:col>3</xdr:col><xdr:colOff>47625</xdr:colOff><xdr:row>1</xdr:row><xdr:rowOff>19050</xdr:rowOff></xdr:from><xdr:ext cx="762000" cy="95250"/>`)
+		c.Assert(drawing, qt.Contains, `<xdr:cNvPr id="2" name="Picture 1" descr="Acme &amp; Co &#34;logo&#34;"/>`)
+		c.Assert(drawing, qt.Contains, `<a:blip r:embed="rId1"/>`)
+		// 100 by 50 pixels from B5 spans one column and a half and
+		// two rows and a half.
+		c.Assert(drawing, qt.Contains, `<xdr:to><xdr:col>2</xdr:col><xdr:colOff>342900</xdr:colOff><xdr:row>6</xdr:row><xdr:rowOff>95250</xdr:rowOff></xdr:to>`)
+		c.Assert(drawing, qt.Contains, `<xdr:absoluteAnchor><xdr:pos x="2857500" y="95250"/><xdr:ext cx="95250" cy="95250"/>`)
+		c.Assert(strings.Count(drawing, `<xdr:clientData/>`), qt.Equals, 3)
+
+		drawingRels := parts["xl/drawings/_rels/drawing1.xml.rels"]
+		c.Assert(drawingRels, qt.Contains, `Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="../media/image2.jpeg"`)
+		c.Assert(parts["xl/drawings/_rels/drawing2.xml.rels"], qt.Contains, `Target="../media/image1.png"`)
+
+		c.Assert(parts["xl/worksheets/_rels/sheet1.xml.rels"], qt.Contains, `Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/drawing" Target="../drawings/drawing1.xml"`)
+		c.Assert(parts["xl/worksheets/sheet1.xml"], qt.Contains, `</sheetData><drawing r:id="rId1"`)
+		c.Assert(parts["xl/worksheets/sheet2.xml"], qt.Contains, `<drawing r:id="rId1"`)
+		c.Assert(parts["xl/worksheets/sheet2.xml"], qt.Contains, `<legacyDrawing r:id="rId3"`)
+		c.Assert(parts["xl/drawings/drawing2.xml"], qt.Contains, `<xdr:twoCellAnchor>`)
+
+		types := parts["[Content_Types].xml"]
+		c.Assert(types, qt.Contains, `<Default Extension="png" ContentType="image/png">`)
+		c.Assert(types, qt.Contains, `<Default Extension="jpeg" ContentType="image/jpeg">`)
+		c.Assert(types, qt.Contains, `<Override PartName="/xl/drawings/drawing2.xml" ContentType="application/vnd.openxmlformats-officedocument.drawing+xml">`)
+	})
+
+	csRunO(c, "RoundTrip", func(c *qt.C, option FileOption) {
+		f, logo := setUp(c, option)
+		f = reopen(c, f, option)
+
+		pictures := f.Sheets[0].Pictures
+		c.Assert(pictures, qt.HasLen, 3)
+		c.Assert(pictures[0].Data, qt.DeepEquals, logo)
+		c.Assert(pictures[0].Format, qt.Equals, PictureFormatPNG)
+		c.Assert(pictures[0].Name, qt.Equals, "Picture 1")
+		c.Assert(pictures[0].AltText, qt.Equals, `Acme & Co "logo"`)
+		c.Assert(pictures[0].Anchor, qt.Equals, Anchor{Col: 3, Row: 1, OffsetX: 5, OffsetY: 2, ScaleX: 2, ScaleY: 0.5})
+
+		c.Assert(pictures[1].Format, qt.Equals, PictureFormatJPEG)
+		c.Assert(pictures[1].Anchor, qt.Equals, Anchor{
+			Type: TwoCellAnchor, Col: 1, Row: 4,
+			ToCol: 2, ToRow: 6, ToOffsetX: 36, ToOffsetY: 10,
+			ScaleX: 1, ScaleY: 1,
+		})
+		c.Assert(pictures[2].Format, qt.Equals, PictureFormatGIF)
+		c.Assert(pictures[2].Anchor, qt.Equals, Anchor{Type: AbsoluteAnchor, OffsetX: 300, OffsetY: 10, ScaleX: 1, ScaleY: 1})
+
+		catalog := f.Sheets[1]
+		c.Assert(catalog.Pictures, qt.HasLen, 1)
+		c.Assert(catalog.Pictures[0].Anchor.ToCol, qt.Equals, 2)
+		c.Assert(cellAt(c, catalog, "B2").Comment.PlainText(), qt.Equals, "Bestseller")
+	})
+
+	csRunO(c, "UndecodableImage", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		cellAt(c, sheet, "A1").SetString("Logo")
+		_, err = sheet.AddPicture(testImage(c, PictureFormatPNG, 20, 10), PictureFormatPNG, Anchor{Col: 1, Row: 2})
+		c.Assert(err, qt.IsNil)
+
+		// Swap the image for one that Go can't decode, as Excel
+		// can write emf, wmf, tiff and svg images.
+		emf := "\x01\x00\x00\x00 not a decodable image"
+		parts := writtenParts(c, f)
+		delete(parts, "xl/media/image1.png")
+		parts["xl/media/image1.emf"] = emf
+		parts["xl/drawings/_rels/drawing1.xml.rels"] = strings.Replace(parts["xl/drawings/_rels/drawing1.xml.rels"], "image1.png", "image1.emf", 1)
+		parts["[Content_Types].xml"] = strings.Replace(parts["[Content_Types].xml"], `Extension="png" ContentType="image/png"`, `Extension="emf" ContentType="image/x-emf"`, 1)
+
+		f, err = OpenBinary(zipParts(c, parts), option)
+		c.Assert(err, qt.IsNil)
+		pictures := f.Sheets[0].Pictures
+		c.Assert(pictures, qt.HasLen, 1)
+		c.Assert(string(pictures[0].Data), qt.Equals, emf)
+		c.Assert(pictures[0].Format, qt.Equals, PictureFormat("emf"))
+		c.Assert(pictures[0].Anchor, qt.Equals, Anchor{Col: 1, Row: 2})
+
+		parts = writtenParts(c, f)
+		c.Assert(parts["xl/media/image1.emf"], qt.Equals, emf)
+		c.Assert(parts["[Content_Types].xml"], qt.Contains, `<Default Extension="emf" ContentType="image/x-emf">`)
+		c.Assert(parts["xl/drawings/drawing1.xml"], qt.Contains, `<xdr:ext cx="190500" cy="95250"/>`)
+		c.Assert(parts["xl/drawings/_rels/drawing1.xml.rels"], qt.Contains, `Target="../media/image1.emf"`)
+	})
+}
@@ -0,0 +1,1481 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tealeg/xlsx/v3/formula"
+)
+
+// PivotFunction is the function that a data field of a pivot table
+// summarises its values with.
+type PivotFunction string
+
+const (
+	PivotSum       PivotFunction = "sum"
+	PivotCount     PivotFunction = "count"
+	PivotAverage   PivotFunction = "average"
+	PivotMax       PivotFunction = "max"
+	PivotMin       PivotFunction = "min"
+	PivotProduct   PivotFunction = "product"
+	PivotCountNums PivotFunction = "countNums"
+	PivotStdDev    PivotFunction = "stdDev"
+	PivotStdDevP   PivotFunction = "stdDevp"
+	PivotVar       PivotFunction = "var"
+	PivotVarP      PivotFunction = "varp"
+)
+
+// PivotCache is the copy of the source data that one or more pivot
+// tables summarise, as it was when they were last refreshed.  The
+// records are often the only copy of the data in a workbook.
+type PivotCache struct {
+	// ID is the id that pivot tables refer to the cache by.
+	ID int
+	// SourceSheet and SourceRange are the sheet and range that the
+	// data was taken from, or SourceName the table or defined name
+	// that was.
+	SourceSheet string
+	SourceRange string
+	SourceName  string
+	// Fields are the columns of the data.
+	Fields []*PivotCacheField
+	// Records are the rows of the data, with a value for each
+	// field.  A value is a float64, string, bool or time.Time, or
+	// nil if it is missing.  Error values, such as "#N/A", are
+	// strings.  Records is empty if the workbook wasn't saved with
+	// them.
+	Records [][]interface{}
+
+	definition []byte // the pivotCacheDefinition part
+	records    []byte // the pivotCacheRecords part
+	recordsRID string // the id of the relationship to records
+}
+
+// PivotCacheField is a column of the data in a PivotCache.
+type PivotCacheField struct {
+	Name string
+	// Formula is set for a calculated field, which has no values
+	// in the records.
+	Formula string
+	// Items are the distinct values of the field that the records
+	// and pivot tables refer to.  It may be empty for a field of
+	// numbers or dates.
+	Items []interface{}
+
+	inRecords bool                // set if the field has values in the records
+	index     map[interface{}]int // the indexes of Items, for a field written by AddPivotTable
+}
+
+// PivotTable is a pivot table, which summarises the data in a
+// PivotCache.
+type PivotTable struct {
+	Name string
+	// Location is the range of cells that the pivot table is shown
+	// in, not including its filters.
+	Location string
+	Sheet    *Sheet
+	Cache    *PivotCache
+	// RowFields and ColFields are the names of the fields whose
+	// values label the rows and columns of the pivot table, from
+	// the outermost in.
+	RowFields []string
+	ColFields []string
+	// Filters are the fields that the data is filtered on.
+	Filters []PivotFilter
+	// DataFields are the fields whose values are summarised.
+	DataFields []PivotDataField
+	// DataOnRows is set if there is a row, rather than a column,
+	// for each of several DataFields.
+	DataOnRows bool
+
+	definition []byte // the pivotTableDefinition part
+}
+
+// PivotFilter is a field of a pivot table that its data is filtered
+// on.
+type PivotFilter struct {
+	Field string
+	// Item is the value that the data is restricted to, or nil if
+	// it isn't.
+	Item interface{}
+}
+
+// PivotDataField is a field of a pivot table whose values are
+// summarised.
+type PivotDataField struct {
+	// Name is the caption of the summarised values, such as "Sum of
+	// Amount".
+	Name     string
+	Field    string
+	Function PivotFunction
+}
+
+// PivotCaches returns the pivot caches of the File.
+func (f *File) PivotCaches() []*PivotCache {
+	return f.pivotCaches
+}
+
+// PivotTables returns the pivot tables on the Sheet.
+func (s *Sheet) PivotTables() []*PivotTable {
+	return s.pivotTables
+}
+
+// pivotCache returns the pivot cache of f with the id, or nil if
+// there isn't one.
+func (f *File) pivotCache(id int) *PivotCache {
+	for _, pc := range f.pivotCaches {
+		if pc.ID == id {
+			return pc
+		}
+	}
+	return nil
+}
+
+// field returns the name of the field with the index i, which is
+// empty if there isn't one.
+func (pc *PivotCache) field(i int) string {
+	if pc == nil || i < 0 || i >= len(pc.Fields) {
+		return ""
+	}
+	return pc.Fields[i].Name
+}
+
+// pivotCache returns the one based index of the part that the pivot
+// cache pc is written as.
+func (wp *workbookParts) pivotCache(pc *PivotCache) int {
+	for i, cache := range wp.pivotCaches {
+		if cache == pc {
+			return i + 1
+		}
+	}
+	wp.pivotCaches = append(wp.pivotCaches, pc)
+	return len(wp.pivotCaches)
+}
+
+// makePivotTableParts adds the parts holding the pivot tables of the
+// Sheet to sp.  The pivot caches that they refer to are added to the
+// workbook by makePivotCacheParts.
+func (s *Sheet) makePivotTableParts(sp *sheetParts) error {
+	for _, pt := range s.pivotTables {
+		if pt.Cache == nil || pt.definition == nil {
+			continue
+		}
+		sp.wp.pivotTables++
+		name := fmt.Sprintf("xl/pivotTables/pivotTable%d.xml", sp.wp.pivotTables)
+		sp.addPart(name, string(pt.definition),
+			"application/vnd.openxmlformats-officedocument.spreadsheetml.pivotTable+xml")
+		rels, err := xml.Marshal(xlsxWorksheetRels{Relationships: []xlsxWorksheetRelation{{
+			Id:     "rId1",
+			Type:   RelationshipTypePivotCacheDefinition,
+			Target: fmt.Sprintf("../pivotCache/pivotCacheDefinition%d.xml", sp.wp.pivotCache(pt.Cache)),
+		}}})
+		if err != nil {
+			return err
+		}
+		sp.addPart(relsPartName(name), xml.Header+string(rels), "")
+		sp.addRelation(RelationshipTypePivotTable, "../"+strings.TrimPrefix(name, "xl/"))
+	}
+	return nil
+}
+
+// makePivotCacheParts returns the parts holding the pivot caches of
+// f, and those that the pivot tables written so far refer to, adding
+// them to the workbook, its relationships and the content types.
+func (f *File) makePivotCacheParts(workbook *xlsxWorkbook, xWRel *xlsxWorkbookRels, types *xlsxTypes, wp *workbookParts) (*sheetParts, error) {
+	for _, pc := range f.pivotCaches {
+		wp.pivotCache(pc)
+	}
+	sp := newSheetParts(0, nil, types, wp)
+	for i, pc := range wp.pivotCaches {
+		name := fmt.Sprintf("xl/pivotCache/pivotCacheDefinition%d.xml", i+1)
+		sp.addPart(name, string(pc.definition),
+			"application/vnd.openxmlformats-officedocument.spreadsheetml.pivotCacheDefinition+xml")
+		if pc.records != nil && pc.recordsRID != "" {
+			recordsName := fmt.Sprintf("xl/pivotCache/pivotCacheRecords%d.xml", i+1)
+			sp.addPart(recordsName, string(pc.records),
+				"application/vnd.openxmlformats-officedocument.spreadsheetml.pivotCacheRecords+xml")
+			rels, err := xml.Marshal(xlsxWorksheetRels{Relationships: []xlsxWorksheetRelation{{
+				Id:     pc.recordsRID,
+				Type:   RelationshipTypePivotCacheRecords,
+				Target: path.Base(recordsName),
+			}}})
+			if err != nil {
+				return nil, err
+			}
+			sp.addPart(relsPartName(name), xml.Header+string(rels), "")
+		}
+		id := fmt.Sprintf("rId%d", len(xWRel.Relationships)+1)
+		xWRel.Relationships = append(xWRel.Relationships, xlsxWorkbookRelation{
+			Id:     id,
+			Target: strings.TrimPrefix(name, "xl/"),
+			Type:   string(RelationshipTypePivotCacheDefinition)})
+		if workbook.PivotCaches == nil {
+			workbook.PivotCaches = &xlsxPivotCaches{}
+		}
+		workbook.PivotCaches.PivotCache = append(workbook.PivotCaches.PivotCache, xlsxPivotCache{CacheId: pc.ID, Id: id})
+	}
+	return sp, nil
+}
+
+// readPivotCaches reads the pivot caches of workbook, which is read
+// from the part called name, into fi.
+func readPivotCaches(fi *File, workbook *xlsxWorkbook, name string) error {
+	if workbook.PivotCaches == nil {
+		return nil
+	}
+	wbRels := new(xlsxWorksheetRels)
+	if relsFile, ok := fi.zipFiles[relsPartName(name)]; ok {
+		err := decodeZipFile(relsFile, wbRels)
+		if err != nil {
+			return err
+		}
+	}
+	for _, xCache := range workbook.PivotCaches.PivotCache {
+		for _, rel := range wbRels.Relationships {
+			if rel.Id != xCache.Id {
+				continue
+			}
+			pc, err := readPivotCache(fi, resolveTarget(path.Dir(name), rel.Target))
+			if err != nil {
+				return fmt.Errorf("pivot cache %d: %w", xCache.CacheId, err)
+			}
+			if pc != nil {
+				pc.ID = xCache.CacheId
+				fi.pivotCaches = append(fi.pivotCaches, pc)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// readPivotCache reads the pivot cache definition part called name,
+// and its records.  It returns nil if there is no such part.
+func readPivotCache(fi *File, name string) (*PivotCache, error) {
+	defFile, ok := fi.zipFiles[name]
+	if !ok {
+		return nil, nil
+	}
+	definition, err := readZipFile(defFile)
+	if err != nil {
+		return nil, err
+	}
+	var xDef xlsxPivotCacheDefinition
+	err = xml.Unmarshal(definition, &xDef)
+	if err != nil {
+		return nil, fmt.Errorf("xml.Unmarshal: %w", err)
+	}
+	pc := &PivotCache{definition: definition, recordsRID: xDef.RID}
+	if src := xDef.CacheSource.WorksheetSource; src != nil {
+		pc.SourceSheet = src.Sheet
+		pc.SourceRange = src.Ref
+		pc.SourceName = src.Name
+	}
+	for _, xField := range xDef.CacheFields.CacheField {
+		field := &PivotCacheField{
+			Name:      xField.Name,
+			Formula:   xField.Formula,
+			inRecords: xField.Formula == "" && (xField.DatabaseField == nil || *xField.DatabaseField),
+		}
+		for _, item := range xField.SharedItems.Items {
+			v, err := pivotItemValue(item)
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %w", field.Name, err)
+			}
+			field.Items = append(field.Items, v)
+		}
+		pc.Fields = append(pc.Fields, field)
+	}
+
+	if xDef.RID == "" {
+		return pc, nil
+	}
+	defRels := new(xlsxWorksheetRels)
+	if relsFile, ok := fi.zipFiles[relsPartName(name)]; ok {
+		err = decodeZipFile(relsFile, defRels)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, rel := range defRels.Relationships {
+		if rel.Id != xDef.RID {
+			continue
+		}
+		recordsFile, ok := fi.zipFiles[resolveTarget(path.Dir(name), rel.Target)]
+		if !ok {
+			break
+		}
+		pc.records, err = readZipFile(recordsFile)
+		if err != nil {
+			return nil, err
+		}
+		err = pc.readRecords()
+		if err != nil {
+			return nil, err
+		}
+		break
+	}
+	return pc, nil
+}
+
+// readRecords fills in the Records of pc from its records part.
+func (pc *PivotCache) readRecords() error {
+	var xRecords xlsxPivotCacheRecords
+	err := xml.Unmarshal(pc.records, &xRecords)
+	if err != nil {
+		return fmt.Errorf("xml.Unmarshal: %w", err)
+	}
+	for i, xRecord := range xRecords.R {
+		record := make([]interface{}, len(pc.Fields))
+		j := 0
+		for _, item := range xRecord.Items {
+			for j < len(pc.Fields) && !pc.Fields[j].inRecords {
+				j++
+			}
+			if j == len(pc.Fields) {
+				return fmt.Errorf("record %d has more values than the cache has fields", i+1)
+			}
+			field := pc.Fields[j]
+			if item.XMLName.Local == "x" {
+				x, err := strconv.Atoi(item.V)
+				if err != nil || x < 0 || x >= len(field.Items) {
+					return fmt.Errorf("record %d: invalid item %q of field %q", i+1, item.V, field.Name)
+				}
+				record[j] = field.Items[x]
+			} else {
+				record[j], err = pivotItemValue(item)
+				if err != nil {
+					return fmt.Errorf("record %d: %w", i+1, err)
+				}
+			}
+			j++
+		}
+		pc.Records = append(pc.Records, record)
+	}
+	return nil
+}
+
+// pivotItemValue returns the value held by an item of a pivot cache.
+func pivotItemValue(item xlsxPivotItem) (interface{}, error) {
+	switch item.XMLName.Local {
+	case "n":
+		return strconv.ParseFloat(item.V, 64)
+	case "s", "e":
+		return item.V, nil
+	case "b":
+		return item.V == "1" || item.V == "true", nil
+	case "d":
+		return time.Parse("2006-01-02T15:04:05", item.V)
+	case "m":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown pivot cache item %q", item.XMLName.Local)
+}
+
+// readPivotTables adds the pivot tables, in the parts that the
+// worksheet relationships rels refer to, to sheet.
+func readPivotTables(fi *File, sheet *Sheet, rels *xlsxWorksheetRels) error {
+	for _, rel := range rels.Relationships {
+		if rel.Type != RelationshipTypePivotTable {
+			continue
+		}
+		ptFile, ok := fi.zipFiles[resolveTarget("xl/worksheets", rel.Target)]
+		if !ok {
+			continue
+		}
+		definition, err := readZipFile(ptFile)
+		if err != nil {
+			return err
+		}
+		var xPT xlsxPivotTableDefinition
+		err = xml.Unmarshal(definition, &xPT)
+		if err != nil {
+			return fmt.Errorf("xml.Unmarshal: %w", err)
+		}
+		pt := &PivotTable{
+			Name:       xPT.Name,
+			Location:   xPT.Location.Ref,
+			Sheet:      sheet,
+			Cache:      fi.pivotCache(xPT.CacheId),
+			DataOnRows: xPT.DataOnRows,
+			definition: definition,
+		}
+		fields := func(refs *xlsxPivotFieldRefs) []string {
+			var names []string
+			if refs == nil {
+				return names
+			}
+			for _, ref := range refs.Field {
+				// -2 places the data fields among the others.
+				if ref.X >= 0 {
+					names = append(names, pt.Cache.field(ref.X))
+				}
+			}
+			return names
+		}
+		pt.RowFields = fields(xPT.RowFields)
+		pt.ColFields = fields(xPT.ColFields)
+		if xPT.PageFields != nil {
+			for _, pf := range xPT.PageFields.PageField {
+				filter := PivotFilter{Field: pt.Cache.field(pf.Fld)}
+				if pf.Item != nil {
+					filter.Item = pt.pivotFieldItem(xPT.PivotFields, pf.Fld, *pf.Item)
+				}
+				pt.Filters = append(pt.Filters, filter)
+			}
+		}
+		if xPT.DataFields != nil {
+			for _, df := range xPT.DataFields.DataField {
+				function := PivotFunction(df.Subtotal)
+				if function == "" {
+					function = PivotSum
+				}
+				pt.DataFields = append(pt.DataFields, PivotDataField{
+					Name:     df.Name,
+					Field:    pt.Cache.field(df.Fld),
+					Function: function,
+				})
+			}
+		}
+		sheet.pivotTables = append(sheet.pivotTables, pt)
+	}
+	return nil
+}
+
+// pivotFieldItem returns the value of the item with the index item
+// of the pivot field with the index fld, or nil if there isn't one.
+func (pt *PivotTable) pivotFieldItem(fields xlsxPivotFields, fld, item int) interface{} {
+	if pt.Cache == nil || fld < 0 || fld >= len(fields.PivotField) || fld >= len(pt.Cache.Fields) {
+		return nil
+	}
+	items := fields.PivotField[fld].Items
+	if items == nil || item < 0 || item >= len(items.Item) || items.Item[item].X == nil {
+		return nil
+	}
+	x := *items.Item[item].X
+	if cacheItems := pt.Cache.Fields[fld].Items; x >= 0 && x < len(cacheItems) {
+		return cacheItems[x]
+	}
+	return nil
+}
+
+// PivotSpec describes a pivot table for AddPivotTable.  Fields are
+// named by the headings of the columns of its source range.
+type PivotSpec struct {
+	// Name is the name of the pivot table, which defaults to
+	// PivotTable1, PivotTable2 and so on.
+	Name string
+	// Location is the top left cell of the pivot table.  It
+	// defaults to A1, or, if there are Filters, to the cell below
+	// them and a blank row.
+	Location string
+	// Rows and Cols are the fields whose values label the rows and
+	// columns, from the outermost in.
+	Rows []string
+	Cols []string
+	// Data are the fields whose values are summarised.  Function
+	// is one of PivotSum, the default, PivotCount, PivotAverage,
+	// PivotMin and PivotMax, and Name defaults to one such as "Sum
+	// of Amount".
+	Data []PivotDataField
+	// Filters restrict the data to the records in which a field has
+	// the value Item, or, where Item is nil, show the field above
+	// the pivot table for the reader to filter on in Excel.
+	Filters []PivotFilter
+}
+
+// pivotFunctionNames are the functions that AddPivotTable supports,
+// with the names that the captions of data fields start with.
+var pivotFunctionNames = map[PivotFunction]string{
+	PivotSum:     "Sum",
+	PivotCount:   "Count",
+	PivotAverage: "Average",
+	PivotMin:     "Min",
+	PivotMax:     "Max",
+}
+
+// pivotVersion is the version of Excel that AddPivotTable writes pivot
+// tables for.
+const pivotVersion = 3
+
+// AddPivotTable adds a pivot table summarising the data in
+// sourceRange, a range including its sheet such as "Data!A1:D100"
+// whose first row holds the headings of the fields, to the target
+// Sheet, and returns it.  The pivot table is laid out in tabular form
+// and its values are written to the cells of target, so that they can
+// be read without Excel, which refreshes the pivot table from the
+// source data when it opens the File.
+func (f *File) AddPivotTable(sourceRange string, target *Sheet, spec PivotSpec) (*PivotTable, error) {
+	wrap := func(err error) (*PivotTable, error) {
+		return nil, fmt.Errorf("AddPivotTable: %w", err)
+	}
+
+	ref, err := formula.ParseRef(sourceRange)
+	if err != nil || ref.Sheet == "" || ref.IsWholeCols() || ref.IsWholeRows() {
+		return wrap(fmt.Errorf("invalid source range %q", sourceRange))
+	}
+	source, ok := f.Sheet[ref.Sheet]
+	if !ok {
+		return wrap(fmt.Errorf("no sheet called %q", ref.Sheet))
+	}
+	if target == nil || f.Sheet[target.Name] != target {
+		return wrap(errors.New("the target sheet is not in the File"))
+	}
+	if ref.Row2 == ref.Row1 {
+		return wrap(errors.New("the source range needs a heading row and at least one record"))
+	}
+	if len(spec.Data) == 0 {
+		return wrap(errors.New("a pivot table needs at least one data field"))
+	}
+
+	pc, err := f.readPivotSource(source, ref)
+	if err != nil {
+		return wrap(err)
+	}
+	p := &pivotLayout{cache: pc}
+	used := make(map[int]bool)
+	field := func(name string, axis bool) (int, error) {
+		for i, fld := range pc.Fields {
+			if fld.Name == name {
+				if axis && used[i] {
+					return -1, fmt.Errorf("field %q is used more than once", name)
+				}
+				used[i] = used[i] || axis
+				return i, nil
+			}
+		}
+		return -1, fmt.Errorf("no field %q", name)
+	}
+	for _, name := range spec.Rows {
+		i, err := field(name, true)
+		if err != nil {
+			return wrap(err)
+		}
+		p.rows = append(p.rows, i)
+	}
+	for _, name := range spec.Cols {
+		i, err := field(name, true)
+		if err != nil {
+			return wrap(err)
+		}
+		p.cols = append(p.cols, i)
+	}
+	for _, filter := range spec.Filters {
+		i, err := field(filter.Field, true)
+		if err != nil {
+			return wrap(err)
+		}
+		p.filters = append(p.filters, i)
+	}
+	for _, df := range spec.Data {
+		i, err := field(df.Field, false)
+		if err != nil {
+			return wrap(err)
+		}
+		if df.Function == "" {
+			df.Function = PivotSum
+		}
+		fname, ok := pivotFunctionNames[df.Function]
+		if !ok {
+			return wrap(fmt.Errorf("unsupported function %q", df.Function))
+		}
+		if df.Name == "" {
+			df.Name = fname + " of " + df.Field
+		}
+		p.data = append(p.data, df)
+		p.dataFields = append(p.dataFields, i)
+	}
+	pc.share(used)
+	p.filterItems = make([]int, len(p.filters))
+	for i, filter := range spec.Filters {
+		p.filterItems[i] = -1
+		if filter.Item != nil {
+			p.filterItems[i] = pc.Fields[p.filters[i]].itemIndex(pivotValue(filter.Item))
+			if p.filterItems[i] < 0 {
+				return wrap(fmt.Errorf("field %q has no item %v", filter.Field, filter.Item))
+			}
+		}
+	}
+
+	if spec.Location == "" {
+		spec.Location = "A1"
+		if len(spec.Filters) > 0 {
+			spec.Location = GetCellIDStringFromCoords(0, len(spec.Filters)+1)
+		}
+	}
+	loc, err := formula.ParseRef(spec.Location)
+	if err != nil || loc.Sheet != "" || loc.IsArea {
+		return wrap(fmt.Errorf("invalid location %q", spec.Location))
+	}
+	if len(spec.Filters) > 0 && loc.Row1 < len(spec.Filters)+1 {
+		return wrap(fmt.Errorf("there is no room for the filters above %s", spec.Location))
+	}
+	p.row, p.col = loc.Row1, loc.Col1
+
+	if spec.Name == "" {
+		for n := 1; ; n++ {
+			spec.Name = fmt.Sprintf("PivotTable%d", n)
+			if f.pivotTable(spec.Name) == nil {
+				break
+			}
+		}
+	} else if f.pivotTable(spec.Name) != nil {
+		return wrap(fmt.Errorf("there is already a pivot table called %q", spec.Name))
+	}
+	for _, other := range f.pivotCaches {
+		if other.ID >= pc.ID {
+			pc.ID = other.ID + 1
+		}
+	}
+
+	p.aggregate()
+	err = p.render(target)
+	if err != nil {
+		return wrap(err)
+	}
+	err = pc.makeParts()
+	if err != nil {
+		return wrap(err)
+	}
+	pt := &PivotTable{
+		Name:       spec.Name,
+		Location:   p.location(),
+		Sheet:      target,
+		Cache:      pc,
+		RowFields:  spec.Rows,
+		ColFields:  spec.Cols,
+		DataFields: p.data,
+	}
+	for i, fld := range p.filters {
+		filter := PivotFilter{Field: pc.Fields[fld].Name}
+		if p.filterItems[i] >= 0 {
+			filter.Item = pc.Fields[fld].Items[p.filterItems[i]]
+		}
+		pt.Filters = append(pt.Filters, filter)
+	}
+	pt.definition, err = p.definition(pt)
+	if err != nil {
+		return wrap(err)
+	}
+	f.pivotCaches = append(f.pivotCaches, pc)
+	target.pivotTables = append(target.pivotTables, pt)
+	return pt, nil
+}
+
+// pivotTable returns the pivot table of f called name, or nil if
+// there isn't one.
+func (f *File) pivotTable(name string) *PivotTable {
+	for _, s := range f.Sheets {
+		for _, pt := range s.pivotTables {
+			if strings.EqualFold(pt.Name, name) {
+				return pt
+			}
+		}
+	}
+	return nil
+}
+
+// readPivotSource returns a PivotCache holding the data in the range
+// ref of source.
+func (f *File) readPivotSource(source *Sheet, ref formula.Ref) (*PivotCache, error) {
+	area := formula.Ref{Col1: ref.Col1, Row1: ref.Row1, Col2: ref.Col2, Row2: ref.Row2, IsArea: true}
+	pc := &PivotCache{ID: 1, SourceSheet: source.Name, SourceRange: area.String()}
+	names := make(map[string]bool)
+	for x := ref.Col1; x <= ref.Col2; x++ {
+		cell, err := source.Cell(ref.Row1, x)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSpace(cell.String())
+		switch {
+		case name == "":
+			return nil, fmt.Errorf("column %s of the source range has no heading", ColIndexToLetters(x))
+		case names[strings.ToLower(name)]:
+			return nil, fmt.Errorf("the source range has more than one field called %q", name)
+		}
+		names[strings.ToLower(name)] = true
+		pc.Fields = append(pc.Fields, &PivotCacheField{Name: name, inRecords: true})
+	}
+	for y := ref.Row1 + 1; y <= ref.Row2; y++ {
+		record := make([]interface{}, len(pc.Fields))
+		for x := ref.Col1; x <= ref.Col2; x++ {
+			cell, err := source.Cell(y, x)
+			if err != nil {
+				return nil, err
+			}
+			record[x-ref.Col1] = pivotCellValue(cell, f.Date1904)
+		}
+		pc.Records = append(pc.Records, record)
+	}
+	return pc, nil
+}
+
+// pivotCellValue returns the value of a cell as it is held in a
+// PivotCache.
+func pivotCellValue(c *Cell, date1904 bool) interface{} {
+	v := cellCalcValue(c)
+	switch v.kind {
+	case calcNumber:
+		if c.IsTime() {
+			return TimeFromExcelTime(v.num, date1904)
+		}
+		return v.num
+	case calcString, calcError:
+		return v.str
+	case calcBool:
+		return v.b
+	}
+	return nil
+}
+
+// pivotValue converts the integers that a PivotFilter may be given
+// to the float64s that a PivotCache holds.
+func pivotValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	}
+	return v
+}
+
+// share gives the fields of pc that are used on an axis of a pivot
+// table, and those that hold values other than numbers, sorted lists
+// of the distinct values that they hold.
+func (pc *PivotCache) share(axis map[int]bool) {
+	for i, fld := range pc.Fields {
+		numeric := true
+		for _, record := range pc.Records {
+			if _, ok := record[i].(float64); !ok && record[i] != nil {
+				numeric = false
+			}
+		}
+		if numeric && !axis[i] {
+			continue
+		}
+		seen := make(map[interface{}]bool)
+		for _, record := range pc.Records {
+			if !seen[record[i]] {
+				seen[record[i]] = true
+				fld.Items = append(fld.Items, record[i])
+			}
+		}
+		sort.SliceStable(fld.Items, func(a, b int) bool {
+			return comparePivotValues(fld.Items[a], fld.Items[b]) < 0
+		})
+		fld.index = make(map[interface{}]int, len(fld.Items))
+		for j, item := range fld.Items {
+			fld.index[item] = j
+		}
+	}
+}
+
+// itemIndex returns the index of the item of fld that is v, or -1 if
+// there isn't one.
+func (fld *PivotCacheField) itemIndex(v interface{}) int {
+	for i, item := range fld.Items {
+		if (item == nil) == (v == nil) && comparePivotValues(item, v) == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// comparePivotValues compares two values of a PivotCache in the order
+// that Excel sorts them: numbers, dates, text, booleans and then
+// blanks.
+func comparePivotValues(a, b interface{}) int {
+	rank := func(v interface{}) int {
+		switch v.(type) {
+		case float64:
+			return 0
+		case time.Time:
+			return 1
+		case string:
+			return 2
+		case bool:
+			return 3
+		}
+		return 4
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return ra - rb
+	}
+	switch a := a.(type) {
+	case float64:
+		switch b := b.(float64); {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	case time.Time:
+		switch b := b.(time.Time); {
+		case a.Before(b):
+			return -1
+		case a.After(b):
+			return 1
+		}
+	case string:
+		b := b.(string)
+		if c := strings.Compare(strings.ToLower(a), strings.ToLower(b)); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	case bool:
+		switch b := b.(bool); {
+		case !a && b:
+			return -1
+		case a && !b:
+			return 1
+		}
+	}
+	return 0
+}
+
+// pivotLayout works out the rows and columns of a pivot table added
+// by AddPivotTable, and the values shown in them.
+type pivotLayout struct {
+	cache       *PivotCache
+	rows, cols  []int // the fields labelling the rows and columns
+	filters     []int // the fields filtered on
+	filterItems []int // the item each filter restricts the data to, or -1
+	data        []PivotDataField
+	dataFields  []int // the field of each of data
+	row, col    int   // the top left cell of the pivot table
+
+	rowKeys [][]int // the items labelling each row, in order
+	colKeys [][]int // the items labelling each column, in order
+	values  map[pivotCellKey]*pivotAggregate
+
+	headerRows    int // the number of rows above the first row of data
+	width, height int
+}
+
+// pivotCellKey identifies a value of a pivot table by the items
+// labelling its row and column, or "*" for a grand total, and its
+// data field.
+type pivotCellKey struct {
+	row, col string
+	data     int
+}
+
+// pivotColumn is a column of the values of a pivot table.
+type pivotColumn struct {
+	items []int
+	data  int
+	grand bool
+}
+
+// pivotAggregate accumulates the values summarised in a cell of a
+// pivot table.
+type pivotAggregate struct {
+	count, nums   int
+	sum, min, max float64
+}
+
+// add adds the value v of a record to a.
+func (a *pivotAggregate) add(v interface{}) {
+	if v == nil {
+		return
+	}
+	a.count++
+	f, ok := v.(float64)
+	if !ok {
+		return
+	}
+	if a.nums == 0 || f < a.min {
+		a.min = f
+	}
+	if a.nums == 0 || f > a.max {
+		a.max = f
+	}
+	a.nums++
+	a.sum += f
+}
+
+// value returns the values added to a summarised with fn.
+func (a *pivotAggregate) value(fn PivotFunction) interface{} {
+	switch fn {
+	case PivotCount:
+		return float64(a.count)
+	case PivotAverage:
+		if a.nums == 0 {
+			return nil
+		}
+		return a.sum / float64(a.nums)
+	case PivotMin:
+		return a.min
+	case PivotMax:
+		return a.max
+	}
+	return a.sum
+}
+
+// valuesOnCols reports whether there is a level of column labels for
+// the data fields, as there is when there are several.
+func (p *pivotLayout) valuesOnCols() bool {
+	return len(p.data) > 1
+}
+
+// colLevels returns the number of rows of column labels.
+func (p *pivotLayout) colLevels() int {
+	if p.valuesOnCols() {
+		return len(p.cols) + 1
+	}
+	return len(p.cols)
+}
+
+// items returns the indexes of the items of record in fields.
+func (p *pivotLayout) items(record []interface{}, fields []int) []int {
+	items := make([]int, len(fields))
+	for i, fld := range fields {
+		items[i] = p.cache.Fields[fld].index[record[fld]]
+	}
+	return items
+}
+
+// aggregate works out the rows and columns of the pivot table and the
+// values in them.
+func (p *pivotLayout) aggregate() {
+	p.values = make(map[pivotCellKey]*pivotAggregate)
+	seenRows := make(map[string]bool)
+	seenCols := make(map[string]bool)
+	add := func(key pivotCellKey, v interface{}) {
+		a, ok := p.values[key]
+		if !ok {
+			a = &pivotAggregate{}
+			p.values[key] = a
+		}
+		a.add(v)
+	}
+records:
+	for _, record := range p.cache.Records {
+		for i, fld := range p.filters {
+			if p.filterItems[i] >= 0 && p.cache.Fields[fld].index[record[fld]] != p.filterItems[i] {
+				continue records
+			}
+		}
+		rowItems := p.items(record, p.rows)
+		colItems := p.items(record, p.cols)
+		rk, ck := fmt.Sprint(rowItems), fmt.Sprint(colItems)
+		if !seenRows[rk] {
+			seenRows[rk] = true
+			p.rowKeys = append(p.rowKeys, rowItems)
+		}
+		if !seenCols[ck] {
+			seenCols[ck] = true
+			p.colKeys = append(p.colKeys, colItems)
+		}
+		for d, fld := range p.dataFields {
+			add(pivotCellKey{rk, ck, d}, record[fld])
+			add(pivotCellKey{rk, "*", d}, record[fld])
+			add(pivotCellKey{"*", ck, d}, record[fld])
+			add(pivotCellKey{"*", "*", d}, record[fld])
+		}
+	}
+	sortItems := func(keys [][]int) {
+		sort.Slice(keys, func(i, j int) bool {
+			return compareItems(keys[i], keys[j]) < 0
+		})
+	}
+	sortItems(p.rowKeys)
+	sortItems(p.colKeys)
+}
+
+// compareItems compares two lists of item indexes.
+func compareItems(a, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return len(a) - len(b)
+}
+
+// sharedPrefix returns the number of leading items that a and b have
+// in common.
+func sharedPrefix(a, b []int) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// columns returns the columns of values of the pivot table, including
+// those of the grand totals.
+func (p *pivotLayout) columns() []pivotColumn {
+	colKeys := p.colKeys
+	if len(p.cols) == 0 {
+		colKeys = [][]int{{}}
+	}
+	var columns []pivotColumn
+	for _, items := range colKeys {
+		for d := range p.data {
+			columns = append(columns, pivotColumn{items: items, data: d})
+		}
+	}
+	if len(p.cols) > 0 {
+		for d := range p.data {
+			columns = append(columns, pivotColumn{data: d, grand: true})
+		}
+	}
+	return columns
+}
+
+// labels returns the items labelling column, including the index of
+// its data field if there is a level of labels for them.
+func (p *pivotLayout) labels(column pivotColumn) []int {
+	labels := append([]int(nil), column.items...)
+	if p.valuesOnCols() {
+		labels = append(labels, column.data)
+	}
+	return labels
+}
+
+// rowKeysWithTotal returns the items labelling each row of the pivot
+// table, with nil for the grand total.  A pivot table without row
+// fields has a single row.
+func (p *pivotLayout) rowKeysWithTotal() [][]int {
+	if len(p.rows) == 0 {
+		return [][]int{{}}
+	}
+	return append(append([][]int(nil), p.rowKeys...), nil)
+}
+
+// itemLabel returns the label of the item with the index i of fld.
+func (p *pivotLayout) itemLabel(fld, i int) interface{} {
+	if v := p.cache.Fields[fld].Items[i]; v != nil {
+		return v
+	}
+	return "(blank)"
+}
+
+// render writes the pivot table, and its filters, to the cells of
+// target.
+func (p *pivotLayout) render(target *Sheet) error {
+	var err error
+	set := func(row, col int, v interface{}) {
+		if err != nil || v == nil {
+			return
+		}
+		var cell *Cell
+		cell, err = target.Cell(p.row+row, p.col+col)
+		if err != nil {
+			return
+		}
+		switch v := v.(type) {
+		case float64:
+			cell.SetFloat(v)
+		case bool:
+			cell.SetBool(v)
+		case time.Time:
+			cell.SetDate(v)
+		default:
+			cell.SetString(fmt.Sprint(v))
+		}
+	}
+
+	for i, fld := range p.filters {
+		row := i - len(p.filters) - 1
+		set(row, 0, p.cache.Fields[fld].Name)
+		if p.filterItems[i] >= 0 {
+			set(row, 1, p.itemLabel(fld, p.filterItems[i]))
+		} else {
+			set(row, 1, "(All)")
+		}
+	}
+
+	nRows := len(p.rows)
+	levels := p.colLevels()
+	columns := p.columns()
+	if levels == 0 {
+		p.headerRows = 1
+		set(0, nRows, p.data[0].Name)
+	} else {
+		p.headerRows = levels + 1
+		if !p.valuesOnCols() && nRows > 0 {
+			set(0, 0, p.data[0].Name)
+		}
+		for k, fld := range p.cols {
+			set(0, nRows+k, p.cache.Fields[fld].Name)
+		}
+		if p.valuesOnCols() {
+			set(0, nRows+len(p.cols), "Values")
+		}
+		var prev []int
+		for j, column := range columns {
+			x := nRows + j
+			if column.grand {
+				if p.valuesOnCols() {
+					set(1, x, "Total "+p.data[column.data].Name)
+				} else {
+					set(1, x, "Grand Total")
+				}
+				continue
+			}
+			labels := p.labels(column)
+			from := 0
+			if j > 0 {
+				from = sharedPrefix(prev, labels)
+			}
+			for level := from; level < levels; level++ {
+				if level < len(p.cols) {
+					set(1+level, x, p.itemLabel(p.cols[level], labels[level]))
+				} else {
+					set(1+level, x, p.data[column.data].Name)
+				}
+			}
+			prev = labels
+		}
+	}
+	for i, fld := range p.rows {
+		set(p.headerRows-1, i, p.cache.Fields[fld].Name)
+	}
+
+	rows := p.rowKeysWithTotal()
+	for i, items := range rows {
+		y := p.headerRows + i
+		rk := fmt.Sprint(items)
+		if items == nil {
+			rk = "*"
+			set(y, 0, "Grand Total")
+		} else {
+			from := 0
+			if i > 0 {
+				from = sharedPrefix(rows[i-1], items)
+			}
+			for level := from; level < nRows; level++ {
+				set(y, level, p.itemLabel(p.rows[level], items[level]))
+			}
+		}
+		for j, column := range columns {
+			ck := fmt.Sprint(column.items)
+			if column.grand {
+				ck = "*"
+			}
+			if a, ok := p.values[pivotCellKey{rk, ck, column.data}]; ok {
+				set(y, nRows+j, a.value(p.data[column.data].Function))
+			}
+		}
+	}
+	p.width = nRows + len(columns)
+	p.height = p.headerRows + len(rows)
+	return err
+}
+
+// location returns the range of the pivot table.
+func (p *pivotLayout) location() string {
+	return formula.Ref{
+		Col1: p.col, Row1: p.row,
+		Col2: p.col + p.width - 1, Row2: p.row + p.height - 1,
+		IsArea: true,
+	}.String()
+}
+
+// pivotItemRow returns the i element listing the items of a row or
+// column of a pivot table that aren't the same as those of the one
+// before, prev.
+func pivotItemRow(prev, items []int) xlsxPivotRow {
+	r := sharedPrefix(prev, items)
+	row := xlsxPivotRow{R: r}
+	for _, item := range items[r:] {
+		row.X = append(row.X, xlsxPivotRowX{V: item})
+	}
+	return row
+}
+
+// definition returns the pivotTableDefinition part of pt.
+func (p *pivotLayout) definition(pt *PivotTable) ([]byte, error) {
+	no := false
+	indent := 0
+	x := xlsxPivotTableDefinition{
+		Name:                  pt.Name,
+		CacheId:               p.cache.ID,
+		DataCaption:           "Values",
+		UpdatedVersion:        pivotVersion,
+		MinRefreshableVersion: pivotVersion,
+		UseAutoFormatting:     true,
+		ItemPrintTitles:       true,
+		CreatedVersion:        pivotVersion,
+		Indent:                &indent,
+		Compact:               &no,
+		CompactData:           &no,
+		Location: xlsxPivotLocation{
+			Ref:            pt.Location,
+			FirstHeaderRow: 1,
+			FirstDataRow:   p.headerRows,
+			FirstDataCol:   len(p.rows),
+		},
+		PivotTableStyleInfo: &xlsxPivotTableStyleInfo{
+			Name:           "PivotStyleLight16",
+			ShowRowHeaders: true,
+			ShowColHeaders: true,
+			ShowLastColumn: true,
+		},
+	}
+	if len(p.filters) > 0 {
+		x.Location.RowPageCount = len(p.filters)
+		x.Location.ColPageCount = 1
+	}
+
+	axes := make(map[int]string)
+	for _, fld := range p.rows {
+		axes[fld] = "axisRow"
+	}
+	for _, fld := range p.cols {
+		axes[fld] = "axisCol"
+	}
+	for _, fld := range p.filters {
+		axes[fld] = "axisPage"
+	}
+	for i, fld := range p.cache.Fields {
+		pf := xlsxPivotField{Compact: &no, Outline: &no, ShowAll: &no}
+		if axis, ok := axes[i]; ok {
+			pf.Axis = axis
+			pf.DefaultSubtotal = &no
+			pf.Items = &xlsxPivotFieldItems{Count: len(fld.Items)}
+			for j := range fld.Items {
+				j := j
+				pf.Items.Item = append(pf.Items.Item, xlsxPivotFieldItem{X: &j})
+			}
+		}
+		for _, df := range p.dataFields {
+			pf.DataField = pf.DataField || df == i
+		}
+		x.PivotFields.PivotField = append(x.PivotFields.PivotField, pf)
+	}
+	x.PivotFields.Count = len(x.PivotFields.PivotField)
+
+	x.RowItems = &xlsxPivotItems{}
+	if len(p.rows) > 0 {
+		x.RowFields = &xlsxPivotFieldRefs{}
+		for _, fld := range p.rows {
+			x.RowFields.Field = append(x.RowFields.Field, xlsxPivotFieldRef{X: fld})
+		}
+		x.RowFields.Count = len(x.RowFields.Field)
+		var prev []int
+		for _, items := range p.rowKeys {
+			x.RowItems.I = append(x.RowItems.I, pivotItemRow(prev, items))
+			prev = items
+		}
+		x.RowItems.I = append(x.RowItems.I, xlsxPivotRow{T: "grand", X: []xlsxPivotRowX{{}}})
+	} else {
+		x.RowItems.I = []xlsxPivotRow{{}}
+	}
+	x.RowItems.Count = len(x.RowItems.I)
+
+	x.ColItems = &xlsxPivotItems{}
+	if p.colLevels() > 0 {
+		x.ColFields = &xlsxPivotFieldRefs{}
+		for _, fld := range p.cols {
+			x.ColFields.Field = append(x.ColFields.Field, xlsxPivotFieldRef{X: fld})
+		}
+		if p.valuesOnCols() {
+			x.ColFields.Field = append(x.ColFields.Field, xlsxPivotFieldRef{X: -2})
+		}
+		x.ColFields.Count = len(x.ColFields.Field)
+		var prev []int
+		for _, column := range p.columns() {
+			if column.grand {
+				x.ColItems.I = append(x.ColItems.I, xlsxPivotRow{T: "grand", I: column.data, X: []xlsxPivotRowX{{}}})
+				continue
+			}
+			labels := p.labels(column)
+			row := pivotItemRow(prev, labels)
+			if p.valuesOnCols() {
+				row.I = column.data
+			}
+			x.ColItems.I = append(x.ColItems.I, row)
+			prev = labels
+		}
+	} else {
+		x.ColItems.I = []xlsxPivotRow{{}}
+	}
+	x.ColItems.Count = len(x.ColItems.I)
+
+	if len(p.filters) > 0 {
+		x.PageFields = &xlsxPivotPageFields{}
+		for i, fld := range p.filters {
+			pf := xlsxPivotPageField{Fld: fld, Hier: -1}
+			if p.filterItems[i] >= 0 {
+				item := p.filterItems[i]
+				pf.Item = &item
+			}
+			x.PageFields.PageField = append(x.PageFields.PageField, pf)
+		}
+		x.PageFields.Count = len(x.PageFields.PageField)
+	}
+
+	x.DataFields = &xlsxPivotDataFields{}
+	for d, df := range p.data {
+		xdf := xlsxPivotDataField{Name: df.Name, Fld: p.dataFields[d]}
+		if df.Function != PivotSum {
+			xdf.Subtotal = string(df.Function)
+		}
+		x.DataFields.DataField = append(x.DataFields.DataField, xdf)
+	}
+	x.DataFields.Count = len(x.DataFields.DataField)
+
+	body, err := xml.Marshal(x)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(xml.Header + string(body)), nil
+}
+
+// pivotItem returns the item holding the value v in a pivot cache.
+func pivotItem(v interface{}) xlsxPivotItem {
+	switch v := v.(type) {
+	case float64:
+		return xlsxPivotItem{XMLName: xml.Name{Local: "n"}, V: strconv.FormatFloat(v, 'f', -1, 64)}
+	case string:
+		return xlsxPivotItem{XMLName: xml.Name{Local: "s"}, V: v}
+	case bool:
+		if v {
+			return xlsxPivotItem{XMLName: xml.Name{Local: "b"}, V: "1"}
+		}
+		return xlsxPivotItem{XMLName: xml.Name{Local: "b"}, V: "0"}
+	case time.Time:
+		return xlsxPivotItem{XMLName: xml.Name{Local: "d"}, V: v.Format("2006-01-02T15:04:05")}
+	}
+	return xlsxPivotItem{XMLName: xml.Name{Local: "m"}}
+}
+
+// sharedItems returns the sharedItems element of the field of pc with
+// the index i, which describes the types of its values and lists its
+// items.
+func (pc *PivotCache) sharedItems(i int) xlsxPivotSharedItems {
+	var hasString, hasNumber, hasDate, hasBool, hasBlank bool
+	allIntegers := true
+	var minValue, maxValue float64
+	var minDate, maxDate time.Time
+	for _, record := range pc.Records {
+		switch v := record[i].(type) {
+		case float64:
+			if !hasNumber || v < minValue {
+				minValue = v
+			}
+			if !hasNumber || v > maxValue {
+				maxValue = v
+			}
+			hasNumber = true
+			allIntegers = allIntegers && v == float64(int64(v))
+		case time.Time:
+			if !hasDate || v.Before(minDate) {
+				minDate = v
+			}
+			if !hasDate || v.After(maxDate) {
+				maxDate = v
+			}
+			hasDate = true
+		case string:
+			hasString = true
+		case bool:
+			hasBool = true
+		default:
+			hasBlank = true
+		}
+	}
+	no := false
+	si := xlsxPivotSharedItems{
+		ContainsBlank:   hasBlank,
+		ContainsNumber:  hasNumber,
+		ContainsInteger: hasNumber && allIntegers,
+		ContainsDate:    hasDate,
+	}
+	types := 0
+	for _, has := range []bool{hasString, hasNumber, hasDate, hasBool} {
+		if has {
+			types++
+		}
+	}
+	si.ContainsMixedTypes = types > 1
+	if !hasString {
+		si.ContainsString = &no
+		if !hasBlank {
+			si.ContainsSemiMixedTypes = &no
+		}
+	}
+	if hasDate && !hasString && !hasNumber && !hasBool {
+		si.ContainsNonDate = &no
+	}
+	if hasNumber {
+		si.MinValue, si.MaxValue = &minValue, &maxValue
+	}
+	if hasDate {
+		si.MinDate = minDate.Format("2006-01-02T15:04:05")
+		si.MaxDate = maxDate.Format("2006-01-02T15:04:05")
+	}
+	if fld := pc.Fields[i]; fld.index != nil {
+		si.Count = len(fld.Items)
+		for _, item := range fld.Items {
+			si.Items = append(si.Items, pivotItem(item))
+		}
+	}
+	return si
+}
+
+// makeParts makes the pivotCacheDefinition and pivotCacheRecords
+// parts of a pivot cache made by AddPivotTable.
+func (pc *PivotCache) makeParts() error {
+	pc.recordsRID = "rId1"
+	xDef := xlsxPivotCacheDefinition{
+		RID:                   pc.recordsRID,
+		RefreshOnLoad:         true,
+		CreatedVersion:        pivotVersion,
+		RefreshedVersion:      pivotVersion,
+		MinRefreshableVersion: pivotVersion,
+		RecordCount:           len(pc.Records),
+		CacheSource: xlsxPivotCacheSource{
+			Type:            "worksheet",
+			WorksheetSource: &xlsxPivotWorksheetSource{Ref: pc.SourceRange, Sheet: pc.SourceSheet},
+		},
+	}
+	for i, fld := range pc.Fields {
+		xDef.CacheFields.CacheField = append(xDef.CacheFields.CacheField, xlsxPivotCacheField{
+			Name:        fld.Name,
+			SharedItems: pc.sharedItems(i),
+		})
+	}
+	xDef.CacheFields.Count = len(xDef.CacheFields.CacheField)
+	body, err := xml.Marshal(xDef)
+	if err != nil {
+		return err
+	}
+	pc.definition = []byte(xml.Header + strings.Replace(string(body),
+		`xmlns:relationships="http://schemas.openxmlformats.org/officeDocument/2006/relationships" relationships:id`,
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" r:id`, 1))
+
+	xRecords := xlsxPivotCacheRecords{Count: len(pc.Records)}
+	for _, record := range pc.Records {
+		var xRecord xlsxPivotCacheRecord
+		for i, v := range record {
+			if index := pc.Fields[i].index; index != nil {
+				xRecord.Items = append(xRecord.Items, xlsxPivotItem{
+					XMLName: xml.Name{Local: "x"},
+					V:       strconv.Itoa(index[v]),
+				})
+			} else {
+				xRecord.Items = append(xRecord.Items, pivotItem(v))
+			}
+		}
+		xRecords.R = append(xRecords.R, xRecord)
+	}
+	body, err = xml.Marshal(xRecords)
+	if err != nil {
+		return err
+	}
+	pc.records = []byte(xml.Header + string(body))
+	return nil
+}
@@ -0,0 +1,338 @@
+package xlsx
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+const (
+	testPivotCacheDefinition = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<pivotCacheDefinition xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" r:id="rId1" refreshOnLoad="1" recordCount="3">
+<cacheSource type="worksheet"><worksheetSource ref="A1:D4" sheet="Data"/></cacheSource>
+<cacheFields count="5">
+<cacheField name="Region" numFmtId="0"><sharedItems count="2"><s v="East"/><s v="West"/></sharedItems></cacheField>
+<cacheField name="Product" numFmtId="0"><sharedItems count="2"><s v="Apples"/><s v="Pears"/></sharedItems></cacheField>
+<cacheField name="Amount" numFmtId="0"><sharedItems containsSemiMixedTypes="0" containsString="0" containsNumber="1" containsInteger="1" minValue="5" maxValue="20"/></cacheField>
+<cacheField name="Date" numFmtId="14"><sharedItems containsNonDate="0" containsDate="1" containsString="0" containsBlank="1"/></cacheField>
+<cacheField name="Double" numFmtId="0" formula="Amount*2" databaseField="0"/>
+</cacheFields>
+</pivotCacheDefinition>`
+
+	testPivotCacheRecords = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<pivotCacheRecords xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="3">
+<r><x v="0"/><x v="0"/><n v="10"/><d v="2020-01-02T00:00:00"/></r>
+<r><x v="1"/><x v="0"/><n v="20"/><m/></r>
+<r><x v="0"/><x v="1"/><n v="5"/><d v="2020-02-01T00:00:00"/></r>
+</pivotCacheRecords>`
+
+	testPivotTableDefinition = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<pivotTableDefinition xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" name="PivotTable1" cacheId="7" dataCaption="Values">
+<location ref="A3:C6" firstHeaderRow="0" firstDataRow="1" firstDataCol="1" rowPageCount="1" colPageCount="1"/>
+<pivotFields count="5">
+<pivotField axis="axisRow" showAll="0"><items count="3"><item x="0"/><item x="1"/><item t="default"/></items></pivotField>
+<pivotField axis="axisPage" showAll="0"><items count="3"><item x="1"/><item x="0"/><item t="default"/></items></pivotField>
+<pivotField dataField="1" showAll="0"/>
+<pivotField showAll="0"/>
+<pivotField dataField="1" showAll="0"/>
+</pivotFields>
+<rowFields count="1"><field x="0"/></rowFields>
+<colFields count="1"><field x="-2"/></colFields>
+<pageFields count="1"><pageField fld="1" item="1" hier="-1"/></pageFields>
+<dataFields count="2"><dataField name="Sum of Amount" fld="2" baseField="0" baseItem="0"/><dataField name="Count of Amount" fld="2" subtotal="count" baseField="0" baseItem="0"/></dataFields>
+</pivotTableDefinition>`
+)
+
+func TestPivotTables(t *testing.T) {
+	c := qt.New(t)
+
+	// setUp returns a workbook, as Excel would save it, with a pivot
+	// table on its second sheet that summarises the data on its
+	// first.
+	setUp := func(c *qt.C, option FileOption) *File {
+		f := NewFile(option)
+		data, err := f.AddSheet("Data")
+		c.Assert(err, qt.IsNil)
+		setCells(c, data, map[string]interface{}{
+			"A1": "Region", "B1": "Product", "C1": "Amount", "D1": "Date",
+			"A2": "East", "B2": "Apples", "C2": 10, "D2": 43832,
+			"A3": "West", "B3": "Apples", "C3": 20,
+			"A4": "East", "B4": "Pears", "C4": 5, "D4": 43862,
+		})
+		summary, err := f.AddSheet("Summary")
+		c.Assert(err, qt.IsNil)
+		setCells(c, summary, map[string]interface{}{
+			"A1": "Product", "B1": "Apples",
+			"A3": "Region", "B3": "Sum of Amount", "C3": "Count of Amount",
+			"A4": "East", "B4": 10, "C4": 1,
+			"A5": "West", "B5": 20, "C5": 1,
+			"A6": "Grand Total", "B6": 30, "C6": 2,
+		})
+
+		parts := writtenParts(c, f)
+		parts["xl/workbook.xml"] = strings.Replace(parts["xl/workbook.xml"], "</workbook>",
+			`<pivotCaches><pivotCache cacheId="7" r:id="rId99"/></pivotCaches></workbook>`, 1)
+		parts["xl/_rels/workbook.xml.rels"] = strings.Replace(parts["xl/_rels/workbook.xml.rels"], "</Relationships>",
+			`<Relationship Id="rId99" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotCacheDefinition" Target="pivotCache/pivotCacheDefinition1.xml"/></Relationships>`, 1)
+		parts["xl/pivotCache/pivotCacheDefinition1.xml"] = testPivotCacheDefinition
+		parts["xl/pivotCache/_rels/pivotCacheDefinition1.xml.rels"] = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotCacheRecords" Target="pivotCacheRecords1.xml"/></Relationships>`
+		parts["xl/pivotCache/pivotCacheRecords1.xml"] = testPivotCacheRecords
+		parts["xl/pivotTables/pivotTable1.xml"] = testPivotTableDefinition
+		parts["xl/pivotTables/_rels/pivotTable1.xml.rels"] = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotCacheDefinition" Target="../pivotCache/pivotCacheDefinition1.xml"/></Relationships>`
+		parts["xl/worksheets/_rels/sheet2.xml.rels"] = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotTable" Target="../pivotTables/pivotTable1.xml"/></Relationships>`
+
+		f, err = OpenBinary(zipParts(c, parts), option)
+		c.Assert(err, qt.IsNil)
+		return f
+	}
+
+	// check checks that f holds the pivot cache and table of setUp.
+	check := func(c *qt.C, f *File) {
+		caches := f.PivotCaches()
+		c.Assert(caches, qt.HasLen, 1)
+		pc := caches[0]
+		c.Assert(pc.ID, qt.Equals, 7)
+		c.Assert(pc.SourceSheet, qt.Equals, "Data")
+		c.Assert(pc.SourceRange, qt.Equals, "A1:D4")
+		c.Assert(pc.SourceName, qt.Equals, "")
+		var names []string
+		for _, field := range pc.Fields {
+			names = append(names, field.Name)
+		}
+		c.Assert(names, qt.DeepEquals, []string{"Region", "Product", "Amount", "Date", "Double"})
+		c.Assert(pc.Fields[0].Items, qt.DeepEquals, []interface{}{"East", "West"})
+		c.Assert(pc.Fields[4].Formula, qt.Equals, "Amount*2")
+		c.Assert(pc.Records, qt.DeepEquals, [][]interface{}{
+			{"East", "Apples", 10.0, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), nil},
+			{"West", "Apples", 20.0, nil, nil},
+			{"East", "Pears", 5.0, time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC), nil},
+		})
+
+		c.Assert(f.Sheet["Data"].PivotTables(), qt.HasLen, 0)
+		pts := f.Sheet["Summary"].PivotTables()
+		c.Assert(pts, qt.HasLen, 1)
+		pt := pts[0]
+		c.Assert(pt.Name, qt.Equals, "PivotTable1")
+		c.Assert(pt.Location, qt.Equals, "A3:C6")
+		c.Assert(pt.Sheet, qt.Equals, f.Sheet["Summary"])
+		c.Assert(pt.Cache, qt.Equals, pc)
+		c.Assert(pt.RowFields, qt.DeepEquals, []string{"Region"})
+		c.Assert(pt.ColFields, qt.HasLen, 0)
+		c.Assert(pt.DataOnRows, qt.Equals, false)
+		c.Assert(pt.Filters, qt.DeepEquals, []PivotFilter{{Field: "Product", Item: "Apples"}})
+		c.Assert(pt.DataFields, qt.DeepEquals, []PivotDataField{
+			{Name: "Sum of Amount", Field: "Amount", Function: PivotSum},
+			{Name: "Count of Amount", Field: "Amount", Function: PivotCount},
+		})
+	}
+
+	csRunO(c, "Read", func(c *qt.C, option FileOption) {
+		check(c, setUp(c, option))
+	})
+
+	csRunParts(c, "Parts", setUp, func(c *qt.C, parts map[string]string) {
+		c.Assert(parts["xl/workbook.xml"], qt.Contains, `<pivotCaches><pivotCache cacheId="7" r:id="rId6"></pivotCache></pivotCaches>`)
+		c.Assert(parts["xl/_rels/workbook.xml.rels"], qt.Contains, `<Relationship Id="rId6" Target="pivotCache/pivotCacheDefinition1.xml" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotCacheDefinition">`)
+		c.Assert(parts["xl/pivotCache/pivotCacheDefinition1.xml"], qt.Equals, testPivotCacheDefinition)
+		c.Assert(parts["xl/pivotCache/_rels/pivotCacheDefinition1.xml.rels"], qt.Contains, `Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotCacheRecords" Target="pivotCacheRecords1.xml"`)
+		c.Assert(parts["xl/pivotCache/pivotCacheRecords1.xml"], qt.Equals, testPivotCacheRecords)
+		c.Assert(parts["xl/pivotTables/pivotTable1.xml"], qt.Equals, testPivotTableDefinition)
+		c.Assert(parts["xl/pivotTables/_rels/pivotTable1.xml.rels"], qt.Contains, `Target="../pivotCache/pivotCacheDefinition1.xml"`)
+		c.Assert(parts["xl/worksheets/_rels/sheet2.xml.rels"], qt.Contains, `Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotTable" Target="../pivotTables/pivotTable1.xml"`)
+		c.Assert(parts["[Content_Types].xml"], qt.Contains, `<Override PartName="/xl/pivotTables/pivotTable1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.pivotTable+xml">`)
+		c.Assert(parts["[Content_Types].xml"], qt.Contains, `<Override PartName="/xl/pivotCache/pivotCacheDefinition1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.pivotCacheDefinition+xml">`)
+		c.Assert(parts["[Content_Types].xml"], qt.Contains, `<Override PartName="/xl/pivotCache/pivotCacheRecords1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.pivotCacheRecords+xml">`)
+	})
+
+	csRunO(c, "RoundTrip", func(c *qt.C, option FileOption) {
+		f := reopen(c, setUp(c, option), option)
+		check(c, f)
+		cell := cellAt(c, f.Sheet["Summary"], "B6")
+		c.Assert(cell.Value, qt.Equals, "30")
+	})
+}
+
+func TestAddPivotTable(t *testing.T) {
+	c := qt.New(t)
+
+	setUp := func(c *qt.C, option FileOption) *File {
+		f := NewFile(option)
+		data, err := f.AddSheet("Data")
+		c.Assert(err, qt.IsNil)
+		setCells(c, data, map[string]interface{}{
+			"A1": "Region", "B1": "Product", "C1": "Amount", "D1": "Year",
+			"A2": "East", "B2": "Apples", "C2": 10, "D2": 2020,
+			"A3": "West", "B3": "Apples", "C3": 20, "D3": 2020,
+			"A4": "East", "B4": "Pears", "C4": 5, "D4": 2020,
+			"A5": "East", "B5": "Apples", "C5": 7, "D5": 2021,
+			"A6": "West", "B6": "Pears", "C6": 3, "D6": 2020,
+		})
+		_, err = f.AddSheet("Summary")
+		c.Assert(err, qt.IsNil)
+		return f
+	}
+
+	// values returns the values of the cells of sheet in the range
+	// A1 to D7, by reference.
+	values := func(c *qt.C, sheet *Sheet) map[string]string {
+		got := make(map[string]string)
+		for y := 0; y < 7; y++ {
+			for x := 0; x < 4; x++ {
+				cell, err := sheet.Cell(y, x)
+				c.Assert(err, qt.IsNil)
+				if cell.Value != "" {
+					got[GetCellIDStringFromCoords(x, y)] = cell.Value
+				}
+			}
+		}
+		return got
+	}
+
+	csRunO(c, "RowsColsAndFilter", func(c *qt.C, option FileOption) {
+		f := setUp(c, option)
+		summary := f.Sheet["Summary"]
+		pt, err := f.AddPivotTable("Data!A1:D6", summary, PivotSpec{
+			Rows:    []string{"Region"},
+			Cols:    []string{"Product"},
+			Data:    []PivotDataField{{Field: "Amount"}},
+			Filters: []PivotFilter{{Field: "Year", Item: 2020}},
+		})
+		c.Assert(err, qt.IsNil)
+		c.Assert(pt.Name, qt.Equals, "PivotTable1")
+		c.Assert(pt.Location, qt.Equals, "A3:D7")
+		c.Assert(pt.DataFields, qt.DeepEquals, []PivotDataField{{Name: "Sum of Amount", Field: "Amount", Function: PivotSum}})
+		c.Assert(pt.Filters, qt.DeepEquals, []PivotFilter{{Field: "Year", Item: 2020.0}})
+		c.Assert(summary.PivotTables(), qt.HasLen, 1)
+		c.Assert(summary.PivotTables()[0], qt.Equals, pt)
+		c.Assert(f.PivotCaches(), qt.HasLen, 1)
+		c.Assert(f.PivotCaches()[0], qt.Equals, pt.Cache)
+		c.Assert(pt.Cache.SourceSheet, qt.Equals, "Data")
+		c.Assert(pt.Cache.SourceRange, qt.Equals, "A1:D6")
+		c.Assert(pt.Cache.Fields[0].Items, qt.DeepEquals, []interface{}{"East", "West"})
+		c.Assert(pt.Cache.Fields[2].Items, qt.HasLen, 0)
+
+		c.Assert(values(c, summary), qt.DeepEquals, map[string]string{
+			"A1": "Year", "B1": "2020",
+			"A3": "Sum of Amount", "B3": "Product",
+			"A4": "Region", "B4": "Apples", "C4": "Pears", "D4": "Grand Total",
+			"A5": "East", "B5": "10", "C5": "5", "D5": "15",
+			"A6": "West", "B6": "20", "C6": "3", "D6": "23",
+			"A7": "Grand Total", "B7": "30", "C7": "8", "D7": "38",
+		})
+
+		parts := writtenParts(c, f)
+		def := parts["xl/pivotTables/pivotTable1.xml"]
+		c.Assert(def, qt.Contains, `<location ref="A3:D7" firstHeaderRow="1" firstDataRow="2" firstDataCol="1" rowPageCount="1" colPageCount="1"></location>`)
+		c.Assert(def, qt.Contains, `<rowFields count="1"><field x="0"></field></rowFields><rowItems count="3"><i><x></x></i><i><x v="1"></x></i><i t="grand"><x></x></i></rowItems>`)
+		c.Assert(def, qt.Contains, `<colFields count="1"><field x="1"></field></colFields><colItems count="3"><i><x></x></i><i><x v="1"></x></i><i t="grand"><x></x></i></colItems>`)
+		c.Assert(def, qt.Contains, `<pageFields count="1"><pageField fld="3" item="0" hier="-1"></pageField></pageFields>`)
+		c.Assert(def, qt.Contains, `<dataFields count="1"><dataField name="Sum of Amount" fld="2" baseField="0" baseItem="0"></dataField></dataFields>`)
+		cacheDef := parts["xl/pivotCache/pivotCacheDefinition1.xml"]
+		c.Assert(cacheDef, qt.Contains, `<pivotCacheDefinition xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" r:id="rId1" refreshOnLoad="true"`)
+		c.Assert(cacheDef, qt.Contains, `<worksheetSource ref="A1:D6" sheet="Data"></worksheetSource>`)
+		c.Assert(cacheDef, qt.Contains, `<cacheField name="Region" numFmtId="0"><sharedItems count="2"><s v="East"></s><s v="West"></s></sharedItems></cacheField>`)
+		c.Assert(cacheDef, qt.Contains, `<cacheField name="Amount" numFmtId="0"><sharedItems containsSemiMixedTypes="false" containsString="false" containsNumber="true" containsInteger="true" minValue="3" maxValue="20"></sharedItems></cacheField>`)
+		c.Assert(parts["xl/pivotCache/pivotCacheRecords1.xml"], qt.Contains, `<r><x v="0"></x><x v="0"></x><n v="10"></n><x v="0"></x></r>`)
+	})
+
+	csRunO(c, "SeveralDataFields", func(c *qt.C, option FileOption) {
+		f := setUp(c, option)
+		summary := f.Sheet["Summary"]
+		pt, err := f.AddPivotTable("Data!$A$1:$C$6", summary, PivotSpec{
+			Name: "Totals",
+			Rows: []string{"Region", "Product"},
+			Data: []PivotDataField{{Field: "Amount"}, {Field: "Amount", Function: PivotCount}},
+		})
+		c.Assert(err, qt.IsNil)
+		c.Assert(pt.Location, qt.Equals, "A1:D7")
+		c.Assert(values(c, summary), qt.DeepEquals, map[string]string{
+			"C1": "Values",
+			"A2": "Region", "B2": "Product", "C2": "Sum of Amount", "D2": "Count of Amount",
+			"A3": "East", "B3": "Apples", "C3": "17", "D3": "2",
+			"B4": "Pears", "C4": "5", "D4": "1",
+			"A5": "West", "B5": "Apples", "C5": "20", "D5": "1",
+			"B6": "Pears", "C6": "3", "D6": "1",
+			"A7": "Grand Total", "C7": "45", "D7": "5",
+		})
+		def := string(pt.definition)
+		c.Assert(def, qt.Contains, `<rowItems count="5"><i><x></x><x></x></i><i r="1"><x v="1"></x></i><i><x v="1"></x><x></x></i><i r="1"><x v="1"></x></i><i t="grand"><x></x></i></rowItems>`)
+		c.Assert(def, qt.Contains, `<colFields count="1"><field x="-2"></field></colFields><colItems count="2"><i><x></x></i><i i="1"><x v="1"></x></i></colItems>`)
+	})
+
+	csRunO(c, "Average", func(c *qt.C, option FileOption) {
+		f := setUp(c, option)
+		summary := f.Sheet["Summary"]
+		_, err := f.AddPivotTable("Data!A1:D6", summary, PivotSpec{
+			Location: "B2",
+			Cols:     []string{"Region"},
+			Data:     []PivotDataField{{Name: "Mean", Field: "Amount", Function: PivotAverage}},
+		})
+		c.Assert(err, qt.IsNil)
+		c.Assert(values(c, summary), qt.DeepEquals, map[string]string{
+			"B2": "Region",
+			"B3": "East", "C3": "West", "D3": "Grand Total",
+			"B4": "7.333333333333333", "C4": "11.5", "D4": "9",
+		})
+	})
+
+	csRunO(c, "RoundTrip", func(c *qt.C, option FileOption) {
+		f := setUp(c, option)
+		want, err := f.AddPivotTable("Data!A1:D6", f.Sheet["Summary"], PivotSpec{
+			Rows:    []string{"Region"},
+			Cols:    []string{"Product"},
+			Data:    []PivotDataField{{Field: "Amount", Function: PivotMax}},
+			Filters: []PivotFilter{{Field: "Year"}},
+		})
+		c.Assert(err, qt.IsNil)
+		f = reopen(c, f, option)
+		caches := f.PivotCaches()
+		c.Assert(caches, qt.HasLen, 1)
+		c.Assert(caches[0].ID, qt.Equals, want.Cache.ID)
+		c.Assert(caches[0].Records, qt.DeepEquals, want.Cache.Records)
+		pts := f.Sheet["Summary"].PivotTables()
+		c.Assert(pts, qt.HasLen, 1)
+		c.Assert(pts[0].Name, qt.Equals, want.Name)
+		c.Assert(pts[0].Location, qt.Equals, want.Location)
+		c.Assert(pts[0].RowFields, qt.DeepEquals, want.RowFields)
+		c.Assert(pts[0].ColFields, qt.DeepEquals, want.ColFields)
+		c.Assert(pts[0].Filters, qt.DeepEquals, []PivotFilter{{Field: "Year"}})
+		c.Assert(pts[0].DataFields, qt.DeepEquals, want.DataFields)
+		c.Assert(cellAt(c, f.Sheet["Summary"], "B1").Value, qt.Equals, "(All)")
+		c.Assert(cellAt(c, f.Sheet["Summary"], "D7").Value, qt.Equals, "20")
+	})
+
+	c.Run("Errors", func(c *qt.C) {
+		f := setUp(c, UseMemoryCellStore)
+		summary := f.Sheet["Summary"]
+		amount := []PivotDataField{{Field: "Amount"}}
+		tests := []struct {
+			source string
+			spec   PivotSpec
+			err    string
+		}{
+			{"A1:D6", PivotSpec{Data: amount}, `AddPivotTable: invalid source range "A1:D6"`},
+			{"Other!A1:D6", PivotSpec{Data: amount}, `AddPivotTable: no sheet called "Other"`},
+			{"Data!A1:D1", PivotSpec{Data: amount}, `AddPivotTable: the source range needs a heading row and at least one record`},
+			{"Data!A1:D6", PivotSpec{}, `AddPivotTable: a pivot table needs at least one data field`},
+			{"Data!A1:E6", PivotSpec{Data: amount}, `AddPivotTable: column E of the source range has no heading`},
+			{"Data!A1:D6", PivotSpec{Rows: []string{"Colour"}, Data: amount}, `AddPivotTable: no field "Colour"`},
+			{"Data!A1:D6", PivotSpec{Rows: []string{"Region"}, Cols: []string{"Region"}, Data: amount}, `AddPivotTable: field "Region" is used more than once`},
+			{"Data!A1:D6", PivotSpec{Data: []PivotDataField{{Field: "Amount", Function: PivotStdDev}}}, `AddPivotTable: unsupported function "stdDev"`},
+			{"Data!A1:D6", PivotSpec{Data: amount, Filters: []PivotFilter{{Field: "Year", Item: 1999}}}, `AddPivotTable: field "Year" has no item 1999`},
+			{"Data!A1:D6", PivotSpec{Data: amount, Filters: []PivotFilter{{Field: "Year"}}, Location: "A2"}, `AddPivotTable: there is no room for the filters above A2`},
+		}
+		for _, test := range tests {
+			_, err := f.AddPivotTable(test.source, summary, test.spec)
+			c.Check(err, qt.ErrorMatches, test.err, qt.Commentf("%s %+v", test.source, test.spec))
+		}
+		_, err := f.AddPivotTable("Data!A1:D6", summary, PivotSpec{Data: amount})
+		c.Assert(err, qt.IsNil)
+		_, err = f.AddPivotTable("Data!A1:D6", summary, PivotSpec{Name: "pivottable1", Data: amount, Location: "H1"})
+		c.Assert(err, qt.ErrorMatches, `AddPivotTable: there is already a pivot table called "pivottable1"`)
+	})
+}
@@ -0,0 +1,434 @@
+package xlsx
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/tealeg/xlsx/v3/formula"
+)
+
+// ProtectionOptions says what users may still do to a protected
+// Sheet.  The zero value allows nothing, not even selecting cells,
+// whereas Excel, by default, lets users select both locked and
+// unlocked cells.
+type ProtectionOptions struct {
+	AllowSelectLockedCells   bool
+	AllowSelectUnlockedCells bool
+	AllowFormatCells         bool
+	AllowFormatColumns       bool
+	AllowFormatRows          bool
+	AllowInsertColumns       bool
+	AllowInsertRows          bool
+	AllowInsertHyperlinks    bool
+	AllowDeleteColumns       bool
+	AllowDeleteRows          bool
+	AllowSort                bool
+	AllowAutoFilter          bool
+	AllowPivotTables         bool
+	AllowEditObjects         bool
+	AllowEditScenarios       bool
+}
+
+// SheetProtection is the protection of a Sheet against changes.  The
+// cells of a protected Sheet whose Style is locked, which they are
+// unless it says otherwise, can't be changed.
+type SheetProtection struct {
+	Options  ProtectionOptions
+	password passwordHash
+}
+
+// HasPassword reports whether a password is needed to unprotect the
+// Sheet.
+func (p *SheetProtection) HasPassword() bool {
+	return p.password.isSet()
+}
+
+// VerifyPassword reports whether password is the one that unprotects
+// the Sheet.
+func (p *SheetProtection) VerifyPassword(password string) bool {
+	return p.password.verify(password)
+}
+
+// Protect protects the Sheet against changes, other than those that
+// options allow.  An empty password protects the Sheet without one,
+// so that anyone can unprotect it.
+func (s *Sheet) Protect(password string, options ProtectionOptions) error {
+	h, err := newPasswordHash(password)
+	if err != nil {
+		return fmt.Errorf("Protect: %w", err)
+	}
+	s.Protection = &SheetProtection{Options: options, password: h}
+	return nil
+}
+
+// ProtectedRange is a range of a protected Sheet that users may
+// change, once they give its password if it has one.
+type ProtectedRange struct {
+	Name string
+	// Ref is the range, or several ranges separated by spaces,
+	// such as "B2:B10 D2:D10".
+	Ref      string
+	password passwordHash
+}
+
+// HasPassword reports whether a password is needed to change the
+// range.
+func (r *ProtectedRange) HasPassword() bool {
+	return r.password.isSet()
+}
+
+// VerifyPassword reports whether password is the one that lets users
+// change the range.
+func (r *ProtectedRange) VerifyPassword(password string) bool {
+	return r.password.verify(password)
+}
+
+// AddProtectedRange adds a range, called name, that users may change
+// when the Sheet is protected, provided they give the password.  An
+// empty password lets anyone change it.  ref is the range, or several
+// ranges separated by spaces, such as "B2:B10 D2:D10".
+func (s *Sheet) AddProtectedRange(name, ref, password string) (*ProtectedRange, error) {
+	wrap := func(err error) (*ProtectedRange, error) {
+		return nil, fmt.Errorf("AddProtectedRange: %w", err)
+	}
+
+	if name == "" {
+		return wrap(errors.New("a protected range needs a name"))
+	}
+	for _, r := range s.ProtectedRanges {
+		if strings.EqualFold(r.Name, name) {
+			return wrap(fmt.Errorf("there is already a protected range called %q", name))
+		}
+	}
+	refs := strings.Fields(ref)
+	if len(refs) == 0 {
+		return wrap(fmt.Errorf("invalid range %q", ref))
+	}
+	for _, part := range refs {
+		r, err := formula.ParseRef(part)
+		if err != nil || r.Sheet != "" {
+			return wrap(fmt.Errorf("invalid range %q", ref))
+		}
+	}
+	h, err := newPasswordHash(password)
+	if err != nil {
+		return wrap(err)
+	}
+	r := &ProtectedRange{Name: name, Ref: strings.Join(refs, " "), password: h}
+	s.ProtectedRanges = append(s.ProtectedRanges, r)
+	return r, nil
+}
+
+// WorkbookProtection is the protection of the structure of a File,
+// which stops users adding, removing, renaming, moving, hiding and
+// showing its sheets, and of its windows.
+type WorkbookProtection struct {
+	LockStructure bool
+	LockWindows   bool
+	password      passwordHash
+}
+
+// HasPassword reports whether a password is needed to unprotect the
+// File.
+func (p *WorkbookProtection) HasPassword() bool {
+	return p.password.isSet()
+}
+
+// VerifyPassword reports whether password is the one that unprotects
+// the File.
+func (p *WorkbookProtection) VerifyPassword(password string) bool {
+	return p.password.verify(password)
+}
+
+// ProtectStructure protects the structure of the File, so that users
+// can't add, remove, rename, move, hide or show its sheets.  An empty
+// password protects it without one, so that anyone can unprotect it.
+func (f *File) ProtectStructure(password string) error {
+	h, err := newPasswordHash(password)
+	if err != nil {
+		return fmt.Errorf("ProtectStructure: %w", err)
+	}
+	f.Protection = &WorkbookProtection{LockStructure: true, password: h}
+	return nil
+}
+
+// The algorithm and spin count that passwords are hashed with, which
+// are what Excel uses.
+const (
+	passwordAlgorithm = "SHA-512"
+	passwordSpinCount = 100000
+)
+
+// passwordHash is a password as it is kept in a file: a legacy hash,
+// which is easily broken, and a hash by a named algorithm with a
+// salt, which is repeated spinCount times.  Either may be missing
+// from a file that was read.
+type passwordHash struct {
+	legacy    string
+	algorithm string
+	hash      string
+	salt      string
+	spinCount int
+}
+
+// newPasswordHash hashes password in both ways, or returns an empty
+// passwordHash if there is no password.
+func newPasswordHash(password string) (passwordHash, error) {
+	if password == "" {
+		return passwordHash{}, nil
+	}
+	salt := make([]byte, 16)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return passwordHash{}, err
+	}
+	h := passwordHash{
+		legacy:    legacyPasswordHash(password),
+		algorithm: passwordAlgorithm,
+		salt:      base64.StdEncoding.EncodeToString(salt),
+		spinCount: passwordSpinCount,
+	}
+	sum, err := saltedPasswordHash(password, h.algorithm, salt, h.spinCount)
+	if err != nil {
+		return passwordHash{}, err
+	}
+	h.hash = base64.StdEncoding.EncodeToString(sum)
+	return h, nil
+}
+
+// isSet reports whether there is a password.
+func (h passwordHash) isSet() bool {
+	return h.hash != "" || (h.legacy != "" && h.legacy != "0000")
+}
+
+// verify reports whether password is the one that was hashed.  The
+// salted hash is preferred, as the legacy one has many collisions.
+func (h passwordHash) verify(password string) bool {
+	if !h.isSet() {
+		return password == ""
+	}
+	if h.hash != "" {
+		salt, err := base64.StdEncoding.DecodeString(h.salt)
+		if err != nil {
+			return false
+		}
+		want, err := base64.StdEncoding.DecodeString(h.hash)
+		if err != nil {
+			return false
+		}
+		got, err := saltedPasswordHash(password, h.algorithm, salt, h.spinCount)
+		return err == nil && bytes.Equal(got, want)
+	}
+	return password != "" && strings.EqualFold(legacyPasswordHash(password), h.legacy)
+}
+
+// saltedPasswordHash hashes the UTF-16 password, after the salt, with
+// the named algorithm, and then hashes the result, followed by the
+// number of the iteration, spinCount times.
+func saltedPasswordHash(password, algorithm string, salt []byte, spinCount int) ([]byte, error) {
+	var hf hash.Hash
+	switch strings.ToUpper(algorithm) {
+	case "SHA-512":
+		hf = sha512.New()
+	case "SHA-384":
+		hf = sha512.New384()
+	case "SHA-256":
+		hf = sha256.New()
+	case "SHA-1":
+		hf = sha1.New()
+	default:
+		return nil, fmt.Errorf("unsupported hash algorithm %q", algorithm)
+	}
+	hf.Write(salt)
+	for _, u := range utf16.Encode([]rune(password)) {
+		hf.Write([]byte{byte(u), byte(u >> 8)})
+	}
+	sum := hf.Sum(nil)
+	iterator := make([]byte, 4)
+	for i := 0; i < spinCount; i++ {
+		hf.Reset()
+		hf.Write(sum)
+		binary.LittleEndian.PutUint32(iterator, uint32(i))
+		hf.Write(iterator)
+		sum = hf.Sum(sum[:0])
+	}
+	return sum, nil
+}
+
+// legacyPasswordHash returns the 16 bit hash of password that older
+// versions of Excel use, as four hexadecimal digits.  Only the first
+// 15 characters count, and each is taken as a single byte.
+func legacyPasswordHash(password string) string {
+	units := utf16.Encode([]rune(password))
+	if len(units) > 15 {
+		units = units[:15]
+	}
+	var h uint16
+	for i := len(units) - 1; i >= 0; i-- {
+		b := uint16(units[i] & 0xff)
+		if b == 0 {
+			b = units[i] >> 8
+		}
+		h = ((h >> 14) & 0x01) | ((h << 1) & 0x7fff)
+		h ^= b
+	}
+	h = ((h >> 14) & 0x01) | ((h << 1) & 0x7fff)
+	h ^= uint16(len(units))
+	h ^= 0xce4b
+	return fmt.Sprintf("%04X", h)
+}
+
+// protectionFlag returns the value of a sheetProtection flag that is
+// true unless stated otherwise: nil if the action is forbidden.
+func protectionFlag(allow bool) *bool {
+	if allow {
+		return bPtr(false)
+	}
+	return nil
+}
+
+// allowed reports whether the action of a sheetProtection flag that
+// is true unless stated otherwise is allowed.
+func allowed(flag *bool) bool {
+	return flag != nil && !*flag
+}
+
+// makeProtection adds the protection of the Sheet, and its protected
+// ranges, to worksheet.
+func (s *Sheet) makeProtection(worksheet *xlsxWorksheet) {
+	if p := s.Protection; p != nil {
+		o := p.Options
+		worksheet.SheetProtection = &xlsxSheetProtection{
+			Password:            p.password.legacy,
+			AlgorithmName:       p.password.algorithm,
+			HashValue:           p.password.hash,
+			SaltValue:           p.password.salt,
+			SpinCount:           p.password.spinCount,
+			Sheet:               true,
+			Objects:             !o.AllowEditObjects,
+			Scenarios:           !o.AllowEditScenarios,
+			FormatCells:         protectionFlag(o.AllowFormatCells),
+			FormatColumns:       protectionFlag(o.AllowFormatColumns),
+			FormatRows:          protectionFlag(o.AllowFormatRows),
+			InsertColumns:       protectionFlag(o.AllowInsertColumns),
+			InsertRows:          protectionFlag(o.AllowInsertRows),
+			InsertHyperlinks:    protectionFlag(o.AllowInsertHyperlinks),
+			DeleteColumns:       protectionFlag(o.AllowDeleteColumns),
+			DeleteRows:          protectionFlag(o.AllowDeleteRows),
+			SelectLockedCells:   !o.AllowSelectLockedCells,
+			Sort:                protectionFlag(o.AllowSort),
+			AutoFilter:          protectionFlag(o.AllowAutoFilter),
+			PivotTables:         protectionFlag(o.AllowPivotTables),
+			SelectUnlockedCells: !o.AllowSelectUnlockedCells,
+		}
+	}
+	if len(s.ProtectedRanges) > 0 {
+		worksheet.ProtectedRanges = &xlsxProtectedRanges{}
+		for _, r := range s.ProtectedRanges {
+			worksheet.ProtectedRanges.ProtectedRange = append(worksheet.ProtectedRanges.ProtectedRange, xlsxProtectedRange{
+				Password:      r.password.legacy,
+				Sqref:         r.Ref,
+				Name:          r.Name,
+				AlgorithmName: r.password.algorithm,
+				HashValue:     r.password.hash,
+				SaltValue:     r.password.salt,
+				SpinCount:     r.password.spinCount,
+			})
+		}
+	}
+}
+
+// readProtection reads the protection of the sheet, and its protected
+// ranges, from worksheet.
+func readProtection(sheet *Sheet, worksheet *xlsxWorksheet) {
+	if x := worksheet.SheetProtection; x != nil && x.Sheet {
+		sheet.Protection = &SheetProtection{
+			Options: ProtectionOptions{
+				AllowSelectLockedCells:   !x.SelectLockedCells,
+				AllowSelectUnlockedCells: !x.SelectUnlockedCells,
+				AllowFormatCells:         allowed(x.FormatCells),
+				AllowFormatColumns:       allowed(x.FormatColumns),
+				AllowFormatRows:          allowed(x.FormatRows),
+				AllowInsertColumns:       allowed(x.InsertColumns),
+				AllowInsertRows:          allowed(x.InsertRows),
+				AllowInsertHyperlinks:    allowed(x.InsertHyperlinks),
+				AllowDeleteColumns:       allowed(x.DeleteColumns),
+				AllowDeleteRows:          allowed(x.DeleteRows),
+				AllowSort:                allowed(x.Sort),
+				AllowAutoFilter:          allowed(x.AutoFilter),
+				AllowPivotTables:         allowed(x.PivotTables),
+				AllowEditObjects:         !x.Objects,
+				AllowEditScenarios:       !x.Scenarios,
+			},
+			password: passwordHash{
+				legacy:    x.Password,
+				algorithm: x.AlgorithmName,
+				hash:      x.HashValue,
+				salt:      x.SaltValue,
+				spinCount: x.SpinCount,
+			},
+		}
+	}
+	if worksheet.ProtectedRanges != nil {
+		for _, x := range worksheet.ProtectedRanges.ProtectedRange {
+			sheet.ProtectedRanges = append(sheet.ProtectedRanges, &ProtectedRange{
+				Name: x.Name,
+				Ref:  x.Sqref,
+				password: passwordHash{
+					legacy:    x.Password,
+					algorithm: x.AlgorithmName,
+					hash:      x.HashValue,
+					salt:      x.SaltValue,
+					spinCount: x.SpinCount,
+				},
+			})
+		}
+	}
+}
+
+// makeXLSXWorkbookProtection returns the workbookProtection element
+// for the protection of the File.
+func (f *File) makeXLSXWorkbookProtection() xlsxWorkbookProtection {
+	p := f.Protection
+	if p == nil {
+		return xlsxWorkbookProtection{}
+	}
+	return xlsxWorkbookProtection{
+		WorkbookPassword:      p.password.legacy,
+		LockStructure:         p.LockStructure,
+		LockWindows:           p.LockWindows,
+		WorkbookAlgorithmName: p.password.algorithm,
+		WorkbookHashValue:     p.password.hash,
+		WorkbookSaltValue:     p.password.salt,
+		WorkbookSpinCount:     p.password.spinCount,
+	}
+}
+
+// readWorkbookProtection reads the protection of the File from the
+// workbookProtection element x.
+func readWorkbookProtection(fi *File, x xlsxWorkbookProtection) {
+	if !x.LockStructure && !x.LockWindows {
+		return
+	}
+	fi.Protection = &WorkbookProtection{
+		LockStructure: x.LockStructure,
+		LockWindows:   x.LockWindows,
+		password: passwordHash{
+			legacy:    x.WorkbookPassword,
+			algorithm: x.WorkbookAlgorithmName,
+			hash:      x.WorkbookHashValue,
+			salt:      x.WorkbookSaltValue,
+			spinCount: x.WorkbookSpinCount,
+		},
+	}
+}
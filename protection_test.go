@@ -0,0 +1,150 @@
+package xlsx
+
+import (
+	"encoding/base64"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestProtection(t *testing.T) {
+	c := qt.New(t)
+
+	c.Run("LegacyPasswordHash", func(c *qt.C) {
+		for password, want := range map[string]string{
+			"password": "83AF",
+			"test":     "CBEB",
+			"":         "CE4B",
+		} {
+			c.Assert(legacyPasswordHash(password), qt.Equals, want, qt.Commentf("%q", password))
+		}
+		// Only the first 15 characters count.
+		c.Assert(legacyPasswordHash("abcdefghijklmnopq"), qt.Equals, legacyPasswordHash("abcdefghijklmno"))
+	})
+
+	c.Run("SaltedPasswordHash", func(c *qt.C) {
+		h, err := newPasswordHash("secret")
+		c.Assert(err, qt.IsNil)
+		c.Assert(h.algorithm, qt.Equals, "SHA-512")
+		c.Assert(h.spinCount, qt.Equals, 100000)
+		salt, err := base64.StdEncoding.DecodeString(h.salt)
+		c.Assert(err, qt.IsNil)
+		c.Assert(salt, qt.HasLen, 16)
+		sum, err := base64.StdEncoding.DecodeString(h.hash)
+		c.Assert(err, qt.IsNil)
+		c.Assert(sum, qt.HasLen, 64)
+
+		c.Assert(h.verify("secret"), qt.Equals, true)
+		c.Assert(h.verify("Secret"), qt.Equals, false)
+		c.Assert(h.verify(""), qt.Equals, false)
+
+		// Without a salted hash the legacy one is used.
+		legacy := passwordHash{legacy: h.legacy}
+		c.Assert(legacy.verify("secret"), qt.Equals, true)
+		c.Assert(legacy.verify("other"), qt.Equals, false)
+
+		none, err := newPasswordHash("")
+		c.Assert(err, qt.IsNil)
+		c.Assert(none.isSet(), qt.Equals, false)
+		c.Assert(none.verify(""), qt.Equals, true)
+		c.Assert(none.verify("secret"), qt.Equals, false)
+
+		_, err = saltedPasswordHash("secret", "MD4", salt, 1)
+		c.Assert(err, qt.ErrorMatches, `unsupported hash algorithm "MD4"`)
+	})
+
+	csRunO(c, "Sheet", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Budget")
+		c.Assert(err, qt.IsNil)
+		setCells(c, sheet, map[string]interface{}{
+			"A1": 10, "A2": 20, "A3": "=A1+A2",
+		})
+		options := ProtectionOptions{
+			AllowSelectLockedCells:   true,
+			AllowSelectUnlockedCells: true,
+			AllowFormatColumns:       true,
+			AllowSort:                true,
+		}
+		err = sheet.Protect("budget", options)
+		c.Assert(err, qt.IsNil)
+		_, err = sheet.AddProtectedRange("Inputs", "A1:A2", "")
+		c.Assert(err, qt.IsNil)
+		_, err = sheet.AddProtectedRange("Notes", "C1:C5  E1", "notes")
+		c.Assert(err, qt.IsNil)
+
+		_, err = sheet.AddProtectedRange("inputs", "B1", "")
+		c.Assert(err, qt.ErrorMatches, `AddProtectedRange: there is already a protected range called "inputs"`)
+		_, err = sheet.AddProtectedRange("Other", "Budget!B1", "")
+		c.Assert(err, qt.ErrorMatches, `AddProtectedRange: invalid range "Budget!B1"`)
+		_, err = sheet.AddProtectedRange("", "B1", "")
+		c.Assert(err, qt.ErrorMatches, `AddProtectedRange: a protected range needs a name`)
+
+		parts := writtenParts(c, f)
+		xml := parts["xl/worksheets/sheet1.xml"]
+		c.Assert(xml, qt.Contains, `password="`+legacyPasswordHash("budget")+`"`)
+		c.Assert(xml, qt.Contains, `algorithmName="SHA-512"`)
+		c.Assert(xml, qt.Contains, `spinCount="100000"`)
+		c.Assert(xml, qt.Contains, `<protectedRange sqref="A1:A2" name="Inputs"`)
+		c.Assert(xml, qt.Contains, `sqref="C1:C5 E1" name="Notes"`)
+
+		f = reopen(c, f, option)
+		sheet = f.Sheets[0]
+		p := sheet.Protection
+		c.Assert(p, qt.Not(qt.IsNil))
+		c.Assert(p.Options, qt.Equals, options)
+		c.Assert(p.HasPassword(), qt.Equals, true)
+		c.Assert(p.VerifyPassword("budget"), qt.Equals, true)
+		c.Assert(p.VerifyPassword("wrong"), qt.Equals, false)
+
+		c.Assert(sheet.ProtectedRanges, qt.HasLen, 2)
+		c.Assert(sheet.ProtectedRanges[0].Name, qt.Equals, "Inputs")
+		c.Assert(sheet.ProtectedRanges[0].HasPassword(), qt.Equals, false)
+		c.Assert(sheet.ProtectedRanges[1].Ref, qt.Equals, "C1:C5 E1")
+		c.Assert(sheet.ProtectedRanges[1].VerifyPassword("notes"), qt.Equals, true)
+
+		// Protected ranges follow inserted rows.
+		_, err = sheet.AddRowAtIndex(0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.ProtectedRanges[0].Ref, qt.Equals, "A2:A3")
+	})
+
+	csRunO(c, "SheetWithoutPassword", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		err = sheet.Protect("", ProtectionOptions{})
+		c.Assert(err, qt.IsNil)
+
+		parts := writtenParts(c, f)
+		c.Assert(parts["xl/worksheets/sheet1.xml"], qt.Contains, `<sheetProtection sheet="true" objects="true" scenarios="true" selectLockedCells="true" selectUnlockedCells="true"`)
+
+		f = reopen(c, f, option)
+		p := f.Sheets[0].Protection
+		c.Assert(p, qt.Not(qt.IsNil))
+		c.Assert(p.Options, qt.Equals, ProtectionOptions{})
+		c.Assert(p.HasPassword(), qt.Equals, false)
+		c.Assert(p.VerifyPassword(""), qt.Equals, true)
+	})
+
+	csRunO(c, "Workbook", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		_, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(f.Protection, qt.IsNil)
+		err = f.ProtectStructure("locked")
+		c.Assert(err, qt.IsNil)
+
+		parts := writtenParts(c, f)
+		c.Assert(parts["xl/workbook.xml"], qt.Contains, `<workbookProtection workbookPassword="`+legacyPasswordHash("locked")+`" lockStructure="true" workbookAlgorithmName="SHA-512"`)
+
+		f = reopen(c, f, option)
+		p := f.Protection
+		c.Assert(p, qt.Not(qt.IsNil))
+		c.Assert(p.LockStructure, qt.Equals, true)
+		c.Assert(p.LockWindows, qt.Equals, false)
+		c.Assert(p.VerifyPassword("locked"), qt.Equals, true)
+		c.Assert(p.VerifyPassword("open"), qt.Equals, false)
+		c.Assert(f.Sheets[0].Protection, qt.IsNil)
+	})
+}
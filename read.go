@@ -1,7 +1,10 @@
 package xlsx
 
 import (
+	"database/sql"
+	"encoding"
 	"errors"
+	"fmt"
 	"reflect"
 	"strconv"
 	"time"
@@ -126,3 +129,193 @@ func (r *Row) ReadStruct(ptr interface{}) error {
 	ptr = &value
 	return nil
 }
+
+// ReadOptions affect the behaviour of Sheet.ReadStructs.
+type ReadOptions struct {
+	// HeaderRow is the zero based index of the Row that contains the
+	// column names.  Data is read from every subsequent Row.
+	HeaderRow int
+	// SkipEmptyRows causes completely empty Rows to be ignored,
+	// rather than producing a zero valued struct.
+	SkipEmptyRows bool
+}
+
+// ReadStructs reads the Rows of a Sheet into the slice pointed to by
+// slicePtr, which must be a pointer to a slice of structs or of
+// pointers to structs.  Columns are matched to fields by the names
+// in the header row, using the same `xlsx:"..."` tags as
+// Sheet.WriteStructs, so that a slice written by WriteStructs can be
+// read back unchanged.  Fields without a matching column, and empty
+// cells, leave the field with its zero value.
+func (s *Sheet) ReadStructs(slicePtr interface{}, options ReadOptions) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("ReadStructs: %w", err)
+	}
+
+	v := reflect.ValueOf(slicePtr)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return wrap(errors.New("argument must be a pointer to a slice of structs"))
+	}
+	v = v.Elem()
+	elemType := v.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return wrap(errors.New("argument must be a pointer to a slice of structs"))
+	}
+
+	columns, err := structColumns(elemType)
+	if err != nil {
+		return wrap(err)
+	}
+
+	if options.HeaderRow >= s.MaxRow {
+		return nil
+	}
+	header, err := s.Row(options.HeaderRow)
+	if err != nil {
+		return wrap(err)
+	}
+	// A header may legitimately appear more than once (for example
+	// when two nested structs share field names), so the Nth column
+	// with a given name is matched to the Nth field with that name.
+	positions := make(map[string][]int)
+	header.ForEachCell(func(c *Cell) error {
+		positions[c.Value] = append(positions[c.Value], c.num)
+		return nil
+	}, SkipEmptyCells)
+	columnPos := make([]int, len(columns))
+	seen := make(map[string]int)
+	for i, column := range columns {
+		n := seen[column.name]
+		seen[column.name]++
+		if n < len(positions[column.name]) {
+			columnPos[i] = positions[column.name][n]
+		} else {
+			columnPos[i] = -1
+		}
+	}
+
+	for i := options.HeaderRow + 1; i < s.MaxRow; i++ {
+		row, err := s.Row(i)
+		if err != nil {
+			return wrap(err)
+		}
+		if options.SkipEmptyRows && row.isEmpty() {
+			continue
+		}
+		elem := reflect.New(elemType)
+		for j, column := range columns {
+			pos := columnPos[j]
+			if pos < 0 {
+				continue
+			}
+			cell := row.GetCell(pos)
+			if cell.Value == "" && len(cell.RichText) == 0 {
+				continue
+			}
+			field, _ := fieldByIndex(elem.Elem(), column.index, true)
+			err := readStructField(cell, field)
+			if err != nil {
+				return wrap(fmt.Errorf("row %d, column %q: %w", i+1, column.name, err))
+			}
+		}
+		if isPtr {
+			v.Set(reflect.Append(v, elem))
+		} else {
+			v.Set(reflect.Append(v, elem.Elem()))
+		}
+	}
+	return nil
+}
+
+// readStructField sets a single struct field from the value of cell.
+func readStructField(cell *Cell, field reflect.Value) error {
+	if field.Kind() == reflect.Ptr {
+		ptr := reflect.New(field.Type().Elem())
+		err := readStructField(cell, ptr.Elem())
+		if err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+
+	var date1904 bool
+	if cell.Row != nil && cell.Row.Sheet != nil && cell.Row.Sheet.File != nil {
+		date1904 = cell.Row.Sheet.File.Date1904
+	}
+
+	if field.Type() == timeType {
+		t, err := cell.GetTime(date1904)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	value := cell.Value
+	if len(cell.RichText) > 0 {
+		value = richTextToPlainText(cell.RichText)
+	}
+
+	switch target := field.Addr().Interface().(type) {
+	case encoding.TextUnmarshaler:
+		return target.UnmarshalText([]byte(value))
+	case sql.Scanner:
+		if cell.cellType == CellTypeBool {
+			return target.Scan(cell.Bool())
+		}
+		return target.Scan(value)
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		switch cell.cellType {
+		case CellTypeString, CellTypeInline, CellTypeStringFormula:
+			field.SetString(value)
+		default:
+			formatted, err := cell.FormattedValue()
+			if err != nil {
+				return err
+			}
+			field.SetString(formatted)
+		}
+	case reflect.Bool:
+		field.SetBool(cell.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			f, ferr := cell.Float()
+			if ferr != nil {
+				return err
+			}
+			n = int64(f)
+			if float64(n) != f {
+				return fmt.Errorf("%s is not an integer", value)
+			}
+		}
+		if field.OverflowInt(n) {
+			return fmt.Errorf("%s overflows %s", value, field.Type())
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
@@ -231,3 +231,86 @@ func TestRead(t *testing.T) {
 	})
 
 }
+
+func TestReadStructs(t *testing.T) {
+	c := qt.New(t)
+
+	type item struct {
+		Name  string  `xlsx:"name=Item"`
+		Price float64 `xlsx:"name=Price"`
+		Qty   *int    `xlsx:"name=Quantity"`
+		Note  string
+	}
+
+	csRunO(c, "HeaderRowAndEmptyRows", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Items")
+		c.Assert(err, qt.IsNil)
+
+		title := sheet.AddRow()
+		title.AddCell().SetString("Price list")
+		header := sheet.AddRow()
+		// Columns appear in a different order to the struct fields.
+		header.AddCell().SetString("Quantity")
+		header.AddCell().SetString("Item")
+		header.AddCell().SetString("Price")
+		row := sheet.AddRow()
+		row.AddCell().SetInt(3)
+		row.AddCell().SetString("Widget")
+		row.AddCell().SetFloat(1.5)
+		sheet.AddRow()
+		row = sheet.AddRow()
+		row.AddCell()
+		row.AddCell().SetString("Gadget")
+		row.AddCell().SetString("2.25")
+
+		var items []*item
+		err = sheet.ReadStructs(&items, ReadOptions{HeaderRow: 1, SkipEmptyRows: true})
+		c.Assert(err, qt.IsNil)
+		c.Assert(items, qt.HasLen, 2)
+		c.Assert(items[0].Name, qt.Equals, "Widget")
+		c.Assert(items[0].Price, qt.Equals, 1.5)
+		c.Assert(*items[0].Qty, qt.Equals, 3)
+		c.Assert(items[1].Name, qt.Equals, "Gadget")
+		c.Assert(items[1].Price, qt.Equals, 2.25)
+		c.Assert(items[1].Qty, qt.IsNil)
+
+		var all []item
+		err = sheet.ReadStructs(&all, ReadOptions{HeaderRow: 1})
+		c.Assert(err, qt.IsNil)
+		c.Assert(all, qt.HasLen, 3)
+		c.Assert(all[1], qt.DeepEquals, item{})
+	})
+
+	csRunO(c, "Errors", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, _ := f.AddSheet("Items")
+		header := sheet.AddRow()
+		header.AddCell().SetString("Price")
+		sheet.AddRow().AddCell().SetString("cheap")
+
+		var items []item
+		err := sheet.ReadStructs(items, ReadOptions{})
+		c.Assert(err, qt.ErrorMatches, "ReadStructs: argument must be a pointer to a slice of structs")
+
+		err = sheet.ReadStructs(&items, ReadOptions{})
+		c.Assert(err, qt.ErrorMatches, `ReadStructs: row 2, column "Price": .*`)
+
+		type count struct {
+			Count int8
+		}
+		var counts []count
+		header.GetCell(0).SetString("Count")
+		cell := cellAt(c, sheet, "A2")
+		cell.SetFloat(1.9)
+		err = sheet.ReadStructs(&counts, ReadOptions{})
+		c.Assert(err, qt.ErrorMatches, `ReadStructs: row 2, column "Count": 1.9 is not an integer`)
+		cell.SetInt(300)
+		err = sheet.ReadStructs(&counts, ReadOptions{})
+		c.Assert(err, qt.ErrorMatches, `ReadStructs: row 2, column "Count": 300 overflows int8`)
+		cell.SetInt(-12)
+		err = sheet.ReadStructs(&counts, ReadOptions{})
+		c.Assert(err, qt.IsNil)
+		c.Assert(counts, qt.DeepEquals, []count{{Count: -12}})
+	})
+}
@@ -7,6 +7,7 @@ import (
 // Row represents a single Row in the current Sheet.
 type Row struct {
 	Hidden       bool    // Hidden determines whether this Row is hidden or not.
+	Collapsed    bool    // Collapsed marks this Row as the summary of a collapsed outline group.
 	Sheet        *Sheet  // Sheet is a reference back to the Sheet that this Row is within.
 	height       float64 // Height is the current height of the Row in PostScript Points
 	outlineLevel uint8   // OutlineLevel contains the outline level of this Row.  Used for collapsing.
@@ -48,6 +49,12 @@ func (r *Row) GetOutlineLevel() uint8 {
 	return r.outlineLevel
 }
 
+// isFormatted reports whether the Row is hidden, collapsed or in an
+// outline group, which are kept even if the Row has no cells.
+func (r *Row) isFormatted() bool {
+	return r.Hidden || r.Collapsed || r.outlineLevel > 0
+}
+
 // AddCell adds a new Cell to the Row
 func (r *Row) AddCell() *Cell {
 	cell := newCell(r, r.cellCount)
@@ -83,6 +90,11 @@ func (r *Row) growCellsSlice(newSize int) {
 
 // GetCell returns the Cell at a given column index, creating it if it doesn't exist.
 func (r *Row) GetCell(colIdx int) *Cell {
+	if colIdx >= r.cellCount {
+		// Keep the count in step, so that the Row isn't considered
+		// empty and AddCell appends after this Cell.
+		r.cellCount = colIdx + 1
+	}
 	if colIdx >= len(r.cells) {
 		cell := newCell(r, colIdx)
 		r.growCellsSlice(colIdx + 1)
@@ -99,6 +111,37 @@ func (r *Row) GetCell(colIdx int) *Cell {
 	return cell
 }
 
+// shiftCells moves the cells of the Row from the zero based column at
+// onwards n columns to the right or, when n is negative, removes -n
+// cells from at onwards and moves those after them to the left.
+func (r *Row) shiftCells(at, n int) {
+	if at < len(r.cells) {
+		if n > 0 {
+			cells := make([]*Cell, len(r.cells)+n)
+			copy(cells, r.cells[:at])
+			copy(cells[at+n:], r.cells[at:])
+			r.cells = cells
+		} else {
+			end := at - n
+			if end > len(r.cells) {
+				end = len(r.cells)
+			}
+			r.cells = append(r.cells[:at:at], r.cells[end:]...)
+		}
+		for i, c := range r.cells {
+			if c != nil {
+				c.num = i
+			}
+		}
+	}
+	if r.cellCount > at {
+		r.cellCount += n
+		if r.cellCount < at {
+			r.cellCount = at
+		}
+	}
+}
+
 // cellVisitorFlags contains flags that can be set by CellVisitorOption implementations to modify the behaviour of ForEachCell
 type cellVisitorFlags struct {
 	// skipEmptyCells indicates if we should skip nil cells.
@@ -110,7 +153,8 @@ type cellVisitorFlags struct {
 type CellVisitorOption func(flags *cellVisitorFlags)
 
 // SkipEmptyCells can be passed as an option to Row.ForEachCell in
-// order to make it skip over empty cells in the sheet.
+// order to make it skip over empty cells in the sheet.  A cell with a
+// formula or rich text is not empty, even if it has no value.
 func SkipEmptyCells(flags *cellVisitorFlags) {
 	flags.skipEmptyCells = true
 }
@@ -132,7 +176,7 @@ func (r *Row) ForEachCell(cvf CellVisitorFunc, option ...CellVisitorOption) erro
 			}
 			c = r.GetCell(ci)
 		}
-		if c.Value == "" && flags.skipEmptyCells {
+		if c.Value == "" && c.formula == "" && len(c.RichText) == 0 && flags.skipEmptyCells {
 			return nil
 		}
 		c.Row = r
@@ -159,3 +203,13 @@ func (r *Row) ForEachCell(cvf CellVisitorFunc, option ...CellVisitorOption) erro
 
 	return nil
 }
+
+// isEmpty reports whether the Row has no cells with content.
+func (r *Row) isEmpty() bool {
+	for _, c := range r.cells {
+		if c != nil && (c.Value != "" || len(c.RichText) > 0 || c.formula != "") {
+			return false
+		}
+	}
+	return true
+}
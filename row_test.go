@@ -81,4 +81,25 @@ func TestRow(t *testing.T) {
 		})
 
 	})
+
+	// A formula that hasn't been calculated, and rich text, have no
+	// value, but their cells aren't empty.
+	csRunO(c, "SkipEmptyCellsVisitsFormulasAndRichText", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		row := sheet.AddRow()
+		row.AddCell().SetString("Total")
+		row.AddCell()
+		row.AddCell().SetFormula("SUM(D1:D9)")
+		row.AddCell().SetRichText([]RichTextRun{{Text: "Note"}})
+
+		var visited []int
+		err = row.ForEachCell(func(c *Cell) error {
+			visited = append(visited, c.num)
+			return nil
+		}, SkipEmptyCells)
+		c.Assert(err, qt.IsNil)
+		c.Assert(visited, qt.DeepEquals, []int{0, 2, 3})
+	})
 }
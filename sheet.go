@@ -8,25 +8,43 @@ import (
 	"strconv"
 
 	"github.com/shabbyrobe/xmlwriter"
+	"github.com/tealeg/xlsx/v3/formula"
 )
 
 // Sheet is a high level structure intended to provide user access to
 // the contents of a particular sheet within an XLSX file.
 type Sheet struct {
-	Name            string
-	File            *File
-	Cols            *ColStore
-	MaxRow          int
-	MaxCol          int
-	Hidden          bool
-	Selected        bool
-	SheetViews      []SheetView
-	SheetFormat     SheetFormat
-	AutoFilter      *AutoFilter
-	Relations       []Relation
-	DataValidations []*xlsxDataValidation
-	cellStore       CellStore
-	currentRow      *Row
+	Name               string
+	File               *File
+	Cols               *ColStore
+	MaxRow             int
+	MaxCol             int
+	Hidden             bool
+	Selected           bool
+	SheetViews         []SheetView
+	SheetFormat        SheetFormat
+	AutoFilter         *AutoFilter
+	Relations          []Relation
+	DataValidations    []*xlsxDataValidation
+	Pictures           []*Picture
+	Charts             []*Chart
+	SparklineGroups    []*SparklineGroup
+	ConditionalFormats []*ConditionalFormat
+	Tables             []*Table
+	Protection         *SheetProtection
+	ProtectedRanges    []*ProtectedRange
+	PageSetup          *PageSetup
+	PrintOptions       *PrintOptions
+	Margins            *PageMargins
+	HeaderFooter       *HeaderFooter
+	Outline            Outline
+	pivotTables        []*PivotTable
+	rowBreaks          []int
+	colBreaks          []int
+	cellStore          CellStore
+	currentRow         *Row
+	dynamicArrays      bool // set if the sheet was written with dynamic array formulas
+	merged             map[mergeOrigin]*Range
 }
 
 // NewSheet constructs a Sheet with the default CellStore and returns
@@ -64,16 +82,37 @@ func (s *Sheet) getState() string {
 	return "visible"
 }
 
-type SheetView struct {
-	Pane *Pane
-}
+// SetName renames the Sheet.  Formulas, defined names and the other
+// references throughout the File that use the old name, such as
+// "'Old Name'!A1", are changed to use the new one.
+func (s *Sheet) SetName(name string) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("SetName: %w", err)
+	}
 
-type Pane struct {
-	XSplit      float64
-	YSplit      float64
-	TopLeftCell string
-	ActivePane  string
-	State       string // Either "split" or "frozen"
+	if name == s.Name {
+		return nil
+	}
+	err := checkSheetName(name)
+	if err != nil {
+		return wrap(err)
+	}
+	if s.File != nil {
+		if other := s.File.sheetByName(name); other != nil && other != s {
+			return wrap(fmt.Errorf("duplicate sheet name '%s'.", name))
+		}
+	}
+	oldName := s.Name
+	err = s.adjustReferences(renameAdjustment(oldName, name))
+	if err != nil {
+		return wrap(err)
+	}
+	s.Name = name
+	if s.File != nil && s.File.Sheet[oldName] == s {
+		delete(s.File.Sheet, oldName)
+		s.File.Sheet[name] = s
+	}
+	return nil
 }
 
 type SheetFormat struct {
@@ -116,6 +155,119 @@ func (s *Sheet) addRelation(relType RelationshipType, target string, targetMode
 	s.Relations = append(s.Relations, newRel)
 }
 
+// sheetParts collects the parts, other than the worksheet itself,
+// that a Sheet is written with, and the relationships and content
+// types they need.
+type sheetParts struct {
+	index int // the one based index of the Sheet in the File
+	rels  *xlsxWorksheetRels
+	types *xlsxTypes
+	wp    *workbookParts
+	dp    *drawingPart
+	names []string
+	parts map[string]string
+}
+
+// workbookParts keeps track of the parts that are shared by, or
+// numbered across, the sheets of a File.
+type workbookParts struct {
+	// media holds the names of the media parts, such as images,
+	// by their contents, so that each is written once however many
+	// times it is used.
+	media map[string]string
+	// charts is the number of charts written so far.
+	charts int
+	// tables is the number of tables written so far, which is also
+	// the id of the last one.
+	tables int
+	// pivotTables is the number of pivot tables written so far.
+	pivotTables int
+	// pivotCaches are the pivot caches that the pivot tables written
+	// so far refer to, in the order they are written.
+	pivotCaches []*PivotCache
+}
+
+func newWorkbookParts() *workbookParts {
+	return &workbookParts{media: make(map[string]string)}
+}
+
+func newSheetParts(index int, rels *xlsxWorksheetRels, types *xlsxTypes, wp *workbookParts) *sheetParts {
+	return &sheetParts{index: index, rels: rels, types: types, wp: wp, parts: make(map[string]string)}
+}
+
+// addPart adds the part called name.  A content type override is
+// added for the part unless contentType is empty.
+func (sp *sheetParts) addPart(name, body, contentType string) {
+	sp.names = append(sp.names, name)
+	sp.parts[name] = body
+	if contentType != "" {
+		sp.types.Overrides = append(sp.types.Overrides, xlsxOverride{PartName: "/" + name, ContentType: contentType})
+	}
+}
+
+// addDefault adds the default content type for parts with the given
+// extension, if there isn't one already.
+func (sp *sheetParts) addDefault(extension, contentType string) {
+	for _, d := range sp.types.Defaults {
+		if d.Extension == extension {
+			return
+		}
+	}
+	sp.types.Defaults = append(sp.types.Defaults, xlsxDefault{Extension: extension, ContentType: contentType})
+}
+
+// addMedia adds a media part holding data, with the given file
+// extension and content type, unless there is one already, and
+// returns its name.
+func (sp *sheetParts) addMedia(data []byte, extension, contentType string) string {
+	if name, ok := sp.wp.media[string(data)]; ok {
+		return name
+	}
+	name := fmt.Sprintf("xl/media/image%d.%s", len(sp.wp.media)+1, extension)
+	sp.wp.media[string(data)] = name
+	sp.addPart(name, string(data), "")
+	sp.addDefault(extension, contentType)
+	return name
+}
+
+// addRelation adds a relationship from the worksheet to target and
+// returns its id.
+func (sp *sheetParts) addRelation(relType RelationshipType, target string) string {
+	if sp.rels == nil {
+		sp.rels = &xlsxWorksheetRels{XMLName: xml.Name{Local: "Relationships"}}
+	}
+	id := "rId" + strconv.Itoa(len(sp.rels.Relationships)+1)
+	sp.rels.Relationships = append(sp.rels.Relationships, xlsxWorksheetRelation{Id: id, Type: relType, Target: target})
+	return id
+}
+
+// makeSheetParts returns the parts, other than the worksheet itself,
+// that the Sheet with the one based index sheetIndex is written with.
+// rels are the relationships of the Sheet's worksheet so far and wp
+// the parts of the File written so far.
+func (s *Sheet) makeSheetParts(sheetIndex int, rels *xlsxWorksheetRels, types *xlsxTypes, wp *workbookParts) (*sheetParts, error) {
+	sp := newSheetParts(sheetIndex, rels, types, wp)
+	s.makePictureParts(sp)
+	s.makeChartParts(sp)
+	err := sp.makeDrawingPart()
+	if err != nil {
+		return nil, err
+	}
+	err = s.makeCommentParts(sp)
+	if err != nil {
+		return nil, err
+	}
+	err = s.makeTableParts(sp)
+	if err != nil {
+		return nil, err
+	}
+	err = s.makePivotTableParts(sp)
+	if err != nil {
+		return nil, err
+	}
+	return sp, nil
+}
+
 func (s *Sheet) setCurrentRow(r *Row) {
 	if r == nil {
 		return
@@ -134,7 +286,8 @@ func (s *Sheet) setCurrentRow(r *Row) {
 
 // rowVisitorFlags contains flags that can be set by a RowVisitorOption to affect the behaviour of sheet.ForEachRow
 type rowVisitorFlags struct {
-	skipEmptyRows bool
+	skipEmptyRows     bool
+	keepFormattedRows bool
 }
 
 // RowVisitorOption defines the call signature of functions that can be passed as options to the Sheet.ForEachRow function to affect its behaviour.
@@ -146,6 +299,14 @@ func SkipEmptyRows(flags *rowVisitorFlags) {
 	flags.skipEmptyRows = true
 }
 
+// skipUnformattedEmptyRows skips the Rows that SkipEmptyRows does,
+// except for those that must be written despite having no cells,
+// because they are hidden, or part of an outline group.
+func skipUnformattedEmptyRows(flags *rowVisitorFlags) {
+	flags.skipEmptyRows = true
+	flags.keepFormattedRows = true
+}
+
 // A RowVisitor function should be provided by the user when calling
 // Sheet.ForEachRow, it will be called once for every Row visited.
 type RowVisitor func(r *Row) error
@@ -171,7 +332,9 @@ func (s *Sheet) ForEachRow(rv RowVisitor, options ...RowVisitorOption) error {
 			r = &Row{num: i}
 		}
 		if r.cellCount == 0 && flags.skipEmptyRows {
-			continue
+			if !flags.keepFormattedRows || !r.isFormatted() {
+				continue
+			}
 		}
 		r.Sheet = s
 		s.setCurrentRow(r)
@@ -199,14 +362,24 @@ func makeRowKey(s *Sheet, i int) string {
 	return fmt.Sprintf("%s:%06d", s.Name, i)
 }
 
-// Add a new Row to a Sheet at a specific index
+// Add a new Row to a Sheet at a specific index.  References to the
+// Rows that move, in formulas, merged cells, data validations, the
+// AutoFilter and defined names throughout the File, are updated to
+// follow them, as they are in Excel.
 func (s *Sheet) AddRowAtIndex(index int) (*Row, error) {
 	if index < 0 || index > s.MaxRow {
 		return nil, errors.New("AddRowAtIndex: index out of bounds")
 	}
 
+	err := s.adjustReferences(rowAdjustment(s, index, 1))
+	if err != nil {
+		return nil, fmt.Errorf("AddRowAtIndex: %w", err)
+	}
+
 	if s.currentRow != nil {
 		s.cellStore.WriteRow(s.currentRow)
+		// The current Row is about to move
+		s.currentRow = nil
 	}
 
 	// We move rows in reverse order to avoid overwriting anyting
@@ -219,10 +392,11 @@ func (s *Sheet) AddRowAtIndex(index int) (*Row, error) {
 		s.cellStore.MoveRow(nRow, i+1)
 	}
 	row := &Row{Sheet: s, num: index}
-	err := s.cellStore.WriteRow(row)
+	err = s.cellStore.WriteRow(row)
 	if err != nil {
 		return nil, err
 	}
+	s.currentRow = row
 	s.MaxRow++
 	return row, nil
 }
@@ -232,19 +406,25 @@ func (s *Sheet) AddDataValidation(dv *xlsxDataValidation) {
 	s.DataValidations = append(s.DataValidations, dv)
 }
 
-// Removes a row at a specific index
+// Removes a row at a specific index.  References to the Rows that
+// move are updated as they are by AddRowAtIndex, and references to
+// cells in the removed Row become #REF!.
 func (s *Sheet) RemoveRowAtIndex(index int) error {
 	if index < 0 || index >= s.MaxRow {
 		return fmt.Errorf("Cannot remove row: index out of range: %d", index)
 	}
+	err := s.adjustReferences(rowAdjustment(s, index, -1))
+	if err != nil {
+		return fmt.Errorf("RemoveRowAtIndex: %w", err)
+	}
 	if s.currentRow != nil {
-		if index == s.currentRow.num {
-			s.currentRow = nil
-		} else {
+		if index != s.currentRow.num {
 			s.cellStore.WriteRow(s.currentRow)
 		}
+		// The current Row is about to move or be removed
+		s.currentRow = nil
 	}
-	err := s.cellStore.RemoveRow(makeRowKey(s, index))
+	err = s.cellStore.RemoveRow(makeRowKey(s, index))
 	if err != nil {
 		return err
 	}
@@ -261,6 +441,74 @@ func (s *Sheet) RemoveRowAtIndex(index int) error {
 	return nil
 }
 
+// InsertColsAt inserts n empty columns before the zero based column
+// index, moving the cells of every Row, and the Cols that describe
+// the columns, to the right.  As with AddRowAtIndex, references to
+// the cells that move are updated throughout the File.
+func (s *Sheet) InsertColsAt(index, n int) error {
+	if index < 0 || n < 1 || index+n > formula.MaxCols {
+		return errors.New("InsertColsAt: index out of bounds")
+	}
+	err := s.adjustReferences(colAdjustment(s, index, n))
+	if err != nil {
+		return fmt.Errorf("InsertColsAt: %w", err)
+	}
+	err = s.shiftCols(index, n)
+	if err != nil {
+		return fmt.Errorf("InsertColsAt: %w", err)
+	}
+	if s.MaxCol > index {
+		s.MaxCol += n
+	}
+	err = s.nameInsertedTableColumns()
+	if err != nil {
+		return fmt.Errorf("InsertColsAt: %w", err)
+	}
+	return nil
+}
+
+// RemoveColsAt removes n columns from the zero based column index
+// onwards, moving the cells of every Row, and the Cols that describe
+// the columns, that follow them to the left.  References to the cells
+// that move are updated throughout the File, and references to
+// removed cells become #REF!.
+func (s *Sheet) RemoveColsAt(index, n int) error {
+	if index < 0 || n < 1 || index+n > formula.MaxCols {
+		return fmt.Errorf("RemoveColsAt: index out of range: %d", index)
+	}
+	err := s.adjustReferences(colAdjustment(s, index, -n))
+	if err != nil {
+		return fmt.Errorf("RemoveColsAt: %w", err)
+	}
+	err = s.shiftCols(index, -n)
+	if err != nil {
+		return fmt.Errorf("RemoveColsAt: %w", err)
+	}
+	if s.MaxCol > index {
+		s.MaxCol -= n
+		if s.MaxCol < index {
+			s.MaxCol = index
+		}
+	}
+	return nil
+}
+
+// shiftCols moves the cells of every Row, and the Cols, for n columns
+// inserted before column at, or -n columns removed from at onwards.
+func (s *Sheet) shiftCols(at, n int) error {
+	err := s.ForEachRow(func(r *Row) error {
+		r.shiftCells(at, n)
+		return nil
+	}, SkipEmptyRows)
+	if err != nil {
+		return err
+	}
+	if s.Cols != nil {
+		s.Cols.shiftCols(at+1, n)
+	}
+	return nil
+}
+
 // Make sure we always have as many Rows as we do cells.
 func (s *Sheet) maybeAddRow(rowCount int) {
 	if rowCount > s.MaxRow {
@@ -374,7 +622,9 @@ func (s *Sheet) setCol(min, max int, setter func(col *Col)) {
 			setter(newCol)
 			s.Cols.Add(newCol)
 		default:
-			newCol := col.copyToRange(min, max)
+			// The column lies within the range, so it
+			// keeps its own bounds.
+			newCol := col.copyToRange(col.Min, col.Max)
 			setter(newCol)
 			s.Cols.Add(newCol)
 
@@ -441,16 +691,11 @@ func (s *Sheet) handleMerged() {
 }
 
 func (s *Sheet) makeSheetView(worksheet *xlsxWorksheet) {
-	for index, sheetView := range s.SheetViews {
-		if sheetView.Pane != nil {
-			worksheet.SheetViews.SheetView[index].Pane = &xlsxPane{
-				XSplit:      sheetView.Pane.XSplit,
-				YSplit:      sheetView.Pane.YSplit,
-				TopLeftCell: sheetView.Pane.TopLeftCell,
-				ActivePane:  sheetView.Pane.ActivePane,
-				State:       sheetView.Pane.State,
-			}
-
+	if len(s.SheetViews) > 0 {
+		defaultView := worksheet.SheetViews.SheetView[0]
+		worksheet.SheetViews.SheetView = make([]xlsxSheetView, len(s.SheetViews))
+		for index, sheetView := range s.SheetViews {
+			worksheet.SheetViews.SheetView[index] = sheetView.makeXLSXSheetView(defaultView)
 		}
 	}
 	if s.Selected {
@@ -498,8 +743,8 @@ func (s *Sheet) makeCols(worksheet *xlsxWorksheet, styles *xlsxStyleSheet) (maxL
 			}
 			worksheet.Cols.Col = append(worksheet.Cols.Col,
 				xlsxCol{
-					Min:          col.Min + 1,
-					Max:          col.Max + 1,
+					Min:          col.Min,
+					Max:          col.Max,
 					Hidden:       col.Hidden,
 					Width:        col.Width,
 					CustomWidth:  col.CustomWidth,
@@ -614,6 +859,10 @@ func (s *Sheet) makeRows(worksheet *xlsxWorksheet, styles *xlsxStyleSheet, refTa
 	maxCell := 0
 	var maxLevelRow uint8
 	xSheet := xlsxSheetData{}
+	fw, err := newFormulaWriter(s)
+	if err != nil {
+		return err
+	}
 	makeR := func(row *Row) error {
 		r := row.num
 		if r > maxRow {
@@ -626,6 +875,8 @@ func (s *Sheet) makeRows(worksheet *xlsxWorksheet, styles *xlsxStyleSheet, refTa
 			xRow.Ht = fmt.Sprintf("%g", row.GetHeight())
 		}
 		xRow.OutlineLevel = row.GetOutlineLevel()
+		xRow.Hidden = row.Hidden
+		xRow.Collapsed = row.Collapsed
 		if xRow.OutlineLevel > maxLevelRow {
 			maxLevelRow = xRow.OutlineLevel
 		}
@@ -660,9 +911,7 @@ func (s *Sheet) makeRows(worksheet *xlsxWorksheet, styles *xlsxStyleSheet, refTa
 				S: XfId,
 				R: GetCellIDStringFromCoords(c, r),
 			}
-			if cell.formula != "" {
-				xC.F = &xlsxF{Content: cell.formula}
-			}
+			fw.write(&xC, cell, r)
 			switch cell.cellType {
 			case CellTypeInline:
 				// Inline strings are turned into shared strings since they are more efficient.
@@ -751,10 +1000,11 @@ func (s *Sheet) makeRows(worksheet *xlsxWorksheet, styles *xlsxStyleSheet, refTa
 		return nil
 	}
 
-	err := s.ForEachRow(makeR, SkipEmptyRows)
+	err = s.ForEachRow(makeR, skipUnformattedEmptyRows)
 	if err != nil {
 		return err
 	}
+	s.dynamicArrays = fw.dynamicArrays
 
 	// Update sheet format with the freshly determined max levels
 	s.SheetFormat.OutlineLevelCol = maxLevelCol
@@ -798,13 +1048,21 @@ func (s *Sheet) MarshalSheet(w io.Writer, refTable *RefTable, styles *xlsxStyleS
 	s.handleMerged()
 	s.makeSheetView(worksheet)
 	s.makeSheetFormatPr(worksheet)
+	s.makeOutline(worksheet)
 	maxLevelCol := s.makeCols(worksheet, styles)
+	s.makeConditionalFormatting(worksheet, styles)
 	s.makeDataValidations(worksheet)
+	s.makeProtection(worksheet)
+	s.makePageSetup(worksheet)
+	s.makePageBreaks(worksheet)
 	s.prepSheetForMarshalling(maxLevelCol)
 	err := s.prepWorksheetFromRows(worksheet, relations)
 	if err != nil {
 		return err
 	}
+	s.makeDrawings(worksheet, relations)
+	s.makeTableRefs(worksheet, relations)
+	s.makeSparklines(worksheet)
 	xw := xmlwriter.Open(w)
 
 	err = xw.StartDoc(xmlwriter.Doc{})
@@ -830,9 +1088,17 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet, relati
 
 	s.makeSheetView(worksheet)
 	s.makeSheetFormatPr(worksheet)
+	s.makeOutline(worksheet)
 	maxLevelCol := s.makeCols(worksheet, styles)
+	s.makeConditionalFormatting(worksheet, styles)
 	s.makeDataValidations(worksheet)
+	s.makeProtection(worksheet)
+	s.makePageSetup(worksheet)
+	s.makePageBreaks(worksheet)
 	s.makeRows(worksheet, styles, refTable, relations, maxLevelCol)
+	s.makeDrawings(worksheet, relations)
+	s.makeTableRefs(worksheet, relations)
+	s.makeSparklines(worksheet)
 
 	return worksheet
 }
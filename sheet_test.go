@@ -615,6 +615,48 @@ func TestMakeXLSXSheet(t *testing.T) {
 		c.Assert(sheet.Cols.FindColByIndex(2).Min, qt.Equals, 2)
 	})
 
+	// A column that is already set within the range keeps its own
+	// bounds and settings, other than the width.
+	csRunO(c, "SetColWidthOverNarrowerCol", func(c *qt.C, option FileOption) {
+		file := NewFile(option)
+		sheet, _ := file.AddSheet("Sheet1")
+		sheet.SetColWidth(3, 3, 5)
+		sheet.Cols.FindColByIndex(3).Hidden = bPtr(true)
+		sheet.SetColWidth(1, 6, 20)
+		for i := 1; i <= 6; i++ {
+			col := sheet.Cols.FindColByIndex(i)
+			c.Assert(col, qt.Not(qt.IsNil), qt.Commentf("column %d", i))
+			c.Assert(*col.Width, qt.Equals, float64(20), qt.Commentf("column %d", i))
+			c.Assert(col.Hidden != nil && *col.Hidden, qt.Equals, i == 3, qt.Commentf("column %d", i))
+		}
+		col := sheet.Cols.FindColByIndex(3)
+		c.Assert(col.Min, qt.Equals, 3)
+		c.Assert(col.Max, qt.Equals, 3)
+		c.Assert(sheet.Cols.FindColByIndex(7), qt.IsNil)
+	})
+
+	// MakeStreamParts and MarshalSheet both write the 1 based
+	// column numbers of the ColStore.
+	csRunO(c, "ColMinMax", func(c *qt.C, option FileOption) {
+		file := NewFile(option)
+		sheet, _ := file.AddSheet("Sheet1")
+		row := sheet.AddRow()
+		cell1 := row.AddCell()
+		cell1.Value = "A cell!"
+		sheet.SetColWidth(2, 3, 14)
+
+		parts, err := file.MakeStreamParts()
+		c.Assert(err, qt.IsNil)
+		c.Assert(parts["xl/worksheets/sheet1.xml"], qt.Contains, `<col max="3" min="2"`)
+
+		var buf bytes.Buffer
+		refTable := NewSharedStringRefTable()
+		styles := newXlsxStyleSheet(nil)
+		err = sheet.MarshalSheet(&buf, refTable, styles, nil)
+		c.Assert(err, qt.IsNil)
+		c.Assert(buf.String(), qt.Contains, `<col max="3" min="2"`)
+	})
+
 	csRunO(c, "SetDataValidation", func(c *qt.C, option FileOption) {
 		file := NewFile(option)
 		sheet, _ := file.AddSheet("Sheet1")
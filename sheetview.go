@@ -0,0 +1,219 @@
+package xlsx
+
+import (
+	"fmt"
+
+	"github.com/tealeg/xlsx/v3/formula"
+)
+
+// ViewMode is the way that a SheetView shows its Sheet.
+type ViewMode string
+
+const (
+	ViewNormal           ViewMode = "normal"
+	ViewPageLayout       ViewMode = "pageLayout"
+	ViewPageBreakPreview ViewMode = "pageBreakPreview"
+)
+
+// SheetView is a way of looking at a Sheet in a window.  The zero
+// value looks at it as Excel would by default, so the things that
+// Excel shows unless told otherwise, such as the grid lines, are
+// hidden by the Hide fields rather than shown by Show ones.
+type SheetView struct {
+	Pane *Pane
+	// View is ViewNormal if it is empty.
+	View ViewMode
+	// TopLeftCell is the cell, such as "A1", shown at the top left
+	// of the window, or of its top left pane if it is split.
+	TopLeftCell string
+	// ZoomScale is the percentage, from 10 to 400, that the Sheet
+	// is shown at; 0 is 100.  ZoomScaleNormal and
+	// ZoomScalePageLayoutView are the percentages that Excel
+	// returns to when the view is switched to ViewNormal or
+	// ViewPageLayout.
+	ZoomScale               int
+	ZoomScaleNormal         int
+	ZoomScalePageLayoutView int
+	HideGridLines           bool
+	HideRowColHeaders       bool
+	HideZeros               bool
+	ShowFormulas            bool
+	// RightToLeft shows the first column on the right, as is usual
+	// for languages such as Arabic and Hebrew.
+	RightToLeft bool
+	// Selection holds the selected cells of each pane.  If it is
+	// empty the top left cell of the active pane is selected.
+	Selection []Selection
+}
+
+// Pane splits a SheetView into two or four panes, which scroll
+// together or, if the State is "frozen", don't scroll at all.  XSplit
+// and YSplit are the number of columns and rows before the split if
+// it is frozen, and its position, in twentieths of a point, if not.
+type Pane struct {
+	XSplit      float64
+	YSplit      float64
+	TopLeftCell string
+	// ActivePane is "bottomRight", "topRight", "bottomLeft" or
+	// "topLeft".
+	ActivePane string
+	State      string // Either "split" or "frozen"
+}
+
+// Selection is the selected cells of a pane of a SheetView.
+type Selection struct {
+	// Pane is the pane the selection is in, which is "topLeft" if
+	// it is empty.
+	Pane string
+	// ActiveCell is the cell, such as "B2", that has the focus.
+	ActiveCell string
+	// ActiveCellId is the index in SQRef of the range that holds
+	// the active cell.
+	ActiveCellId int
+	// SQRef is the selected ranges, separated by spaces, such as
+	// "B2:C4 E2".
+	SQRef string
+}
+
+// FreezePanes freezes the first rows and the first cols of the Sheet,
+// so that they stay in place as the rest of it scrolls.  Freezing no
+// rows and no columns unfreezes them.  It changes the first of the
+// SheetViews, which it adds if there are none.
+func (s *Sheet) FreezePanes(rows, cols int) error {
+	if rows < 0 || rows >= formula.MaxRows || cols < 0 || cols >= formula.MaxCols {
+		return fmt.Errorf("FreezePanes: cannot freeze %d rows and %d columns", rows, cols)
+	}
+	if len(s.SheetViews) == 0 {
+		s.SheetViews = []SheetView{{}}
+	}
+	view := &s.SheetViews[0]
+	view.Selection = nil
+	if rows == 0 && cols == 0 {
+		view.Pane = nil
+		return nil
+	}
+	view.Pane = &Pane{
+		XSplit:      float64(cols),
+		YSplit:      float64(rows),
+		TopLeftCell: GetCellIDStringFromCoords(cols, rows),
+		State:       "frozen",
+	}
+	switch {
+	case rows > 0 && cols > 0:
+		view.Pane.ActivePane = "bottomRight"
+	case rows > 0:
+		view.Pane.ActivePane = "bottomLeft"
+	default:
+		view.Pane.ActivePane = "topRight"
+	}
+	return nil
+}
+
+// FrozenPanes returns the number of rows and columns that are frozen
+// in the first of the SheetViews.
+func (s *Sheet) FrozenPanes() (rows, cols int) {
+	if len(s.SheetViews) == 0 {
+		return 0, 0
+	}
+	pane := s.SheetViews[0].Pane
+	if pane == nil || (pane.State != "frozen" && pane.State != "frozenSplit") {
+		return 0, 0
+	}
+	return int(pane.YSplit), int(pane.XSplit)
+}
+
+// makeXLSXSheetView returns the xlsxSheetView for v, with anything
+// that v leaves to the defaults taken from defaultView.
+func (v SheetView) makeXLSXSheetView(defaultView xlsxSheetView) xlsxSheetView {
+	xView := defaultView
+	xView.ShowFormulas = v.ShowFormulas
+	xView.ShowGridLines = bPtr(!v.HideGridLines)
+	xView.ShowRowColHeaders = bPtr(!v.HideRowColHeaders)
+	xView.ShowZeros = bPtr(!v.HideZeros)
+	xView.RightToLeft = v.RightToLeft
+	if v.View != "" {
+		xView.View = string(v.View)
+	}
+	if v.TopLeftCell != "" {
+		xView.TopLeftCell = v.TopLeftCell
+	}
+	if v.ZoomScale != 0 {
+		xView.ZoomScale = float64(v.ZoomScale)
+	}
+	if v.ZoomScaleNormal != 0 {
+		xView.ZoomScaleNormal = float64(v.ZoomScaleNormal)
+	}
+	if v.ZoomScalePageLayoutView != 0 {
+		xView.ZoomScalePageLayoutView = float64(v.ZoomScalePageLayoutView)
+	}
+
+	if v.Pane != nil {
+		xView.Pane = &xlsxPane{
+			XSplit:      v.Pane.XSplit,
+			YSplit:      v.Pane.YSplit,
+			TopLeftCell: v.Pane.TopLeftCell,
+			ActivePane:  v.Pane.ActivePane,
+			State:       v.Pane.State,
+		}
+	}
+
+	xView.Selection = nil
+	for _, selection := range v.Selection {
+		xView.Selection = append(xView.Selection, xlsxSelection{
+			Pane:         selection.Pane,
+			ActiveCell:   selection.ActiveCell,
+			ActiveCellId: selection.ActiveCellId,
+			SQRef:        selection.SQRef,
+		})
+	}
+	if len(xView.Selection) == 0 {
+		selection := xlsxSelection{Pane: "topLeft", ActiveCell: "A1", SQRef: "A1"}
+		if xView.Pane != nil && xView.Pane.ActivePane != "" {
+			selection.Pane = xView.Pane.ActivePane
+		}
+		if xView.Pane != nil && xView.Pane.TopLeftCell != "" {
+			selection.ActiveCell = xView.Pane.TopLeftCell
+			selection.SQRef = xView.Pane.TopLeftCell
+		}
+		xView.Selection = []xlsxSelection{selection}
+	}
+	return xView
+}
+
+// readSheetView returns the SheetView that xSheetView describes.
+func readSheetView(xSheetView xlsxSheetView) SheetView {
+	hidden := func(shown *bool) bool {
+		return shown != nil && !*shown
+	}
+	sheetView := SheetView{
+		View:                    ViewMode(xSheetView.View),
+		TopLeftCell:             xSheetView.TopLeftCell,
+		ZoomScale:               int(xSheetView.ZoomScale),
+		ZoomScaleNormal:         int(xSheetView.ZoomScaleNormal),
+		ZoomScalePageLayoutView: int(xSheetView.ZoomScalePageLayoutView),
+		HideGridLines:           hidden(xSheetView.ShowGridLines),
+		HideRowColHeaders:       hidden(xSheetView.ShowRowColHeaders),
+		HideZeros:               hidden(xSheetView.ShowZeros),
+		ShowFormulas:            xSheetView.ShowFormulas,
+		RightToLeft:             xSheetView.RightToLeft,
+	}
+	if xSheetView.Pane != nil {
+		xlsxPane := xSheetView.Pane
+		sheetView.Pane = &Pane{
+			XSplit:      xlsxPane.XSplit,
+			YSplit:      xlsxPane.YSplit,
+			TopLeftCell: xlsxPane.TopLeftCell,
+			ActivePane:  xlsxPane.ActivePane,
+			State:       xlsxPane.State,
+		}
+	}
+	for _, selection := range xSheetView.Selection {
+		sheetView.Selection = append(sheetView.Selection, Selection{
+			Pane:         selection.Pane,
+			ActiveCell:   selection.ActiveCell,
+			ActiveCellId: selection.ActiveCellId,
+			SQRef:        selection.SQRef,
+		})
+	}
+	return sheetView
+}
@@ -0,0 +1,108 @@
+package xlsx
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestSheetView(t *testing.T) {
+	c := qt.New(t)
+
+	csRunO(c, "FreezePanes", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Report")
+		c.Assert(err, qt.IsNil)
+		setCells(c, sheet, map[string]interface{}{"A1": "Name", "B1": "Total"})
+
+		err = sheet.FreezePanes(1, 2)
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.SheetViews[0].Pane, qt.DeepEquals, &Pane{
+			XSplit:      2,
+			YSplit:      1,
+			TopLeftCell: "C2",
+			ActivePane:  "bottomRight",
+			State:       "frozen",
+		})
+
+		xml := writtenParts(c, f)["xl/worksheets/sheet1.xml"]
+		c.Assert(xml, qt.Contains, `<pane xSplit="2" ySplit="1" topLeftCell="C2" activePane="bottomRight" state="frozen"`)
+		c.Assert(xml, qt.Contains, `<selection pane="bottomRight" activeCell="C2" activeCellId="0" sqref="C2"`)
+
+		f = reopen(c, f, option)
+		sheet = f.Sheets[0]
+		rows, cols := sheet.FrozenPanes()
+		c.Assert(rows, qt.Equals, 1)
+		c.Assert(cols, qt.Equals, 2)
+
+		err = sheet.FreezePanes(1, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.SheetViews[0].Pane.ActivePane, qt.Equals, "bottomLeft")
+		c.Assert(sheet.SheetViews[0].Pane.TopLeftCell, qt.Equals, "A2")
+		err = sheet.FreezePanes(0, 3)
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.SheetViews[0].Pane.ActivePane, qt.Equals, "topRight")
+		c.Assert(sheet.SheetViews[0].Pane.TopLeftCell, qt.Equals, "D1")
+
+		err = sheet.FreezePanes(0, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.SheetViews[0].Pane, qt.IsNil)
+		rows, cols = sheet.FrozenPanes()
+		c.Assert(rows, qt.Equals, 0)
+		c.Assert(cols, qt.Equals, 0)
+
+		err = sheet.FreezePanes(-1, 0)
+		c.Assert(err, qt.ErrorMatches, `FreezePanes: cannot freeze -1 rows and 0 columns`)
+	})
+
+	csRunO(c, "RoundTrip", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("تقرير")
+		c.Assert(err, qt.IsNil)
+		setCells(c, sheet, map[string]interface{}{"A1": "Name"})
+		sheet.SheetViews = []SheetView{{
+			View:              ViewPageLayout,
+			TopLeftCell:       "B3",
+			ZoomScale:         150,
+			HideGridLines:     true,
+			HideRowColHeaders: true,
+			RightToLeft:       true,
+			Selection: []Selection{
+				{ActiveCell: "C4", SQRef: "C4:D5 F6"},
+			},
+		}}
+
+		xml := writtenParts(c, f)["xl/worksheets/sheet1.xml"]
+		c.Assert(xml, qt.Contains, `showGridLines="false" showRowColHeaders="false" showZeros="true" rightToLeft="true"`)
+		c.Assert(xml, qt.Contains, `view="pageLayout" topLeftCell="B3" colorId="64" zoomScale="150" zoomScaleNormal="100"`)
+		c.Assert(xml, qt.Contains, `<selection activeCell="C4" activeCellId="0" sqref="C4:D5 F6"`)
+
+		f = reopen(c, f, option)
+		c.Assert(f.Sheets[0].SheetViews, qt.DeepEquals, []SheetView{{
+			View:                    ViewPageLayout,
+			TopLeftCell:             "B3",
+			ZoomScale:               150,
+			ZoomScaleNormal:         100,
+			ZoomScalePageLayoutView: 100,
+			HideGridLines:           true,
+			HideRowColHeaders:       true,
+			RightToLeft:             true,
+			Selection: []Selection{
+				{ActiveCell: "C4", SQRef: "C4:D5 F6"},
+			},
+		}})
+	})
+
+	c.Run("ReadDefaults", func(c *qt.C) {
+		// Attributes that are missing take the defaults of the
+		// schema.
+		sheetView := readSheetView(xlsxSheetView{
+			ShowGridLines: bPtr(false),
+			Selection:     []xlsxSelection{{ActiveCell: "D3", SQRef: "D3"}},
+		})
+		c.Assert(sheetView, qt.DeepEquals, SheetView{
+			HideGridLines: true,
+			Selection:     []Selection{{ActiveCell: "D3", SQRef: "D3"}},
+		})
+	})
+}
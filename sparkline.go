@@ -0,0 +1,358 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tealeg/xlsx/v3/formula"
+)
+
+// sparklineExtURI identifies the worksheet extension that holds its
+// sparklines.
+const sparklineExtURI = "{05C60535-1F16-4fd2-B633-F4F36F0B64E0}"
+
+// SparklineType is the kind of chart a sparkline draws.
+type SparklineType string
+
+const (
+	SparklineLine    SparklineType = "line"
+	SparklineColumn  SparklineType = "column"
+	SparklineWinLoss SparklineType = "stacked"
+)
+
+// SparklineAxis determines how the minimum or maximum of the vertical
+// axis of the sparklines in a group is chosen.
+type SparklineAxis string
+
+const (
+	// SparklineAxisIndividual scales each sparkline to its own
+	// values.  It is the default.
+	SparklineAxisIndividual SparklineAxis = ""
+	// SparklineAxisGroup scales all the sparklines of a group alike.
+	SparklineAxisGroup SparklineAxis = "group"
+	// SparklineAxisCustom uses the value given in the options.
+	SparklineAxisCustom SparklineAxis = "custom"
+)
+
+// SparklineEmptyCells determines how a sparkline shows empty cells.
+type SparklineEmptyCells string
+
+const (
+	SparklineEmptyCellsGap  SparklineEmptyCells = "" // the default
+	SparklineEmptyCellsZero SparklineEmptyCells = "zero"
+	SparklineEmptyCellsSpan SparklineEmptyCells = "span"
+)
+
+// The colors, as ARGB, that sparklines are drawn in when no other is
+// given.
+const (
+	defaultSparklineColor      = "FF376092"
+	defaultSparklinePointColor = "FFD00000"
+	defaultSparklineAxisColor  = "FF000000"
+)
+
+// SparklineOptions are the settings shared by the sparklines of a
+// SparklineGroup.  Colors are ARGB hex strings, such as "FF376092";
+// empty means the color Excel uses by default.
+type SparklineOptions struct {
+	// Markers shows a marker at every point of a line sparkline.
+	Markers bool
+	// High, Low, First, Last and Negative highlight the highest,
+	// lowest, first, last and negative points in their own colors.
+	High, Low, First, Last, Negative bool
+
+	SeriesColor   string
+	NegativeColor string
+	AxisColor     string
+	MarkersColor  string
+	HighColor     string
+	LowColor      string
+	FirstColor    string
+	LastColor     string
+
+	// ShowAxis draws the horizontal axis when the values cross zero.
+	ShowAxis bool
+	// MinAxis and MaxAxis determine the range of the vertical axis.
+	// ManualMin and ManualMax are used with SparklineAxisCustom.
+	MinAxis, MaxAxis     SparklineAxis
+	ManualMin, ManualMax float64
+	// RightToLeft plots the values from right to left.
+	RightToLeft bool
+	// ShowHidden plots the values of hidden rows and columns.
+	ShowHidden bool
+	// EmptyCells determines how empty cells are shown.
+	EmptyCells SparklineEmptyCells
+	// LineWeight is the width, in points, of the line of a line
+	// sparkline.  Zero means 0.75.
+	LineWeight float64
+}
+
+// Sparkline is a tiny chart drawn in a single cell.
+type Sparkline struct {
+	// Data is the range of values the Sparkline plots, qualified by
+	// its sheet name, such as "Sheet1!$B$2:$M$2".
+	Data string
+	// Location is the cell, on the Sheet of its group, that the
+	// Sparkline is drawn in, such as "N2".
+	Location string
+}
+
+// SparklineGroup is a set of sparklines that share their type and
+// options.
+type SparklineGroup struct {
+	Type       SparklineType
+	Options    SparklineOptions
+	Sparklines []Sparkline
+}
+
+// AddSparklineGroup adds a group of sparklines of the given type to the
+// Sheet.  locationRange is a row or column of cells on the Sheet, such
+// as "N2:N2001", and each of its cells shows a sparkline of the
+// matching row or, for a row of cells, column of dataRange.  A
+// dataRange without a sheet name refers to the Sheet.
+func (s *Sheet) AddSparklineGroup(typ SparklineType, dataRange, locationRange string, opts SparklineOptions) (*SparklineGroup, error) {
+	wrap := func(err error) (*SparklineGroup, error) {
+		return nil, fmt.Errorf("AddSparklineGroup: %w", err)
+	}
+
+	switch typ {
+	case SparklineLine, SparklineColumn, SparklineWinLoss:
+	default:
+		return wrap(fmt.Errorf("unsupported sparkline type %q", typ))
+	}
+	for _, axis := range []SparklineAxis{opts.MinAxis, opts.MaxAxis} {
+		switch axis {
+		case SparklineAxisIndividual, SparklineAxisGroup, SparklineAxisCustom:
+		default:
+			return wrap(fmt.Errorf("unsupported sparkline axis %q", axis))
+		}
+	}
+	switch opts.EmptyCells {
+	case SparklineEmptyCellsGap, SparklineEmptyCellsZero, SparklineEmptyCellsSpan:
+	default:
+		return wrap(fmt.Errorf("unsupported display of empty cells %q", opts.EmptyCells))
+	}
+	if opts.LineWeight < 0 {
+		return wrap(errors.New("line weight must not be negative"))
+	}
+
+	location, err := formula.ParseRef(locationRange)
+	if err != nil || location.IsWholeCols() || location.IsWholeRows() ||
+		(location.Sheet != "" && location.Sheet != s.Name) {
+		return wrap(fmt.Errorf("invalid location range %q", locationRange))
+	}
+	data, err := formula.ParseRef(dataRange)
+	if err != nil || data.IsWholeCols() || data.IsWholeRows() {
+		return wrap(fmt.Errorf("invalid data range %q", dataRange))
+	}
+	if data.Sheet == "" {
+		data.Sheet = s.Name
+	}
+	data.AbsCol1, data.AbsRow1, data.AbsCol2, data.AbsRow2 = true, true, true, true
+	data.IsArea = true
+
+	locRows, locCols := location.Row2-location.Row1+1, location.Col2-location.Col1+1
+	dataRows, dataCols := data.Row2-data.Row1+1, data.Col2-data.Col1+1
+	group := &SparklineGroup{Type: typ, Options: opts}
+	switch {
+	case locRows == 1 && locCols == 1 && (dataRows == 1 || dataCols == 1):
+		group.Sparklines = []Sparkline{{Data: data.String(), Location: GetCellIDStringFromCoords(location.Col1, location.Row1)}}
+	case locCols == 1 && locRows == dataRows:
+		for i := 0; i < locRows; i++ {
+			ref := data
+			ref.Row1, ref.Row2 = data.Row1+i, data.Row1+i
+			group.Sparklines = append(group.Sparklines, Sparkline{
+				Data:     ref.String(),
+				Location: GetCellIDStringFromCoords(location.Col1, location.Row1+i),
+			})
+		}
+	case locRows == 1 && locCols == dataCols:
+		for i := 0; i < locCols; i++ {
+			ref := data
+			ref.Col1, ref.Col2 = data.Col1+i, data.Col1+i
+			group.Sparklines = append(group.Sparklines, Sparkline{
+				Data:     ref.String(),
+				Location: GetCellIDStringFromCoords(location.Col1+i, location.Row1),
+			})
+		}
+	default:
+		return wrap(fmt.Errorf("data range %q doesn't match location range %q", dataRange, locationRange))
+	}
+	s.SparklineGroups = append(s.SparklineGroups, group)
+	return group, nil
+}
+
+// makeSparklines adds the sparklines of the Sheet to the extensions of
+// worksheet.
+func (s *Sheet) makeSparklines(worksheet *xlsxWorksheet) {
+	if len(s.SparklineGroups) == 0 {
+		return
+	}
+	var b strings.Builder
+	b.WriteString(`<x14:sparklineGroups xmlns:xm="http://schemas.microsoft.com/office/excel/2006/main">`)
+	for _, group := range s.SparklineGroups {
+		group.write(&b)
+	}
+	b.WriteString(`</x14:sparklineGroups>`)
+	if worksheet.ExtLst == nil {
+		worksheet.ExtLst = &xlsxExtLst{}
+	}
+	worksheet.ExtLst.Ext = append(worksheet.ExtLst.Ext, xlsxExt{
+		URI:      sparklineExtURI,
+		XMLNSX14: "http://schemas.microsoft.com/office/spreadsheetml/2009/9/main",
+		Content:  b.String(),
+	})
+}
+
+// write writes the sparklineGroup element of the group.
+func (group *SparklineGroup) write(b *strings.Builder) {
+	opts := group.Options
+	b.WriteString(`<x14:sparklineGroup`)
+	attr := func(name, value string) {
+		fmt.Fprintf(b, ` %s="%s"`, name, escapeXML(value))
+	}
+	flag := func(name string, set bool) {
+		if set {
+			attr(name, "1")
+		}
+	}
+	if group.Type != SparklineLine {
+		attr("type", string(group.Type))
+	}
+	if opts.LineWeight != 0 {
+		attr("lineWeight", strconv.FormatFloat(opts.LineWeight, 'f', -1, 64))
+	}
+	emptyCells := string(opts.EmptyCells)
+	if emptyCells == "" {
+		emptyCells = "gap"
+	}
+	attr("displayEmptyCellsAs", emptyCells)
+	flag("markers", opts.Markers)
+	flag("high", opts.High)
+	flag("low", opts.Low)
+	flag("first", opts.First)
+	flag("last", opts.Last)
+	flag("negative", opts.Negative)
+	flag("displayXAxis", opts.ShowAxis)
+	flag("displayHidden", opts.ShowHidden)
+	if opts.MinAxis != SparklineAxisIndividual {
+		attr("minAxisType", string(opts.MinAxis))
+	}
+	if opts.MaxAxis != SparklineAxisIndividual {
+		attr("maxAxisType", string(opts.MaxAxis))
+	}
+	if opts.MinAxis == SparklineAxisCustom {
+		attr("manualMin", strconv.FormatFloat(opts.ManualMin, 'f', -1, 64))
+	}
+	if opts.MaxAxis == SparklineAxisCustom {
+		attr("manualMax", strconv.FormatFloat(opts.ManualMax, 'f', -1, 64))
+	}
+	flag("rightToLeft", opts.RightToLeft)
+	b.WriteString(`>`)
+
+	color := func(name, value, def string) {
+		if value == "" {
+			value = def
+		}
+		fmt.Fprintf(b, `<x14:%s rgb="%s"/>`, name, escapeXML(value))
+	}
+	color("colorSeries", opts.SeriesColor, defaultSparklineColor)
+	color("colorNegative", opts.NegativeColor, defaultSparklinePointColor)
+	color("colorAxis", opts.AxisColor, defaultSparklineAxisColor)
+	color("colorMarkers", opts.MarkersColor, defaultSparklinePointColor)
+	color("colorFirst", opts.FirstColor, defaultSparklinePointColor)
+	color("colorLast", opts.LastColor, defaultSparklinePointColor)
+	color("colorHigh", opts.HighColor, defaultSparklinePointColor)
+	color("colorLow", opts.LowColor, defaultSparklinePointColor)
+
+	b.WriteString(`<x14:sparklines>`)
+	for _, sparkline := range group.Sparklines {
+		fmt.Fprintf(b, `<x14:sparkline><xm:f>%s</xm:f><xm:sqref>%s</xm:sqref></x14:sparkline>`,
+			escapeXML(sparkline.Data), escapeXML(sparkline.Location))
+	}
+	b.WriteString(`</x14:sparklines></x14:sparklineGroup>`)
+}
+
+// readSparklines adds the sparklines in the extensions of worksheet to
+// sheet.
+func readSparklines(sheet *Sheet, worksheet *xlsxWorksheet) error {
+	if worksheet.ExtLst == nil {
+		return nil
+	}
+	for _, ext := range worksheet.ExtLst.Ext {
+		if ext.URI != sparklineExtURI {
+			continue
+		}
+		var xGroups xlsxX14SparklineGroups
+		err := xml.Unmarshal([]byte(ext.Content), &xGroups)
+		if err != nil {
+			return fmt.Errorf("xml.Unmarshal: %w", err)
+		}
+		for _, xGroup := range xGroups.SparklineGroups {
+			sheet.SparklineGroups = append(sheet.SparklineGroups, readSparklineGroup(xGroup))
+		}
+	}
+	return nil
+}
+
+// readSparklineGroup returns the SparklineGroup of xGroup.  Colors
+// given other than as ARGB, such as those of the theme, are read as
+// the default colors.
+func readSparklineGroup(xGroup xlsxX14SparklineGroup) *SparklineGroup {
+	group := &SparklineGroup{Type: SparklineType(xGroup.Type)}
+	if group.Type == "" {
+		group.Type = SparklineLine
+	}
+	color := func(xColor *xlsxColor) string {
+		if xColor == nil {
+			return ""
+		}
+		return xColor.RGB
+	}
+	axis := func(axisType string) SparklineAxis {
+		if axisType == "individual" {
+			return SparklineAxisIndividual
+		}
+		return SparklineAxis(axisType)
+	}
+	group.Options = SparklineOptions{
+		Markers:       xGroup.Markers,
+		High:          xGroup.High,
+		Low:           xGroup.Low,
+		First:         xGroup.First,
+		Last:          xGroup.Last,
+		Negative:      xGroup.Negative,
+		SeriesColor:   color(xGroup.ColorSeries),
+		NegativeColor: color(xGroup.ColorNegative),
+		AxisColor:     color(xGroup.ColorAxis),
+		MarkersColor:  color(xGroup.ColorMarkers),
+		HighColor:     color(xGroup.ColorHigh),
+		LowColor:      color(xGroup.ColorLow),
+		FirstColor:    color(xGroup.ColorFirst),
+		LastColor:     color(xGroup.ColorLast),
+		ShowAxis:      xGroup.DisplayXAxis,
+		MinAxis:       axis(xGroup.MinAxisType),
+		MaxAxis:       axis(xGroup.MaxAxisType),
+		ManualMin:     xGroup.ManualMin,
+		ManualMax:     xGroup.ManualMax,
+		RightToLeft:   xGroup.RightToLeft,
+		ShowHidden:    xGroup.DisplayHidden,
+		LineWeight:    xGroup.LineWeight,
+	}
+	switch xGroup.DisplayEmptyCellsAs {
+	case "gap":
+		group.Options.EmptyCells = SparklineEmptyCellsGap
+	case "":
+		// Zero is the default of the file format.
+		group.Options.EmptyCells = SparklineEmptyCellsZero
+	default:
+		group.Options.EmptyCells = SparklineEmptyCells(xGroup.DisplayEmptyCellsAs)
+	}
+	for _, xSparkline := range xGroup.Sparklines {
+		group.Sparklines = append(group.Sparklines, Sparkline{Data: xSparkline.F, Location: xSparkline.Sqref})
+	}
+	return group
+}
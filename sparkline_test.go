@@ -0,0 +1,128 @@
+package xlsx
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestSparklines(t *testing.T) {
+	c := qt.New(t)
+
+	setUp := func(c *qt.C, option FileOption) *File {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("KPIs")
+		c.Assert(err, qt.IsNil)
+		for row := 1; row <= 3; row++ {
+			for col := 1; col <= 4; col++ {
+				cell, err := sheet.Cell(row, col)
+				c.Assert(err, qt.IsNil)
+				cell.SetInt(row*col - 4)
+			}
+		}
+		_, err = sheet.AddSparklineGroup(SparklineLine, "B2:E4", "F2:F4", SparklineOptions{
+			Markers:     true,
+			High:        true,
+			Low:         true,
+			SeriesColor: "FF00B050",
+			HighColor:   "FF0070C0",
+			LineWeight:  1.5,
+		})
+		c.Assert(err, qt.IsNil)
+		_, err = sheet.AddSparklineGroup(SparklineWinLoss, "B2:E4", "B6:E6", SparklineOptions{
+			Negative:   true,
+			ShowAxis:   true,
+			MinAxis:    SparklineAxisCustom,
+			ManualMin:  -2,
+			MaxAxis:    SparklineAxisGroup,
+			EmptyCells: SparklineEmptyCellsSpan,
+		})
+		c.Assert(err, qt.IsNil)
+		return f
+	}
+
+	c.Run("AddSparklineGroup", func(c *qt.C) {
+		sheet, err := NewSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		_, err = sheet.AddSparklineGroup("pie", "A1:C1", "D1", SparklineOptions{})
+		c.Assert(err, qt.ErrorMatches, `AddSparklineGroup: unsupported sparkline type "pie"`)
+		_, err = sheet.AddSparklineGroup(SparklineLine, "A1:C1", "D1", SparklineOptions{MaxAxis: "fixed"})
+		c.Assert(err, qt.ErrorMatches, `AddSparklineGroup: unsupported sparkline axis "fixed"`)
+		_, err = sheet.AddSparklineGroup(SparklineLine, "A1:C1", "D1", SparklineOptions{EmptyCells: "none"})
+		c.Assert(err, qt.ErrorMatches, `AddSparklineGroup: unsupported display of empty cells "none"`)
+		_, err = sheet.AddSparklineGroup(SparklineLine, "A1:C1", "Other!D1", SparklineOptions{})
+		c.Assert(err, qt.ErrorMatches, `AddSparklineGroup: invalid location range "Other!D1"`)
+		_, err = sheet.AddSparklineGroup(SparklineLine, "A:C", "D1", SparklineOptions{})
+		c.Assert(err, qt.ErrorMatches, `AddSparklineGroup: invalid data range "A:C"`)
+		_, err = sheet.AddSparklineGroup(SparklineLine, "A1:C2", "D1:D3", SparklineOptions{})
+		c.Assert(err, qt.ErrorMatches, `AddSparklineGroup: data range "A1:C2" doesn't match location range "D1:D3"`)
+		_, err = sheet.AddSparklineGroup(SparklineLine, "A1:C2", "D1:E2", SparklineOptions{})
+		c.Assert(err, qt.ErrorMatches, `AddSparklineGroup: data range "A1:C2" doesn't match location range "D1:E2"`)
+
+		group, err := sheet.AddSparklineGroup(SparklineColumn, "Data!A1:C2", "D1:D2", SparklineOptions{})
+		c.Assert(err, qt.IsNil)
+		c.Assert(group.Sparklines, qt.DeepEquals, []Sparkline{
+			{Data: "Data!$A$1:$C$1", Location: "D1"},
+			{Data: "Data!$A$2:$C$2", Location: "D2"},
+		})
+		group, err = sheet.AddSparklineGroup(SparklineLine, "A1:B3", "A4:B4", SparklineOptions{})
+		c.Assert(err, qt.IsNil)
+		c.Assert(group.Sparklines, qt.DeepEquals, []Sparkline{
+			{Data: "Sheet1!$A$1:$A$3", Location: "A4"},
+			{Data: "Sheet1!$B$1:$B$3", Location: "B4"},
+		})
+		group, err = sheet.AddSparklineGroup(SparklineLine, "A1:A3", "C1", SparklineOptions{})
+		c.Assert(err, qt.IsNil)
+		c.Assert(group.Sparklines, qt.DeepEquals, []Sparkline{{Data: "Sheet1!$A$1:$A$3", Location: "C1"}})
+		c.Assert(sheet.SparklineGroups, qt.HasLen, 3)
+	})
+
+	csRunParts(c, "Parts", setUp, func(c *qt.C, parts map[string]string) {
+		sheet := parts["xl/worksheets/sheet1.xml"]
+		c.Assert(sheet, qt.Contains, `<extLst><ext uri="{05C60535-1F16-4fd2-B633-F4F36F0B64E0}" xmlns:x14="http://schemas.microsoft.com/office/spreadsheetml/2009/9/main">`+
+			`<x14:sparklineGroups xmlns:xm="http://schemas.microsoft.com/office/excel/2006/main">`+
+			`<x14:sparklineGroup lineWeight="1.5" displayEmptyCellsAs="gap" markers="1" high="1" low="1">`+
+			`<x14:colorSeries rgb="FF00B050"/><x14:colorNegative rgb="FFD00000"/>`)
+		c.Assert(sheet, qt.Contains, `<x14:colorHigh rgb="FF0070C0"/>`)
+		c.Assert(sheet, qt.Contains, `<x14:sparkline><xm:f>KPIs!$B$2:$E$2</xm:f><xm:sqref>F2</xm:sqref></x14:sparkline>`)
+		c.Assert(sheet, qt.Contains, `<x14:sparkline><xm:f>KPIs!$B$3:$E$3</xm:f><xm:sqref>F3</xm:sqref></x14:sparkline>`)
+		c.Assert(sheet, qt.Contains, `<x14:sparklineGroup type="stacked" displayEmptyCellsAs="span" negative="1" displayXAxis="1" minAxisType="custom" maxAxisType="group" manualMin="-2">`)
+		c.Assert(sheet, qt.Contains, `<xm:f>KPIs!$E$2:$E$4</xm:f><xm:sqref>E6</xm:sqref>`)
+		c.Assert(sheet, qt.Contains, `</x14:sparklineGroups></ext></extLst></worksheet>`)
+	})
+
+	csRunO(c, "RoundTrip", func(c *qt.C, option FileOption) {
+		f := setUp(c, option)
+		want := f.Sheets[0].SparklineGroups
+		f = reopen(c, f, option)
+		groups := f.Sheets[0].SparklineGroups
+		c.Assert(groups, qt.HasLen, 2)
+
+		c.Assert(groups[0].Type, qt.Equals, SparklineLine)
+		c.Assert(groups[0].Sparklines, qt.DeepEquals, want[0].Sparklines)
+		c.Assert(groups[0].Options, qt.Equals, SparklineOptions{
+			Markers:       true,
+			High:          true,
+			Low:           true,
+			SeriesColor:   "FF00B050",
+			NegativeColor: "FFD00000",
+			AxisColor:     "FF000000",
+			MarkersColor:  "FFD00000",
+			HighColor:     "FF0070C0",
+			LowColor:      "FFD00000",
+			FirstColor:    "FFD00000",
+			LastColor:     "FFD00000",
+			LineWeight:    1.5,
+		})
+
+		c.Assert(groups[1].Type, qt.Equals, SparklineWinLoss)
+		c.Assert(groups[1].Sparklines, qt.DeepEquals, want[1].Sparklines)
+		opts := groups[1].Options
+		c.Assert(opts.Negative, qt.Equals, true)
+		c.Assert(opts.ShowAxis, qt.Equals, true)
+		c.Assert(opts.MinAxis, qt.Equals, SparklineAxisCustom)
+		c.Assert(opts.ManualMin, qt.Equals, -2.0)
+		c.Assert(opts.MaxAxis, qt.Equals, SparklineAxisGroup)
+		c.Assert(opts.EmptyCells, qt.Equals, SparklineEmptyCellsSpan)
+	})
+}
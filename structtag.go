@@ -0,0 +1,209 @@
+package xlsx
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	valuerType          = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	scannerType         = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// structTag holds the parsed contents of an `xlsx:"..."` struct tag,
+// as used by Sheet.WriteStructs and Sheet.ReadStructs.  The tag is a
+// comma separated list of options:
+//
+//    name=Header Text   the column header, defaults to the field name
+//    format=0.00%       the number format applied to the cell
+//    width=14           the width of the column, in characters
+//    omitempty          leave the cell empty if the field has its zero value
+//
+// A tag of "-" causes the field to be skipped entirely.  A bare
+// integer, as used by Row.ReadStruct, is accepted and ignored.
+type structTag struct {
+	skip      bool
+	name      string
+	format    string
+	width     float64
+	omitEmpty bool
+}
+
+// isStructTagKey reports whether a comma separated segment of a tag
+// starts a new option.  Anything else is treated as a continuation of
+// the previous option's value, which allows number formats such as
+// "#,##0.00" to be used without escaping.
+func isStructTagKey(segment string) bool {
+	key := segment
+	if i := strings.Index(segment, "="); i >= 0 {
+		key = segment[:i]
+	}
+	switch strings.TrimSpace(key) {
+	case "name", "format", "width":
+		return strings.Contains(segment, "=")
+	case "omitempty":
+		return !strings.Contains(segment, "=")
+	}
+	return false
+}
+
+// parseStructTag converts the value of an `xlsx:"..."` tag into a
+// structTag.
+func parseStructTag(tag string) (structTag, error) {
+	st := structTag{}
+	if tag == "-" {
+		st.skip = true
+		return st, nil
+	}
+	if tag == "" {
+		return st, nil
+	}
+	if _, err := strconv.Atoi(tag); err == nil {
+		// A positional tag, as used by Row.ReadStruct
+		return st, nil
+	}
+
+	var options []string
+	for _, segment := range strings.Split(tag, ",") {
+		if len(options) > 0 && !isStructTagKey(segment) {
+			options[len(options)-1] += "," + segment
+			continue
+		}
+		options = append(options, segment)
+	}
+
+	for _, option := range options {
+		parts := strings.SplitN(option, "=", 2)
+		key := strings.TrimSpace(parts[0])
+		switch key {
+		case "omitempty":
+			st.omitEmpty = true
+		case "name":
+			st.name = parts[1]
+		case "format":
+			st.format = parts[1]
+		case "width":
+			width, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+			if err != nil {
+				return st, fmt.Errorf("invalid width in tag %q: %w", tag, err)
+			}
+			st.width = width
+		default:
+			return st, fmt.Errorf("invalid tag %q: unknown option %q", tag, key)
+		}
+	}
+	return st, nil
+}
+
+// structColumn describes a single spreadsheet column that is mapped
+// to a (possibly nested) field of a struct.
+type structColumn struct {
+	index []int
+	name  string
+	tag   structTag
+	typ   reflect.Type
+}
+
+// isLeafType reports whether values of type t are written to a single
+// cell, rather than being expanded into one column per field.
+func isLeafType(t reflect.Type) bool {
+	if t == timeType {
+		return true
+	}
+	pt := reflect.PtrTo(t)
+	if t.Implements(textMarshalerType) || pt.Implements(textUnmarshalerType) {
+		return true
+	}
+	if t.Implements(valuerType) || pt.Implements(scannerType) {
+		return true
+	}
+	return t.Kind() != reflect.Struct
+}
+
+// structColumns returns the columns that a struct of type t maps to,
+// in field declaration order.  Nested and embedded structs, and
+// pointers to them, are flattened into their parent.
+func structColumns(t reflect.Type) ([]structColumn, error) {
+	return appendStructColumns(nil, t, nil, map[reflect.Type]bool{})
+}
+
+func appendStructColumns(columns []structColumn, t reflect.Type, parent []int, seen map[reflect.Type]bool) ([]structColumn, error) {
+	if seen[t] {
+		return nil, fmt.Errorf("recursive struct type %s is not supported", t)
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			// unexported
+			continue
+		}
+		tag, err := parseStructTag(field.Tag.Get("xlsx"))
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		if tag.skip {
+			continue
+		}
+		index := make([]int, len(parent)+1)
+		copy(index, parent)
+		index[len(parent)] = i
+
+		ft := field.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if !isLeafType(ft) {
+			columns, err = appendStructColumns(columns, ft, index, seen)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			// An unexported embedded type that isn't a struct
+			continue
+		}
+		name := tag.name
+		if name == "" {
+			name = field.Name
+		}
+		columns = append(columns, structColumn{
+			index: index,
+			name:  name,
+			tag:   tag,
+			typ:   field.Type,
+		})
+	}
+	return columns, nil
+}
+
+// fieldByIndex returns the field identified by index, following any
+// pointers on the way.  When alloc is true nil pointers are
+// allocated, otherwise a nil pointer causes ok to be false.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (field reflect.Value, ok bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc {
+					return v, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
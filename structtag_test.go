@@ -0,0 +1,34 @@
+package xlsx
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestParseStructTag(t *testing.T) {
+	c := qt.New(t)
+
+	cases := []struct {
+		tag      string
+		expected structTag
+	}{
+		{"", structTag{}},
+		{"-", structTag{skip: true}},
+		{"3", structTag{}},
+		{"name=Amount", structTag{name: "Amount"}},
+		{"name=Amount,format=0.00%,width=14,omitempty", structTag{name: "Amount", format: "0.00%", width: 14, omitEmpty: true}},
+		{"format=#,##0.00,name=Total", structTag{name: "Total", format: "#,##0.00"}},
+		{"omitempty,format=#,##0", structTag{format: "#,##0", omitEmpty: true}},
+	}
+	for _, tc := range cases {
+		c.Run(tc.tag, func(c *qt.C) {
+			st, err := parseStructTag(tc.tag)
+			c.Assert(err, qt.IsNil)
+			c.Assert(st, qt.Equals, tc.expected)
+		})
+	}
+
+	_, err := parseStructTag("size=3")
+	c.Assert(err, qt.ErrorMatches, `invalid tag "size=3": unknown option "size"`)
+}
@@ -36,16 +36,22 @@ type Style struct {
 	ApplyFont       bool
 	ApplyAlignment  bool
 	Alignment       Alignment
+	ApplyProtection bool
+	// Protection is written when ApplyProtection is set, or when
+	// it isn't DefaultProtection.  A Style made without NewStyle
+	// has a zero Protection, which unlocks its cells.
+	Protection      Protection
 	NamedStyleIndex *int
 }
 
 // Return a new Style structure initialised with the default values.
 func NewStyle() *Style {
 	return &Style{
-		Alignment: *DefaultAlignment(),
-		Border:    *DefaultBorder(),
-		Fill:      *DefaultFill(),
-		Font:      *DefaultFont(),
+		Alignment:  *DefaultAlignment(),
+		Border:     *DefaultBorder(),
+		Fill:       *DefaultFill(),
+		Font:       *DefaultFont(),
+		Protection: *DefaultProtection(),
 	}
 }
 
@@ -111,12 +117,73 @@ func (style *Style) makeXLSXStyleElements() (xFont xlsxFont, xFill xlsxFill, xBo
 	xCellXf.ApplyFill = style.ApplyFill
 	xCellXf.ApplyFont = style.ApplyFont
 	xCellXf.ApplyAlignment = style.ApplyAlignment
+	xCellXf.ApplyProtection = style.ApplyProtection || style.Protection != *DefaultProtection()
+	if xCellXf.ApplyProtection {
+		xCellXf.Protection = &xlsxProtection{
+			Locked: bPtr(style.Protection.Locked),
+			Hidden: style.Protection.Hidden,
+		}
+	}
 	if style.NamedStyleIndex != nil {
 		xCellXf.XfId = style.NamedStyleIndex
 	}
 	return
 }
 
+// makeXLSXDxf returns the differential format, as used by conditional
+// formatting, for the Style.  It has the font color and effects, the
+// fill and the border of the Style, leaving out those that aren't
+// set; the name and size of the font can't be changed by a
+// differential format.  A solid fill is given by its FgColor.
+func (style *Style) makeXLSXDxf() xlsxDxf {
+	var xDxf xlsxDxf
+	font := style.Font
+	if font.Color != "" || font.Bold || font.Italic || font.Underline || font.Strike {
+		xFont := &xlsxFont{Color: xlsxColor{RGB: font.Color}}
+		if font.Bold {
+			xFont.B = &xlsxVal{}
+		}
+		if font.Italic {
+			xFont.I = &xlsxVal{}
+		}
+		if font.Underline {
+			xFont.U = &xlsxVal{}
+		}
+		if font.Strike {
+			xFont.Strike = &xlsxVal{}
+		}
+		xDxf.Font = xFont
+	}
+	switch fill := style.Fill; fill.PatternType {
+	case "", "none":
+	case Solid_Cell_Fill:
+		xDxf.Fill = &xlsxFill{PatternFill: xlsxPatternFill{BgColor: xlsxColor{RGB: fill.FgColor}}}
+	default:
+		xDxf.Fill = &xlsxFill{PatternFill: xlsxPatternFill{
+			PatternType: fill.PatternType,
+			FgColor:     xlsxColor{RGB: fill.FgColor},
+			BgColor:     xlsxColor{RGB: fill.BgColor},
+		}}
+	}
+	line := func(lineStyle, color string) xlsxLine {
+		if lineStyle == "none" {
+			lineStyle = ""
+		}
+		return xlsxLine{Style: lineStyle, Color: xlsxColor{RGB: color}}
+	}
+	border := style.Border
+	xBorder := xlsxBorder{
+		Left:   line(border.Left, border.LeftColor),
+		Right:  line(border.Right, border.RightColor),
+		Top:    line(border.Top, border.TopColor),
+		Bottom: line(border.Bottom, border.BottomColor),
+	}
+	if xBorder.Left.Style != "" || xBorder.Right.Style != "" || xBorder.Top.Style != "" || xBorder.Bottom.Style != "" {
+		xDxf.Border = &xBorder
+	}
+	return xDxf
+}
+
 func makeXLSXCellElement() (xCellXf xlsxXf) {
 	xCellXf.NumFmtId = 0
 	return
@@ -185,6 +252,16 @@ type Alignment struct {
 	WrapText     bool
 }
 
+// Protection says whether a cell can be changed, and whether its
+// formula is shown, when its Sheet is protected.  It only has an
+// effect once the Sheet is protected.
+type Protection struct {
+	// Locked stops the cell being changed.
+	Locked bool
+	// Hidden hides the formula of the cell.
+	Hidden bool
+}
+
 var defaultFontSize = 12.0
 var defaultFontName = "Verdana"
 
@@ -206,6 +283,12 @@ func DefaultBorder() *Border {
 	return NewBorder("none", "none", "none", "none")
 }
 
+// DefaultProtection returns the Protection that cells have unless
+// their Style says otherwise: locked, with their formulas shown.
+func DefaultProtection() *Protection {
+	return &Protection{Locked: true}
+}
+
 func DefaultAlignment() *Alignment {
 	return &Alignment{
 		Horizontal: "general",
@@ -1,6 +1,7 @@
 package xlsx
 
 import (
+	"strings"
 	"testing"
 
 	qt "github.com/frankban/quicktest"
@@ -89,3 +90,73 @@ func TestNewFont(t *testing.T) {
 	c.Assert(font.Name, qt.Equals, "Verdana")
 	c.Assert(font.Size, qt.Equals, 12.2)
 }
+
+func TestStyleProtection(t *testing.T) {
+	c := qt.New(t)
+
+	c.Run("MakeXLSXStyleElements", func(c *qt.C) {
+		style := NewStyle()
+		c.Assert(style.Protection, qt.Equals, Protection{Locked: true})
+		_, _, _, xCellXf := style.makeXLSXStyleElements()
+		c.Assert(xCellXf.Protection, qt.IsNil)
+
+		style.ApplyProtection = true
+		style.Protection = Protection{Hidden: true}
+		_, _, _, xCellXf = style.makeXLSXStyleElements()
+		c.Assert(xCellXf.ApplyProtection, qt.Equals, true)
+		c.Assert(xCellXf.Protection, qt.DeepEquals, &xlsxProtection{Locked: bPtr(false), Hidden: true})
+
+		// A Protection other than the default is written without
+		// ApplyProtection.
+		style = NewStyle()
+		style.Protection.Locked = false
+		_, _, _, xCellXf = style.makeXLSXStyleElements()
+		c.Assert(xCellXf.ApplyProtection, qt.Equals, true)
+		c.Assert(xCellXf.Protection, qt.DeepEquals, &xlsxProtection{Locked: bPtr(false)})
+
+		// A Style made without NewStyle unlocks its cells.
+		_, _, _, xCellXf = (&Style{}).makeXLSXStyleElements()
+		c.Assert(xCellXf.ApplyProtection, qt.Equals, true)
+		c.Assert(xCellXf.Protection, qt.DeepEquals, &xlsxProtection{Locked: bPtr(false)})
+	})
+
+	csRunO(c, "RoundTrip", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Budget")
+		c.Assert(err, qt.IsNil)
+		setCells(c, sheet, map[string]interface{}{
+			"A1": 10, "A2": 20, "A3": "=A1+A2", "A4": 30,
+		})
+		input := NewStyle()
+		input.ApplyProtection = true
+		input.Protection.Locked = false
+		total := NewStyle()
+		total.ApplyProtection = true
+		total.Protection.Hidden = true
+		cellAt(c, sheet, "A1").SetStyle(input)
+		cellAt(c, sheet, "A2").SetStyle(input)
+		cellAt(c, sheet, "A3").SetStyle(total)
+		cellAt(c, sheet, "A4").SetStyle(NewStyle())
+		unlocked := NewStyle()
+		unlocked.Protection.Locked = false
+		cellAt(c, sheet, "A5").SetStyle(unlocked)
+		err = sheet.Protect("", ProtectionOptions{AllowSelectLockedCells: true, AllowSelectUnlockedCells: true})
+		c.Assert(err, qt.IsNil)
+
+		styles := writtenParts(c, f)["xl/styles.xml"]
+		c.Assert(strings.Count(styles, `<protection locked="0" hidden="0"/>`), qt.Equals, 1)
+		c.Assert(strings.Count(styles, `<protection locked="1" hidden="1"/>`), qt.Equals, 1)
+
+		f = reopen(c, f, option)
+		sheet = f.Sheets[0]
+		style := cellAt(c, sheet, "A1").GetStyle()
+		c.Assert(style.ApplyProtection, qt.Equals, true)
+		c.Assert(style.Protection, qt.Equals, Protection{})
+		c.Assert(cellAt(c, sheet, "A2").GetStyle().Protection, qt.Equals, Protection{})
+		c.Assert(cellAt(c, sheet, "A3").GetStyle().Protection, qt.Equals, Protection{Locked: true, Hidden: true})
+		c.Assert(cellAt(c, sheet, "A5").GetStyle().Protection, qt.Equals, Protection{})
+		style = cellAt(c, sheet, "A4").GetStyle()
+		c.Assert(style.ApplyProtection, qt.Equals, false)
+		c.Assert(style.Protection, qt.Equals, Protection{Locked: true})
+	})
+}
@@ -0,0 +1,574 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tealeg/xlsx/v3/formula"
+)
+
+// TotalsFunction is the function that summarises a column of a Table
+// in its totals row.
+type TotalsFunction string
+
+const (
+	TotalsNone      TotalsFunction = ""
+	TotalsSum       TotalsFunction = "sum"
+	TotalsAverage   TotalsFunction = "average"
+	TotalsCount     TotalsFunction = "count"
+	TotalsCountNums TotalsFunction = "countNums"
+	TotalsMax       TotalsFunction = "max"
+	TotalsMin       TotalsFunction = "min"
+	TotalsStdDev    TotalsFunction = "stdDev"
+	TotalsVar       TotalsFunction = "var"
+	// TotalsCustom uses the TotalsFormula of the column.
+	TotalsCustom TotalsFunction = "custom"
+)
+
+// subtotalFunctions are the function numbers that SUBTOTAL is called
+// with, in the totals row, for each TotalsFunction.  They are the
+// numbers that ignore hidden rows, so that the totals follow the
+// Table's filter.
+var subtotalFunctions = map[TotalsFunction]int{
+	TotalsAverage:   101,
+	TotalsCountNums: 102,
+	TotalsCount:     103,
+	TotalsMax:       104,
+	TotalsMin:       105,
+	TotalsStdDev:    107,
+	TotalsSum:       109,
+	TotalsVar:       110,
+}
+
+// TableColumn describes a column of a Table.
+type TableColumn struct {
+	// Name is the name of the column, which is shown in its header
+	// cell.  Names are unique within a Table, regardless of case.
+	Name string
+	// TotalsFunction summarises the column in the totals row.
+	TotalsFunction TotalsFunction
+	// TotalsLabel is text shown in the totals row instead of a
+	// function, such as "Total".
+	TotalsLabel string
+	// TotalsFormula is the formula used by TotalsCustom.
+	TotalsFormula string
+	// Formula makes the column a calculated column: every data cell
+	// of the column holds it.  Structured references within the
+	// Table can leave out its name, as in "[@Qty]*[@Price]".
+	Formula string
+}
+
+// TableOptions are the settings of a Table.
+type TableOptions struct {
+	// Name is the name that formulas refer to the Table by.  Names
+	// are unique within a File.  If it is empty, AddTable names the
+	// Table "Table1", "Table2" and so on.
+	Name string
+	// Columns describe the columns of the Table, from left to
+	// right.  Columns that aren't given, and those without a name,
+	// are named after their header cells, or "Column1", "Column2" and
+	// so on if those are empty.
+	Columns []TableColumn
+	// NoHeaderRow leaves out the header row, so that the Table
+	// starts with its data.
+	NoHeaderRow bool
+	// TotalsRow adds a totals row, below the data, to the Table.
+	TotalsRow bool
+	// NoAutoFilter leaves out the filter buttons of the header row.
+	NoAutoFilter bool
+	// Style is the name of the table style, such as
+	// "TableStyleMedium2", the style of a new table in Excel.
+	// Empty means no style.
+	Style string
+	// ShowFirstColumn and ShowLastColumn emphasise the first and
+	// last columns, and ShowRowStripes and ShowColumnStripes band
+	// the rows and columns, as the Style defines.
+	ShowFirstColumn, ShowLastColumn   bool
+	ShowRowStripes, ShowColumnStripes bool
+}
+
+// Table is a range of cells, on a Sheet, that Excel handles as a
+// whole: it can be sorted, filtered and extended, and formulas can
+// refer to its parts by name.
+type Table struct {
+	// Range is the cells of the Table, including its header and
+	// totals rows, such as "A1:D10".
+	Range   string
+	Options TableOptions
+	Sheet   *Sheet
+}
+
+// tableName matches a valid Table name.
+var tableName = regexp.MustCompile(`^[\pL_\\][\pL\pN_.\\]*$`)
+
+// AddTable makes the cells of rangeRef, on the Sheet, a Table, and
+// returns it.  The names of the columns are written to the header
+// row, the formulas of calculated columns to every data row, and the
+// functions and labels of the totals row to that row.
+func (s *Sheet) AddTable(rangeRef string, opts TableOptions) (*Table, error) {
+	wrap := func(err error) (*Table, error) {
+		return nil, fmt.Errorf("AddTable: %w", err)
+	}
+
+	ref, err := formula.ParseRef(rangeRef)
+	if err != nil || ref.Sheet != "" || ref.IsWholeCols() || ref.IsWholeRows() {
+		return wrap(fmt.Errorf("invalid range %q", rangeRef))
+	}
+	ref = formula.Ref{Col1: ref.Col1, Row1: ref.Row1, Col2: ref.Col2, Row2: ref.Row2, IsArea: true}
+	t := &Table{Range: ref.String(), Sheet: s}
+	first, last := tableDataRows(ref, opts)
+	if first > last {
+		return wrap(errors.New("a table needs at least one data row"))
+	}
+	width := ref.Col2 - ref.Col1 + 1
+	if len(opts.Columns) > width {
+		return wrap(fmt.Errorf("%d columns given for a range %d columns wide", len(opts.Columns), width))
+	}
+	for _, other := range s.Tables {
+		if r, err := formula.ParseRef(other.Range); err == nil &&
+			r.Col1 <= ref.Col2 && ref.Col1 <= r.Col2 && r.Row1 <= ref.Row2 && ref.Row1 <= r.Row2 {
+			return wrap(fmt.Errorf("range %q overlaps table %q", rangeRef, other.Options.Name))
+		}
+	}
+
+	if opts.Name == "" {
+		for n := 1; ; n++ {
+			opts.Name = fmt.Sprintf("Table%d", n)
+			if s.tableNameFree(opts.Name) {
+				break
+			}
+		}
+	} else if !validTableName(opts.Name) {
+		return wrap(fmt.Errorf("invalid table name %q", opts.Name))
+	} else if !s.tableNameFree(opts.Name) {
+		return wrap(fmt.Errorf("the name %q is already in use", opts.Name))
+	}
+
+	columns := make([]TableColumn, width)
+	copy(columns, opts.Columns)
+	for i := range columns {
+		col := &columns[i]
+		if col.Name == "" && !opts.NoHeaderRow {
+			cell, err := s.Cell(ref.Row1, ref.Col1+i)
+			if err != nil {
+				return wrap(err)
+			}
+			col.Name = strings.TrimSpace(cell.Value)
+		}
+		switch col.TotalsFunction {
+		case TotalsNone:
+		case TotalsCustom:
+			if col.TotalsFormula == "" {
+				return wrap(fmt.Errorf("column %d: a custom totals function needs a formula", i))
+			}
+		default:
+			if _, ok := subtotalFunctions[col.TotalsFunction]; !ok {
+				return wrap(fmt.Errorf("column %d: unsupported totals function %q", i, col.TotalsFunction))
+			}
+		}
+		for _, f := range []*string{&col.Formula, &col.TotalsFormula} {
+			if *f == "" {
+				continue
+			}
+			if *f, err = formula.QualifyTableRefs(strings.TrimPrefix(*f, "="), opts.Name); err != nil {
+				return wrap(fmt.Errorf("column %d: %w", i, err))
+			}
+		}
+	}
+	opts.Columns = columns
+	t.Options = opts
+	t.nameColumns()
+
+	for i, col := range t.Options.Columns {
+		x := ref.Col1 + i
+		if !opts.NoHeaderRow {
+			cell, err := s.Cell(ref.Row1, x)
+			if err != nil {
+				return wrap(err)
+			}
+			cell.SetString(col.Name)
+		}
+		if col.Formula != "" {
+			for y := first; y <= last; y++ {
+				cell, err := s.Cell(y, x)
+				if err != nil {
+					return wrap(err)
+				}
+				cell.SetFormula(col.Formula)
+			}
+		}
+		if opts.TotalsRow {
+			cell, err := s.Cell(ref.Row2, x)
+			if err != nil {
+				return wrap(err)
+			}
+			switch {
+			case col.TotalsFunction == TotalsCustom:
+				cell.SetFormula(col.TotalsFormula)
+			case col.TotalsFunction != TotalsNone:
+				cell.SetFormula(fmt.Sprintf("SUBTOTAL(%d,%s)", subtotalFunctions[col.TotalsFunction],
+					formula.TableRef{Table: opts.Name, Col1: col.Name}))
+			case col.TotalsLabel != "":
+				cell.SetString(col.TotalsLabel)
+			}
+		}
+	}
+
+	s.Tables = append(s.Tables, t)
+	return t, nil
+}
+
+// validTableName reports whether name can be used to name a Table:
+// it starts with a letter, an underscore or a backslash, carries on
+// with letters, digits, underscores, full stops and backslashes, and
+// can't be taken for a cell reference.
+func validTableName(name string) bool {
+	if len(name) > 255 || !tableName.MatchString(name) {
+		return false
+	}
+	if _, err := formula.ParseRef(name); err == nil {
+		return false
+	}
+	switch strings.ToUpper(name) {
+	case "R", "C":
+		return false
+	}
+	tokens, err := formula.Tokenize(name)
+	return err == nil && len(tokens) == 1 && tokens[0].Kind == formula.TokenName
+}
+
+// tableNameFree reports whether no Table or defined name in the File
+// of the Sheet, or on the Sheet if it isn't in a File, is called name.
+func (s *Sheet) tableNameFree(name string) bool {
+	sheets := []*Sheet{s}
+	if s.File != nil {
+		sheets = s.File.Sheets
+		for _, dn := range s.File.DefinedNames {
+			if strings.EqualFold(dn.Name, name) {
+				return false
+			}
+		}
+	}
+	for _, sheet := range sheets {
+		for _, t := range sheet.Tables {
+			if strings.EqualFold(t.Options.Name, name) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// nameColumns names the columns of the Table that don't have a name,
+// and renames those whose names are already taken, by adding a
+// number, as Excel does.
+func (t *Table) nameColumns() {
+	taken := make(map[string]bool)
+	for i := range t.Options.Columns {
+		col := &t.Options.Columns[i]
+		base := col.Name
+		if base == "" {
+			base = fmt.Sprintf("Column%d", i+1)
+		}
+		name := base
+		for n := 2; taken[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s%d", base, n)
+		}
+		taken[strings.ToLower(name)] = true
+		col.Name = name
+	}
+}
+
+// Table returns the Table in the File called name, regardless of
+// case, or nil if there isn't one.
+func (f *File) Table(name string) *Table {
+	for _, s := range f.Sheets {
+		if t := s.table(name); t != nil {
+			return t
+		}
+	}
+	return nil
+}
+
+// table returns the Table on the Sheet called name, or nil.
+func (s *Sheet) table(name string) *Table {
+	for _, t := range s.Tables {
+		if strings.EqualFold(t.Options.Name, name) {
+			return t
+		}
+	}
+	return nil
+}
+
+// tableAt returns the Table on the Sheet that holds the cell with the
+// zero based row and col, or nil.
+func (s *Sheet) tableAt(row, col int) *Table {
+	for _, t := range s.Tables {
+		ref, err := formula.ParseRef(t.Range)
+		if err == nil && ref.Row1 <= row && row <= ref.Row2 && ref.Col1 <= col && col <= ref.Col2 {
+			return t
+		}
+	}
+	return nil
+}
+
+// ColumnIndex returns the zero based index, among the columns of the
+// Table, of the column called name, regardless of case, or -1.
+func (t *Table) ColumnIndex(name string) int {
+	for i, col := range t.Options.Columns {
+		if strings.EqualFold(col.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// tableDataRows returns the first and last zero based rows of the
+// data of a Table with the range ref and options opts.
+func tableDataRows(ref formula.Ref, opts TableOptions) (int, int) {
+	first, last := ref.Row1, ref.Row2
+	if !opts.NoHeaderRow {
+		first++
+	}
+	if opts.TotalsRow {
+		last--
+	}
+	return first, last
+}
+
+// TableRow is a data row of a Table, whose cells are found by the
+// names of the Table's columns.
+type TableRow struct {
+	Row   *Row
+	table *Table
+	col   int
+}
+
+// Cell returns the cell of the TableRow in the column called column.
+func (tr *TableRow) Cell(column string) (*Cell, error) {
+	i := tr.table.ColumnIndex(column)
+	if i < 0 {
+		return nil, fmt.Errorf("Cell: table %q has no column %q", tr.table.Options.Name, column)
+	}
+	cell := tr.Row.GetCell(tr.col + i)
+	cell.Row = tr.Row
+	return cell, nil
+}
+
+// ForEachRow calls visitor for each data row of the Table, from top
+// to bottom.
+func (t *Table) ForEachRow(visitor func(tr *TableRow) error) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("ForEachRow: %w", err)
+	}
+	ref, err := formula.ParseRef(t.Range)
+	if err != nil {
+		return wrap(err)
+	}
+	first, last := tableDataRows(ref, t.Options)
+	for y := first; y <= last; y++ {
+		row, err := t.Sheet.Row(y)
+		if err != nil {
+			return wrap(err)
+		}
+		err = visitor(&TableRow{Row: row, table: t, col: ref.Col1})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve returns the cells that the structured reference tr, used
+// in a formula in the zero based row, refers to.  It returns false if
+// the reference is to something the Table doesn't have.
+func (t *Table) resolve(tr formula.TableRef, row int) (formula.Ref, bool) {
+	ref, err := formula.ParseRef(t.Range)
+	if err != nil {
+		return formula.Ref{}, false
+	}
+	ref = formula.Ref{Sheet: t.Sheet.Name, Col1: ref.Col1, Row1: ref.Row1, Col2: ref.Col2, Row2: ref.Row2, IsArea: true}
+	first, last := tableDataRows(ref, t.Options)
+
+	if tr.Col1 != "" {
+		col1, col2 := t.ColumnIndex(tr.Col1), t.ColumnIndex(tr.Col1)
+		if tr.Col2 != "" {
+			col2 = t.ColumnIndex(tr.Col2)
+		}
+		if col1 < 0 || col2 < 0 {
+			return formula.Ref{}, false
+		}
+		if col1 > col2 {
+			col1, col2 = col2, col1
+		}
+		ref.Col1, ref.Col2 = ref.Col1+col1, ref.Col1+col2
+	}
+
+	items := tr.Items
+	if len(items) == 0 {
+		items = []string{formula.TableItemData}
+	}
+	row1, row2 := -1, -1
+	span := func(r1, r2 int) {
+		if row1 < 0 || r1 < row1 {
+			row1 = r1
+		}
+		if r2 > row2 {
+			row2 = r2
+		}
+	}
+	for _, item := range items {
+		switch item {
+		case formula.TableItemAll:
+			span(ref.Row1, ref.Row2)
+		case formula.TableItemData:
+			span(first, last)
+		case formula.TableItemHeaders:
+			if t.Options.NoHeaderRow {
+				return formula.Ref{}, false
+			}
+			span(ref.Row1, ref.Row1)
+		case formula.TableItemTotals:
+			if !t.Options.TotalsRow {
+				return formula.Ref{}, false
+			}
+			span(ref.Row2, ref.Row2)
+		case formula.TableItemThisRow:
+			if row < first || row > last {
+				return formula.Ref{}, false
+			}
+			span(row, row)
+		}
+	}
+	ref.Row1, ref.Row2 = row1, row2
+	ref.IsArea = ref.Row1 != ref.Row2 || ref.Col1 != ref.Col2
+	return ref, true
+}
+
+// makeTableParts adds the table parts of the Sheet's Tables to sp.
+func (s *Sheet) makeTableParts(sp *sheetParts) error {
+	for _, t := range s.Tables {
+		ref, err := formula.ParseRef(t.Range)
+		if err != nil {
+			return fmt.Errorf("table %q: %w", t.Options.Name, err)
+		}
+		sp.wp.tables++
+		xTable := xlsxTable{
+			Id:          sp.wp.tables,
+			Name:        t.Options.Name,
+			DisplayName: t.Options.Name,
+			Ref:         t.Range,
+		}
+		if t.Options.NoHeaderRow {
+			xTable.HeaderRowCount = new(int)
+		}
+		if t.Options.TotalsRow {
+			xTable.TotalsRowCount = 1
+		} else {
+			xTable.TotalsRowShown = new(bool)
+		}
+		if !t.Options.NoAutoFilter && !t.Options.NoHeaderRow {
+			filter := ref
+			if t.Options.TotalsRow {
+				filter.Row2--
+			}
+			xTable.AutoFilter = &xlsxAutoFilter{Ref: filter.String()}
+		}
+		for i, col := range t.Options.Columns {
+			xTable.TableColumns.TableColumn = append(xTable.TableColumns.TableColumn, xlsxTableColumn{
+				Id:                      i + 1,
+				Name:                    col.Name,
+				TotalsRowFunction:       string(col.TotalsFunction),
+				TotalsRowLabel:          col.TotalsLabel,
+				CalculatedColumnFormula: col.Formula,
+				TotalsRowFormula:        col.TotalsFormula,
+			})
+		}
+		xTable.TableColumns.Count = len(xTable.TableColumns.TableColumn)
+		if t.Options.Style != "" || t.Options.ShowFirstColumn || t.Options.ShowLastColumn ||
+			t.Options.ShowRowStripes || t.Options.ShowColumnStripes {
+			xTable.TableStyleInfo = &xlsxTableStyleInfo{
+				Name:              t.Options.Style,
+				ShowFirstColumn:   t.Options.ShowFirstColumn,
+				ShowLastColumn:    t.Options.ShowLastColumn,
+				ShowRowStripes:    t.Options.ShowRowStripes,
+				ShowColumnStripes: t.Options.ShowColumnStripes,
+			}
+		}
+		body, err := xml.Marshal(xTable)
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("xl/tables/table%d.xml", sp.wp.tables)
+		sp.addPart(name, xml.Header+string(body),
+			"application/vnd.openxmlformats-officedocument.spreadsheetml.table+xml")
+		sp.addRelation(RelationshipTypeTable, "../"+strings.TrimPrefix(name, "xl/"))
+	}
+	return nil
+}
+
+// makeTableRefs refers the worksheet to the parts holding its Tables.
+func (s *Sheet) makeTableRefs(worksheet *xlsxWorksheet, relations *xlsxWorksheetRels) {
+	if relations == nil {
+		return
+	}
+	for _, rel := range relations.Relationships {
+		if rel.Type != RelationshipTypeTable {
+			continue
+		}
+		if worksheet.TableParts == nil {
+			worksheet.TableParts = &xlsxTableParts{}
+		}
+		worksheet.TableParts.TablePart = append(worksheet.TableParts.TablePart, xlsxTablePart{RID: rel.Id})
+		worksheet.TableParts.Count = len(worksheet.TableParts.TablePart)
+	}
+}
+
+// readTables adds the Tables, in the table parts that the worksheet
+// relationships rels refer to, to sheet.
+func readTables(fi *File, sheet *Sheet, rels *xlsxWorksheetRels) error {
+	for _, rel := range rels.Relationships {
+		if rel.Type != RelationshipTypeTable {
+			continue
+		}
+		tableFile, ok := fi.zipFiles[resolveTarget("xl/worksheets", rel.Target)]
+		if !ok {
+			continue
+		}
+		var xTable xlsxTable
+		err := decodeZipFile(tableFile, &xTable)
+		if err != nil {
+			return err
+		}
+		opts := TableOptions{
+			Name:         xTable.DisplayName,
+			NoHeaderRow:  xTable.HeaderRowCount != nil && *xTable.HeaderRowCount == 0,
+			TotalsRow:    xTable.TotalsRowCount > 0,
+			NoAutoFilter: xTable.AutoFilter == nil,
+		}
+		if opts.Name == "" {
+			opts.Name = xTable.Name
+		}
+		if info := xTable.TableStyleInfo; info != nil {
+			opts.Style = info.Name
+			opts.ShowFirstColumn = info.ShowFirstColumn
+			opts.ShowLastColumn = info.ShowLastColumn
+			opts.ShowRowStripes = info.ShowRowStripes
+			opts.ShowColumnStripes = info.ShowColumnStripes
+		}
+		for _, xCol := range xTable.TableColumns.TableColumn {
+			opts.Columns = append(opts.Columns, TableColumn{
+				Name:           xCol.Name,
+				TotalsFunction: TotalsFunction(xCol.TotalsRowFunction),
+				TotalsLabel:    xCol.TotalsRowLabel,
+				TotalsFormula:  xCol.TotalsRowFormula,
+				Formula:        xCol.CalculatedColumnFormula,
+			})
+		}
+		sheet.Tables = append(sheet.Tables, &Table{Range: xTable.Ref, Options: opts, Sheet: sheet})
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package xlsx
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestTables(t *testing.T) {
+	c := qt.New(t)
+
+	setUp := func(c *qt.C, option FileOption) *File {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Orders")
+		c.Assert(err, qt.IsNil)
+		setCells(c, sheet, map[string]interface{}{
+			"A1": "Item", "B1": "Qty", "C1": "Price",
+			"A2": "Apple", "B2": 3, "C2": 0.5,
+			"A3": "Pear", "B3": 2, "C3": 0.75,
+			"A4": "Plum", "B4": 10, "C4": 0.2,
+		})
+		_, err = sheet.AddTable("A1:D5", TableOptions{
+			Name:      "Sales",
+			TotalsRow: true,
+			Columns: []TableColumn{
+				{TotalsLabel: "Total"},
+				{TotalsFunction: TotalsSum},
+				{TotalsFunction: TotalsAverage},
+				{Name: "Amount", Formula: "[@Qty]*[@Price]", TotalsFunction: TotalsCustom, TotalsFormula: "SUM([Amount])"},
+			},
+			Style:          "TableStyleMedium2",
+			ShowRowStripes: true,
+		})
+		c.Assert(err, qt.IsNil)
+		_, err = sheet.AddTable("F1:G2", TableOptions{NoHeaderRow: true, NoAutoFilter: true})
+		c.Assert(err, qt.IsNil)
+		return f
+	}
+
+	c.Run("AddTable", func(c *qt.C) {
+		f := NewFile()
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		f.DefinedNames = append(f.DefinedNames, &DefinedName{Name: "Taken", RefersTo: "Sheet1!$A$1"})
+
+		for _, test := range []struct {
+			rangeRef string
+			opts     TableOptions
+			err      string
+		}{
+			{"A:B", TableOptions{}, `invalid range "A:B"`},
+			{"Other!A1:B3", TableOptions{}, `invalid range "Other!A1:B3"`},
+			{"A1:B1", TableOptions{}, `a table needs at least one data row`},
+			{"A1:B2", TableOptions{TotalsRow: true}, `a table needs at least one data row`},
+			{"A1:B3", TableOptions{Columns: make([]TableColumn, 3)}, `3 columns given for a range 2 columns wide`},
+			{"A1:B3", TableOptions{Name: "A1"}, `invalid table name "A1"`},
+			{"A1:B3", TableOptions{Name: "My Table"}, `invalid table name "My Table"`},
+			{"A1:B3", TableOptions{Name: "taken"}, `the name "taken" is already in use`},
+			{"A1:B3", TableOptions{Columns: []TableColumn{{TotalsFunction: "median"}}}, `column 0: unsupported totals function "median"`},
+			{"A1:B3", TableOptions{Columns: []TableColumn{{}, {TotalsFunction: TotalsCustom}}}, `column 1: a custom totals function needs a formula`},
+			{"A1:B3", TableOptions{Columns: []TableColumn{{Formula: "[@Qty"}}}, `column 0: invalid formula .*: unterminated structured reference at position 0`},
+		} {
+			_, err := sheet.AddTable(test.rangeRef, test.opts)
+			c.Assert(err, qt.ErrorMatches, `AddTable: `+test.err)
+		}
+		c.Assert(sheet.Tables, qt.HasLen, 0)
+
+		setCells(c, sheet, map[string]interface{}{"A1": " Name ", "B1": "name", "D1": "Name"})
+		table, err := sheet.AddTable("D1:A3", TableOptions{})
+		c.Assert(err, qt.IsNil)
+		c.Assert(table.Range, qt.Equals, "A1:D3")
+		c.Assert(table.Options.Name, qt.Equals, "Table1")
+		var names []string
+		for _, col := range table.Options.Columns {
+			names = append(names, col.Name)
+		}
+		c.Assert(names, qt.DeepEquals, []string{"Name", "name2", "Column3", "Name3"})
+		c.Assert(cellAt(c, sheet, "B1").Value, qt.Equals, "name2")
+		c.Assert(cellAt(c, sheet, "C1").Value, qt.Equals, "Column3")
+		c.Assert(table.ColumnIndex("NAME3"), qt.Equals, 3)
+		c.Assert(table.ColumnIndex("Nope"), qt.Equals, -1)
+
+		_, err = sheet.AddTable("C3:E4", TableOptions{})
+		c.Assert(err, qt.ErrorMatches, `AddTable: range "C3:E4" overlaps table "Table1"`)
+		_, err = sheet.AddTable("A5:B6", TableOptions{Name: "table1"})
+		c.Assert(err, qt.ErrorMatches, `AddTable: the name "table1" is already in use`)
+		table, err = sheet.AddTable("A5:B6", TableOptions{})
+		c.Assert(err, qt.IsNil)
+		c.Assert(table.Options.Name, qt.Equals, "Table2")
+		c.Assert(f.Table("TABLE2"), qt.Equals, table)
+		c.Assert(f.Table("Table3"), qt.IsNil)
+	})
+
+	csRunO(c, "Cells", func(c *qt.C, option FileOption) {
+		f := setUp(c, option)
+		sheet := f.Sheets[0]
+		c.Assert(cellAt(c, sheet, "D1").Value, qt.Equals, "Amount")
+		c.Assert(cellAt(c, sheet, "D3").Formula(), qt.Equals, "Sales[[#This Row],[Qty]]*Sales[[#This Row],[Price]]")
+		c.Assert(cellAt(c, sheet, "A5").Value, qt.Equals, "Total")
+		c.Assert(cellAt(c, sheet, "B5").Formula(), qt.Equals, "SUBTOTAL(109,Sales[Qty])")
+		c.Assert(cellAt(c, sheet, "C5").Formula(), qt.Equals, "SUBTOTAL(101,Sales[Price])")
+		c.Assert(cellAt(c, sheet, "D5").Formula(), qt.Equals, "SUM(Sales[Amount])")
+		c.Assert(sheet.Tables[0].Options.Columns[3].TotalsFormula, qt.Equals, "SUM(Sales[Amount])")
+	})
+
+	csRunO(c, "Recalculate", func(c *qt.C, option FileOption) {
+		f := setUp(c, option)
+		sheet := f.Sheets[0]
+		setCells(c, sheet, map[string]interface{}{
+			"H1": "=SUM(Sales[[#Data],[Qty]:[Price]])",
+			"H2": "=COUNT(Sales[#All])",
+			"H3": "=Sales[[#Headers],[Price]]",
+			"H5": "=Sales[Nope]",
+		})
+		err := f.Recalculate()
+		c.Assert(err, qt.IsNil)
+		c.Assert(cellAt(c, sheet, "D2").Value, qt.Equals, "1.5")
+		c.Assert(cellAt(c, sheet, "D4").Value, qt.Equals, "2")
+		c.Assert(cellAt(c, sheet, "D5").Value, qt.Equals, "5")
+		c.Assert(cellAt(c, sheet, "H1").Value, qt.Equals, "16.45")
+		c.Assert(cellAt(c, sheet, "H2").Value, qt.Equals, "10")
+		c.Assert(cellAt(c, sheet, "H3").Value, qt.Equals, "Price")
+		c.Assert(cellAt(c, sheet, "H5").Value, qt.Equals, "#REF!")
+
+		graph, err := f.FormulaGraph()
+		c.Assert(err, qt.IsNil)
+		precedents, err := graph.Precedents("Orders!D3")
+		c.Assert(err, qt.IsNil)
+		c.Assert(precedents, qt.HasLen, 2)
+		c.Assert(precedents[0].String(), qt.Equals, "Orders!B3")
+		c.Assert(precedents[1].String(), qt.Equals, "Orders!C3")
+	})
+
+	csRunParts(c, "Parts", setUp, func(c *qt.C, parts map[string]string) {
+		c.Assert(parts["xl/tables/table1.xml"], qt.Equals, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+			`<table xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" id="1" name="Sales" displayName="Sales" ref="A1:D5" totalsRowCount="1">`+
+			`<autoFilter ref="A1:D4"></autoFilter><tableColumns count="4">`+
+			`<tableColumn id="1" name="Item" totalsRowLabel="Total"></tableColumn>`+
+			`<tableColumn id="2" name="Qty" totalsRowFunction="sum"></tableColumn>`+
+			`<tableColumn id="3" name="Price" totalsRowFunction="average"></tableColumn>`+
+			`<tableColumn id="4" name="Amount" totalsRowFunction="custom">`+
+			`<calculatedColumnFormula>Sales[[#This Row],[Qty]]*Sales[[#This Row],[Price]]</calculatedColumnFormula>`+
+			`<totalsRowFormula>SUM(Sales[Amount])</totalsRowFormula></tableColumn></tableColumns>`+
+			`<tableStyleInfo name="TableStyleMedium2" showFirstColumn="false" showLastColumn="false" showRowStripes="true" showColumnStripes="false"></tableStyleInfo></table>`)
+		c.Assert(parts["xl/tables/table2.xml"], qt.Contains, `id="2" name="Table1" displayName="Table1" ref="F1:G2" headerRowCount="0" totalsRowShown="false"><tableColumns count="2"><tableColumn id="1" name="Column1">`)
+		c.Assert(parts["xl/worksheets/sheet1.xml"], qt.Contains, `<tableParts count="2"><tablePart r:id="rId1"`)
+		c.Assert(parts["xl/worksheets/sheet1.xml"], qt.Contains, `<tablePart r:id="rId2"`)
+		c.Assert(parts["xl/worksheets/_rels/sheet1.xml.rels"], qt.Contains, `<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/table" Target="../tables/table2.xml">`)
+		c.Assert(parts["[Content_Types].xml"], qt.Contains, `<Override PartName="/xl/tables/table1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.table+xml">`)
+	})
+
+	csRunO(c, "RoundTrip", func(c *qt.C, option FileOption) {
+		f := setUp(c, option)
+		want := f.Sheets[0].Tables
+		f = reopen(c, f, option)
+		tables := f.Sheets[0].Tables
+		c.Assert(tables, qt.HasLen, 2)
+		for i, table := range tables {
+			c.Assert(table.Sheet, qt.Equals, f.Sheets[0])
+			c.Assert(table.Range, qt.Equals, want[i].Range)
+			c.Assert(table.Options, qt.DeepEquals, want[i].Options)
+		}
+
+		sales := f.Table("sales")
+		c.Assert(sales, qt.Equals, tables[0])
+		var items []string
+		err := sales.ForEachRow(func(tr *TableRow) error {
+			cell, err := tr.Cell("item")
+			if err != nil {
+				return err
+			}
+			items = append(items, cell.Value)
+			return nil
+		})
+		c.Assert(err, qt.IsNil)
+		c.Assert(items, qt.DeepEquals, []string{"Apple", "Pear", "Plum"})
+		err = sales.ForEachRow(func(tr *TableRow) error {
+			_, err := tr.Cell("Discount")
+			return err
+		})
+		c.Assert(err, qt.ErrorMatches, `Cell: table "Sales" has no column "Discount"`)
+	})
+
+	csRunO(c, "ColumnsFollowInsertedCols", func(c *qt.C, option FileOption) {
+		f := setUp(c, option)
+		sheet := f.Sheets[0]
+		err := sheet.InsertColsAt(2, 1)
+		c.Assert(err, qt.IsNil)
+		table := sheet.Tables[0]
+		c.Assert(table.Range, qt.Equals, "A1:E5")
+		var names []string
+		for _, col := range table.Options.Columns {
+			names = append(names, col.Name)
+		}
+		c.Assert(names, qt.DeepEquals, []string{"Item", "Qty", "Column3", "Price", "Amount"})
+		c.Assert(cellAt(c, sheet, "C1").Value, qt.Equals, "Column3")
+		c.Assert(sheet.Tables[1].Range, qt.Equals, "G1:H2")
+
+		err = sheet.RemoveColsAt(0, 1)
+		c.Assert(err, qt.IsNil)
+		c.Assert(table.Range, qt.Equals, "A1:D5")
+		c.Assert(table.Options.Columns[0].Name, qt.Equals, "Qty")
+
+		c.Assert(cellAt(c, sheet, "B1").Value, qt.Equals, "Column3")
+		parts := writtenParts(c, f)
+		c.Assert(parts["xl/tables/table1.xml"], qt.Contains, `<tableColumn id="2" name="Column3"></tableColumn>`)
+	})
+}
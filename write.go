@@ -2,8 +2,11 @@ package xlsx
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"encoding"
 	"fmt"
 	"reflect"
+	"strconv"
 	"time"
 )
 
@@ -151,3 +154,172 @@ func (r *Row) WriteStruct(e interface{}, cols int) int {
 
 	return k
 }
+
+// WriteOptions affect the behaviour of Sheet.WriteStructs.
+type WriteOptions struct {
+	// SkipHeader suppresses the header row that is normally written
+	// before the first struct.
+	SkipHeader bool
+	// HeaderStyle, if not nil, is applied to every cell of the header
+	// row.
+	HeaderStyle *Style
+}
+
+// WriteStructs writes a slice of structs, or of pointers to structs,
+// to the Sheet, one Row per struct.  Unless options.SkipHeader is
+// set, a header Row containing the name of each column is written
+// first.
+//
+// Fields are mapped to columns in declaration order, nested structs
+// being flattened into their parent.  The mapping can be controlled
+// with `xlsx:"..."` struct tags, for example:
+//
+//    type Invoice struct {
+//        Number  string    `xlsx:"name=Invoice No,width=14"`
+//        Issued  time.Time `xlsx:"name=Issued,format=yyyy-mm-dd"`
+//        Rate    float64   `xlsx:"name=Rate,format=0.00%"`
+//        Comment string    `xlsx:"omitempty"`
+//        Secret  string    `xlsx:"-"`
+//    }
+//
+// In addition to the basic Go types, fields of type time.Time and
+// types implementing encoding.TextMarshaler or driver.Valuer are
+// supported.  An error is returned for any other type, and for a nil
+// pointer in the slice.
+//
+// Sheet.ReadStructs performs the inverse operation.
+func (s *Sheet) WriteStructs(slice interface{}, options WriteOptions) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("WriteStructs: %w", err)
+	}
+
+	v := reflect.ValueOf(slice)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return wrap(fmt.Errorf("expected a slice of structs, got %s", v.Kind()))
+	}
+	elemType := v.Type().Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return wrap(fmt.Errorf("expected a slice of structs, got a slice of %s", elemType))
+	}
+
+	columns, err := structColumns(elemType)
+	if err != nil {
+		return wrap(err)
+	}
+
+	if !options.SkipHeader {
+		row := s.AddRow()
+		for _, column := range columns {
+			cell := row.AddCell()
+			cell.SetString(column.name)
+			if options.HeaderStyle != nil {
+				cell.SetStyle(options.HeaderStyle)
+			}
+		}
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		elem := v.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				return wrap(fmt.Errorf("element %d is a nil pointer", i))
+			}
+			elem = elem.Elem()
+		}
+		row := s.AddRow()
+		for _, column := range columns {
+			cell := row.AddCell()
+			field, ok := fieldByIndex(elem, column.index, false)
+			if !ok {
+				continue
+			}
+			err := writeStructField(cell, field, column.tag)
+			if err != nil {
+				return wrap(fmt.Errorf("row %d, column %q: %w", row.num+1, column.name, err))
+			}
+		}
+	}
+
+	for i, column := range columns {
+		if column.tag.width > 0 {
+			s.SetColWidth(i+1, i+1, column.tag.width)
+		}
+	}
+	return nil
+}
+
+// writeStructField sets the value of cell from a single struct
+// field, applying the options from its tag.
+func writeStructField(cell *Cell, field reflect.Value, tag structTag) error {
+	if tag.omitEmpty && field.IsZero() {
+		return nil
+	}
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return nil
+		}
+		field = field.Elem()
+	}
+
+	if field.Type() == timeType {
+		t := field.Interface().(time.Time)
+		if tag.format == "" {
+			cell.SetDateTime(t)
+			return nil
+		}
+		cell.SetDateWithOptions(t, DateTimeOptions{
+			Location:        timeLocationUTC,
+			ExcelTimeFormat: tag.format,
+		})
+		return nil
+	}
+
+	switch value := field.Interface().(type) {
+	case encoding.TextMarshaler:
+		text, err := value.MarshalText()
+		if err != nil {
+			return err
+		}
+		cell.SetString(string(text))
+	case driver.Valuer:
+		dv, err := value.Value()
+		if err != nil {
+			return err
+		}
+		switch t := dv.(type) {
+		case nil:
+			cell.SetString("")
+		case bool:
+			cell.SetBool(t)
+		default:
+			cell.SetValue(t)
+		}
+	default:
+		switch field.Kind() {
+		case reflect.String:
+			cell.SetString(field.String())
+		case reflect.Bool:
+			cell.SetBool(field.Bool())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			cell.SetInt64(field.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			cell.SetNumeric(strconv.FormatUint(field.Uint(), 10))
+		case reflect.Float32:
+			cell.SetNumeric(strconv.FormatFloat(field.Float(), 'f', -1, 32))
+		case reflect.Float64:
+			cell.SetFloat(field.Float())
+		default:
+			return fmt.Errorf("unsupported type %s", field.Type())
+		}
+	}
+	if tag.format != "" {
+		cell.NumFmt = tag.format
+	}
+	return nil
+}
@@ -1,8 +1,10 @@
 package xlsx
 
 import (
+	"bytes"
 	"database/sql"
 	"math"
+	"strings"
 	"testing"
 	"time"
 
@@ -284,3 +286,187 @@ func TestWrite(t *testing.T) {
 		c.Assert(c11Null, qt.Equals, "")
 	})
 }
+
+type testTextMarshaler struct {
+	Code string
+}
+
+func (t testTextMarshaler) MarshalText() ([]byte, error) {
+	return []byte("code:" + t.Code), nil
+}
+
+func (t *testTextMarshaler) UnmarshalText(text []byte) error {
+	t.Code = strings.TrimPrefix(string(text), "code:")
+	return nil
+}
+
+func TestWriteStructs(t *testing.T) {
+	c := qt.New(t)
+
+	type address struct {
+		City    string `xlsx:"name=City"`
+		Country string `xlsx:"name=Country,omitempty"`
+	}
+	type record struct {
+		Name     string            `xlsx:"name=Full Name,width=20"`
+		Age      int               `xlsx:"name=Age"`
+		Rate     float64           `xlsx:"name=Rate,format=0.00%"`
+		Amount   float64           `xlsx:"name=Amount,format=#,##0.00,width=14"`
+		Active   bool              `xlsx:"name=Active"`
+		Joined   time.Time         `xlsx:"name=Joined,format=yyyy-mm-dd"`
+		Code     testTextMarshaler `xlsx:"name=Code"`
+		Stars    sql.NullInt64     `xlsx:"name=Stars"`
+		Nickname *string           `xlsx:"name=Nickname"`
+		Count    uint16
+		Secret   string `xlsx:"-"`
+		Home     address
+		Work     *address
+		private  string
+	}
+
+	nick := "Bob"
+	records := []record{
+		{
+			Name:     "Robert",
+			Age:      42,
+			Rate:     0.125,
+			Amount:   1234.5,
+			Active:   true,
+			Joined:   time.Date(2019, 3, 4, 0, 0, 0, 0, time.UTC),
+			Code:     testTextMarshaler{"X1"},
+			Stars:    sql.NullInt64{Int64: 7, Valid: true},
+			Nickname: &nick,
+			Count:    3,
+			Secret:   "hidden",
+			Home:     address{City: "Berlin", Country: "DE"},
+			Work:     &address{City: "Paris"},
+		},
+		{
+			Name:   "Alice",
+			Joined: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	csRunO(c, "HeaderFormatsAndWidths", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Records")
+		c.Assert(err, qt.IsNil)
+		err = sheet.WriteStructs(records, WriteOptions{})
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.MaxRow, qt.Equals, 3)
+
+		header, err := sheet.Row(0)
+		c.Assert(err, qt.IsNil)
+		var names []string
+		header.ForEachCell(func(cell *Cell) error {
+			names = append(names, cell.Value)
+			return nil
+		})
+		c.Assert(names, qt.DeepEquals, []string{
+			"Full Name", "Age", "Rate", "Amount", "Active", "Joined",
+			"Code", "Stars", "Nickname", "Count", "City", "Country",
+			"City", "Country"})
+
+		cell, err := sheet.Cell(1, 2)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.NumFmt, qt.Equals, "0.00%")
+		c.Assert(cell.Value, qt.Equals, "0.125")
+		cell, err = sheet.Cell(1, 3)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.NumFmt, qt.Equals, "#,##0.00")
+		cell, err = sheet.Cell(1, 5)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.NumFmt, qt.Equals, "yyyy-mm-dd")
+		c.Assert(cell.IsTime(), qt.Equals, true)
+		cell, err = sheet.Cell(1, 6)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Value, qt.Equals, "code:X1")
+		cell, err = sheet.Cell(2, 11)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Value, qt.Equals, "")
+
+		c.Assert(*sheet.Col(0).Width, qt.Equals, 20.0)
+		c.Assert(*sheet.Col(3).Width, qt.Equals, 14.0)
+		c.Assert(sheet.Col(1), qt.IsNil)
+	})
+
+	csRunO(c, "RoundTrip", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Records")
+		c.Assert(err, qt.IsNil)
+		err = sheet.WriteStructs(&records, WriteOptions{})
+		c.Assert(err, qt.IsNil)
+
+		var buf bytes.Buffer
+		err = f.Write(&buf)
+		c.Assert(err, qt.IsNil)
+		f2, err := OpenBinary(buf.Bytes(), option)
+		c.Assert(err, qt.IsNil)
+
+		var read []record
+		err = f2.Sheets[0].ReadStructs(&read, ReadOptions{})
+		c.Assert(err, qt.IsNil)
+		c.Assert(read, qt.HasLen, 2)
+
+		expected := records[0]
+		expected.Secret = ""
+		c.Assert(read[0].Name, qt.Equals, expected.Name)
+		c.Assert(read[0].Age, qt.Equals, expected.Age)
+		c.Assert(read[0].Rate, qt.Equals, expected.Rate)
+		c.Assert(read[0].Amount, qt.Equals, expected.Amount)
+		c.Assert(read[0].Active, qt.Equals, expected.Active)
+		c.Assert(read[0].Joined.Equal(expected.Joined), qt.Equals, true)
+		c.Assert(read[0].Code, qt.Equals, expected.Code)
+		c.Assert(read[0].Stars, qt.Equals, expected.Stars)
+		c.Assert(*read[0].Nickname, qt.Equals, nick)
+		c.Assert(read[0].Count, qt.Equals, expected.Count)
+		c.Assert(read[0].Secret, qt.Equals, "")
+		c.Assert(read[0].Home, qt.Equals, expected.Home)
+		c.Assert(*read[0].Work, qt.Equals, *expected.Work)
+
+		c.Assert(read[1].Name, qt.Equals, "Alice")
+		c.Assert(read[1].Nickname, qt.IsNil)
+		c.Assert(read[1].Stars.Valid, qt.Equals, false)
+		c.Assert(read[1].Joined.Equal(records[1].Joined), qt.Equals, true)
+
+		// Column widths survive the trip to disk and back.
+		c.Assert(*f2.Sheets[0].Col(0).Width, qt.Equals, 20.0)
+		c.Assert(*f2.Sheets[0].Col(3).Width, qt.Equals, 14.0)
+	})
+
+	csRunO(c, "SkipHeader", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, _ := f.AddSheet("Records")
+		err := sheet.WriteStructs(records[:1], WriteOptions{SkipHeader: true})
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.MaxRow, qt.Equals, 1)
+		cell, _ := sheet.Cell(0, 0)
+		c.Assert(cell.Value, qt.Equals, "Robert")
+	})
+
+	csRunO(c, "Errors", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, _ := f.AddSheet("Records")
+
+		err := sheet.WriteStructs(42, WriteOptions{})
+		c.Assert(err, qt.ErrorMatches, "WriteStructs: expected a slice of structs, got int")
+
+		err = sheet.WriteStructs([]int{1}, WriteOptions{})
+		c.Assert(err, qt.ErrorMatches, "WriteStructs: expected a slice of structs, got a slice of int")
+
+		type unsupported struct {
+			Values []int
+		}
+		err = sheet.WriteStructs([]unsupported{{Values: []int{1}}}, WriteOptions{})
+		c.Assert(err, qt.ErrorMatches, `WriteStructs: row 2, column "Values": unsupported type \[\]int`)
+
+		type badTag struct {
+			Value int `xlsx:"width=wide"`
+		}
+		err = sheet.WriteStructs([]badTag{{}}, WriteOptions{})
+		c.Assert(err, qt.ErrorMatches, `WriteStructs: field Value: invalid width in tag .*`)
+
+		err = sheet.WriteStructs([]*unsupported{nil}, WriteOptions{})
+		c.Assert(err, qt.ErrorMatches, `WriteStructs: element 0 is a nil pointer`)
+	})
+}
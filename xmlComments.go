@@ -0,0 +1,48 @@
+package xlsx
+
+import (
+	"encoding/xml"
+)
+
+// xlsxComments directly maps the comments element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxComments struct {
+	XMLName     xml.Name      `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main comments"`
+	Authors     []string      `xml:"authors>author"`
+	CommentList []xlsxComment `xml:"commentList>comment"`
+}
+
+// xlsxComment directly maps the comment element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxComment struct {
+	Ref      string `xml:"ref,attr"`
+	AuthorId int    `xml:"authorId,attr"`
+	Text     xlsxSI `xml:"text"`
+}
+
+// vmlDrawing maps the parts of a VML drawing that describe the
+// shapes of notes.  VML written by Excel isn't always well formed
+// XML, so elements are matched by their local names only and the
+// drawing should be decoded leniently.
+type vmlDrawing struct {
+	Shapes []vmlShape `xml:"shape"`
+}
+
+// vmlShape maps the shape element of a VML drawing.
+type vmlShape struct {
+	Style      string         `xml:"style,attr"`
+	ClientData *vmlClientData `xml:"ClientData"`
+}
+
+// vmlClientData maps the ClientData element of a VML shape, which
+// ties the shape of a note to its cell.
+type vmlClientData struct {
+	ObjectType string    `xml:"ObjectType,attr"`
+	Row        int       `xml:"Row"`
+	Column     int       `xml:"Column"`
+	Visible    *struct{} `xml:"Visible"`
+}
@@ -0,0 +1,67 @@
+package xlsx
+
+import (
+	"encoding/xml"
+)
+
+// xlsxWsDr directly maps the wsDr element in the namespace
+// http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxWsDr struct {
+	XMLName xml.Name            `xml:"http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing wsDr"`
+	Anchors []xlsxDrawingAnchor `xml:",any"`
+}
+
+// xlsxDrawingAnchor maps the oneCellAnchor, twoCellAnchor and
+// absoluteAnchor elements of a drawing, which are told apart by
+// XMLName.
+type xlsxDrawingAnchor struct {
+	XMLName xml.Name
+	From    *xlsxMarker       `xml:"from"`
+	To      *xlsxMarker       `xml:"to"`
+	Pos     *xlsxPoint        `xml:"pos"`
+	Ext     *xlsxPositiveSize `xml:"ext"`
+	Pic     *xlsxPic          `xml:"pic"`
+}
+
+// xlsxMarker maps the from and to elements of a drawing anchor.
+// Offsets are in EMUs.
+type xlsxMarker struct {
+	Col    int   `xml:"col"`
+	ColOff int64 `xml:"colOff"`
+	Row    int   `xml:"row"`
+	RowOff int64 `xml:"rowOff"`
+}
+
+// xlsxPoint maps the pos element of an absolute anchor.
+type xlsxPoint struct {
+	X int64 `xml:"x,attr"`
+	Y int64 `xml:"y,attr"`
+}
+
+// xlsxPositiveSize maps the ext elements of a drawing.
+type xlsxPositiveSize struct {
+	Cx int64 `xml:"cx,attr"`
+	Cy int64 `xml:"cy,attr"`
+}
+
+// xlsxPic maps the pic element of a drawing anchor.
+type xlsxPic struct {
+	NvPicPr struct {
+		CNvPr struct {
+			Name  string `xml:"name,attr"`
+			Descr string `xml:"descr,attr"`
+		} `xml:"cNvPr"`
+	} `xml:"nvPicPr"`
+	BlipFill struct {
+		Blip struct {
+			Embed string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships embed,attr"`
+		} `xml:"blip"`
+	} `xml:"blipFill"`
+	SpPr struct {
+		Xfrm *struct {
+			Ext *xlsxPositiveSize `xml:"ext"`
+		} `xml:"xfrm"`
+	} `xml:"spPr"`
+}
@@ -0,0 +1,279 @@
+package xlsx
+
+import (
+	"encoding/xml"
+)
+
+// xlsxPivotCaches directly maps the pivotCaches element of a workbook
+// in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxPivotCaches struct {
+	PivotCache []xlsxPivotCache `xml:"pivotCache"`
+}
+
+// xlsxPivotCache directly maps the pivotCache element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxPivotCache struct {
+	CacheId int    `xml:"cacheId,attr"`
+	Id      string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+}
+
+// xlsxPivotCacheDefinition directly maps the pivotCacheDefinition
+// element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxPivotCacheDefinition struct {
+	XMLName               xml.Name             `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main pivotCacheDefinition"`
+	RID                   string               `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	RefreshOnLoad         bool                 `xml:"refreshOnLoad,attr,omitempty"`
+	CreatedVersion        int                  `xml:"createdVersion,attr,omitempty"`
+	RefreshedVersion      int                  `xml:"refreshedVersion,attr,omitempty"`
+	MinRefreshableVersion int                  `xml:"minRefreshableVersion,attr,omitempty"`
+	RecordCount           int                  `xml:"recordCount,attr"`
+	CacheSource           xlsxPivotCacheSource `xml:"cacheSource"`
+	CacheFields           xlsxPivotCacheFields `xml:"cacheFields"`
+}
+
+// xlsxPivotCacheSource directly maps the cacheSource element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxPivotCacheSource struct {
+	Type            string                    `xml:"type,attr"`
+	WorksheetSource *xlsxPivotWorksheetSource `xml:"worksheetSource"`
+}
+
+// xlsxPivotWorksheetSource directly maps the worksheetSource element
+// in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxPivotWorksheetSource struct {
+	Ref   string `xml:"ref,attr,omitempty"`
+	Name  string `xml:"name,attr,omitempty"`
+	Sheet string `xml:"sheet,attr,omitempty"`
+}
+
+// xlsxPivotCacheFields directly maps the cacheFields element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxPivotCacheFields struct {
+	Count      int                   `xml:"count,attr"`
+	CacheField []xlsxPivotCacheField `xml:"cacheField"`
+}
+
+// xlsxPivotCacheField directly maps the cacheField element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxPivotCacheField struct {
+	Name          string               `xml:"name,attr"`
+	NumFmtId      int                  `xml:"numFmtId,attr"`
+	Formula       string               `xml:"formula,attr,omitempty"`
+	DatabaseField *bool                `xml:"databaseField,attr"`
+	SharedItems   xlsxPivotSharedItems `xml:"sharedItems"`
+}
+
+// xlsxPivotSharedItems directly maps the sharedItems element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+// Items holds the s, n, b, d, e and m elements in the order they
+// appear.
+type xlsxPivotSharedItems struct {
+	ContainsSemiMixedTypes *bool           `xml:"containsSemiMixedTypes,attr"`
+	ContainsNonDate        *bool           `xml:"containsNonDate,attr"`
+	ContainsDate           bool            `xml:"containsDate,attr,omitempty"`
+	ContainsString         *bool           `xml:"containsString,attr"`
+	ContainsBlank          bool            `xml:"containsBlank,attr,omitempty"`
+	ContainsMixedTypes     bool            `xml:"containsMixedTypes,attr,omitempty"`
+	ContainsNumber         bool            `xml:"containsNumber,attr,omitempty"`
+	ContainsInteger        bool            `xml:"containsInteger,attr,omitempty"`
+	MinValue               *float64        `xml:"minValue,attr"`
+	MaxValue               *float64        `xml:"maxValue,attr"`
+	MinDate                string          `xml:"minDate,attr,omitempty"`
+	MaxDate                string          `xml:"maxDate,attr,omitempty"`
+	Count                  int             `xml:"count,attr,omitempty"`
+	Items                  []xlsxPivotItem `xml:",any"`
+}
+
+// xlsxPivotItem maps the s, n, b, d, e, m and x elements that hold
+// the values of a pivot cache, the element name giving the type of
+// the value.
+type xlsxPivotItem struct {
+	XMLName xml.Name
+	V       string `xml:"v,attr,omitempty"`
+}
+
+// xlsxPivotCacheRecords directly maps the pivotCacheRecords element
+// in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxPivotCacheRecords struct {
+	XMLName xml.Name               `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main pivotCacheRecords"`
+	Count   int                    `xml:"count,attr"`
+	R       []xlsxPivotCacheRecord `xml:"r"`
+}
+
+// xlsxPivotCacheRecord directly maps the r element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxPivotCacheRecord struct {
+	Items []xlsxPivotItem `xml:",any"`
+}
+
+// xlsxPivotTableDefinition directly maps the pivotTableDefinition
+// element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxPivotTableDefinition struct {
+	XMLName               xml.Name                 `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main pivotTableDefinition"`
+	Name                  string                   `xml:"name,attr"`
+	CacheId               int                      `xml:"cacheId,attr"`
+	DataOnRows            bool                     `xml:"dataOnRows,attr,omitempty"`
+	DataCaption           string                   `xml:"dataCaption,attr"`
+	UpdatedVersion        int                      `xml:"updatedVersion,attr,omitempty"`
+	MinRefreshableVersion int                      `xml:"minRefreshableVersion,attr,omitempty"`
+	UseAutoFormatting     bool                     `xml:"useAutoFormatting,attr,omitempty"`
+	ItemPrintTitles       bool                     `xml:"itemPrintTitles,attr,omitempty"`
+	CreatedVersion        int                      `xml:"createdVersion,attr,omitempty"`
+	Indent                *int                     `xml:"indent,attr"`
+	Compact               *bool                    `xml:"compact,attr"`
+	CompactData           *bool                    `xml:"compactData,attr"`
+	Location              xlsxPivotLocation        `xml:"location"`
+	PivotFields           xlsxPivotFields          `xml:"pivotFields"`
+	RowFields             *xlsxPivotFieldRefs      `xml:"rowFields"`
+	RowItems              *xlsxPivotItems          `xml:"rowItems"`
+	ColFields             *xlsxPivotFieldRefs      `xml:"colFields"`
+	ColItems              *xlsxPivotItems          `xml:"colItems"`
+	PageFields            *xlsxPivotPageFields     `xml:"pageFields"`
+	DataFields            *xlsxPivotDataFields     `xml:"dataFields"`
+	PivotTableStyleInfo   *xlsxPivotTableStyleInfo `xml:"pivotTableStyleInfo"`
+}
+
+// xlsxPivotLocation directly maps the location element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxPivotLocation struct {
+	Ref            string `xml:"ref,attr"`
+	FirstHeaderRow int    `xml:"firstHeaderRow,attr"`
+	FirstDataRow   int    `xml:"firstDataRow,attr"`
+	FirstDataCol   int    `xml:"firstDataCol,attr"`
+	RowPageCount   int    `xml:"rowPageCount,attr,omitempty"`
+	ColPageCount   int    `xml:"colPageCount,attr,omitempty"`
+}
+
+// xlsxPivotFields directly maps the pivotFields element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxPivotFields struct {
+	Count      int              `xml:"count,attr"`
+	PivotField []xlsxPivotField `xml:"pivotField"`
+}
+
+// xlsxPivotField directly maps the pivotField element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxPivotField struct {
+	Axis            string               `xml:"axis,attr,omitempty"`
+	DataField       bool                 `xml:"dataField,attr,omitempty"`
+	Compact         *bool                `xml:"compact,attr"`
+	Outline         *bool                `xml:"outline,attr"`
+	ShowAll         *bool                `xml:"showAll,attr"`
+	DefaultSubtotal *bool                `xml:"defaultSubtotal,attr"`
+	Items           *xlsxPivotFieldItems `xml:"items"`
+}
+
+// xlsxPivotFieldItems directly maps the items element of a pivot
+// field in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxPivotFieldItems struct {
+	Count int                  `xml:"count,attr"`
+	Item  []xlsxPivotFieldItem `xml:"item"`
+}
+
+// xlsxPivotFieldItem directly maps the item element of a pivot field
+// in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.  X is
+// the index of the shared item of the cache field, and T the type of
+// the item, which is empty for one that holds a value.
+type xlsxPivotFieldItem struct {
+	X *int   `xml:"x,attr"`
+	T string `xml:"t,attr,omitempty"`
+	H bool   `xml:"h,attr,omitempty"`
+}
+
+// xlsxPivotFieldRefs directly maps the rowFields and colFields
+// elements in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxPivotFieldRefs struct {
+	Count int                 `xml:"count,attr"`
+	Field []xlsxPivotFieldRef `xml:"field"`
+}
+
+// xlsxPivotFieldRef directly maps the field element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.  An X of
+// -2 refers to the data fields rather than to a pivot field.
+type xlsxPivotFieldRef struct {
+	X int `xml:"x,attr"`
+}
+
+// xlsxPivotItems directly maps the rowItems and colItems elements in
+// the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main, which
+// list the rows and columns of a pivot table as it is shown.
+type xlsxPivotItems struct {
+	Count int            `xml:"count,attr"`
+	I     []xlsxPivotRow `xml:"i"`
+}
+
+// xlsxPivotRow directly maps the i element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.  T is
+// the type of the row or column, R the number of its leading items
+// that are the same as those of the one before, I the index of its
+// data field and X the indexes of its remaining items.
+type xlsxPivotRow struct {
+	T string          `xml:"t,attr,omitempty"`
+	R int             `xml:"r,attr,omitempty"`
+	I int             `xml:"i,attr,omitempty"`
+	X []xlsxPivotRowX `xml:"x"`
+}
+
+// xlsxPivotRowX directly maps the x element of an i element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxPivotRowX struct {
+	V int `xml:"v,attr,omitempty"`
+}
+
+// xlsxPivotPageFields directly maps the pageFields element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxPivotPageFields struct {
+	Count     int                  `xml:"count,attr"`
+	PageField []xlsxPivotPageField `xml:"pageField"`
+}
+
+// xlsxPivotPageField directly maps the pageField element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxPivotPageField struct {
+	Fld  int  `xml:"fld,attr"`
+	Item *int `xml:"item,attr"`
+	Hier int  `xml:"hier,attr"`
+}
+
+// xlsxPivotDataFields directly maps the dataFields element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxPivotDataFields struct {
+	Count     int                  `xml:"count,attr"`
+	DataField []xlsxPivotDataField `xml:"dataField"`
+}
+
+// xlsxPivotDataField directly maps the dataField element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxPivotDataField struct {
+	Name      string `xml:"name,attr,omitempty"`
+	Fld       int    `xml:"fld,attr"`
+	Subtotal  string `xml:"subtotal,attr,omitempty"`
+	BaseField int    `xml:"baseField,attr"`
+	BaseItem  int    `xml:"baseItem,attr"`
+	NumFmtId  int    `xml:"numFmtId,attr,omitempty"`
+}
+
+// xlsxPivotTableStyleInfo directly maps the pivotTableStyleInfo
+// element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxPivotTableStyleInfo struct {
+	Name           string `xml:"name,attr,omitempty"`
+	ShowRowHeaders bool   `xml:"showRowHeaders,attr"`
+	ShowColHeaders bool   `xml:"showColHeaders,attr"`
+	ShowRowStripes bool   `xml:"showRowStripes,attr"`
+	ShowColStripes bool   `xml:"showColStripes,attr"`
+	ShowLastColumn bool   `xml:"showLastColumn,attr"`
+}
@@ -228,6 +228,7 @@ func (styles *xlsxStyleSheet) reset() {
 	styles.numFmtRefTableMU.Lock()
 	styles.numFmtRefTable = nil
 	styles.numFmtRefTableMU.Unlock()
+	styles.DXfs = xlsxDXFs{}
 }
 
 //
@@ -236,6 +237,12 @@ func (styles *xlsxStyleSheet) populateStyleFromXf(style *Style, xf xlsxXf) {
 	style.ApplyFill = xf.ApplyFill
 	style.ApplyFont = xf.ApplyFont
 	style.ApplyAlignment = xf.ApplyAlignment
+	style.ApplyProtection = xf.ApplyProtection || xf.Protection != nil
+	style.Protection = *DefaultProtection()
+	if xf.Protection != nil {
+		style.Protection.Locked = xf.Protection.isLocked()
+		style.Protection.Hidden = xf.Protection.Hidden
+	}
 
 	if xf.BorderId > -1 && xf.BorderId < styles.Borders.Count {
 		var border xlsxBorder
@@ -304,7 +311,7 @@ func (styles *xlsxStyleSheet) getStyle(styleIndex int) *Style {
 		return style
 	}
 
-	style = &Style{}
+	style = &Style{Protection: *DefaultProtection()}
 
 	xfCount := styles.CellXfs.Count
 	if styleIndex > -1 && xfCount > 0 && styleIndex < xfCount {
@@ -403,6 +410,60 @@ func (styles *xlsxStyleSheet) addFont(xFont xlsxFont) (index int) {
 	return
 }
 
+// addDxf adds xDxf to the differential formats, unless an equal one
+// is there already, and returns its index.
+func (styles *xlsxStyleSheet) addDxf(xDxf xlsxDxf) int {
+	for index, dxf := range styles.DXfs.Dxf {
+		if dxf.Equals(xDxf) {
+			return index
+		}
+	}
+	styles.DXfs.Dxf = append(styles.DXfs.Dxf, xDxf)
+	styles.DXfs.Count = len(styles.DXfs.Dxf)
+	return styles.DXfs.Count - 1
+}
+
+// getDxfStyle returns the Style of the differential format with the
+// index dxfId, or nil if there is no such format.  Only the parts of
+// the Style that the format changes are set.
+func (styles *xlsxStyleSheet) getDxfStyle(dxfId int) *Style {
+	if dxfId < 0 || dxfId >= len(styles.DXfs.Dxf) {
+		return nil
+	}
+	dxf := styles.DXfs.Dxf[dxfId]
+	style := &Style{}
+	if xFont := dxf.Font; xFont != nil {
+		style.ApplyFont = true
+		style.Font.Color = styles.argbValue(xFont.Color)
+		style.Font.Bold = xFont.B != nil && xFont.B.Val != "0"
+		style.Font.Italic = xFont.I != nil && xFont.I.Val != "0"
+		style.Font.Underline = xFont.U != nil && xFont.U.Val != "0"
+		style.Font.Strike = xFont.Strike != nil && xFont.Strike.Val != "0"
+	}
+	if xFill := dxf.Fill; xFill != nil {
+		style.ApplyFill = true
+		style.Fill.PatternType = xFill.PatternFill.PatternType
+		style.Fill.FgColor = styles.argbValue(xFill.PatternFill.FgColor)
+		style.Fill.BgColor = styles.argbValue(xFill.PatternFill.BgColor)
+		if style.Fill.PatternType == "" || (style.Fill.PatternType == Solid_Cell_Fill && style.Fill.FgColor == "") {
+			style.Fill.PatternType = Solid_Cell_Fill
+			style.Fill.FgColor, style.Fill.BgColor = style.Fill.BgColor, ""
+		}
+	}
+	if xBorder := dxf.Border; xBorder != nil {
+		style.ApplyBorder = true
+		style.Border.Left = xBorder.Left.Style
+		style.Border.LeftColor = styles.argbValue(xBorder.Left.Color)
+		style.Border.Right = xBorder.Right.Style
+		style.Border.RightColor = styles.argbValue(xBorder.Right.Color)
+		style.Border.Top = xBorder.Top.Style
+		style.Border.TopColor = styles.argbValue(xBorder.Top.Color)
+		style.Border.Bottom = xBorder.Bottom.Style
+		style.Border.BottomColor = styles.argbValue(xBorder.Bottom.Color)
+	}
+	return style
+}
+
 func (styles *xlsxStyleSheet) addFill(xFill xlsxFill) (index int) {
 	var fill xlsxFill
 	for index, fill = range styles.Fills.Fill {
@@ -579,11 +640,94 @@ func (styles *xlsxStyleSheet) Marshal() (string, error) {
 		result += xcellStyles
 	}
 
+	xdxfs, err := styles.DXfs.Marshal()
+	if err != nil {
+		return "", err
+	}
+	result += xdxfs
+
 	return result + "</styleSheet>", nil
 }
 
+// xlsxDXFs directly maps the dxfs element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main, which
+// holds the differential formats applied by conditional formatting.
 type xlsxDXFs struct {
-	Count int `xml:"count,attr"`
+	Count int       `xml:"count,attr"`
+	Dxf   []xlsxDxf `xml:"dxf,omitempty"`
+}
+
+func (dxfs *xlsxDXFs) Marshal() (result string, err error) {
+	if len(dxfs.Dxf) == 0 {
+		return "", nil
+	}
+	result = fmt.Sprintf(`<dxfs count="%d">`, len(dxfs.Dxf))
+	for _, dxf := range dxfs.Dxf {
+		var xdxf string
+		xdxf, err = dxf.Marshal()
+		if err != nil {
+			return
+		}
+		result += xdxf
+	}
+	return result + `</dxfs>`, nil
+}
+
+// xlsxDxf directly maps the dxf element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.  Only the
+// parts of a format that it changes are present.
+type xlsxDxf struct {
+	Font   *xlsxFont   `xml:"font,omitempty"`
+	Fill   *xlsxFill   `xml:"fill,omitempty"`
+	Border *xlsxBorder `xml:"border,omitempty"`
+}
+
+func (dxf *xlsxDxf) Equals(other xlsxDxf) bool {
+	if (dxf.Font == nil) != (other.Font == nil) || (dxf.Font != nil && !dxf.Font.Equals(*other.Font)) {
+		return false
+	}
+	if (dxf.Fill == nil) != (other.Fill == nil) || (dxf.Fill != nil && !dxf.Fill.Equals(*other.Fill)) {
+		return false
+	}
+	if (dxf.Border == nil) != (other.Border == nil) || (dxf.Border != nil && !dxf.Border.Equals(*other.Border)) {
+		return false
+	}
+	return true
+}
+
+// A solid fill in a dxf is written, as Excel does, without a pattern
+// type and with its color as the background color.
+func (dxf *xlsxDxf) Marshal() (result string, err error) {
+	result = `<dxf>`
+	if dxf.Font != nil {
+		var xfont string
+		xfont, err = dxf.Font.Marshal()
+		if err != nil {
+			return
+		}
+		result += xfont
+	}
+	if dxf.Fill != nil {
+		if dxf.Fill.PatternFill.PatternType == "" {
+			result += fmt.Sprintf(`<fill><patternFill><bgColor rgb="%s"/></patternFill></fill>`, dxf.Fill.PatternFill.BgColor.RGB)
+		} else {
+			var xfill string
+			xfill, err = dxf.Fill.Marshal()
+			if err != nil {
+				return
+			}
+			result += xfill
+		}
+	}
+	if dxf.Border != nil {
+		var xborder string
+		xborder, err = dxf.Border.Marshal()
+		if err != nil {
+			return
+		}
+		result += xborder
+	}
+	return result + `</dxf>`, nil
 }
 
 // xlsxNumFmts directly maps the numFmts element in the namespace
@@ -1060,18 +1204,19 @@ func (cellXfs *xlsxCellXfs) Marshal(outputBorderMap, outputFillMap, outputFontMa
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxXf struct {
-	ApplyAlignment    bool          `xml:"applyAlignment,attr"`
-	ApplyBorder       bool          `xml:"applyBorder,attr"`
-	ApplyFont         bool          `xml:"applyFont,attr"`
-	ApplyFill         bool          `xml:"applyFill,attr"`
-	ApplyNumberFormat bool          `xml:"applyNumberFormat,attr"`
-	ApplyProtection   bool          `xml:"applyProtection,attr"`
-	BorderId          int           `xml:"borderId,attr"`
-	FillId            int           `xml:"fillId,attr"`
-	FontId            int           `xml:"fontId,attr"`
-	NumFmtId          int           `xml:"numFmtId,attr"`
-	XfId              *int          `xml:"xfId,attr,omitempty"`
-	Alignment         xlsxAlignment `xml:"alignment"`
+	ApplyAlignment    bool            `xml:"applyAlignment,attr"`
+	ApplyBorder       bool            `xml:"applyBorder,attr"`
+	ApplyFont         bool            `xml:"applyFont,attr"`
+	ApplyFill         bool            `xml:"applyFill,attr"`
+	ApplyNumberFormat bool            `xml:"applyNumberFormat,attr"`
+	ApplyProtection   bool            `xml:"applyProtection,attr"`
+	BorderId          int             `xml:"borderId,attr"`
+	FillId            int             `xml:"fillId,attr"`
+	FontId            int             `xml:"fontId,attr"`
+	NumFmtId          int             `xml:"numFmtId,attr"`
+	XfId              *int            `xml:"xfId,attr,omitempty"`
+	Alignment         xlsxAlignment   `xml:"alignment"`
+	Protection        *xlsxProtection `xml:"protection,omitempty"`
 }
 
 func (xf *xlsxXf) Equals(other xlsxXf) bool {
@@ -1087,7 +1232,8 @@ func (xf *xlsxXf) Equals(other xlsxXf) bool {
 		(xf.XfId == other.XfId ||
 			((xf.XfId != nil && other.XfId != nil) &&
 				*xf.XfId == *other.XfId)) &&
-		xf.Alignment.Equals(other.Alignment)
+		xf.Alignment.Equals(other.Alignment) &&
+		xf.Protection.Equals(other.Protection)
 }
 
 func (xf *xlsxXf) Marshal(outputBorderMap, outputFillMap, outputFontMap map[int]int) (result string, err error) {
@@ -1100,7 +1246,11 @@ func (xf *xlsxXf) Marshal(outputBorderMap, outputFillMap, outputFontMap map[int]
 	if err != nil {
 		return result, err
 	}
-	return result + xAlignment + "</xf>", nil
+	result += xAlignment
+	if xf.Protection != nil {
+		result += xf.Protection.Marshal()
+	}
+	return result + "</xf>", nil
 }
 
 type xlsxAlignment struct {
@@ -1131,6 +1281,31 @@ func (alignment *xlsxAlignment) Marshal() (result string, err error) {
 	return fmt.Sprintf(`<alignment horizontal="%s" indent="%d" shrinkToFit="%b" textRotation="%d" vertical="%s" wrapText="%b"/>`, alignment.Horizontal, alignment.Indent, bool2Int(alignment.ShrinkToFit), alignment.TextRotation, alignment.Vertical, bool2Int(alignment.WrapText)), nil
 }
 
+// xlsxProtection directly maps the protection element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+// Cells are locked unless Locked says otherwise.
+type xlsxProtection struct {
+	Locked *bool `xml:"locked,attr,omitempty"`
+	Hidden bool  `xml:"hidden,attr,omitempty"`
+}
+
+func (protection *xlsxProtection) Equals(other *xlsxProtection) bool {
+	if protection == nil || other == nil {
+		return protection == other
+	}
+	return protection.isLocked() == other.isLocked() &&
+		protection.Hidden == other.Hidden
+}
+
+// isLocked reports whether the cells are locked.
+func (protection *xlsxProtection) isLocked() bool {
+	return protection.Locked == nil || *protection.Locked
+}
+
+func (protection *xlsxProtection) Marshal() string {
+	return fmt.Sprintf(`<protection locked="%b" hidden="%b"/>`, bool2Int(protection.isLocked()), bool2Int(protection.Hidden))
+}
+
 func bool2Int(b bool) int {
 	if b {
 		return 1
@@ -42,6 +42,7 @@ type xlsxWorkbook struct {
 	Sheets             xlsxSheets             `xml:"sheets"`
 	DefinedNames       xlsxDefinedNames       `xml:"definedNames"`
 	CalcPr             xlsxCalcPr             `xml:"calcPr"`
+	PivotCaches        *xlsxPivotCaches       `xml:"pivotCaches"`
 }
 
 // xlsxWorkbookProtection directly maps the workbookProtection element from the
@@ -49,7 +50,13 @@ type xlsxWorkbook struct {
 // - currently I have not checked it for completeness - it does as
 // much as I need.
 type xlsxWorkbookProtection struct {
-	// We don't need this, yet.
+	WorkbookPassword      string `xml:"workbookPassword,attr,omitempty"`
+	LockStructure         bool   `xml:"lockStructure,attr,omitempty"`
+	LockWindows           bool   `xml:"lockWindows,attr,omitempty"`
+	WorkbookAlgorithmName string `xml:"workbookAlgorithmName,attr,omitempty"`
+	WorkbookHashValue     string `xml:"workbookHashValue,attr,omitempty"`
+	WorkbookSaltValue     string `xml:"workbookSaltValue,attr,omitempty"`
+	WorkbookSpinCount     int    `xml:"workbookSpinCount,attr,omitempty"`
 }
 
 // xlsxFileVersion directly maps the fileVersion element from the
@@ -141,7 +148,7 @@ type xlsxDefinedName struct {
 	Help              string `xml:"help,attr,omitempty"`
 	ShortcutKey       string `xml:"shortcutKey,attr,omitempty"`
 	StatusBar         string `xml:"statusBar,attr,omitempty"`
-	LocalSheetID      int    `xml:"localSheetId,attr,omitempty"`
+	LocalSheetID      *int   `xml:"localSheetId,attr,omitempty"`
 	FunctionGroupID   int    `xml:"functionGroupId,attr,omitempty"`
 	Function          bool   `xml:"function,attr,omitempty"`
 	Hidden            bool   `xml:"hidden,attr,omitempty"`
@@ -156,11 +163,13 @@ type xlsxDefinedName struct {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxCalcPr struct {
-	CalcId       string  `xml:"calcId,attr,omitempty"`
-	IterateCount int     `xml:"iterateCount,attr,omitempty"`
-	RefMode      string  `xml:"refMode,attr,omitempty"`
-	Iterate      bool    `xml:"iterate,attr,omitempty"`
-	IterateDelta float64 `xml:"iterateDelta,attr,omitempty"`
+	CalcId         string  `xml:"calcId,attr,omitempty"`
+	CalcMode       string  `xml:"calcMode,attr,omitempty"`
+	FullCalcOnLoad bool    `xml:"fullCalcOnLoad,attr,omitempty"`
+	IterateCount   int     `xml:"iterateCount,attr,omitempty"`
+	RefMode        string  `xml:"refMode,attr,omitempty"`
+	Iterate        bool    `xml:"iterate,attr,omitempty"`
+	IterateDelta   float64 `xml:"iterateDelta,attr,omitempty"`
 }
 
 // Helper function to lookup the file corresponding to a xlsxSheet object in the worksheets map
@@ -14,7 +14,16 @@ import (
 type RelationshipType string
 
 const (
-	RelationshipTypeHyperlink RelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink"
+	RelationshipTypeHyperlink            RelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink"
+	RelationshipTypeComments             RelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/comments"
+	RelationshipTypeVMLDrawing           RelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/vmlDrawing"
+	RelationshipTypeDrawing              RelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/drawing"
+	RelationshipTypeImage                RelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"
+	RelationshipTypeChart                RelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/chart"
+	RelationshipTypeTable                RelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/table"
+	RelationshipTypePivotTable           RelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotTable"
+	RelationshipTypePivotCacheDefinition RelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotCacheDefinition"
+	RelationshipTypePivotCacheRecords    RelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotCacheRecords"
 )
 
 type RelationshipTargetMode string
@@ -33,7 +42,7 @@ type xlsxWorksheetRelation struct {
 	Id         string                 `xml:"Id,attr"`
 	Type       RelationshipType       `xml:"Type,attr"`
 	Target     string                 `xml:"Target,attr"`
-	TargetMode RelationshipTargetMode `xml:"TargetMode,attr"`
+	TargetMode RelationshipTargetMode `xml:"TargetMode,attr,omitempty"`
 }
 
 // xlsxWorksheet directly maps the worksheet element in the namespace
@@ -41,22 +50,234 @@ type xlsxWorksheetRelation struct {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxWorksheet struct {
-	XMLName         xml.Name             `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main worksheet"`
-	XMLNSR          string               `xml:"xmlns:r,attr"`
-	SheetPr         xlsxSheetPr          `xml:"sheetPr"`
-	Dimension       xlsxDimension        `xml:"dimension"`
-	SheetViews      xlsxSheetViews       `xml:"sheetViews"`
-	SheetFormatPr   xlsxSheetFormatPr    `xml:"sheetFormatPr"`
-	Cols            *xlsxCols            `xml:"cols,omitempty"`
-	SheetData       xlsxSheetData        `xml:"sheetData"`
-	Hyperlinks      *xlsxHyperlinks      `xml:"hyperlinks,omitempty"`
-	DataValidations *xlsxDataValidations `xml:"dataValidations"`
-	AutoFilter      *xlsxAutoFilter      `xml:"autoFilter,omitempty"`
-	MergeCells      *xlsxMergeCells      `xml:"mergeCells,omitempty"`
-	PrintOptions    *xlsxPrintOptions    `xml:"printOptions,omitempty"`
-	PageMargins     *xlsxPageMargins     `xml:"pageMargins,omitempty"`
-	PageSetUp       *xlsxPageSetUp       `xml:"pageSetup,omitempty"`
-	HeaderFooter    *xlsxHeaderFooter    `xml:"headerFooter,omitempty"`
+	XMLName               xml.Name                    `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main worksheet"`
+	XMLNSR                string                      `xml:"xmlns:r,attr"`
+	SheetPr               xlsxSheetPr                 `xml:"sheetPr"`
+	Dimension             xlsxDimension               `xml:"dimension"`
+	SheetViews            xlsxSheetViews              `xml:"sheetViews"`
+	SheetFormatPr         xlsxSheetFormatPr           `xml:"sheetFormatPr"`
+	Cols                  *xlsxCols                   `xml:"cols,omitempty"`
+	SheetData             xlsxSheetData               `xml:"sheetData"`
+	SheetProtection       *xlsxSheetProtection        `xml:"sheetProtection,omitempty"`
+	ProtectedRanges       *xlsxProtectedRanges        `xml:"protectedRanges,omitempty"`
+	AutoFilter            *xlsxAutoFilter             `xml:"autoFilter,omitempty"`
+	MergeCells            *xlsxMergeCells             `xml:"mergeCells,omitempty"`
+	ConditionalFormatting []xlsxConditionalFormatting `xml:"conditionalFormatting,omitempty"`
+	DataValidations       *xlsxDataValidations        `xml:"dataValidations"`
+	Hyperlinks            *xlsxHyperlinks             `xml:"hyperlinks,omitempty"`
+	PrintOptions          *xlsxPrintOptions           `xml:"printOptions,omitempty"`
+	PageMargins           *xlsxPageMargins            `xml:"pageMargins,omitempty"`
+	PageSetUp             *xlsxPageSetUp              `xml:"pageSetup,omitempty"`
+	HeaderFooter          *xlsxHeaderFooter           `xml:"headerFooter,omitempty"`
+	RowBreaks             *xlsxPageBreaks             `xml:"rowBreaks,omitempty"`
+	ColBreaks             *xlsxPageBreaks             `xml:"colBreaks,omitempty"`
+	Drawing               *xlsxDrawing                `xml:"drawing,omitempty"`
+	LegacyDrawing         *xlsxLegacyDrawing          `xml:"legacyDrawing,omitempty"`
+	TableParts            *xlsxTableParts             `xml:"tableParts,omitempty"`
+	ExtLst                *xlsxExtLst                 `xml:"extLst,omitempty"`
+}
+
+// xlsxSheetProtection directly maps the sheetProtection element in
+// the namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+// Each of the flags is true when the action is forbidden; those that
+// are true unless stated otherwise are pointers.
+type xlsxSheetProtection struct {
+	Password            string `xml:"password,attr,omitempty"`
+	AlgorithmName       string `xml:"algorithmName,attr,omitempty"`
+	HashValue           string `xml:"hashValue,attr,omitempty"`
+	SaltValue           string `xml:"saltValue,attr,omitempty"`
+	SpinCount           int    `xml:"spinCount,attr,omitempty"`
+	Sheet               bool   `xml:"sheet,attr,omitempty"`
+	Objects             bool   `xml:"objects,attr,omitempty"`
+	Scenarios           bool   `xml:"scenarios,attr,omitempty"`
+	FormatCells         *bool  `xml:"formatCells,attr,omitempty"`
+	FormatColumns       *bool  `xml:"formatColumns,attr,omitempty"`
+	FormatRows          *bool  `xml:"formatRows,attr,omitempty"`
+	InsertColumns       *bool  `xml:"insertColumns,attr,omitempty"`
+	InsertRows          *bool  `xml:"insertRows,attr,omitempty"`
+	InsertHyperlinks    *bool  `xml:"insertHyperlinks,attr,omitempty"`
+	DeleteColumns       *bool  `xml:"deleteColumns,attr,omitempty"`
+	DeleteRows          *bool  `xml:"deleteRows,attr,omitempty"`
+	SelectLockedCells   bool   `xml:"selectLockedCells,attr,omitempty"`
+	Sort                *bool  `xml:"sort,attr,omitempty"`
+	AutoFilter          *bool  `xml:"autoFilter,attr,omitempty"`
+	PivotTables         *bool  `xml:"pivotTables,attr,omitempty"`
+	SelectUnlockedCells bool   `xml:"selectUnlockedCells,attr,omitempty"`
+}
+
+// xlsxProtectedRanges directly maps the protectedRanges element in
+// the namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxProtectedRanges struct {
+	ProtectedRange []xlsxProtectedRange `xml:"protectedRange"`
+}
+
+// xlsxProtectedRange directly maps the protectedRange element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxProtectedRange struct {
+	Password      string `xml:"password,attr,omitempty"`
+	Sqref         string `xml:"sqref,attr"`
+	Name          string `xml:"name,attr"`
+	AlgorithmName string `xml:"algorithmName,attr,omitempty"`
+	HashValue     string `xml:"hashValue,attr,omitempty"`
+	SaltValue     string `xml:"saltValue,attr,omitempty"`
+	SpinCount     int    `xml:"spinCount,attr,omitempty"`
+}
+
+// xlsxExtLst directly maps the extLst element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main, which
+// holds the extensions to a worksheet, such as its sparklines.
+type xlsxExtLst struct {
+	Ext []xlsxExt `xml:"ext"`
+}
+
+// xlsxExt directly maps the ext element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.  Each
+// extension is in a namespace of its own, so its content is kept as
+// raw XML.  XMLNSX14 declares the namespace that Excel's extensions
+// are in; it isn't read.
+type xlsxExt struct {
+	URI      string `xml:"uri,attr"`
+	XMLNSX14 string `xml:"xmlns:x14,attr,omitempty"`
+	Content  string `xml:",innerxml"`
+}
+
+// xlsxX14SparklineGroups directly maps the sparklineGroups element in
+// the namespace http://schemas.microsoft.com/office/spreadsheetml/2009/9/main,
+// the content of the worksheet extension that holds its sparklines.
+// It is only used to read sparklines, which are written by
+// makeSparklineExt.
+type xlsxX14SparklineGroups struct {
+	SparklineGroups []xlsxX14SparklineGroup `xml:"sparklineGroup"`
+}
+
+// xlsxX14SparklineGroup directly maps the sparklineGroup element in
+// the namespace http://schemas.microsoft.com/office/spreadsheetml/2009/9/main.
+type xlsxX14SparklineGroup struct {
+	Type                string             `xml:"type,attr"`
+	LineWeight          float64            `xml:"lineWeight,attr"`
+	DisplayEmptyCellsAs string             `xml:"displayEmptyCellsAs,attr"`
+	Markers             bool               `xml:"markers,attr"`
+	High                bool               `xml:"high,attr"`
+	Low                 bool               `xml:"low,attr"`
+	First               bool               `xml:"first,attr"`
+	Last                bool               `xml:"last,attr"`
+	Negative            bool               `xml:"negative,attr"`
+	DisplayXAxis        bool               `xml:"displayXAxis,attr"`
+	DisplayHidden       bool               `xml:"displayHidden,attr"`
+	MinAxisType         string             `xml:"minAxisType,attr"`
+	MaxAxisType         string             `xml:"maxAxisType,attr"`
+	ManualMin           float64            `xml:"manualMin,attr"`
+	ManualMax           float64            `xml:"manualMax,attr"`
+	RightToLeft         bool               `xml:"rightToLeft,attr"`
+	ColorSeries         *xlsxColor         `xml:"colorSeries"`
+	ColorNegative       *xlsxColor         `xml:"colorNegative"`
+	ColorAxis           *xlsxColor         `xml:"colorAxis"`
+	ColorMarkers        *xlsxColor         `xml:"colorMarkers"`
+	ColorFirst          *xlsxColor         `xml:"colorFirst"`
+	ColorLast           *xlsxColor         `xml:"colorLast"`
+	ColorHigh           *xlsxColor         `xml:"colorHigh"`
+	ColorLow            *xlsxColor         `xml:"colorLow"`
+	Sparklines          []xlsxX14Sparkline `xml:"sparklines>sparkline"`
+}
+
+// xlsxX14Sparkline directly maps the sparkline element in the
+// namespace http://schemas.microsoft.com/office/spreadsheetml/2009/9/main.
+// F is the range of the values it plots and Sqref the cell it is
+// drawn in.
+type xlsxX14Sparkline struct {
+	F     string `xml:"f"`
+	Sqref string `xml:"sqref"`
+}
+
+// xlsxConditionalFormatting directly maps the conditionalFormatting
+// element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxConditionalFormatting struct {
+	Sqref  string       `xml:"sqref,attr"`
+	CfRule []xlsxCfRule `xml:"cfRule"`
+}
+
+// xlsxCfRule directly maps the cfRule element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxCfRule struct {
+	Type         string          `xml:"type,attr"`
+	DxfId        *int            `xml:"dxfId,attr,omitempty"`
+	Priority     int             `xml:"priority,attr"`
+	StopIfTrue   bool            `xml:"stopIfTrue,attr,omitempty"`
+	AboveAverage *bool           `xml:"aboveAverage,attr,omitempty"`
+	Percent      bool            `xml:"percent,attr,omitempty"`
+	Bottom       bool            `xml:"bottom,attr,omitempty"`
+	Operator     string          `xml:"operator,attr,omitempty"`
+	Text         string          `xml:"text,attr,omitempty"`
+	TimePeriod   string          `xml:"timePeriod,attr,omitempty"`
+	Rank         int             `xml:"rank,attr,omitempty"`
+	StdDev       int             `xml:"stdDev,attr,omitempty"`
+	EqualAverage bool            `xml:"equalAverage,attr,omitempty"`
+	Formula      []string        `xml:"formula,omitempty"`
+	ColorScale   *xlsxColorScale `xml:"colorScale,omitempty"`
+	DataBar      *xlsxDataBar    `xml:"dataBar,omitempty"`
+	IconSet      *xlsxIconSet    `xml:"iconSet,omitempty"`
+}
+
+// xlsxCfvo directly maps the cfvo element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main, a
+// threshold of a color scale, data bar or icon set.
+type xlsxCfvo struct {
+	Type string `xml:"type,attr"`
+	Val  string `xml:"val,attr,omitempty"`
+}
+
+// xlsxColorScale directly maps the colorScale element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxColorScale struct {
+	Cfvo  []xlsxCfvo  `xml:"cfvo"`
+	Color []xlsxColor `xml:"color"`
+}
+
+// xlsxDataBar directly maps the dataBar element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxDataBar struct {
+	ShowValue *bool      `xml:"showValue,attr,omitempty"`
+	Cfvo      []xlsxCfvo `xml:"cfvo"`
+	Color     xlsxColor  `xml:"color"`
+}
+
+// xlsxIconSet directly maps the iconSet element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxIconSet struct {
+	IconSet   string     `xml:"iconSet,attr,omitempty"`
+	ShowValue *bool      `xml:"showValue,attr,omitempty"`
+	Reverse   bool       `xml:"reverse,attr,omitempty"`
+	Cfvo      []xlsxCfvo `xml:"cfvo"`
+}
+
+// xlsxDrawing directly maps the drawing element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main, which
+// refers to the drawing holding a sheet's pictures and charts.
+type xlsxDrawing struct {
+	RID string `xml:"id,attr"`
+}
+
+// xlsxLegacyDrawing directly maps the legacyDrawing element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main,
+// which refers to the VML drawing holding the shapes of a sheet's
+// notes.
+type xlsxLegacyDrawing struct {
+	RID string `xml:"id,attr"`
+}
+
+// xlsxTableParts directly maps the tableParts element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main, which
+// refers to the parts holding a sheet's tables.
+type xlsxTableParts struct {
+	Count     int             `xml:"count,attr"`
+	TablePart []xlsxTablePart `xml:"tablePart"`
+}
+
+// xlsxTablePart directly maps the tablePart element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxTablePart struct {
+	RID string `xml:"id,attr"`
 }
 
 // xlsxHeaderFooter directly maps the headerFooter element in the namespace
@@ -64,26 +285,35 @@ type xlsxWorksheet struct {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxHeaderFooter struct {
-	DifferentFirst   *bool           `xml:"differentFirst,attr,omitempty"`
-	DifferentOddEven *bool           `xml:"differentOddEven,attr,omitempty"`
-	OddHeader        []xlsxOddHeader `xml:"oddHeader"`
-	OddFooter        []xlsxOddFooter `xml:"oddFooter"`
+	DifferentOddEven *bool   `xml:"differentOddEven,attr,omitempty"`
+	DifferentFirst   *bool   `xml:"differentFirst,attr,omitempty"`
+	ScaleWithDoc     *bool   `xml:"scaleWithDoc,attr,omitempty"`
+	AlignWithMargins *bool   `xml:"alignWithMargins,attr,omitempty"`
+	OddHeader        *string `xml:"oddHeader,omitempty"`
+	OddFooter        *string `xml:"oddFooter,omitempty"`
+	EvenHeader       *string `xml:"evenHeader,omitempty"`
+	EvenFooter       *string `xml:"evenFooter,omitempty"`
+	FirstHeader      *string `xml:"firstHeader,omitempty"`
+	FirstFooter      *string `xml:"firstFooter,omitempty"`
 }
 
-// xlsxOddHeader directly maps the oddHeader element in the namespace
-// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
-// currently I have not checked it for completeness - it does as much
-// as I need.
-type xlsxOddHeader struct {
-	Content string `xml:",chardata"`
+// xlsxPageBreaks directly maps the rowBreaks and colBreaks elements
+// in the namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main.
+type xlsxPageBreaks struct {
+	Count            int             `xml:"count,attr,omitempty"`
+	ManualBreakCount int             `xml:"manualBreakCount,attr,omitempty"`
+	Brk              []xlsxPageBreak `xml:"brk"`
 }
 
-// xlsxOddFooter directly maps the oddFooter element in the namespace
-// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
-// currently I have not checked it for completeness - it does as much
-// as I need.
-type xlsxOddFooter struct {
-	Content string `xml:",chardata"`
+// xlsxPageBreak directly maps the brk element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.  Id is
+// the zero based row or column before which the page breaks.
+type xlsxPageBreak struct {
+	Id  int  `xml:"id,attr,omitempty"`
+	Min int  `xml:"min,attr,omitempty"`
+	Max int  `xml:"max,attr,omitempty"`
+	Man bool `xml:"man,attr,omitempty"`
+	Pt  bool `xml:"pt,attr,omitempty"`
 }
 
 // xlsxPageSetUp directly maps the pageSetup element in the namespace
@@ -91,21 +321,21 @@ type xlsxOddFooter struct {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxPageSetUp struct {
-	PaperSize          string  `xml:"paperSize,attr"`
-	Scale              int     `xml:"scale,attr"`
-	FirstPageNumber    int     `xml:"firstPageNumber,attr"`
-	FitToWidth         int     `xml:"fitToWidth,attr"`
-	FitToHeight        int     `xml:"fitToHeight,attr"`
-	PageOrder          string  `xml:"pageOrder,attr"`
-	Orientation        string  `xml:"orientation,attr"`
-	UsePrinterDefaults bool    `xml:"usePrinterDefaults,attr"`
-	BlackAndWhite      bool    `xml:"blackAndWhite,attr"`
-	Draft              bool    `xml:"draft,attr"`
-	CellComments       string  `xml:"cellComments,attr"`
-	UseFirstPageNumber bool    `xml:"useFirstPageNumber,attr"`
-	HorizontalDPI      float32 `xml:"horizontalDpi,attr"`
-	VerticalDPI        float32 `xml:"verticalDpi,attr"`
-	Copies             int     `xml:"copies,attr"`
+	PaperSize          int    `xml:"paperSize,attr,omitempty"`
+	Scale              int    `xml:"scale,attr,omitempty"`
+	FirstPageNumber    int    `xml:"firstPageNumber,attr,omitempty"`
+	FitToWidth         *int   `xml:"fitToWidth,attr,omitempty"`
+	FitToHeight        *int   `xml:"fitToHeight,attr,omitempty"`
+	PageOrder          string `xml:"pageOrder,attr,omitempty"`
+	Orientation        string `xml:"orientation,attr,omitempty"`
+	UsePrinterDefaults *bool  `xml:"usePrinterDefaults,attr,omitempty"`
+	BlackAndWhite      bool   `xml:"blackAndWhite,attr,omitempty"`
+	Draft              bool   `xml:"draft,attr,omitempty"`
+	CellComments       string `xml:"cellComments,attr,omitempty"`
+	UseFirstPageNumber bool   `xml:"useFirstPageNumber,attr,omitempty"`
+	HorizontalDPI      uint   `xml:"horizontalDpi,attr,omitempty"`
+	VerticalDPI        uint   `xml:"verticalDpi,attr,omitempty"`
+	Copies             int    `xml:"copies,attr,omitempty"`
 }
 
 // xlsxPrintOptions directly maps the printOptions element in the namespace
@@ -113,11 +343,10 @@ type xlsxPageSetUp struct {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxPrintOptions struct {
-	Headings           bool `xml:"headings,attr"`
-	GridLines          bool `xml:"gridLines,attr"`
-	GridLinesSet       bool `xml:"gridLinesSet,attr"`
-	HorizontalCentered bool `xml:"horizontalCentered,attr"`
-	VerticalCentered   bool `xml:"verticalCentered,attr"`
+	HorizontalCentered bool `xml:"horizontalCentered,attr,omitempty"`
+	VerticalCentered   bool `xml:"verticalCentered,attr,omitempty"`
+	Headings           bool `xml:"headings,attr,omitempty"`
+	GridLines          bool `xml:"gridLines,attr,omitempty"`
 }
 
 // xlsxPageMargins directly maps the pageMargins element in the namespace
@@ -159,13 +388,13 @@ type xlsxSheetViews struct {
 type xlsxSheetView struct {
 	WindowProtection        bool            `xml:"windowProtection,attr"`
 	ShowFormulas            bool            `xml:"showFormulas,attr"`
-	ShowGridLines           bool            `xml:"showGridLines,attr"`
-	ShowRowColHeaders       bool            `xml:"showRowColHeaders,attr"`
-	ShowZeros               bool            `xml:"showZeros,attr"`
+	ShowGridLines           *bool           `xml:"showGridLines,attr"`
+	ShowRowColHeaders       *bool           `xml:"showRowColHeaders,attr"`
+	ShowZeros               *bool           `xml:"showZeros,attr"`
 	RightToLeft             bool            `xml:"rightToLeft,attr"`
 	TabSelected             bool            `xml:"tabSelected,attr"`
-	ShowOutlineSymbols      bool            `xml:"showOutlineSymbols,attr"`
-	DefaultGridColor        bool            `xml:"defaultGridColor,attr"`
+	ShowOutlineSymbols      *bool           `xml:"showOutlineSymbols,attr"`
+	DefaultGridColor        *bool           `xml:"defaultGridColor,attr"`
 	View                    string          `xml:"view,attr"`
 	TopLeftCell             string          `xml:"topLeftCell,attr"`
 	ColorId                 int             `xml:"colorId,attr"`
@@ -182,22 +411,22 @@ type xlsxSheetView struct {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxSelection struct {
-	Pane         string `xml:"pane,attr"`
+	Pane         string `xml:"pane,attr,omitempty"`
 	ActiveCell   string `xml:"activeCell,attr"`
 	ActiveCellId int    `xml:"activeCellId,attr"`
 	SQRef        string `xml:"sqref,attr"`
 }
 
-// xlsxSelection directly maps the selection element in the namespace
+// xlsxPane directly maps the pane element in the namespace
 // http://schemas.openxmlformats.org/spreadsheetml/2006/main -
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxPane struct {
-	XSplit      float64 `xml:"xSplit,attr"`
-	YSplit      float64 `xml:"ySplit,attr"`
-	TopLeftCell string  `xml:"topLeftCell,attr"`
-	ActivePane  string  `xml:"activePane,attr"`
-	State       string  `xml:"state,attr"` // Either "split" or "frozen"
+	XSplit      float64 `xml:"xSplit,attr,omitempty"`
+	YSplit      float64 `xml:"ySplit,attr,omitempty"`
+	TopLeftCell string  `xml:"topLeftCell,attr,omitempty"`
+	ActivePane  string  `xml:"activePane,attr,omitempty"`
+	State       string  `xml:"state,attr,omitempty"` // Either "split" or "frozen"
 }
 
 // xlsxSheetPr directly maps the sheetPr element in the namespace
@@ -206,9 +435,19 @@ type xlsxPane struct {
 // as I need.
 type xlsxSheetPr struct {
 	FilterMode  bool              `xml:"filterMode,attr"`
+	OutlinePr   *xlsxOutlinePr    `xml:"outlinePr,omitempty"`
 	PageSetUpPr []xlsxPageSetUpPr `xml:"pageSetUpPr"`
 }
 
+// xlsxOutlinePr directly maps the outlinePr element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.  Summary
+// rows are below, and summary columns right of, their groups unless
+// stated otherwise.
+type xlsxOutlinePr struct {
+	SummaryBelow *bool `xml:"summaryBelow,attr,omitempty"`
+	SummaryRight *bool `xml:"summaryRight,attr,omitempty"`
+}
+
 // xlsxPageSetUpPr directly maps the pageSetupPr element in the namespace
 // http://schemas.openxmlformats.org/spreadsheetml/2006/main -
 // currently I have not checked it for completeness - it does as much
@@ -321,6 +560,7 @@ type xlsxRow struct {
 	Ht           string  `xml:"ht,attr,omitempty"`
 	CustomHeight bool    `xml:"customHeight,attr,omitempty"`
 	OutlineLevel uint8   `xml:"outlineLevel,attr,omitempty"`
+	Collapsed    bool    `xml:"collapsed,attr,omitempty"`
 }
 
 type xlsxAutoFilter struct {
@@ -388,12 +628,13 @@ func (mc *xlsxMergeCells) getExtent(cellRef string) (int, int, error) {
 // as I need.
 type xlsxC struct {
 	XMLName xml.Name
-	R       string  `xml:"r,attr"`           // Cell ID, e.g. A1
-	S       int     `xml:"s,attr,omitempty"` // Style reference.
-	T       string  `xml:"t,attr,omitempty"` // Type.
-	F       *xlsxF  `xml:"f,omitempty"`      // Formula
-	V       string  `xml:"v,omitempty"`      // Value
-	Is      *xlsxSI `xml:"is,omitempty"`     // Inline String.
+	R       string  `xml:"r,attr"`            // Cell ID, e.g. A1
+	S       int     `xml:"s,attr,omitempty"`  // Style reference.
+	T       string  `xml:"t,attr,omitempty"`  // Type.
+	Cm      int     `xml:"cm,attr,omitempty"` // Cell metadata index.
+	F       *xlsxF  `xml:"f,omitempty"`       // Formula
+	V       string  `xml:"v,omitempty"`       // Value
+	Is      *xlsxSI `xml:"is,omitempty"`      // Inline String.
 }
 
 // xlsxF directly maps the f element in the namespace
@@ -404,7 +645,7 @@ type xlsxF struct {
 	Content string `xml:",chardata"`
 	T       string `xml:"t,attr,omitempty"`   // Formula type
 	Ref     string `xml:"ref,attr,omitempty"` // Shared formula ref
-	Si      int    `xml:"si,attr,omitempty"`  // Shared formula index
+	Si      *int   `xml:"si,attr,omitempty"`  // Shared formula index
 }
 
 // Create a new XLSX Worksheet with default values populated.
@@ -418,14 +659,14 @@ func newXlsxWorksheet() (worksheet *xlsxWorksheet) {
 	worksheet.SheetViews.SheetView = make([]xlsxSheetView, 1)
 	worksheet.SheetViews.SheetView[0] = xlsxSheetView{
 		ColorId:                 64,
-		DefaultGridColor:        true,
+		DefaultGridColor:        bPtr(true),
 		RightToLeft:             false,
 		Selection:               make([]xlsxSelection, 1),
 		ShowFormulas:            false,
-		ShowGridLines:           true,
-		ShowOutlineSymbols:      true,
-		ShowRowColHeaders:       true,
-		ShowZeros:               true,
+		ShowGridLines:           bPtr(true),
+		ShowOutlineSymbols:      bPtr(true),
+		ShowRowColHeaders:       bPtr(true),
+		ShowZeros:               bPtr(true),
 		TabSelected:             false,
 		TopLeftCell:             "A1",
 		View:                    "normal",
@@ -572,7 +813,7 @@ func emitStructAsXML(v reflect.Value, name, xmlNS string) (xmlwriter.Elem, error
 				continue
 			}
 
-			if output.Name == "hyperlink" && name == "id" {
+			if relationshipIdElems[output.Name] && name == "id" {
 				// Hack to respect the relationship namespace
 				name = "r:id"
 			}
@@ -598,6 +839,10 @@ func emitStructAsXML(v reflect.Value, name, xmlNS string) (xmlwriter.Elem, error
 			// Skip SheetData here, we explicitly generate
 			// this in writeXML below
 			continue
+		case "ExtLst":
+			// The extensions are raw XML, which only
+			// writeExtLst can write.
+			continue
 		default:
 			if fv.Kind() == reflect.Ptr {
 				if fv.IsNil() {
@@ -615,6 +860,13 @@ func emitStructAsXML(v reflect.Value, name, xmlNS string) (xmlwriter.Elem, error
 			case reflect.Slice:
 				for i := 0; i < fv.Len(); i++ {
 					v := fv.Index(i)
+					if v.Kind() == reflect.String {
+						output.Content = append(output.Content, xmlwriter.Elem{
+							Name:    name,
+							Content: []xmlwriter.Writable{xmlwriter.Text(v.String())},
+						})
+						continue
+					}
 					elem, err := emitStructAsXML(v, name, xmlNS)
 					if err != nil {
 						return output, err
@@ -640,7 +892,7 @@ func emitStructAsXML(v reflect.Value, name, xmlNS string) (xmlwriter.Elem, error
 
 }
 
-func (worksheet *xlsxWorksheet) makeXlsxRowFromRow(row *Row, styles *xlsxStyleSheet, refTable *RefTable) (*xlsxRow, error) {
+func (worksheet *xlsxWorksheet) makeXlsxRowFromRow(row *Row, styles *xlsxStyleSheet, refTable *RefTable, fw *formulaWriter) (*xlsxRow, error) {
 	xRow := &xlsxRow{}
 	xRow.R = row.num + 1
 	if row.isCustom {
@@ -648,6 +900,8 @@ func (worksheet *xlsxWorksheet) makeXlsxRowFromRow(row *Row, styles *xlsxStyleSh
 		xRow.Ht = fmt.Sprintf("%g", row.GetHeight())
 	}
 	xRow.OutlineLevel = row.GetOutlineLevel()
+	xRow.Hidden = row.Hidden
+	xRow.Collapsed = row.Collapsed
 
 	err := row.ForEachCell(func(cell *Cell) error {
 		var XfId int
@@ -675,9 +929,7 @@ func (worksheet *xlsxWorksheet) makeXlsxRowFromRow(row *Row, styles *xlsxStyleSh
 			S: XfId,
 			R: GetCellIDStringFromCoords(cell.num, row.num),
 		}
-		if cell.formula != "" {
-			xC.F = &xlsxF{Content: cell.formula}
-		}
+		fw.write(&xC, cell, row.num)
 		switch cell.cellType {
 		case CellTypeInline:
 			// Inline strings are turned into shared strings since they are more efficient.
@@ -716,6 +968,25 @@ func (worksheet *xlsxWorksheet) makeXlsxRowFromRow(row *Row, styles *xlsxStyleSh
 	return xRow, err
 }
 
+// relationshipIdElems are the worksheet elements whose id attribute
+// is in the relationships namespace.
+var relationshipIdElems = map[string]bool{
+	"hyperlink":     true,
+	"drawing":       true,
+	"legacyDrawing": true,
+	"tablePart":     true,
+}
+
+// sheetDataPredecessors are the worksheet elements that come before
+// sheetData.
+var sheetDataPredecessors = map[string]bool{
+	"sheetPr":       true,
+	"dimension":     true,
+	"sheetViews":    true,
+	"sheetFormatPr": true,
+	"cols":          true,
+}
+
 func (worksheet *xlsxWorksheet) WriteXML(xw *xmlwriter.Writer, s *Sheet, styles *xlsxStyleSheet, refTable *RefTable) (err error) {
 	var output xmlwriter.Elem
 	worksheet.XMLNSR = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
@@ -724,6 +995,23 @@ func (worksheet *xlsxWorksheet) WriteXML(xw *xmlwriter.Writer, s *Sheet, styles
 	if err != nil {
 		return
 	}
+	fw, err := newFormulaWriter(s)
+	if err != nil {
+		return
+	}
+
+	// Only the elements that precede sheetData are written along with
+	// the start of the worksheet, the rest follow the rows.
+	var after []xmlwriter.Writable
+	var before []xmlwriter.Writable
+	for _, content := range output.Content {
+		if elem, ok := content.(xmlwriter.Elem); ok && !sheetDataPredecessors[elem.Name] {
+			after = append(after, content)
+			continue
+		}
+		before = append(before, content)
+	}
+	output.Content = before
 
 	ec := xmlwriter.ErrCollector{}
 	defer ec.Set(&err)
@@ -731,7 +1019,7 @@ func (worksheet *xlsxWorksheet) WriteXML(xw *xmlwriter.Writer, s *Sheet, styles
 		xw.StartElem(output),
 		xw.StartElem(xmlwriter.Elem{Name: "sheetData"}),
 		s.ForEachRow(func(row *Row) error {
-			xRow, err := worksheet.makeXlsxRowFromRow(row, styles, refTable)
+			xRow, err := worksheet.makeXlsxRowFromRow(row, styles, refTable, fw)
 			if err != nil {
 				return err
 			}
@@ -746,11 +1034,49 @@ func (worksheet *xlsxWorksheet) WriteXML(xw *xmlwriter.Writer, s *Sheet, styles
 			}
 			return xw.Flush()
 
-		}, SkipEmptyRows),
+		}, skipUnformattedEmptyRows),
 		xw.EndElem("sheetData"),
+		xw.Write(after...),
+		worksheet.writeExtLst(xw),
 		xw.EndElem(output.Name),
 		xw.Flush(),
 	)
+	s.dynamicArrays = fw.dynamicArrays
 	return
 
 }
+
+// writeExtLst writes the extensions of the worksheet, if it has any.
+func (worksheet *xlsxWorksheet) writeExtLst(xw *xmlwriter.Writer) error {
+	if worksheet.ExtLst == nil {
+		return nil
+	}
+	err := xw.StartElem(xmlwriter.Elem{Name: "extLst"})
+	if err != nil {
+		return err
+	}
+	for _, ext := range worksheet.ExtLst.Ext {
+		elem := xmlwriter.Elem{Name: "ext", Attrs: []xmlwriter.Attr{{Name: "uri", Value: ext.URI}}}
+		if ext.XMLNSX14 != "" {
+			elem.Attrs = append(elem.Attrs, xmlwriter.Attr{Name: "xmlns:x14", Value: ext.XMLNSX14})
+		}
+		err = xw.StartElem(elem)
+		if err != nil {
+			return err
+		}
+		// Raw content is otherwise written into the start tag.
+		err = xw.Next()
+		if err != nil {
+			return err
+		}
+		err = xw.WriteRaw(ext.Content)
+		if err != nil {
+			return err
+		}
+		err = xw.EndElem("ext")
+		if err != nil {
+			return err
+		}
+	}
+	return xw.EndElem("extLst")
+}